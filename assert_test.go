@@ -0,0 +1,31 @@
+package ztex_test
+
+import (
+	"testing"
+
+	"github.com/aljumi/ztex"
+	"github.com/aljumi/ztex/ztextest"
+)
+
+func TestDiffNil(t *testing.T) {
+	c := &ztex.DeviceConfig{}
+	c.DescriptorSerial = ztex.DescriptorSerial{'a'}
+	for _, tc := range []struct {
+		name      string
+		got, want any
+		same      bool
+	}{
+		{"nil and nil", nil, nil, true},
+		{"nil and empty", nil, &ztex.DeviceConfig{}, true},
+		{"empty and nil", ztex.DeviceConfig{}, nil, true},
+		{"nil pointer and empty", (*ztex.DeviceConfig)(nil), &ztex.DeviceConfig{}, true},
+		{"nil and config", nil, c, false},
+		{"config and nil pointer", c, (*ztex.DeviceConfig)(nil), false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if d := ztextest.Diff(tc.got, tc.want); (d == "") != tc.same {
+				t.Errorf("ztextest.Diff: got %q, want equal %v", d, tc.same)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+// Package asset embeds firmware images and bitstreams in Go programs, so
+// that applications which program ZTEX modules can be shipped as single,
+// self-contained binaries.
+//
+// The command ztex-embed, run through go generate, writes a Go file which
+// embeds the given files with the //go:embed directive and declares a
+// variable of type *Firmware or *Bitstream for each, holding its data and
+// metadata:
+//
+//	//go:generate go run github.com/aljumi/ztex/cmd/ztex-embed -o assets.go firmware.ihx top.bit
+//
+// declares the variables FirmwareIhx and TopBit, so that
+//
+//	err := TopBit.Configure(ctx, d, nil)
+//
+// configures the FPGA of a device with the embedded bitstream, after
+// checking that it was built for the FPGA of the device.
+package asset
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aljumi/ztex"
+)
+
+// Metadata describes an embedded file.
+type Metadata struct {
+	// Name is the name of the embedded file.
+	Name string
+
+	// Part is the target of the file: the FPGA a bitstream was built for,
+	// normalized with bitstream.NormalizePart, or the controller a firmware
+	// image is for, "fx2" or "fx3".  It is empty if the target is not
+	// known, in which case it is not checked.
+	Part string
+
+	// SHA256 is the SHA-256 hash of the file, in hexadecimal.
+	SHA256 string
+
+	// BuildTime is when the file was built: the time recorded in the
+	// header of .bit files, and otherwise the time given by
+	// SOURCE_DATE_EPOCH, or zero if it is not known.
+	BuildTime time.Time
+}
+
+// String returns a human-readable description of the metadata.
+func (m Metadata) String() string {
+	x := []string{}
+	x = append(x, fmt.Sprintf("Name(%v)", m.Name))
+	if m.Part != "" {
+		x = append(x, fmt.Sprintf("Part(%v)", m.Part))
+	}
+	x = append(x, fmt.Sprintf("SHA256(%v)", m.SHA256))
+	x = append(x, fmt.Sprintf("BuildTime(%v)", m.BuildTime.Format(time.RFC3339)))
+	return strings.Join(x, ", ")
+}
+
+// check returns an error unless b has the hash of the metadata.
+func (m Metadata) check(b []byte) error {
+	if h := sha256.Sum256(b); hex.EncodeToString(h[:]) != m.SHA256 {
+		return fmt.Errorf("got SHA-256 %x for %v, want %v", h, m.Name, m.SHA256)
+	}
+	return nil
+}
+
+// Firmware is an embedded firmware image: an FX2 image in the Intel HEX
+// format or an FX3 image.
+type Firmware struct {
+	Metadata
+	Data []byte
+}
+
+// Check returns an error unless the data of the image has the hash given
+// by its metadata.
+func (f *Firmware) Check() error {
+	if err := f.Metadata.check(f.Data); err != nil {
+		return fmt.Errorf("(*asset.Firmware).Check: %w", err)
+	}
+	return nil
+}
+
+// Reader returns a reader of the data of the image.
+func (f *Firmware) Reader() io.Reader { return bytes.NewReader(f.Data) }
+
+// checkDevice returns an error unless the image is for the controller of
+// the device.
+func (f *Firmware) checkDevice(d *ztex.Device) error {
+	want := "fx2"
+	if d.Capability().FX3Firmware() {
+		want = "fx3"
+	}
+	if f.Part != "" && f.Part != want {
+		return fmt.Errorf("got firmware for %v, want firmware for %v", f.Part, want)
+	}
+	return nil
+}
+
+// Upload uploads the image into the RAM of the device; see
+// (*ztex.Device).UploadFirmware.
+func (f *Firmware) Upload(ctx context.Context, d *ztex.Device, progress ztex.Progress) error {
+	if err := f.checkDevice(d); err != nil {
+		return fmt.Errorf("(*asset.Firmware).Upload: %w", err)
+	}
+	return d.UploadFirmware(ctx, f.Reader(), progress)
+}
+
+// Install installs the image on the device; see
+// (*ztex.Device).InstallFirmware.
+func (f *Firmware) Install(ctx context.Context, d *ztex.Device, progress ztex.Progress) error {
+	if err := f.checkDevice(d); err != nil {
+		return fmt.Errorf("(*asset.Firmware).Install: %w", err)
+	}
+	return d.InstallFirmware(ctx, f.Reader(), progress)
+}
+
+// Verify reports whether or not the image is installed on the device; see
+// (*ztex.Device).VerifyFirmware.
+func (f *Firmware) Verify(ctx context.Context, d *ztex.Device, progress ztex.Progress) (bool, error) {
+	if err := f.checkDevice(d); err != nil {
+		return false, fmt.Errorf("(*asset.Firmware).Verify: %w", err)
+	}
+	return d.VerifyFirmware(ctx, f.Reader(), progress)
+}
+
+// Bitstream is an embedded bitstream, in either bit order.
+type Bitstream struct {
+	Metadata
+	Data []byte
+}
+
+// Check returns an error unless the data of the bitstream has the hash
+// given by its metadata.
+func (b *Bitstream) Check() error {
+	if err := b.Metadata.check(b.Data); err != nil {
+		return fmt.Errorf("(*asset.Bitstream).Check: %w", err)
+	}
+	return nil
+}
+
+// Reader returns a reader of the data of the bitstream.
+func (b *Bitstream) Reader() io.Reader { return bytes.NewReader(b.Data) }
+
+// checkDevice returns an error unless the bitstream is for the FPGA of the
+// device.
+func (b *Bitstream) checkDevice(d *ztex.Device) error {
+	if b.Part == "" {
+		return nil
+	}
+	return d.CheckPart(b.Part)
+}
+
+// Configure configures the selected FPGA of the device with the bitstream,
+// through the bulk endpoint if the device supports it; see
+// (*ztex.Device).ConfigureFPGAHighSpeed.
+func (b *Bitstream) Configure(ctx context.Context, d *ztex.Device, progress ztex.Progress) error {
+	if err := b.checkDevice(d); err != nil {
+		return fmt.Errorf("(*asset.Bitstream).Configure: %w", err)
+	}
+	if d.Capability().HighSpeedFPGAConfiguration() {
+		return d.ConfigureFPGAHighSpeed(ctx, b.Reader(), progress)
+	}
+	return d.ConfigureFPGA(ctx, b.Reader(), progress)
+}
+
+// Install writes the bitstream to the flash of the device; see
+// (*ztex.Device).InstallBitstream.
+func (b *Bitstream) Install(ctx context.Context, d *ztex.Device, progress ztex.Progress) error {
+	if err := b.checkDevice(d); err != nil {
+		return fmt.Errorf("(*asset.Bitstream).Install: %w", err)
+	}
+	return d.InstallBitstream(ctx, b.Reader(), progress)
+}
@@ -0,0 +1,167 @@
+package asset
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go/format"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/aljumi/ztex/bitstream"
+)
+
+// Source is a file to be embedded by Generate.
+type Source struct {
+	// Var is the name of the variable declared for the file.
+	Var string
+
+	// Path is the path of the file, relative to the directory of the
+	// package in which it is embedded.
+	Path string
+
+	// Firmware reports whether the file is a firmware image, as opposed to
+	// a bitstream.
+	Firmware bool
+
+	Metadata
+}
+
+// ReadSource reads the file at the given path, relative to the directory
+// of the package in which it is to be embedded, and returns a source
+// describing it.  The kind of the file is given by its extension: .ihx and
+// .hex files are FX2 firmware images, .img files FX3 firmware images, and
+// .bit and .bin files bitstreams.  The part and build time of .bit files
+// are read from their header, whose time is taken to be UTC.  The build
+// time of other files is given by the environment variable
+// SOURCE_DATE_EPOCH in seconds since the Unix epoch, if set, and is zero
+// otherwise, so that the generated file does not depend on when the
+// files were checked out.  The variable of the file is named after the
+// file, such as TopBit for top.bit.
+func ReadSource(path string) (*Source, error) {
+	if !filepath.IsLocal(path) {
+		return nil, fmt.Errorf("asset.ReadSource: got path %v, want a path within the package directory", path)
+	} else if n := filepath.Base(path); strings.HasPrefix(n, ".") || strings.HasPrefix(n, "_") {
+		return nil, fmt.Errorf("asset.ReadSource: got file %v, want a file which can be embedded", n)
+	}
+
+	epoch := time.Time{}
+	if v := os.Getenv("SOURCE_DATE_EPOCH"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("asset.ReadSource: got SOURCE_DATE_EPOCH %q, want a number of seconds", v)
+		}
+		epoch = time.Unix(n, 0).UTC()
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	h := sha256.Sum256(b)
+	s := &Source{
+		Var:  varName(filepath.Base(path)),
+		Path: filepath.ToSlash(path),
+		Metadata: Metadata{
+			Name:      filepath.Base(path),
+			SHA256:    hex.EncodeToString(h[:]),
+			BuildTime: epoch,
+		},
+	}
+
+	switch filepath.Ext(path) {
+	case ".ihx", ".hex":
+		s.Firmware, s.Part = true, "fx2"
+	case ".img":
+		s.Firmware, s.Part = true, "fx3"
+	case ".bit":
+		x, err := bitstream.ParseHeader(b)
+		if err != nil {
+			return nil, fmt.Errorf("asset.ReadSource: %v: %w", path, err)
+		}
+		s.Part = bitstream.NormalizePart(x.Part)
+		if t, err := time.ParseInLocation("2006/01/02 15:04:05", x.Date+" "+x.Time, time.UTC); err == nil {
+			s.BuildTime = t
+		}
+	case ".bin":
+	default:
+		return nil, fmt.Errorf("asset.ReadSource: got file %v, want a .ihx, .hex, .img, .bit, or .bin file", path)
+	}
+	return s, nil
+}
+
+// varName returns the name of the exported variable for the file with the
+// given name.  The words of the name are capitalized and joined, and the
+// name is prefixed with Asset unless it starts with an upper case letter.
+func varName(name string) string {
+	v := ""
+	for _, x := range strings.FieldsFunc(name, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		r, n := utf8.DecodeRuneInString(x)
+		v += string(unicode.ToUpper(r)) + x[n:]
+	}
+	if r, _ := utf8.DecodeRuneInString(v); !unicode.IsUpper(r) {
+		v = "Asset" + v
+	}
+	return v
+}
+
+// Generate writes a Go source file of the given package, which embeds the
+// files of the sources and declares a variable of type *Firmware or
+// *Bitstream for each.
+func Generate(w io.Writer, pkg string, s ...*Source) error {
+	b := &bytes.Buffer{}
+	fmt.Fprintf(b, "// Code generated by ztex-embed; DO NOT EDIT.\n\n")
+	fmt.Fprintf(b, "package %v\n\n", pkg)
+	imports := "_ \"embed\"\n"
+	for _, x := range s {
+		if !x.BuildTime.IsZero() {
+			imports += "\t\"time\"\n"
+			break
+		}
+	}
+	fmt.Fprintf(b, "import (\n\t%v\n\t\"github.com/aljumi/ztex/asset\"\n)\n", imports)
+
+	vars := map[string]bool{}
+	for _, x := range s {
+		if vars[x.Var] {
+			return fmt.Errorf("asset.Generate: got variable %v twice, want distinct variables", x.Var)
+		}
+		vars[x.Var] = true
+
+		t, kind := "Bitstream", "bitstream"
+		if x.Firmware {
+			t, kind = "Firmware", "firmware image"
+		}
+		data := "embedded" + x.Var
+		fmt.Fprintf(b, "\n//go:embed %v\nvar %v []byte\n", x.Path, data)
+		fmt.Fprintf(b, "\n// %v is the %v embedded from %v.\n", x.Var, kind, x.Path)
+		fmt.Fprintf(b, "var %v = &asset.%v{\n", x.Var, t)
+		fmt.Fprintf(b, "\tMetadata: asset.Metadata{\n")
+		fmt.Fprintf(b, "\t\tName: %q,\n", x.Name)
+		if x.Part != "" {
+			fmt.Fprintf(b, "\t\tPart: %q,\n", x.Part)
+		}
+		fmt.Fprintf(b, "\t\tSHA256: %q,\n", x.SHA256)
+		if !x.BuildTime.IsZero() {
+			fmt.Fprintf(b, "\t\tBuildTime: time.Unix(%v, 0).UTC(),\n", x.BuildTime.Unix())
+		}
+		fmt.Fprintf(b, "\t},\n\tData: %v,\n}\n", data)
+	}
+
+	p, err := format.Source(b.Bytes())
+	if err != nil {
+		return fmt.Errorf("format.Source: %w", err)
+	}
+	if _, err := w.Write(p); err != nil {
+		return fmt.Errorf("(io.Writer).Write: %w", err)
+	}
+	return nil
+}
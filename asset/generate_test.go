@@ -0,0 +1,93 @@
+package asset
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVarName(t *testing.T) {
+	for _, tc := range []struct{ name, want string }{
+		{"top.bit", "TopBit"},
+		{"default_fx2.ihx", "DefaultFx2Ihx"},
+		{"2nd-design.bin", "Asset2ndDesignBin"},
+		{"ölçer.bit", "ÖlçerBit"},
+		{"éclair.ihx", "ÉclairIhx"},
+		{"設計.bit", "Asset設計Bit"},
+		{"---", "Asset"},
+	} {
+		if got := varName(tc.name); got != tc.want {
+			t.Errorf("varName(%q): got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+// bitHeader returns a .bit header with the given fields.
+func bitHeader(part, date, clock string) []byte {
+	b := []byte{0x00, 0x09, 0x0f, 0xf0, 0x0f, 0xf0, 0x0f, 0xf0, 0x0f, 0xf0, 0x00, 0x00, 0x01}
+	for _, f := range []struct {
+		k byte
+		v string
+	}{{'a', "top;UserID=0XFFFFFFFF"}, {'b', part}, {'c', date}, {'d', clock}} {
+		b = append(b, f.k, 0, byte(len(f.v)+1))
+		b = append(b, f.v...)
+		b = append(b, 0)
+	}
+	return append(b, 'e', 0, 0, 0, 0)
+}
+
+func TestReadSource(t *testing.T) {
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("os.Getwd: %v", err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("os.Chdir: %v", err)
+	}
+	defer os.Chdir(dir)
+
+	if err := os.WriteFile("top.bit", bitHeader("7a35tcsg324", "2024/03/01", "12:34:56"), 0o644); err != nil {
+		t.Fatalf("os.WriteFile: %v", err)
+	}
+	if err := os.WriteFile("top.bin", []byte{0xff}, 0o644); err != nil {
+		t.Fatalf("os.WriteFile: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		path  string
+		epoch string
+		want  time.Time
+	}{
+		{"bit header", "top.bit", "", time.Date(2024, 3, 1, 12, 34, 56, 0, time.UTC)},
+		{"bit header with epoch", "top.bit", "1700000000", time.Date(2024, 3, 1, 12, 34, 56, 0, time.UTC)},
+		{"no epoch", "top.bin", "", time.Time{}},
+		{"epoch", "top.bin", "1700000000", time.Unix(1700000000, 0).UTC()},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("SOURCE_DATE_EPOCH", tc.epoch)
+			s, err := ReadSource(tc.path)
+			if err != nil {
+				t.Fatalf("ReadSource: %v", err)
+			}
+			if !s.BuildTime.Equal(tc.want) || s.BuildTime.Location() != time.UTC {
+				t.Errorf("ReadSource: got build time %v, want %v", s.BuildTime, tc.want)
+			}
+
+			b := &bytes.Buffer{}
+			if err := Generate(b, "assets", s); err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			if got := strings.Contains(b.String(), `"time"`); got != !tc.want.IsZero() {
+				t.Errorf("Generate: got time imported %v, want %v:\n%s", got, !tc.want.IsZero(), b)
+			}
+		})
+	}
+
+	t.Setenv("SOURCE_DATE_EPOCH", "yesterday")
+	if _, err := ReadSource("top.bin"); err == nil {
+		t.Errorf("ReadSource with SOURCE_DATE_EPOCH yesterday: got no error, want error")
+	}
+}
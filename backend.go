@@ -0,0 +1,153 @@
+package ztex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// USBDesc describes where a USB device is attached and how it identifies
+// itself.
+type USBDesc struct {
+	Bus     int
+	Address int
+	Port    int
+	Vendor  uint16
+	Product uint16
+	Speed   string
+}
+
+// String returns a human-readable description of the USB device.
+func (u USBDesc) String() string {
+	x := []string{}
+	x = append(x, fmt.Sprintf("Bus(%v)", u.Bus))
+	x = append(x, fmt.Sprintf("Address(%v)", u.Address))
+	x = append(x, fmt.Sprintf("Port(%v)", u.Port))
+	x = append(x, fmt.Sprintf("ID(%04x:%04x)", u.Vendor, u.Product))
+	x = append(x, fmt.Sprintf("Speed(%v)", u.Speed))
+	return strings.Join(x, ", ")
+}
+
+// BulkIn is a bulk IN endpoint of a USB device.
+type BulkIn interface {
+	ReadContext(ctx context.Context, p []byte) (int, error)
+}
+
+// BulkOut is a bulk OUT endpoint of a USB device.
+type BulkOut interface {
+	WriteContext(ctx context.Context, p []byte) (int, error)
+}
+
+// BulkInStream reads from a bulk IN endpoint through a queue of
+// transfers, which are kept in flight until the stream is closed.
+type BulkInStream interface {
+	BulkIn
+	io.Closer
+}
+
+// BulkOutStream writes to a bulk OUT endpoint through a queue of
+// transfers.  The data written is only guaranteed to be sent once the
+// stream is closed.
+type BulkOutStream interface {
+	BulkOut
+	CloseContext(ctx context.Context) error
+}
+
+// StreamingBulkIn is implemented by bulk IN endpoints which can keep
+// several transfers of the given size in flight at once.  Endpoints which
+// do not implement it are read one transfer at a time.
+type StreamingBulkIn interface {
+	BulkIn
+	NewStream(size, count int) (BulkInStream, error)
+}
+
+// StreamingBulkOut is implemented by bulk OUT endpoints which can keep
+// several transfers of the given size in flight at once.  Endpoints which
+// do not implement it are written one transfer at a time.
+type StreamingBulkOut interface {
+	BulkOut
+	NewStream(size, count int) (BulkOutStream, error)
+}
+
+// USBInterface is an interface of a USB device, claimed until it is
+// closed.
+type USBInterface interface {
+	InEndpoint(n int) (BulkIn, error)
+	OutEndpoint(n int) (BulkOut, error)
+	Close()
+}
+
+// USBDevice is a USB device opened through a backend.  Besides control
+// transfers, it provides the bulk endpoints needed for high-speed FPGA
+// configuration and streams.
+type USBDevice interface {
+	Controller
+	io.Closer
+
+	// Describe returns the location and identity of the device.
+	Describe() USBDesc
+
+	// SetControlTimeout sets the timeout for control transfers.
+	SetControlTimeout(timeout time.Duration)
+
+	// Interface claims the interface with the given number, in its
+	// default alternate setting, of the active configuration.
+	Interface(num int) (USBInterface, error)
+}
+
+// Backend opens USB devices.  The gousb backend, which uses libusb, is
+// built unless cgo is disabled or the nolibusb build tag is given, so that
+// code which only uses other backends, such as the simulator in package
+// ztextest, builds without libusb.
+type Backend interface {
+	// OpenDevices opens all devices with the given vendor and product
+	// IDs.  If some of the devices cannot be opened, then the others are
+	// returned along with an error.
+	OpenDevices(vendor, product uint16) ([]USBDevice, error)
+}
+
+// OpenBackendDevice opens a ZTEX USB-FPGA module through the backend and
+// returns its device handle.  If there are multiple modules present, then
+// one is chosen arbitrarily.
+func OpenBackendDevice(b Backend, opt ...DeviceOption) (*Device, error) {
+	devs, err := b.OpenDevices(VendorID, ProductID)
+	if len(devs) == 0 {
+		if err != nil {
+			return nil, fmt.Errorf("(ztex.Backend).OpenDevices: %w", err)
+		}
+		return nil, fmt.Errorf("(ztex.Backend).OpenDevices: %w: got no devices, want at least one device", ErrNoDevice)
+	}
+	for _, dev := range devs[1:] {
+		dev.Close()
+	}
+
+	return NewDevice(devs[0], opt...)
+}
+
+// OpenBackendDevices opens all ZTEX USB-FPGA modules present through the
+// backend and returns their device handles.  If some of the modules cannot
+// be opened, then the handles of the others are returned along with an
+// error describing the failures.
+func OpenBackendDevices(b Backend, opt ...DeviceOption) ([]*Device, error) {
+	devs, err := b.OpenDevices(VendorID, ProductID)
+
+	e := []error{}
+	if err != nil {
+		e = append(e, fmt.Errorf("(ztex.Backend).OpenDevices: %w", err))
+	}
+
+	x := []*Device{}
+	for _, dev := range devs {
+		d, err := NewDevice(dev, opt...)
+		if err != nil {
+			e = append(e, err)
+			continue
+		}
+		x = append(x, d)
+	}
+
+	return x, errors.Join(e...)
+}
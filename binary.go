@@ -0,0 +1,264 @@
+package ztex
+
+import (
+	"fmt"
+	"math"
+)
+
+// The types in this file implement encoding.BinaryMarshaler and
+// encoding.BinaryUnmarshaler with the wire format used by the firmware.
+// Unmarshaling is strict: besides the length, fields with a fixed set of
+// values are checked, so that data which unmarshals successfully marshals
+// back to identical bytes.
+
+// checkLength returns an error unless b holds exactly n bytes.
+func checkLength(b []byte, n int) error {
+	if len(b) != n {
+		return fmt.Errorf("got %v bytes, want %v bytes", len(b), n)
+	}
+	return nil
+}
+
+// checkFlag returns an error unless v is 0 or 1.
+func checkFlag(name string, v uint8) error {
+	if v > 1 {
+		return fmt.Errorf("got %v %v, want %v or %v", name, v, 0, 1)
+	}
+	return nil
+}
+
+// MarshalBinary returns the 40-byte ZTEX descriptor.
+func (d DescriptorConfig) MarshalBinary() ([]byte, error) {
+	if d.DescriptorSize != 40 {
+		return nil, fmt.Errorf("%w: got size %v, want size %v", ErrBadDescriptor, d.DescriptorSize, 40)
+	} else if d.DescriptorVersion != 1 {
+		return nil, fmt.Errorf("%w: got version %v, want version %v", ErrBadDescriptor, d.DescriptorVersion, 1)
+	}
+
+	b := []byte{uint8(d.DescriptorSize), uint8(d.DescriptorVersion)}
+	b = append(b, d.DescriptorMagic.Bytes()...)
+	b = append(b, d.DescriptorProduct[:]...)
+	b = append(b, uint8(d.DescriptorFirmware), uint8(d.DescriptorInterface))
+	b = append(b, d.DescriptorCapability[:]...)
+	b = append(b, d.DescriptorModule[:]...)
+	b = append(b, d.DescriptorSerial.Bytes()...)
+	return b, nil
+}
+
+// UnmarshalBinary decodes the 40-byte ZTEX descriptor, which must carry
+// the magic bytes "ZTEX".
+func (d *DescriptorConfig) UnmarshalBinary(b []byte) error {
+	x, err := ParseDescriptorConfig(b)
+	if err != nil {
+		return err
+	} else if x.DescriptorMagic.String() != "ZTEX" {
+		return fmt.Errorf("%w: got magic %q, want magic %q", ErrBadDescriptor, x.DescriptorMagic, "ZTEX")
+	}
+	*d = x
+	return nil
+}
+
+// MarshalBinary returns the first 32 bytes of the configuration data area,
+// as returned by Bytes.
+func (d DeviceConfig) MarshalBinary() ([]byte, error) { return d.Bytes(), nil }
+
+// UnmarshalBinary decodes the first 32 bytes of the configuration data
+// area, which start with the signature "CD0".
+func (d *DeviceConfig) UnmarshalBinary(b []byte) error {
+	if err := checkLength(b, 32); err != nil {
+		return err
+	}
+	x, err := ParseDeviceConfig(append(append([]byte{}, b...), make([]byte, 96)...))
+	if err != nil {
+		return err
+	}
+	*d = *x
+	return nil
+}
+
+// MarshalBinary returns the 128-byte configuration data area, as returned
+// by Bytes.
+func (b BoardConfigBlock) MarshalBinary() ([]byte, error) { return b.Bytes(), nil }
+
+// UnmarshalBinary decodes the 128-byte configuration data area.
+func (b *BoardConfigBlock) UnmarshalBinary(x []byte) error {
+	c, err := ParseBoardConfigBlock(x)
+	if err != nil {
+		return err
+	}
+	*b = *c
+	return nil
+}
+
+// MarshalBinary returns the 9-byte FPGA state reported by VR 0x30.
+func (f FPGAStatus) MarshalBinary() ([]byte, error) {
+	b := []byte{uint8(f.FPGAConfigured), uint8(f.FPGAChecksum)}
+	b = append(b, f.FPGATransferred[:]...)
+	b = append(b, uint8(f.FPGAInit), uint8(f.FPGAResult), uint8(f.FPGASwapped))
+	return b, nil
+}
+
+// UnmarshalBinary decodes the 9-byte FPGA state reported by VR 0x30.
+func (f *FPGAStatus) UnmarshalBinary(b []byte) error {
+	if err := checkLength(b, 9); err != nil {
+		return err
+	} else if err := checkFlag("configured flag", b[0]); err != nil {
+		return err
+	} else if err := checkFlag("swapped flag", b[8]); err != nil {
+		return err
+	}
+
+	*f = FPGAStatus{
+		FPGAConfigured(b[0]),
+		FPGAChecksum(b[1]),
+		FPGATransferred([4]uint8(b[2:6])),
+		FPGAInit(b[6]),
+		FPGAResult(b[7]),
+		FPGASwapped(b[8]),
+	}
+	return nil
+}
+
+// MarshalBinary returns the 2-byte high-speed FPGA configuration settings
+// reported by VR 0x33.
+func (f FPGAHighSpeedConfig) MarshalBinary() ([]byte, error) {
+	return []byte{uint8(f.FPGAHighSpeedEndpoint), uint8(f.FPGAHighSpeedInterface)}, nil
+}
+
+// UnmarshalBinary decodes the 2-byte high-speed FPGA configuration
+// settings reported by VR 0x33.
+func (f *FPGAHighSpeedConfig) UnmarshalBinary(b []byte) error {
+	if err := checkLength(b, 2); err != nil {
+		return err
+	} else if b[0]&0x80 != 0 || b[0]&0x0f == 0 {
+		return fmt.Errorf("got endpoint %#02x, want an OUT endpoint other than %v", b[0], 0)
+	}
+
+	*f = FPGAHighSpeedConfig{FPGAHighSpeedEndpoint(b[0]), FPGAHighSpeedInterface(b[1])}
+	return nil
+}
+
+// MarshalBinary returns the 8-byte flash state reported by VR 0x40.
+func (f FlashStatus) MarshalBinary() ([]byte, error) {
+	b := []byte{uint8(f.FlashEnabled)}
+	b = append(b, f.FlashSector[:]...)
+	b = append(b, f.FlashCount[:]...)
+	b = append(b, uint8(f.FlashError))
+	return b, nil
+}
+
+// UnmarshalBinary decodes the 8-byte flash state reported by VR 0x40.
+func (f *FlashStatus) UnmarshalBinary(b []byte) error {
+	if err := checkLength(b, 8); err != nil {
+		return err
+	} else if err := checkFlag("enabled flag", b[0]); err != nil {
+		return err
+	}
+
+	*f = FlashStatus{
+		FlashEnabled(b[0]),
+		FlashSector([2]uint8(b[1:3])),
+		FlashCount([4]uint8(b[3:7])),
+		FlashError(b[7]),
+	}
+	return nil
+}
+
+// MarshalBinary returns the 9-byte XMEGA state reported by VR 0x48.
+func (x XMEGAStatus) MarshalBinary() ([]byte, error) {
+	b := []byte{uint8(x.XMEGAError), uint8(x.XMEGABusy)}
+	b = append(b, x.XMEGASignature[:]...)
+	b = append(b, x.XMEGAFlashPage[:]...)
+	b = append(b, x.XMEGAEEPROMPage[:]...)
+	return b, nil
+}
+
+// UnmarshalBinary decodes the 9-byte XMEGA state reported by VR 0x48.
+func (x *XMEGAStatus) UnmarshalBinary(b []byte) error {
+	if err := checkLength(b, 9); err != nil {
+		return err
+	} else if err := checkFlag("busy flag", b[1]); err != nil {
+		return err
+	}
+
+	*x = XMEGAStatus{
+		XMEGAError(b[0]),
+		XMEGABusy(b[1]),
+		XMEGASignature([3]uint8(b[2:5])),
+		XMEGAFlashPage([2]uint8(b[5:7])),
+		XMEGAEEPROMPage([2]uint8(b[7:9])),
+	}
+	return nil
+}
+
+// MarshalBinary returns the 3-byte multi-FPGA information reported by VR
+// 0x50.
+func (m MultiFPGAConfig) MarshalBinary() ([]byte, error) {
+	return []byte{uint8(m.MultiFPGACount), uint8(m.MultiFPGASelected), uint8(m.MultiFPGAParallel)}, nil
+}
+
+// UnmarshalBinary decodes the 3-byte multi-FPGA information reported by
+// VR 0x50.
+func (m *MultiFPGAConfig) UnmarshalBinary(b []byte) error {
+	if err := checkLength(b, 3); err != nil {
+		return err
+	} else if b[1] > b[0] {
+		return fmt.Errorf("got selected FPGA %v, want at most %v", b[1], b[0])
+	} else if err := checkFlag("parallel flag", b[2]); err != nil {
+		return err
+	}
+
+	*m = MultiFPGAConfig{MultiFPGACount(b[0]), MultiFPGASelected(b[1]), MultiFPGAParallel(b[2])}
+	return nil
+}
+
+// MarshalBinary returns the sensor data in the format of its protocol.
+// The readings must be numbered consecutively, and their values must be
+// representable exactly, as they are after decoding sensor data.
+func (s SensorStatus) MarshalBinary() ([]byte, error) {
+	b := []byte{uint8(s.SensorProtocol)}
+	for i, r := range s.SensorReadings {
+		if int(r.SensorChannel) != i {
+			return nil, fmt.Errorf("got channel %v at position %v, want channel %v", r.SensorChannel, i, i)
+		}
+
+		switch s.SensorProtocol {
+		case 1:
+			v := float64(r.SensorValue)
+			if r.SensorKind != 1 {
+				return nil, fmt.Errorf("got kind %v on channel %v, want kind %v", r.SensorKind.Number(), i, 1)
+			} else if v != math.Trunc(v) || v < math.MinInt8 || v > math.MaxInt8 {
+				return nil, fmt.Errorf("got value %v on channel %v, want a signed 8-bit integer", v, i)
+			}
+			b = append(b, uint8(int8(v)))
+		case 2:
+			v := float64(r.SensorValue)
+			switch r.SensorKind {
+			case 1:
+				v *= 256
+			case 2, 3:
+				v *= 1000
+			}
+			// Values in millivolts and milliamperes are scaled back with
+			// rounding error, so only a small deviation is tolerated.
+			if math.Abs(v-math.Round(v)) > 1e-6 || v < math.MinInt16 || v > math.MaxInt16 {
+				return nil, fmt.Errorf("got value %v on channel %v, want a value representable in protocol %v", r.SensorValue, i, 2)
+			}
+			w := uint16(int16(math.Round(v)))
+			b = append(b, r.SensorKind.Number(), uint8(w), uint8(w>>8))
+		default:
+			return nil, fmt.Errorf("got protocol %v, want protocol %v or %v", s.SensorProtocol.Number(), 1, 2)
+		}
+	}
+	return b, nil
+}
+
+// UnmarshalBinary decodes the sensor data reported by VR 0x58.
+func (s *SensorStatus) UnmarshalBinary(b []byte) error {
+	x, err := decodeSensorStatus(b)
+	if err != nil {
+		return err
+	}
+	*s = *x
+	return nil
+}
@@ -1,8 +1,11 @@
 package ztex
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
+
+	"github.com/aljumi/ztex/bitstream"
 )
 
 // BitstreamSize indicates the actual size of the FPGA bitstream in
@@ -11,35 +14,44 @@ type BitstreamSize [2]byte
 
 // String returns a human-readable representation of the bitstream size.
 func (b BitstreamSize) String() string {
-	return binaryPrefix(uint64(b.Number())<<12, "B")
+	return binaryPrefix(b.Bytes(), "B")
 }
 
 // Number returns a raw numeric representation of the bitstream size.
 func (b BitstreamSize) Number() uint16 { return bytesToUint16(b) }
 
+// Bytes returns the size of the bitstream in bytes.
+func (b BitstreamSize) Bytes() uint64 { return uint64(b.Number()) * bitstream.SectorSize }
+
 // BitstreamCapacity indicates the maximum size of the FPGA bitstream in
 // 4 kiB sectors.
 type BitstreamCapacity [2]byte
 
 // String returns a human-readable representation of the bitstream size.
 func (b BitstreamCapacity) String() string {
-	return binaryPrefix(uint64(b.Number())<<12, "B")
+	return binaryPrefix(b.Bytes(), "B")
 }
 
 // Number returns a raw numeric representation of the bitstream size.
 func (b BitstreamCapacity) Number() uint16 { return bytesToUint16(b) }
 
+// Bytes returns the capacity for the bitstream in bytes.
+func (b BitstreamCapacity) Bytes() uint64 { return uint64(b.Number()) * bitstream.SectorSize }
+
 // BitstreamStart indicates the start of the bitstream.
 type BitstreamStart [2]byte
 
 // String returns a human-readable representation of the bitstream size.
 func (b BitstreamStart) String() string {
-	return binaryPrefix(uint64(b.Number())<<12, "B")
+	return binaryPrefix(b.Bytes(), "B")
 }
 
 // Number returns a raw numeric representation of the bitstream size.
 func (b BitstreamStart) Number() uint16 { return bytesToUint16(b) }
 
+// Bytes returns the offset of the bitstream in flash in bytes.
+func (b BitstreamStart) Bytes() uint64 { return uint64(b.Number()) * bitstream.SectorSize }
+
 // BitstreamConfig indicates the configuration of the bitstream in flash.
 type BitstreamConfig struct {
 	BitstreamSize
@@ -56,3 +68,23 @@ func (b BitstreamConfig) String() string {
 	x = append(x, fmt.Sprintf("Start(%v)", b.BitstreamStart))
 	return strings.Join(x, ", ")
 }
+
+// MarshalJSON returns a JSON representation of the bitstream
+// configuration, in both 4 kiB sectors and bytes.
+func (b BitstreamConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Size          uint16 `json:"size"`
+		SizeBytes     uint64 `json:"size_bytes"`
+		Capacity      uint16 `json:"capacity"`
+		CapacityBytes uint64 `json:"capacity_bytes"`
+		Start         uint16 `json:"start"`
+		StartBytes    uint64 `json:"start_bytes"`
+	}{
+		b.BitstreamSize.Number(),
+		b.BitstreamSize.Bytes(),
+		b.BitstreamCapacity.Number(),
+		b.BitstreamCapacity.Bytes(),
+		b.BitstreamStart.Number(),
+		b.BitstreamStart.Bytes(),
+	})
+}
@@ -0,0 +1,71 @@
+// Package bitstream prepares Xilinx FPGA bitstreams for ZTEX modules.
+package bitstream
+
+import (
+	"bytes"
+	"fmt"
+	"math/bits"
+)
+
+// SectorSize is the size of the sectors in which the configuration data
+// area of a ZTEX module records the size, capacity, and start of the
+// bitstream in flash.
+const SectorSize = 1 << 12
+
+// Sectors returns the number of sectors needed to store n bytes.
+func Sectors(n int) int { return (n + SectorSize - 1) / SectorSize }
+
+// reverse maps every byte to the byte with its bits in reverse order.
+var reverse = func() (x [256]byte) {
+	for i := range x {
+		x[i] = bits.Reverse8(uint8(i))
+	}
+	return x
+}()
+
+// Swap prepares a bitstream for transfer to the firmware, which expects
+// the bits of every byte in reverse order.  The bit order of the bitstream
+// is detected from the synchronization word, which is searched for in the
+// first 64 kiB; bitstreams that are already swapped are returned
+// unchanged.
+func Swap(b []byte) ([]byte, error) { return SwapInto(nil, b) }
+
+// SwapInto is like Swap, but stores the swapped bitstream in dst if its
+// capacity suffices, so that a scratch buffer can be reused across
+// bitstreams.  dst may be b itself to swap the bitstream in place;
+// otherwise it must not overlap b.
+func SwapInto(dst, b []byte) ([]byte, error) {
+	n := len(b)
+	if n > 1<<16 {
+		n = 1 << 16
+	}
+
+	switch {
+	case bytes.Contains(b[:n], []byte{0xaa, 0x99, 0x55, 0x66}):
+		if cap(dst) < len(b) {
+			dst = make([]byte, len(b))
+		}
+		dst = dst[:len(b)]
+		reverseBytes(dst, b)
+		return dst, nil
+	case bytes.Contains(b[:n], []byte{0x55, 0x99, 0xaa, 0x66}):
+		return b, nil
+	default:
+		return nil, fmt.Errorf("got no synchronization word, want a Xilinx bitstream")
+	}
+}
+
+// reverseBytes stores the bytes of src with their bits reversed in dst,
+// which must be as long as src.  The loop handles eight bytes at a time,
+// which lets the compiler drop the bounds checks within each block.
+func reverseBytes(dst, src []byte) {
+	i := 0
+	for ; i+8 <= len(src); i += 8 {
+		s, d := src[i:i+8:i+8], dst[i:i+8:i+8]
+		d[0], d[1], d[2], d[3] = reverse[s[0]], reverse[s[1]], reverse[s[2]], reverse[s[3]]
+		d[4], d[5], d[6], d[7] = reverse[s[4]], reverse[s[5]], reverse[s[6]], reverse[s[7]]
+	}
+	for ; i < len(src); i++ {
+		dst[i] = reverse[src[i]]
+	}
+}
@@ -0,0 +1,121 @@
+package bitstream
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Build describes a bitstream written by Vivado or ISE.
+type Build struct {
+	// Path is the path of the .bit or .bin file.
+	Path string
+
+	// Part is the FPGA the bitstream was built for, normalized with
+	// NormalizePart, or empty if it is not known: .bin files have no
+	// header, so their part is only known from a project file.
+	Part string
+
+	// ModTime is when the file was last modified.
+	ModTime time.Time
+}
+
+// String returns a human-readable description of the build.
+func (b Build) String() string {
+	x := []string{}
+	x = append(x, fmt.Sprintf("Path(%v)", b.Path))
+	x = append(x, fmt.Sprintf("Part(%v)", b.Part))
+	x = append(x, fmt.Sprintf("ModTime(%v)", b.ModTime.Format(time.RFC3339)))
+	return strings.Join(x, ", ")
+}
+
+// The patterns of the target part in Vivado (.xpr) and ISE (.xise)
+// project files.
+var (
+	xprPart     = regexp.MustCompile(`<Option Name="Part" Val="([^"]+)"`)
+	xiseDevice  = regexp.MustCompile(`xil_pn:name="Device" xil_pn:value="([^"]+)"`)
+	xisePackage = regexp.MustCompile(`xil_pn:name="Package" xil_pn:value="([^"]+)"`)
+)
+
+// Find returns the most recently modified .bit or .bin file under path,
+// which is either a directory, such as the runs directory of a Vivado
+// project or the directory of an ISE project, or a project file.  For a
+// Vivado project file (.xpr), its runs directory is searched; for an ISE
+// project file (.xise), the directory of the project.  The part of the
+// build is read from the header of .bit files and otherwise from the
+// project file, if given.
+func Find(path string) (*Build, error) {
+	dir, part := path, ""
+	switch filepath.Ext(path) {
+	case ".xpr", ".xise":
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if filepath.Ext(path) == ".xpr" {
+			dir = strings.TrimSuffix(path, ".xpr") + ".runs"
+			if m := xprPart.FindSubmatch(b); m != nil {
+				part = NormalizePart(string(m[1]))
+			}
+		} else {
+			dir = filepath.Dir(path)
+			if m, n := xiseDevice.FindSubmatch(b), xisePackage.FindSubmatch(b); m != nil && n != nil {
+				part = NormalizePart(string(m[1]) + string(n[1]))
+			}
+		}
+	}
+
+	x := &Build{}
+	err := filepath.WalkDir(dir, func(p string, e fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		} else if e.IsDir() || (filepath.Ext(p) != ".bit" && filepath.Ext(p) != ".bin") {
+			return nil
+		}
+		i, err := e.Info()
+		if err != nil {
+			return err
+		}
+		if i.ModTime().After(x.ModTime) {
+			x.Path, x.ModTime = p, i.ModTime()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	} else if x.Path == "" {
+		return nil, fmt.Errorf("got no .bit or .bin file in %v, want a bitstream", dir)
+	}
+
+	x.Part = part
+	if filepath.Ext(x.Path) == ".bit" {
+		h, err := readHeader(x.Path)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", x.Path, err)
+		}
+		x.Part = NormalizePart(h.Part)
+	}
+	return x, nil
+}
+
+// readHeader reads the header of a .bit file, which is far shorter than
+// the bitstream.
+func readHeader(path string) (*Header, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	b := make([]byte, 1<<12)
+	n, err := io.ReadFull(f, b)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	return ParseHeader(b[:n])
+}
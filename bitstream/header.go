@@ -0,0 +1,93 @@
+package bitstream
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Header holds the fields of the header which Vivado and ISE write at the
+// start of .bit files.  Raw bitstreams, such as .bin files, have no
+// header.
+type Header struct {
+	// Design is the name of the design, followed by the user ID and the
+	// version of the tool, separated by semicolons.
+	Design string
+
+	// Part is the FPGA the bitstream was built for, such as
+	// "7a35tcsg324".
+	Part string
+
+	// Date and Time are when the bitstream was built.
+	Date string
+	Time string
+}
+
+// bitMagic starts every .bit file.
+var bitMagic = []byte{0x00, 0x09, 0x0f, 0xf0, 0x0f, 0xf0, 0x0f, 0xf0, 0x0f, 0xf0, 0x00, 0x00, 0x01}
+
+// ParseHeader parses the header at the start of a .bit file.  Only the
+// header needs to be present in b.
+func ParseHeader(b []byte) (*Header, error) {
+	if !bytes.HasPrefix(b, bitMagic) {
+		return nil, fmt.Errorf("got no .bit header, want a .bit file")
+	}
+
+	h := &Header{}
+	for b = b[len(bitMagic):]; ; {
+		if len(b) < 1 {
+			return nil, fmt.Errorf("got end of header, want field")
+		}
+		k := b[0]
+		if k == 'e' {
+			return h, nil
+		} else if len(b) < 3 || len(b) < 3+int(binary.BigEndian.Uint16(b[1:])) {
+			return nil, fmt.Errorf("got truncated field %q, want a complete header", k)
+		}
+		n := int(binary.BigEndian.Uint16(b[1:]))
+		v := strings.TrimRight(string(b[3:3+n]), "\x00")
+		switch k {
+		case 'a':
+			h.Design = v
+		case 'b':
+			h.Part = v
+		case 'c':
+			h.Date = v
+		case 'd':
+			h.Time = v
+		default:
+			return nil, fmt.Errorf("got field %q, want field 'a', 'b', 'c', 'd', or 'e'", k)
+		}
+		b = b[3+n:]
+	}
+}
+
+// NormalizePart returns the device and package of an FPGA part in the
+// form used by .bit headers, such as "7a35tcsg324", from the forms used
+// by Vivado and ISE projects, such as "xc7a35tcsg324-1" and "XC7A35T".
+// The speed grade and the "xc" prefix are dropped, and letters are lower
+// case.
+func NormalizePart(part string) string {
+	p := strings.ToLower(strings.TrimSpace(part))
+	if i := strings.IndexByte(p, '-'); i >= 0 {
+		p = p[:i]
+	}
+	return strings.TrimPrefix(p, "xc")
+}
+
+// xilinxPackage matches the package at the end of a normalized part, such
+// as "csg324" or "tqg144": two letters of the package type, an optional
+// "g" for lead-free packages, and the number of pins.
+var xilinxPackage = regexp.MustCompile(`(?:cl|cp|cs|fb|ff|fg|fh|fl|ft|rb|rf|rs|sb|tq|vq)g?[0-9]+$`)
+
+// SplitPart splits a part normalized by NormalizePart into its device,
+// such as "7a35t", and its package, such as "csg324", which is empty if
+// the part has none.
+func SplitPart(part string) (device, pkg string) {
+	if i := xilinxPackage.FindStringIndex(part); i != nil && i[0] > 0 {
+		return part[:i[0]], part[i[0]:]
+	}
+	return part, ""
+}
@@ -0,0 +1,49 @@
+package bitstream
+
+import "testing"
+
+func TestNormalizePart(t *testing.T) {
+	for _, tc := range []struct {
+		part, want, device, pkg string
+	}{
+		{"xc7a35tcsg324-1", "7a35tcsg324", "7a35t", "csg324"},
+		{"XC7A35T", "7a35t", "7a35t", ""},
+		{" xc6slx25ftg256-3 ", "6slx25ftg256", "6slx25", "ftg256"},
+		{"7a100tfgg484", "7a100tfgg484", "7a100t", "fgg484"},
+		{"xc6slx9tqg144-2", "6slx9tqg144", "6slx9", "tqg144"},
+		{"xcku15p-ffve1517-2-e", "ku15p", "ku15p", ""},
+		{"", "", "", ""},
+	} {
+		p := NormalizePart(tc.part)
+		if p != tc.want {
+			t.Errorf("NormalizePart(%q): got %q, want %q", tc.part, p, tc.want)
+		}
+		if d, k := SplitPart(p); d != tc.device || k != tc.pkg {
+			t.Errorf("SplitPart(%q): got %q, %q, want %q, %q", p, d, k, tc.device, tc.pkg)
+		}
+	}
+}
+
+func TestParseHeader(t *testing.T) {
+	b := []byte{0x00, 0x09, 0x0f, 0xf0, 0x0f, 0xf0, 0x0f, 0xf0, 0x0f, 0xf0, 0x00, 0x00, 0x01}
+	for _, f := range []struct {
+		k byte
+		v string
+	}{{'a', "top;UserID=0XFFFFFFFF"}, {'b', "7a35tcsg324"}, {'c', "2024/03/01"}, {'d', "12:34:56"}} {
+		b = append(b, f.k, 0, byte(len(f.v)+1))
+		b = append(b, f.v...)
+		b = append(b, 0)
+	}
+	b = append(b, 'e', 0, 0, 0, 4, 0xaa, 0x99, 0x55, 0x66)
+
+	h, err := ParseHeader(b)
+	if err != nil {
+		t.Fatalf("ParseHeader: %v", err)
+	}
+	if d, p := SplitPart(NormalizePart(h.Part)); d != "7a35t" || p != "csg324" {
+		t.Errorf("ParseHeader: got part %q, want device 7a35t and package csg324", h.Part)
+	}
+	if _, err := ParseHeader(b[:20]); err == nil {
+		t.Errorf("ParseHeader with truncated header: got no error, want error")
+	}
+}
@@ -1,6 +1,7 @@
 package ztex
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 )
@@ -8,20 +9,30 @@ import (
 // BoardType indicates the board type associated with the device.
 type BoardType uint8
 
+// The board types.
+const (
+	BoardTypeFPGA BoardType = 1 // ZTEX FPGA Module
+	BoardTypeFX2  BoardType = 2 // ZTEX USB-FPGA Module with an EZ-USB FX2
+	BoardTypeFX3  BoardType = 3 // ZTEX USB3-FPGA Module with an EZ-USB FX3S
+)
+
 // String returns a human-readable description of a board type.
 func (b BoardType) String() string {
 	switch b {
-	case 1:
+	case BoardTypeFPGA:
 		return "ZTEX FPGA Module"
-	case 2:
+	case BoardTypeFX2:
 		return "ZTEX USB-FPGA Module [Cypress CY7C68013A EZ-USB FX2]"
-	case 3:
+	case BoardTypeFX3:
 		return "ZTEX USB3-FPGA Module [Cypress CYUSB3033 EZ-USB FX3S]"
 	default:
-		return "Unknown"
+		return Unknown
 	}
 }
 
+// IsKnown returns true if and only if a board type has a known name.
+func (b BoardType) IsKnown() bool { return isKnown(b.String()) }
+
 // Number returns the raw representation of a board type.
 func (b BoardType) Number() uint8 { return uint8(b) }
 
@@ -37,10 +48,13 @@ func (b BoardSeries) String() string {
 	case 2:
 		return "2"
 	default:
-		return "Unknown"
+		return Unknown
 	}
 }
 
+// IsKnown returns true if and only if a board series has a known name.
+func (b BoardSeries) IsKnown() bool { return isKnown(b.String()) }
+
 // Number returns the raw representation of a board series.
 func (b BoardSeries) Number() uint8 { return uint8(b) }
 
@@ -51,12 +65,15 @@ type BoardNumber uint8
 func (b BoardNumber) String() string {
 	switch {
 	case b == 255:
-		return "Unknown"
+		return Unknown
 	default:
 		return fmt.Sprintf("%d", uint8(b))
 	}
 }
 
+// IsKnown returns true if and only if a board number has a known name.
+func (b BoardNumber) IsKnown() bool { return isKnown(b.String()) }
+
 // Number returns the raw representation of a board number.
 func (b BoardNumber) Number() uint8 { return uint8(b) }
 
@@ -104,3 +121,22 @@ func (b BoardConfig) String() string {
 	x = append(x, fmt.Sprintf("Version(%v)", b.BoardVersion))
 	return strings.Join(x, ", ")
 }
+
+// MarshalJSON returns a JSON representation of a board configuration.
+func (b BoardConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type     uint8  `json:"type"`
+		TypeName string `json:"type_name"`
+		Series   uint8  `json:"series"`
+		Number   uint8  `json:"number"`
+		Variant  string `json:"variant"`
+		Version  string `json:"version"`
+	}{
+		b.BoardType.Number(),
+		b.BoardType.String(),
+		b.BoardSeries.Number(),
+		b.BoardNumber.Number(),
+		b.BoardVariant.String(),
+		b.BoardVersion.String(),
+	})
+}
@@ -0,0 +1,64 @@
+package ztex
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/aljumi/ztex/bitstream"
+)
+
+// CheckPart returns an error unless the FPGA of the device is the given
+// part, in any form accepted by bitstream.NormalizePart.  The devices must
+// be the same, and so must the packages, unless either the part or the
+// FPGA has no package.
+func (d *Device) CheckPart(part string) error {
+	if err := d.LoadDeviceConfig(); err != nil {
+		return err
+	}
+
+	p, want := bitstream.NormalizePart(part), d.FPGAConfig.Part()
+	if want == "" {
+		return fmt.Errorf("(*ztex.Device).CheckPart: got FPGA %v, want a known FPGA", d.FPGAConfig.FPGAType)
+	}
+	pd, pp := bitstream.SplitPart(p)
+	wd, wp := bitstream.SplitPart(want)
+	if pd == "" || pd != wd || (pp != "" && wp != "" && pp != wp) {
+		return fmt.Errorf("(*ztex.Device).CheckPart: got bitstream for %v, want bitstream for %v", p, want)
+	}
+	return nil
+}
+
+// ConfigureFPGAFromBuild configures the selected FPGA with the most
+// recently built bitstream under path, which is a Vivado runs directory,
+// an ISE project directory, or a project file, as found by
+// bitstream.Find.  The part of the bitstream is checked against the FPGA
+// beforehand, so that bitstreams for other boards are refused; bitstreams
+// whose part is not known, such as .bin files found without a project
+// file, are refused as well.  The bitstream is transferred through the
+// bulk endpoint if the device supports it.  The build used is returned.
+func (d *Device) ConfigureFPGAFromBuild(ctx context.Context, path string, progress Progress) (*bitstream.Build, error) {
+	b, err := bitstream.Find(path)
+	if err != nil {
+		return nil, fmt.Errorf("bitstream.Find: %w", err)
+	} else if b.Part == "" {
+		return nil, fmt.Errorf("(*ztex.Device).ConfigureFPGAFromBuild: got no part for %v, want a .bit file or a project file naming the part", b.Path)
+	} else if err := d.CheckPart(b.Part); err != nil {
+		return nil, fmt.Errorf("%v: %w", b.Path, err)
+	}
+
+	f, err := os.Open(b.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	configure := d.ConfigureFPGA
+	if d.Capability().HighSpeedFPGAConfiguration() {
+		configure = d.ConfigureFPGAHighSpeed
+	}
+	if err := configure(ctx, f, progress); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
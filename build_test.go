@@ -0,0 +1,40 @@
+package ztex_test
+
+import (
+	"testing"
+
+	"github.com/aljumi/ztex/ztextest"
+)
+
+func TestCheckPart(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		pkg  uint8
+		part string
+		ok   bool
+	}{
+		{"bit header", 2, "7a35tcsg324", true},
+		{"Vivado part", 2, "xc7a35tcsg324-1", true},
+		{"ISE device", 2, "XC7A35T", true},
+		{"other package", 2, "7a35tftg256", false},
+		{"other device", 2, "7a100tcsg324", false},
+		{"device prefix", 2, "7a3", false},
+		{"device with suffix", 2, "7a35ti", false},
+		{"empty part", 2, "", false},
+		{"unknown package", 0, "7a35tcsg324", true},
+		{"unknown package, other device", 0, "7a35ticsg324", false},
+		{"unknown package, device", 0, "xc7a35t-2", true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f := ztextest.NewFakeDevice("fake000001")
+			f.MACEEPROM[10] = tc.pkg
+			d, err := ztextest.Open(f)
+			if err != nil {
+				t.Fatalf("ztextest.Open: %v", err)
+			}
+			if err := d.CheckPart(tc.part); (err == nil) != tc.ok {
+				t.Errorf("(*ztex.Device).CheckPart(%q) with FPGA %v: got error %v, want error %v", tc.part, d.FPGAConfig.Part(), err, !tc.ok)
+			}
+		})
+	}
+}
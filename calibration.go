@@ -0,0 +1,112 @@
+package ztex
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+const (
+	// macEEPROMPageSize is the number of bytes in a MAC EEPROM page.
+	macEEPROMPageSize = 8
+
+	// macEEPROMUserStart is the address of the user area in the MAC
+	// EEPROM, which follows the board configuration data.
+	macEEPROMUserStart = 48
+
+	// macEEPROMUserSize is the number of bytes in the user area of the
+	// MAC EEPROM.
+	macEEPROMUserSize = 80
+)
+
+// SensorCalibration corrects the readings of a sensor channel.  The
+// corrected value is the raw value multiplied by Scale, plus Offset.  A
+// zero Scale is treated as 1, so that the zero value makes no correction.
+type SensorCalibration struct {
+	Offset float64
+	Scale  float64
+}
+
+// String returns a human-readable description of the sensor calibration.
+func (s SensorCalibration) String() string {
+	x := []string{}
+	x = append(x, fmt.Sprintf("Offset(%v)", s.Offset))
+	x = append(x, fmt.Sprintf("Scale(%v)", s.scale()))
+	return strings.Join(x, ", ")
+}
+
+func (s SensorCalibration) scale() float64 {
+	if s.Scale == 0 {
+		return 1
+	}
+	return s.Scale
+}
+
+func (s SensorCalibration) apply(v SensorValue) SensorValue {
+	return SensorValue(float64(v)*s.scale() + s.Offset)
+}
+
+// SensorCalibrations maps sensor channels to their corrections.  Channels
+// without an entry are not corrected.
+type SensorCalibrations map[SensorChannel]SensorCalibration
+
+// String returns a human-readable description of the sensor calibrations.
+func (s SensorCalibrations) String() string {
+	x := []string{}
+	for _, c := range s.channels() {
+		x = append(x, fmt.Sprintf("Channel%v(%v)", c, s[c]))
+	}
+	return strings.Join(x, ", ")
+}
+
+func (s SensorCalibrations) channels() []SensorChannel {
+	x := []SensorChannel{}
+	for c := range s {
+		x = append(x, c)
+	}
+	sort.Slice(x, func(i, j int) bool { return x[i] < x[j] })
+	return x
+}
+
+// The sensor calibrations are stored in the user area of the MAC EEPROM as
+// the signature "SC", the number of entries, and then nine bytes per entry:
+// the channel, followed by the offset and scale as little-endian IEEE 754
+// single-precision numbers.
+const sensorCalibrationEntries = (macEEPROMUserSize - 3) / 9
+
+func encodeSensorCalibrations(s SensorCalibrations) ([]byte, error) {
+	if len(s) > sensorCalibrationEntries {
+		return nil, fmt.Errorf("got %v calibrations, want at most %v calibrations", len(s), sensorCalibrationEntries)
+	}
+
+	b := []byte{'S', 'C', uint8(len(s))}
+	for _, c := range s.channels() {
+		o := math.Float32bits(float32(s[c].Offset))
+		k := math.Float32bits(float32(s[c].scale()))
+		b = append(b, uint8(c),
+			uint8(o>>0), uint8(o>>8), uint8(o>>16), uint8(o>>24),
+			uint8(k>>0), uint8(k>>8), uint8(k>>16), uint8(k>>24))
+	}
+	return b, nil
+}
+
+func decodeSensorCalibrations(b []byte) (SensorCalibrations, error) {
+	if len(b) < 3 || b[0] != 'S' || b[1] != 'C' {
+		return nil, fmt.Errorf("got signature %v, want signature %v", b[:min(len(b), 2)], []byte{'S', 'C'})
+	} else if int(b[2]) > sensorCalibrationEntries || len(b) < 3+9*int(b[2]) {
+		return nil, fmt.Errorf("got %v calibrations, want at most %v calibrations", b[2], sensorCalibrationEntries)
+	}
+
+	s := SensorCalibrations{}
+	for i := 0; i < int(b[2]); i++ {
+		e := b[3+9*i:]
+		o := bytesToUint32([4]uint8{e[1], e[2], e[3], e[4]})
+		k := bytesToUint32([4]uint8{e[5], e[6], e[7], e[8]})
+		s[SensorChannel(e[0])] = SensorCalibration{
+			float64(math.Float32frombits(o)),
+			float64(math.Float32frombits(k)),
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,65 @@
+package ztex
+
+import (
+	"testing"
+)
+
+func TestSensorCalibrationsEncoding(t *testing.T) {
+	s := SensorCalibrations{
+		2: {Offset: -1.5, Scale: 1.25},
+		0: {Offset: 3},
+	}
+	b, err := encodeSensorCalibrations(s)
+	if err != nil {
+		t.Fatalf("encodeSensorCalibrations: %v", err)
+	} else if len(b) != 3+2*9 || b[0] != 'S' || b[1] != 'C' || b[2] != 2 || b[3] != 0 || b[12] != 2 {
+		t.Fatalf("encodeSensorCalibrations: got % x, want two entries ordered by channel", b)
+	}
+
+	// A zero scale is stored as 1.
+	x, err := decodeSensorCalibrations(append(b, make([]byte, macEEPROMUserSize-len(b))...))
+	if err != nil {
+		t.Fatalf("decodeSensorCalibrations: %v", err)
+	} else if len(x) != 2 || x[0] != (SensorCalibration{3, 1}) || x[2] != (SensorCalibration{-1.5, 1.25}) {
+		t.Errorf("decodeSensorCalibrations: got %v, want %v", x, s)
+	}
+}
+
+func TestSensorCalibrationsEncodingErrors(t *testing.T) {
+	s := SensorCalibrations{}
+	for i := 0; i <= sensorCalibrationEntries; i++ {
+		s[SensorChannel(i)] = SensorCalibration{}
+	}
+	if b, err := encodeSensorCalibrations(s); err == nil {
+		t.Errorf("encodeSensorCalibrations with %v entries: got % x, want error", len(s), b)
+	}
+
+	for _, b := range [][]byte{
+		{},
+		{'S', 'X', 0},
+		{0xff, 0xff, 0xff},
+		{'S', 'C', sensorCalibrationEntries + 1},
+		{'S', 'C', 1, 0, 0, 0},
+	} {
+		if s, err := decodeSensorCalibrations(b); err == nil {
+			t.Errorf("decodeSensorCalibrations(% x): got %v, want error", b, s)
+		}
+	}
+}
+
+func TestSensorCalibrationApply(t *testing.T) {
+	for _, tc := range []struct {
+		c    SensorCalibration
+		v    SensorValue
+		want SensorValue
+	}{
+		{SensorCalibration{}, 42, 42},
+		{SensorCalibration{Offset: -2}, 42, 40},
+		{SensorCalibration{Scale: 0.5}, 42, 21},
+		{SensorCalibration{Offset: 1, Scale: 2}, 1.5, 4},
+	} {
+		if got := tc.c.apply(tc.v); got != tc.want {
+			t.Errorf("(%v).apply(%v): got %v, want %v", tc.c, tc.v, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package ztex
+
+import (
+	"errors"
+	"time"
+)
+
+// CallOption represents an option of a single operation of a device, such
+// as a longer timeout for one slow command, which does not affect the
+// other operations of the device.
+type CallOption func(*callOptions)
+
+// callOptions holds the options of a single operation.
+type callOptions struct {
+	timeout  time.Duration
+	retries  int
+	progress Progress
+}
+
+// WithTimeout sets the timeout for the control commands of the operation.
+// The timeout of the device, as set by ControlTimeout, is restored when
+// the operation returns.
+func WithTimeout(timeout time.Duration) CallOption {
+	return func(o *callOptions) { o.timeout = timeout }
+}
+
+// WithRetries retries the operation up to n times if it fails, pausing
+// briefly before each retry.  Operations which are not supported by the
+// device are not retried.
+func WithRetries(n int) CallOption {
+	return func(o *callOptions) { o.retries = n }
+}
+
+// WithProgress reports the progress of operations which transfer data in
+// several steps, such as EEPROM writes.  It is ignored by other
+// operations.
+func WithProgress(progress Progress) CallOption {
+	return func(o *callOptions) { o.progress = progress }
+}
+
+// controlTimeouter is implemented by USB devices whose control timeout can
+// be read, so that it can be restored after a per-call timeout.
+type controlTimeouter interface {
+	controlTimeout() time.Duration
+}
+
+// longOperation sets the control timeout to the one set by
+// OperationTimeout, if any, for commands which start a long operation, and
+// returns a function which restores the timeout of the device.  A timeout
+// set for the call with WithTimeout takes precedence and is left alone.
+func (d *Device) longOperation() func() {
+	if d.opTimeout <= 0 || d.callTimeout > 0 || d.usb == nil {
+		return func() {}
+	}
+	d.usb.SetControlTimeout(d.opTimeout)
+	return func() { d.usb.SetControlTimeout(d.timeout) }
+}
+
+// applyCallOptions returns the options set by opt.
+func applyCallOptions(opt []CallOption) callOptions {
+	o := &callOptions{}
+	for _, x := range opt {
+		x(o)
+	}
+	return *o
+}
+
+// call performs the operation f with the options opt.  The options are
+// passed by value, and only processed if there are any, so that calls
+// without options do not allocate.
+func (d *Device) call(opt []CallOption, f func(o callOptions) error) error {
+	o := callOptions{}
+	if len(opt) > 0 {
+		o = applyCallOptions(opt)
+	}
+
+	if o.timeout > 0 && d.usb != nil {
+		d.usb.SetControlTimeout(o.timeout)
+		d.callTimeout = o.timeout
+		defer func() {
+			d.callTimeout = 0
+			d.usb.SetControlTimeout(d.timeout)
+		}()
+	}
+
+	for i := 0; ; i++ {
+		err := f(o)
+		if err == nil || i >= o.retries || errors.Is(err, ErrNotSupported) {
+			return err
+		}
+		d.clock.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,64 @@
+package ztex_test
+
+import (
+	"testing"
+
+	"github.com/aljumi/ztex"
+	"github.com/aljumi/ztex/ztextest"
+)
+
+func TestStatusIntoDoesNotAllocate(t *testing.T) {
+	d, err := ztextest.Open(ztextest.NewFakeDevice("fake000001"))
+	if err != nil {
+		t.Fatalf("ztextest.Open: %v", err)
+	}
+	defer d.Close()
+
+	buf := make([]byte, ztex.StatusBufferSize)
+	fpga, flash, c := &ztex.FPGAStatus{}, &ztex.FlashStatus{}, &ztex.CombinedStatus{}
+	for _, tc := range []struct {
+		name string
+		f    func() error
+	}{
+		{"FPGAStatusInto", func() error { return d.FPGAStatusInto(fpga, buf) }},
+		{"FlashStatusInto", func() error { return d.FlashStatusInto(flash, buf) }},
+		{"CombinedStatusInto", func() error { return d.CombinedStatusInto(c, buf) }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.f(); err != nil {
+				t.Fatalf("(*ztex.Device).%v: %v", tc.name, err)
+			}
+			if n := testing.AllocsPerRun(100, func() { tc.f() }); n != 0 {
+				t.Errorf("(*ztex.Device).%v: got %v allocations per call, want 0", tc.name, n)
+			}
+		})
+	}
+}
+
+func TestCombinedStatusIntoKeepsParts(t *testing.T) {
+	f := ztextest.NewFakeDevice("fake000001")
+	d, err := ztextest.Open(f)
+	if err != nil {
+		t.Fatalf("ztextest.Open: %v", err)
+	}
+	defer d.Close()
+
+	buf := make([]byte, ztex.StatusBufferSize)
+	c := &ztex.CombinedStatus{}
+	if err := d.CombinedStatusInto(c, buf); err != nil {
+		t.Fatalf("(*ztex.Device).CombinedStatusInto: %v", err)
+	}
+	fpga := c.FPGA
+
+	// VR 0x30: FPGA configuration: get FPGA state
+	f.Fail(0x30, ztextest.ErrStall)
+	if err := d.CombinedStatusInto(c, buf); err == nil {
+		t.Fatalf("(*ztex.Device).CombinedStatusInto: got no error, want error")
+	} else if c.FPGAErr == nil || c.FPGA != fpga {
+		t.Errorf("(*ztex.Device).CombinedStatusInto: got FPGA status %p and error %v, want status %p and error", c.FPGA, c.FPGAErr, fpga)
+	}
+
+	if s, err := d.CombinedStatus(); err == nil || s.FPGA != nil {
+		t.Errorf("(*ztex.Device).CombinedStatus: got FPGA status %v and error %v, want no status and error", s.FPGA, err)
+	}
+}
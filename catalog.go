@@ -0,0 +1,156 @@
+package ztex
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BoardQuirk indicates a peculiarity of a board which applications may
+// need to take into account.
+type BoardQuirk uint8
+
+// The board quirks.
+const (
+	// QuirkNoFPGA marks boards without an FPGA.
+	QuirkNoFPGA BoardQuirk = 1 << iota
+
+	// QuirkMultiFPGA marks boards with several FPGAs, which are selected
+	// with SelectFPGA.
+	QuirkMultiFPGA
+
+	// QuirkXMEGA marks boards with an Atmel XMEGA microcontroller.
+	QuirkXMEGA
+)
+
+// String returns a human-readable description of the board quirks.
+func (b BoardQuirk) String() string {
+	x := []string{}
+	if b&QuirkNoFPGA != 0 {
+		x = append(x, "No FPGA")
+	}
+	if b&QuirkMultiFPGA != 0 {
+		x = append(x, "MultiFPGA")
+	}
+	if b&QuirkXMEGA != 0 {
+		x = append(x, "XMEGA")
+	}
+	return strings.Join(x, ", ")
+}
+
+// BoardInfo is an entry of the board catalog, which describes a ZTEX
+// board as it is usually built.  The FPGA and RAM are those of the default
+// variant, and are zero if the catalog does not record them.
+type BoardInfo struct {
+	Name   string
+	Board  BoardConfig
+	FPGA   FPGAConfig
+	RAM    RAMConfig
+	Stream BoardStream
+	Quirks BoardQuirk
+}
+
+// BoardStream describes the stream of the default firmware of a board:
+// the numbers of its bulk OUT and IN endpoints, and the size in bytes of
+// the FIFO of the controller behind them.  It is zero for boards without
+// an FPGA.
+type BoardStream struct {
+	OutEndpoint uint8
+	InEndpoint  uint8
+	FIFOSize    int
+}
+
+// String returns a human-readable description of the stream.
+func (b BoardStream) String() string {
+	x := []string{}
+	x = append(x, fmt.Sprintf("Out Endpoint(%v)", b.OutEndpoint))
+	x = append(x, fmt.Sprintf("In Endpoint(%v)", b.InEndpoint))
+	x = append(x, fmt.Sprintf("FIFO Size(%v)", binaryPrefix(uint64(b.FIFOSize), "B")))
+	return strings.Join(x, ", ")
+}
+
+// The streams of the default firmware for the EZ-USB FX2, whose endpoints
+// 2 and 6 are quad-buffered with 512-byte packets, and for the EZ-USB FX3,
+// whose DMA channels use four 4 kiB buffers.
+var (
+	fx2Stream = BoardStream{OutEndpoint: 2, InEndpoint: 6, FIFOSize: 2 << 10}
+	fx3Stream = BoardStream{OutEndpoint: 2, InEndpoint: 6, FIFOSize: 16 << 10}
+)
+
+// String returns a human-readable description of the catalog entry.
+func (b BoardInfo) String() string {
+	x := []string{}
+	x = append(x, fmt.Sprintf("Name(%v)", b.Name))
+	x = append(x, fmt.Sprintf("Board(%v)", b.Board))
+	x = append(x, fmt.Sprintf("FPGA(%v)", b.FPGA))
+	x = append(x, fmt.Sprintf("RAM(%v)", b.RAM))
+	x = append(x, fmt.Sprintf("Stream(%v)", b.Stream))
+	x = append(x, fmt.Sprintf("Quirks(%v)", b.Quirks))
+	return strings.Join(x, ", ")
+}
+
+// boardEntry associates a catalog entry with the first two bytes of the
+// ZTEX product IDs of the board.
+type boardEntry struct {
+	product [2]uint8
+	BoardInfo
+}
+
+// boards is the board catalog.  The names of the boards are those of the
+// product table.
+var boards = []boardEntry{
+	{[2]uint8{10, 11}, BoardInfo{Board: board(BoardTypeFX2, 1, 2), Stream: fx2Stream}},
+	{[2]uint8{10, 12}, BoardInfo{Board: board(BoardTypeFX2, 1, 11), Stream: fx2Stream}},
+	{[2]uint8{10, 13}, BoardInfo{Board: board(BoardTypeFX2, 1, 15), Stream: fx2Stream}},
+	{[2]uint8{10, 14}, BoardInfo{Board: board(BoardTypeFX2, 1, 15), Stream: fx2Stream}},
+	{[2]uint8{10, 15}, BoardInfo{
+		Board:  board(BoardTypeFX2, 1, 15),
+		FPGA:   FPGAConfig{FPGAType: MakeFPGAType(FPGAXC6SLX150x4)},
+		Stream: fx2Stream,
+		Quirks: QuirkMultiFPGA,
+	}},
+	{[2]uint8{10, 16}, BoardInfo{Board: board(BoardTypeFX2, 2, 16), Stream: fx2Stream}},
+	{[2]uint8{10, 17}, BoardInfo{
+		Board:  board(BoardTypeFX2, 2, 13),
+		FPGA:   FPGAConfig{FPGAType: MakeFPGAType(FPGAXC7A35T), FPGAPackage: PackageCSG324, FPGAGrade: FPGAGrade{'1', 'C'}},
+		RAM:    RAMConfig{RAMSize: 0x18, RAMType: RAMDDR3_800},
+		Stream: fx2Stream,
+	}},
+	{[2]uint8{10, 18}, BoardInfo{Board: board(BoardTypeFX2, 2, 1), Stream: fx2Stream}},
+	{[2]uint8{10, 19}, BoardInfo{Board: board(BoardTypeFX2, 2, 4), Stream: fx2Stream}},
+	{[2]uint8{10, 20}, BoardInfo{Board: board(BoardTypeFX2, 1, 0), Quirks: QuirkNoFPGA}},
+	{[2]uint8{10, 30}, BoardInfo{Board: board(BoardTypeFX2, 1, 0), Quirks: QuirkNoFPGA | QuirkXMEGA}},
+	{[2]uint8{10, 40}, BoardInfo{Board: board(BoardTypeFX3, 2, 2), Stream: fx3Stream}},
+	{[2]uint8{10, 41}, BoardInfo{Board: board(BoardTypeFX3, 2, 14), Stream: fx3Stream}},
+	{[2]uint8{10, 42}, BoardInfo{Board: board(BoardTypeFX3, 2, 18), Stream: fx3Stream}},
+}
+
+// board returns the board configuration of the given type, series, and
+// number, without a variant.
+func board(t BoardType, s BoardSeries, n BoardNumber) BoardConfig {
+	return BoardConfig{t, BoardVersion{s, n, BoardVariant{}}}
+}
+
+// LookupBoard returns the catalog entry of the board with the given ZTEX
+// product ID, and false if the product ID does not identify a board, such
+// as for experimental products.
+func LookupBoard(p DescriptorProduct) (BoardInfo, bool) {
+	for _, b := range boards {
+		if p[0] == b.product[0] && p[1] == b.product[1] {
+			x := b.BoardInfo
+			x.Name = DescriptorProduct{b.product[0], b.product[1]}.Name()
+			return x, true
+		}
+	}
+	return BoardInfo{}, false
+}
+
+// Equal returns true if and only if the board configurations are the same,
+// including the variant.
+func (b BoardConfig) Equal(x BoardConfig) bool { return b == x }
+
+// SameModel returns true if and only if the board configurations describe
+// the same model of board, that is, the same type, series, and number,
+// regardless of the variant.
+func (b BoardConfig) SameModel(x BoardConfig) bool {
+	return b.BoardType == x.BoardType && b.BoardSeries == x.BoardSeries && b.BoardNumber == x.BoardNumber
+}
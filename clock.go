@@ -0,0 +1,45 @@
+package ztex
+
+import "time"
+
+// Clock provides the time to the polling and timeout logic of the
+// package: busy-waits, debug tails, and monitors.  It can be replaced to
+// run such logic without waiting, as with the fake clock in package
+// ztextest.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+	After(d time.Duration) <-chan time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker delivers ticks at intervals, like time.Ticker.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// SystemClock is the clock of the operating system, which is used unless
+// another clock is given.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (systemClock) NewTicker(d time.Duration) Ticker       { return systemTicker{time.NewTicker(d)} }
+
+type systemTicker struct{ t *time.Ticker }
+
+func (s systemTicker) C() <-chan time.Time { return s.t.C }
+func (s systemTicker) Stop()               { s.t.Stop() }
+
+// DeviceClock sets the clock used by the device and by the monitors of
+// the device.
+func DeviceClock(c Clock) DeviceOption {
+	return func(d *Device) error {
+		d.clock = c
+		return nil
+	}
+}
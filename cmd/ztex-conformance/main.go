@@ -0,0 +1,118 @@
+//go:build hardware
+
+// Command ztex-conformance runs the conformance suite of package ztextest
+// against the ZTEX modules attached to the host and prints a compatibility
+// report for each board type.  It is built only with the "hardware" build
+// tag:
+//
+//	go run -tags hardware ./cmd/ztex-conformance -bitstream loopback.bit -loopback
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aljumi/ztex"
+	"github.com/aljumi/ztex/ztextest"
+	"github.com/google/gousb"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "ztex-conformance: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	bitstream := flag.String("bitstream", "", "bitstream used to check FPGA configuration and streaming")
+	loopback := flag.Bool("loopback", false, "the bitstream implements a loopback design")
+	size := flag.Int("size", 64<<10, "number of bytes transferred by the stream check")
+	serial := flag.String("serial", "", "check only the module with this serial number")
+	asJSON := flag.Bool("json", false, "print the reports as JSON")
+	flag.Parse()
+
+	s := &ztextest.Conformance{Loopback: *loopback, StreamSize: *size}
+	if *bitstream != "" {
+		b, err := os.ReadFile(*bitstream)
+		if err != nil {
+			return err
+		}
+		s.Bitstream = b
+	}
+
+	c, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	ctx := gousb.NewContext()
+	defer ctx.Close()
+
+	ds, err := ztex.OpenDevices(ctx)
+	if err != nil {
+		return err
+	}
+	if *serial != "" {
+		ds = ztex.FilterDevices(ds, ztex.SerialFilter(*serial))
+	} else {
+		ds = ztex.FilterDevices(ds)
+	}
+	if len(ds) == 0 {
+		return fmt.Errorf("got no modules, want at least one module")
+	}
+
+	// The reports are grouped by board type, so that the compatibility of
+	// a firmware release can be read off per board.
+	reports := map[string][]*ztextest.ConformanceReport{}
+	for _, d := range ds {
+		r := s.Run(c, d)
+		reports[r.Board] = append(reports[r.Board], r)
+		d.Close()
+	}
+
+	boards := []string{}
+	for b := range reports {
+		boards = append(boards, b)
+	}
+	sort.Strings(boards)
+
+	failed := false
+	for _, b := range boards {
+		for _, r := range reports[b] {
+			failed = failed || !r.Passed()
+		}
+	}
+
+	if *asJSON {
+		e := json.NewEncoder(os.Stdout)
+		e.SetIndent("", "  ")
+		if err := e.Encode(reports); err != nil {
+			return err
+		}
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		for _, b := range boards {
+			fmt.Fprintf(w, "%v\n", b)
+			for _, r := range reports[b] {
+				fmt.Fprintf(w, "  %v (product %v, firmware %v)\n", r.Serial, r.Product, r.Firmware)
+				for _, k := range r.Checks {
+					fmt.Fprintf(w, "    %v\t%v\t%v\t%v\n", k.Name, k.Status, k.Duration.Round(time.Microsecond), k.Detail)
+				}
+			}
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if failed {
+		return fmt.Errorf("conformance checks failed")
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+// Command ztex-embed writes a Go source file which embeds firmware images
+// and bitstreams, with their metadata, as variables of type
+// *asset.Firmware and *asset.Bitstream.  It is meant to be run by go
+// generate, from the directory of the package embedding the files:
+//
+//	//go:generate go run github.com/aljumi/ztex/cmd/ztex-embed -o assets.go firmware.ihx top.bit
+//
+// A variable may be named explicitly with an argument of the form
+// name=file.  The part of .bin files, which have no header, may be given
+// with -part.  The build time of files other than .bit files, whose header
+// records it, is given by SOURCE_DATE_EPOCH, and is zero if it is not set;
+// see asset.ReadSource.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aljumi/ztex/asset"
+	"github.com/aljumi/ztex/bitstream"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "ztex-embed: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	out := flag.String("o", "ztex_assets.go", "output file")
+	pkg := flag.String("pkg", os.Getenv("GOPACKAGE"), "package of the output file, by default the package run by go generate")
+	part := flag.String("part", "", "FPGA part of .bin files, such as xc7a35tcsg324-1")
+	flag.Parse()
+	if flag.NArg() == 0 {
+		return fmt.Errorf("got no files, want firmware images or bitstreams")
+	} else if *pkg == "" {
+		return fmt.Errorf("got no package, want -pkg outside of go generate")
+	}
+
+	s := []*asset.Source{}
+	for _, a := range flag.Args() {
+		name, path, ok := strings.Cut(a, "=")
+		if !ok {
+			name, path = "", a
+		}
+		x, err := asset.ReadSource(path)
+		if err != nil {
+			return err
+		}
+		if name != "" {
+			x.Var = name
+		}
+		if filepath.Ext(path) == ".bin" && *part != "" {
+			x.Part = bitstream.NormalizePart(*part)
+		}
+		s = append(s, x)
+	}
+
+	b := &bytes.Buffer{}
+	if err := asset.Generate(b, *pkg, s...); err != nil {
+		return err
+	}
+	return os.WriteFile(*out, b.Bytes(), 0o644)
+}
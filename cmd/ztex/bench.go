@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"os/signal"
+
+	"github.com/aljumi/ztex/perf"
+	"github.com/google/gousb"
+)
+
+var benchCommand = &command{
+	name:    "bench",
+	summary: "measure configuration speed, bulk transfer throughput, and flash read rate",
+	run:     runBench,
+}
+
+// benchResult describes one measurement in the report of "ztex bench".
+// The duration is the median of the runs.
+type benchResult struct {
+	Method         string  `json:"method,omitempty"`
+	Bytes          int64   `json:"bytes"`
+	Runs           int     `json:"runs"`
+	Seconds        float64 `json:"seconds"`
+	BytesPerSecond float64 `json:"bytes_per_second"`
+	Mismatches     *int64  `json:"mismatches,omitempty"`
+}
+
+// newBenchResult returns the measurement of a benchmark in the report, or
+// nil if the benchmark was not run.
+func newBenchResult(r *perf.Report, name string) *benchResult {
+	x, ok := r.Result(name)
+	if !ok {
+		return nil
+	}
+	// JSON cannot encode infinities, which a run too short for the clock
+	// to measure would give, so such throughputs are reported as zero.
+	if math.IsInf(x.BytesPerSecond, 0) || math.IsNaN(x.BytesPerSecond) {
+		x.BytesPerSecond = 0
+	}
+	return &benchResult{
+		Method:         x.Method,
+		Bytes:          x.Bytes,
+		Runs:           x.Runs,
+		Seconds:        x.Median.Seconds(),
+		BytesPerSecond: x.BytesPerSecond,
+		Mismatches:     x.Mismatches,
+	}
+}
+
+// benchReport is the report printed by "ztex bench".  Measurements which
+// were not requested are omitted.
+type benchReport struct {
+	Serial        string       `json:"serial"`
+	Speed         string       `json:"speed"`
+	Configuration *benchResult `json:"configuration,omitempty"`
+	Out           *benchResult `json:"out,omitempty"`
+	In            *benchResult `json:"in,omitempty"`
+	Loopback      *benchResult `json:"loopback,omitempty"`
+	FlashRead     *benchResult `json:"flash_read,omitempty"`
+}
+
+func runBench(ctx *gousb.Context, args []string) error {
+	f := flag.NewFlagSet("ztex bench", flag.ContinueOnError)
+	addSelectionFlags(f, true)
+	bitstream := f.String("bitstream", "", "bitstream with which to measure configuration speed")
+	size := f.Int64("size", 64<<20, "number of bytes to transfer in each direction")
+	chunk := f.Int("chunk", 1<<20, "number of bytes per bulk transfer")
+	out := f.Bool("out", false, "measure bulk OUT throughput")
+	in := f.Bool("in", false, "measure bulk IN throughput")
+	loopback := f.Bool("loopback", false, "measure round-trip throughput against a loopback design, verifying the data")
+	sectors := f.Uint("flash", 0, "number of flash sectors with which to measure the flash read rate")
+	runs := f.Int("runs", 1, "number of times to run each measurement")
+	if err := f.Parse(args); err != nil {
+		return err
+	} else if f.NArg() != 0 {
+		return fmt.Errorf("got %v arguments, want no arguments", f.NArg())
+	} else if *size <= 0 || *chunk <= 0 || *runs <= 0 {
+		return fmt.Errorf("got size %v, chunk %v, and runs %v, want positive size, chunk, and runs", *size, *chunk, *runs)
+	}
+
+	d, err := openDevice(ctx)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	c, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	s := perf.Suite{
+		Out:          *out,
+		In:           *in,
+		Loopback:     *loopback,
+		Size:         *size,
+		Chunk:        *chunk,
+		FlashSectors: uint32(*sectors),
+		Runs:         *runs,
+	}
+	if *bitstream != "" {
+		if s.Bitstream, err = os.ReadFile(*bitstream); err != nil {
+			return err
+		}
+	}
+
+	x, err := s.Run(c, d)
+	if err != nil {
+		return err
+	}
+	r := benchReport{
+		Serial:        x.Serial,
+		Speed:         x.Speed,
+		Configuration: newBenchResult(x, perf.Configuration),
+		Out:           newBenchResult(x, perf.Out),
+		In:            newBenchResult(x, perf.In),
+		Loopback:      newBenchResult(x, perf.Loopback),
+		FlashRead:     newBenchResult(x, perf.FlashRead),
+	}
+
+	if jsonOutput {
+		return writeJSON("bench", r)
+	}
+
+	e := json.NewEncoder(os.Stdout)
+	e.SetIndent("", "  ")
+	return e.Encode(r)
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/aljumi/ztex/server"
+	"github.com/google/gousb"
+)
+
+var daemonCommand = &command{
+	name:    "daemon",
+	summary: "hold modules open and serve a local HTTP API",
+	run:     runDaemon,
+}
+
+// defaultSocket returns the unix socket on which the daemon listens by
+// default, in the runtime directory of the user if there is one.
+func defaultSocket() string {
+	d := os.Getenv("XDG_RUNTIME_DIR")
+	if d == "" {
+		d = os.TempDir()
+	}
+	return "unix:" + filepath.Join(d, "ztex.sock")
+}
+
+// removeStaleSocket removes the unix socket at path if no daemon listens
+// on it any more, as a daemon which did not shut down leaves its socket
+// behind, which would keep the next one from listening.
+func removeStaleSocket(path string) error {
+	fi, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	} else if fi.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("got %v, which is not a socket, want a path for the socket", path)
+	}
+	if c, err := net.Dial("unix", path); err == nil {
+		c.Close()
+		return fmt.Errorf("got %v, on which a daemon is listening, want a free socket", path)
+	}
+	return os.Remove(path)
+}
+
+// loopbackHosts are the names of the local host, any of which a client
+// of a daemon listening on one of them may use.
+var loopbackHosts = []string{"localhost", "127.0.0.1", "::1"}
+
+// daemonHosts returns the values of the Host header for which a daemon
+// listening on the given TCP address serves requests, or nil if it
+// listens on all addresses, and then relies on its token.
+func daemonHosts(addr string) ([]string, error) {
+	h, p, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("net.SplitHostPort: %w", err)
+	} else if h == "" {
+		return nil, nil
+	}
+	x := []string{strings.ToLower(net.JoinHostPort(h, p))}
+	for _, l := range loopbackHosts {
+		if strings.EqualFold(h, l) {
+			x = nil
+			for _, l := range loopbackHosts {
+				x = append(x, net.JoinHostPort(l, p))
+			}
+		}
+	}
+	return x, nil
+}
+
+// runDaemon holds the selected modules open and serves the HTTP API of
+// package server for them, until interrupted.  By default, the API is
+// served on a unix socket which only the user can access.  On TCP, it
+// requires a bearer token, which is generated and logged unless set with
+// -token or $ZTEX_TOKEN, and only answers requests for the address it
+// listens on.
+func runDaemon(ctx *gousb.Context, args []string) error {
+	f := flag.NewFlagSet("ztex daemon", flag.ContinueOnError)
+	addSelectionFlags(f, true)
+	listen := f.String("listen", defaultSocket(), "address on which to serve the HTTP API: unix:PATH for a unix socket, or HOST:PORT for TCP")
+	token := f.String("token", os.Getenv("ZTEX_TOKEN"), "bearer token which clients must send in the Authorization header (default: $ZTEX_TOKEN, or a random token on TCP)")
+	maxUpload := f.Int64("max-upload", server.DefaultMaxUploadSize, "maximum size in bytes of an uploaded bitstream or firmware image")
+	maxStream := f.Int("max-stream", server.DefaultMaxStreamSize, "maximum number of bytes read from or written to the stream by one request")
+	if err := f.Parse(args); err != nil {
+		return err
+	} else if f.NArg() != 0 {
+		return fmt.Errorf("got %v arguments, want no arguments", f.NArg())
+	}
+
+	ds, err := openDevices(ctx)
+	if err != nil {
+		return err
+	}
+
+	seen := map[string]bool{}
+	for _, d := range ds {
+		defer d.Close()
+		k := d.Serial().String()
+		if seen[k] {
+			return fmt.Errorf("got several modules with serial number %q, want unique serial numbers", k)
+		}
+		seen[k] = true
+	}
+
+	s := server.New(ds...)
+	s.MaxUploadSize, s.MaxStreamSize = *maxUpload, *maxStream
+	s.Token = *token
+	defer s.Close()
+
+	var l net.Listener
+	if path, ok := strings.CutPrefix(*listen, "unix:"); ok {
+		if err := removeStaleSocket(path); err != nil {
+			return err
+		}
+		if l, err = net.Listen("unix", path); err != nil {
+			return err
+		}
+		defer os.Remove(path)
+		if err := os.Chmod(path, 0o600); err != nil {
+			l.Close()
+			return err
+		}
+	} else {
+		if s.Hosts, err = daemonHosts(*listen); err != nil {
+			return err
+		}
+		if s.Token == "" {
+			b := make([]byte, 16)
+			if _, err := rand.Read(b); err != nil {
+				return err
+			}
+			s.Token = hex.EncodeToString(b)
+			log.Printf("clients must send the header \"Authorization: Bearer %v\"", s.Token)
+		}
+		if l, err = net.Listen("tcp", *listen); err != nil {
+			return err
+		}
+	}
+
+	h := &http.Server{Handler: s}
+	c, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-c.Done()
+		h.Shutdown(context.Background())
+	}()
+
+	log.Printf("serving %v modules on %v", len(ds), *listen)
+	if err := h.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
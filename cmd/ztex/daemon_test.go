@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDaemonHosts(t *testing.T) {
+	for _, tt := range []struct {
+		addr string
+		want []string
+	}{
+		{"localhost:8080", []string{"localhost:8080", "127.0.0.1:8080", "[::1]:8080"}},
+		{"[::1]:80", []string{"localhost:80", "127.0.0.1:80", "[::1]:80"}},
+		{"Example.COM:80", []string{"example.com:80"}},
+		{"192.0.2.1:9000", []string{"192.0.2.1:9000"}},
+		{":8080", nil},
+	} {
+		got, err := daemonHosts(tt.addr)
+		if err != nil {
+			t.Errorf("daemonHosts(%q): %v", tt.addr, err)
+		} else if strings.Join(got, " ") != strings.Join(tt.want, " ") || (got == nil) != (tt.want == nil) {
+			t.Errorf("daemonHosts(%q): got %q, want %q", tt.addr, got, tt.want)
+		}
+	}
+
+	if got, err := daemonHosts("localhost"); err == nil {
+		t.Errorf("daemonHosts(%q): got %q, want error", "localhost", got)
+	}
+}
+
+func TestRemoveStaleSocket(t *testing.T) {
+	dir := t.TempDir()
+
+	// A missing socket is fine, and other files are left alone.
+	p := filepath.Join(dir, "ztex.sock")
+	if err := removeStaleSocket(p); err != nil {
+		t.Errorf("removeStaleSocket of a missing socket: %v", err)
+	}
+	if err := os.WriteFile(p, nil, 0600); err != nil {
+		t.Fatalf("os.WriteFile: %v", err)
+	} else if err := removeStaleSocket(p); err == nil {
+		t.Errorf("removeStaleSocket of a regular file: got no error, want error")
+	} else if _, err := os.Stat(p); err != nil {
+		t.Errorf("removeStaleSocket of a regular file: got file removed, want file kept")
+	}
+	os.Remove(p)
+
+	// A socket on which a daemon listens is kept, and removed once the
+	// daemon is gone.
+	l, err := net.Listen("unix", p)
+	if err != nil {
+		t.Skipf("net.Listen: %v", err)
+	}
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	if err := removeStaleSocket(p); err == nil {
+		t.Errorf("removeStaleSocket of a live socket: got no error, want error")
+	}
+	l.Close()
+	if err := removeStaleSocket(p); err != nil {
+		t.Errorf("removeStaleSocket of a stale socket: %v", err)
+	} else if _, err := os.Lstat(p); !os.IsNotExist(err) {
+		t.Errorf("removeStaleSocket of a stale socket: got %v, want socket removed", err)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"github.com/aljumi/ztex"
+	"github.com/google/gousb"
+)
+
+var debugCommand = &command{
+	name:    "debug",
+	summary: "stream firmware debug messages",
+	run:     runDebug,
+}
+
+// syncWriter serializes writes to an underlying writer, so that lines
+// written by several goroutines are not interleaved.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
+// debugEntry describes a debug message in the JSON output of "ztex debug
+// tail".
+type debugEntry struct {
+	Time     time.Time `json:"time"`
+	Serial   string    `json:"serial"`
+	Sequence uint16    `json:"sequence"`
+	Text     string    `json:"text"`
+}
+
+// writeDebugJSON prints the debug messages of a device as JSON until the
+// context is done.
+func writeDebugJSON(ctx context.Context, d *ztex.Device) error {
+	t, err := d.TailDebug(ctx)
+	if err != nil {
+		return err
+	}
+
+	for m := range t.C {
+		if err := writeJSON("debug", debugEntry{time.Now(), d.Serial().String(), uint16(m.DebugSequence), m.DebugPayload.Text()}); err != nil {
+			return err
+		}
+	}
+
+	return t.Err()
+}
+
+func runDebug(ctx *gousb.Context, args []string) error {
+	if len(args) < 1 || args[0] != "tail" {
+		return fmt.Errorf("got no subcommand, want tail")
+	}
+
+	f := flag.NewFlagSet("ztex debug tail", flag.ContinueOnError)
+	addSelectionFlags(f, true)
+	if err := f.Parse(args[1:]); err != nil {
+		return err
+	} else if f.NArg() != 0 {
+		return fmt.Errorf("got %v arguments, want no arguments", f.NArg())
+	}
+
+	ds, err := openDevices(ctx)
+	if err != nil {
+		return err
+	}
+	for _, d := range ds {
+		defer d.Close()
+	}
+
+	c, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	w := &syncWriter{w: os.Stdout}
+	e := make([]error, len(ds))
+	var wg sync.WaitGroup
+	for i, d := range ds {
+		wg.Add(1)
+		go func(i int, d *ztex.Device) {
+			defer wg.Done()
+			tail := d.WriteDebug
+			if jsonOutput {
+				tail = func(c context.Context, _ io.Writer) error { return writeDebugJSON(c, d) }
+			}
+			if err := tail(c, w); err != nil && !errors.Is(err, context.Canceled) {
+				e[i] = fmt.Errorf("%v: %w", d.Serial(), err)
+			}
+		}(i, d)
+	}
+	wg.Wait()
+
+	return errors.Join(e...)
+}
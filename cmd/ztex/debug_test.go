@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aljumi/ztex/ztextest"
+)
+
+func TestWriteDebugJSON(t *testing.T) {
+	f := ztextest.NewFakeDevice("fake000001")
+	f.Descriptor[12] |= 0x08
+	f.Debug = ztextest.NewFakeDebug(4, 8)
+	f.WriteDebug([]byte("hello"))
+	d, err := ztextest.Open(f)
+	if err != nil {
+		t.Fatalf("ztextest.Open: %v", err)
+	}
+	defer d.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	s := captureStdout(t, func() error {
+		if err := writeDebugJSON(ctx, d); !errors.Is(err, context.DeadlineExceeded) {
+			return err
+		}
+		return nil
+	})
+	if !strings.Contains(s, `"serial":"fake000001","sequence":0,"text":"hello"`) || strings.Count(s, "\n") != 1 {
+		t.Errorf("writeDebugJSON: got %q, want one line with the message", s)
+	}
+}
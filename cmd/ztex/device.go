@@ -0,0 +1,156 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/aljumi/ztex"
+	"github.com/google/gousb"
+)
+
+// selection holds the flags which select the modules a command operates
+// on.  They are accepted both before the command name and among the flags
+// of every command.
+var selection struct {
+	serial string
+	bus    int
+	port   int
+	index  int
+	number int
+	all    bool
+}
+
+// addSelectionFlags adds the module selection flags to a flag set.  The
+// -all flag is left out for commands which use it for another purpose.
+func addSelectionFlags(f *flag.FlagSet, all bool) {
+	f.StringVar(&selection.serial, "serial", selection.serial, "select the module with this serial number")
+	f.IntVar(&selection.bus, "bus", selection.bus, "select the modules on this USB bus")
+	f.IntVar(&selection.port, "port", selection.port, "select the modules on this hub port")
+	f.IntVar(&selection.index, "index", selection.index, "select the module with this index among the selected modules, ordered by bus and address")
+	f.IntVar(&selection.number, "d", selection.number, "select the module with this device number of the ZTEX SDK, as given to FWLoader with -d")
+	if all {
+		f.BoolVar(&selection.all, "all", selection.all, "select all matching modules")
+	}
+}
+
+func init() {
+	selection.bus, selection.port, selection.index, selection.number = -1, -1, -1, -1
+}
+
+// describeSelection returns a description of the selection flags in use.
+func describeSelection() string {
+	x := []string{}
+	if selection.serial != "" {
+		x = append(x, fmt.Sprintf("serial number %q", selection.serial))
+	}
+	if selection.bus >= 0 {
+		x = append(x, fmt.Sprintf("bus %v", selection.bus))
+	}
+	if selection.port >= 0 {
+		x = append(x, fmt.Sprintf("port %v", selection.port))
+	}
+	if selection.number >= 0 {
+		x = append(x, fmt.Sprintf("device number %v", selection.number))
+	}
+	if len(x) == 0 {
+		return "any module"
+	}
+	return strings.Join(x, ", ")
+}
+
+// selectDevices opens the modules selected by the selection flags, which
+// may be none.  The configuration of the modules is only read once they
+// have been selected, which saves a control transfer for every other
+// module.
+func selectDevices(ctx *gousb.Context) ([]*ztex.Device, error) {
+	ds, err := ztex.OpenDevices(ctx, ztex.DeferDeviceConfig())
+	if len(ds) == 0 && err != nil {
+		return nil, err
+	}
+
+	// Device numbers of the SDK count all modules in the order in which
+	// libusb lists them, so the module is picked before the other flags
+	// are applied.
+	if selection.number >= 0 {
+		ds = ztex.FilterDevicesInScanOrder(ds)
+		if selection.number >= len(ds) {
+			n := len(ds)
+			closeDevices(ds)
+			return nil, fmt.Errorf("got device number %v, want device number in [0, %v)", selection.number, n)
+		}
+		d := ds[selection.number]
+		closeDevices(append(ds[:selection.number:selection.number], ds[selection.number+1:]...))
+		ds = []*ztex.Device{d}
+	}
+
+	f := []ztex.DeviceFilter{}
+	if selection.serial != "" {
+		f = append(f, ztex.SerialFilter(selection.serial))
+	}
+	if selection.bus >= 0 {
+		f = append(f, ztex.BusFilter(selection.bus))
+	}
+	if selection.port >= 0 {
+		f = append(f, ztex.PortFilter(selection.port))
+	}
+	ds = ztex.FilterDevices(ds, f...)
+
+	if selection.index >= 0 {
+		if selection.index >= len(ds) {
+			n := len(ds)
+			closeDevices(ds)
+			return nil, fmt.Errorf("got index %v, want index in [0, %v) for %v", selection.index, n, describeSelection())
+		}
+		d := ds[selection.index]
+		closeDevices(append(ds[:selection.index:selection.index], ds[selection.index+1:]...))
+		ds = []*ztex.Device{d}
+	}
+
+	for _, d := range ds {
+		if err := d.LoadDeviceConfig(); err != nil {
+			closeDevices(ds)
+			return nil, err
+		}
+	}
+
+	return ds, nil
+}
+
+// openDevice opens the single module selected by the selection flags.
+func openDevice(ctx *gousb.Context) (*ztex.Device, error) {
+	if selection.all {
+		return nil, fmt.Errorf("got -all, want a single module")
+	}
+
+	ds, err := selectDevices(ctx)
+	if err != nil {
+		return nil, err
+	} else if len(ds) == 0 {
+		return nil, fmt.Errorf("%w: got no modules matching %v, want a module", ztex.ErrNoDevice, describeSelection())
+	} else if len(ds) != 1 {
+		n := len(ds)
+		closeDevices(ds)
+		return nil, fmt.Errorf("got %v modules matching %v, want a single module; use -serial, -bus, -port, or -index to select one", n, describeSelection())
+	}
+	return ds[0], nil
+}
+
+// openDevices opens the modules selected by the selection flags.  Without
+// any selection flags, all attached modules are opened.
+func openDevices(ctx *gousb.Context) ([]*ztex.Device, error) {
+	ds, err := selectDevices(ctx)
+	if err != nil {
+		return nil, err
+	} else if len(ds) == 0 {
+		return nil, fmt.Errorf("%w: got no modules matching %v, want a module", ztex.ErrNoDevice, describeSelection())
+	}
+	return ds, nil
+}
+
+// closeDevices closes every device in ds.
+func closeDevices(ds []*ztex.Device) {
+	for _, d := range ds {
+		d.Close()
+	}
+}
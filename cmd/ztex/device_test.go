@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"testing"
+)
+
+func TestSelectionFlags(t *testing.T) {
+	saved := selection
+	defer func() { selection = saved }()
+
+	if got := describeSelection(); got != "any module" {
+		t.Errorf("describeSelection: got %q, want %q", got, "any module")
+	}
+
+	// Flags given before the command name are kept unless the command
+	// overrides them.
+	g := flag.NewFlagSet("ztex", flag.ContinueOnError)
+	addSelectionFlags(g, true)
+	if err := g.Parse([]string{"-serial", "fake000001", "-bus", "2"}); err != nil {
+		t.Fatalf("(*flag.FlagSet).Parse: %v", err)
+	}
+	f := flag.NewFlagSet("ztex info", flag.ContinueOnError)
+	addSelectionFlags(f, false)
+	if err := f.Parse([]string{"-bus", "3", "-d", "1"}); err != nil {
+		t.Fatalf("(*flag.FlagSet).Parse: %v", err)
+	}
+	if got, want := describeSelection(), `serial number "fake000001", bus 3, device number 1`; got != want {
+		t.Errorf("describeSelection: got %q, want %q", got, want)
+	}
+
+	// Commands which use -all for another purpose do not accept it.
+	f = flag.NewFlagSet("ztex info", flag.ContinueOnError)
+	f.SetOutput(io.Discard)
+	addSelectionFlags(f, false)
+	if err := f.Parse([]string{"-all"}); err == nil {
+		t.Errorf("(*flag.FlagSet).Parse(-all): got no error, want error")
+	}
+}
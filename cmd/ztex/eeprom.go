@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/aljumi/ztex"
+	"github.com/google/gousb"
+)
+
+var eepromCommand = &command{
+	name:    "eeprom",
+	summary: "dump, restore, or show the firmware EEPROM or MAC EEPROM of a module",
+	run:     runEEPROM,
+}
+
+// eepromChunk is the maximum number of bytes read from an EEPROM by one
+// control transfer.
+const eepromChunk = 1024
+
+func runEEPROM(ctx *gousb.Context, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("got no subcommand, want dump, restore, or show")
+	}
+
+	f := flag.NewFlagSet("ztex eeprom "+args[0], flag.ContinueOnError)
+	addSelectionFlags(f, true)
+	mac := f.Bool("mac", false, "access the MAC EEPROM instead of the firmware EEPROM")
+	size := f.Int("size", 0, "number of bytes to dump (default: 16384 for the firmware EEPROM, 128 for the MAC EEPROM)")
+
+	switch args[0] {
+	case "dump":
+		if err := f.Parse(args[1:]); err != nil {
+			return err
+		} else if f.NArg() != 1 {
+			return fmt.Errorf("got %v arguments, want an output file", f.NArg())
+		} else if *size < 0 || *size > 1<<16 {
+			return fmt.Errorf("got size %v, want a size in [0, %v]", *size, 1<<16)
+		}
+	case "restore":
+		if err := f.Parse(args[1:]); err != nil {
+			return err
+		} else if f.NArg() != 1 {
+			return fmt.Errorf("got %v arguments, want an input file", f.NArg())
+		}
+	case "show":
+		if err := f.Parse(args[1:]); err != nil {
+			return err
+		} else if f.NArg() > 1 {
+			return fmt.Errorf("got %v arguments, want at most a dump file", f.NArg())
+		} else if f.NArg() == 1 {
+			b, err := os.ReadFile(f.Arg(0))
+			if err != nil {
+				return err
+			}
+			return showDeviceConfig(b)
+		}
+	default:
+		return fmt.Errorf("got subcommand %q, want dump, restore, or show", args[0])
+	}
+
+	d, err := openDevice(ctx)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	read, write := d.ReadEEPROM, d.WriteEEPROM
+	if *mac || args[0] == "show" {
+		read, write = d.ReadMACEEPROM, d.WriteMACEEPROM
+	}
+
+	switch args[0] {
+	case "dump":
+		n := *size
+		if n == 0 && *mac {
+			n = 128
+		} else if n == 0 {
+			n = 16384
+		}
+		// Control transfers are limited in size, so the EEPROM is read
+		// in chunks.
+		b := make([]byte, n)
+		for i := 0; i < len(b); i += eepromChunk {
+			if err := read(uint16(i), b[i:min(i+eepromChunk, len(b))]); err != nil {
+				return err
+			}
+		}
+		if f.Arg(0) == "-" && jsonOutput {
+			return fmt.Errorf("got output file -, want a file with -json")
+		} else if f.Arg(0) == "-" {
+			_, err := os.Stdout.Write(b)
+			return err
+		} else if err := os.WriteFile(f.Arg(0), b, 0o644); err != nil {
+			return err
+		}
+		return reportEEPROM(d, "dump", *mac, len(b))
+	case "restore":
+		b, err := os.ReadFile(f.Arg(0))
+		if err != nil {
+			return err
+		} else if len(b) > 1<<16 {
+			return fmt.Errorf("got %v bytes, want at most %v bytes", len(b), 1<<16)
+		}
+		if err := write(0, b); err != nil {
+			return err
+		}
+		return reportEEPROM(d, "restore", *mac, len(b))
+	default:
+		b := make([]byte, 128)
+		if err := read(0, b); err != nil {
+			return err
+		}
+		return showDeviceConfig(b)
+	}
+}
+
+// eepromResult describes a completed dump or restore in the JSON output of
+// "ztex eeprom".
+type eepromResult struct {
+	Serial    string `json:"serial"`
+	Operation string `json:"operation"`
+	EEPROM    string `json:"eeprom"`
+	Bytes     int    `json:"bytes"`
+}
+
+// reportEEPROM prints the result of a dump or restore if JSON output is
+// selected.
+func reportEEPROM(d *ztex.Device, op string, mac bool, n int) error {
+	if !jsonOutput {
+		return nil
+	}
+	e := "firmware"
+	if mac {
+		e = "mac"
+	}
+	return writeJSON("eeprom", eepromResult{d.Serial().String(), op, e, n})
+}
+
+// eepromConfig describes the configuration data area in the JSON output
+// of "ztex eeprom show".  The user data is encoded in base64.
+type eepromConfig struct {
+	Board       string        `json:"board"`
+	BoardType   string        `json:"board_type"`
+	FPGA        string        `json:"fpga"`
+	FPGAPackage string        `json:"fpga_package"`
+	FPGAGrade   string        `json:"fpga_grade"`
+	RAMSize     string        `json:"ram_size"`
+	RAMType     string        `json:"ram_type"`
+	Serial      string        `json:"serial"`
+	Bitstream   infoBitstream `json:"bitstream"`
+	UserData    []byte        `json:"user_data"`
+}
+
+// showDeviceConfig prints a decoded view of the configuration data area
+// of the MAC EEPROM.
+func showDeviceConfig(b []byte) error {
+	if len(b) > 128 {
+		b = b[:128]
+	}
+	c, err := ztex.ParseDeviceConfig(b)
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		return writeJSON("eeprom", eepromConfig{
+			Board:       c.BoardConfig.BoardVersion.String(),
+			BoardType:   c.BoardConfig.BoardType.String(),
+			FPGA:        c.FPGAConfig.FPGAType.String(),
+			FPGAPackage: c.FPGAConfig.FPGAPackage.String(),
+			FPGAGrade:   c.FPGAConfig.FPGAGrade.String(),
+			RAMSize:     c.RAMConfig.RAMSize.String(),
+			RAMType:     c.RAMConfig.RAMType.String(),
+			Serial:      c.DescriptorSerial.String(),
+			Bitstream: infoBitstream{
+				Size:     int(c.BitstreamConfig.BitstreamSize.Bytes()),
+				Capacity: int(c.BitstreamConfig.BitstreamCapacity.Bytes()),
+				Start:    int(c.BitstreamConfig.BitstreamStart.Bytes()),
+			},
+			UserData: b[48:],
+		})
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	writeDeviceConfig(w, c, b)
+	return w.Flush()
+}
+
+// writeDeviceConfig writes the fields of the configuration data area and
+// any problems found in them, followed by the user data area as a hex
+// dump.
+func writeDeviceConfig(w io.Writer, c *ztex.DeviceConfig, b []byte) {
+	fmt.Fprintf(w, "Signature:\t%s\n", b[:3])
+	fmt.Fprintf(w, "Board\n")
+	fmt.Fprintf(w, "  Type:\t%v\n", c.BoardConfig.BoardType)
+	fmt.Fprintf(w, "  Version:\t%v\n", c.BoardConfig.BoardVersion)
+	fmt.Fprintf(w, "FPGA\n")
+	fmt.Fprintf(w, "  Type:\t%v\n", c.FPGAConfig.FPGAType)
+	fmt.Fprintf(w, "  Package:\t%v\n", c.FPGAConfig.FPGAPackage)
+	fmt.Fprintf(w, "  Grade:\t%v\n", c.FPGAConfig.FPGAGrade)
+	fmt.Fprintf(w, "RAM\n")
+	fmt.Fprintf(w, "  Size:\t%v\n", c.RAMConfig.RAMSize)
+	fmt.Fprintf(w, "  Type:\t%v\n", c.RAMConfig.RAMType)
+	fmt.Fprintf(w, "Serial:\t%v\n", c.DescriptorSerial)
+	fmt.Fprintf(w, "Bitstream\n")
+	fmt.Fprintf(w, "  Size:\t%v\n", c.BitstreamConfig.BitstreamSize)
+	fmt.Fprintf(w, "  Capacity:\t%v\n", c.BitstreamConfig.BitstreamCapacity)
+	fmt.Fprintf(w, "  Start:\t%v\n", c.BitstreamConfig.BitstreamStart)
+	for _, i := range c.Validate() {
+		fmt.Fprintf(w, "%v:\t%v: %v\n", i.Severity, i.Field, i.Message)
+	}
+	fmt.Fprintf(w, "User Data\n%v", hex.Dump(b[48:]))
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/aljumi/ztex"
+	"github.com/aljumi/ztex/ztextest"
+)
+
+func TestShowDeviceConfig(t *testing.T) {
+	b := ztextest.NewFakeDevice("fake000001").MACEEPROM
+	copy(b[48:], "user")
+
+	c, err := ztex.ParseDeviceConfig(b[:128])
+	if err != nil {
+		t.Fatalf("ztex.ParseDeviceConfig: %v", err)
+	}
+	w := &bytes.Buffer{}
+	writeDeviceConfig(w, c, b[:128])
+	for _, want := range []string{"Signature:\tCD0\n", "  Version:\t2.13a\n", "Serial:\tfake000001\n", "75 73 65 72"} {
+		if !strings.Contains(w.String(), want) {
+			t.Errorf("writeDeviceConfig: got %q, want %q in it", w.String(), want)
+		}
+	}
+
+	// The whole MAC EEPROM may be given, of which only the configuration
+	// data area is shown.
+	jsonOutput = true
+	defer func() { jsonOutput = false }()
+	s := captureStdout(t, func() error { return showDeviceConfig(b) })
+	var x struct {
+		Result eepromConfig `json:"result"`
+	}
+	if err := json.Unmarshal([]byte(s), &x); err != nil {
+		t.Fatalf("json.Unmarshal: %v", err)
+	} else if x.Result.Serial != "fake000001" || x.Result.Board != "2.13a" || len(x.Result.UserData) != 80 || string(x.Result.UserData[:4]) != "user" {
+		t.Errorf("showDeviceConfig: got %+v, want serial fake000001 of a 2.13a with 80 bytes of user data", x.Result)
+	}
+
+	if err := showDeviceConfig(make([]byte, 128)); err == nil {
+		t.Errorf("showDeviceConfig of an empty area: got no error, want error")
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/aljumi/ztex"
+	"github.com/google/gousb"
+)
+
+var firmwareCommand = &command{
+	name:    "firmware",
+	summary: "upload, install, verify, or disable the firmware of a module",
+	run:     runFirmware,
+}
+
+// fx3BootLoader identifies an EZ-USB FX3 in boot loader mode.
+const (
+	fx3BootLoaderVendor  = 0x04b4
+	fx3BootLoaderProduct = 0x00f3
+)
+
+func runFirmware(ctx *gousb.Context, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("got no subcommand, want upload, install, verify, or disable")
+	}
+
+	f := flag.NewFlagSet("ztex firmware "+args[0], flag.ContinueOnError)
+	addSelectionFlags(f, true)
+	quiet := f.Bool("quiet", false, "do not show a progress bar")
+	bootLoader := false
+	if args[0] == "upload" {
+		f.BoolVar(&bootLoader, "bootloader", false, "upload to an FX3 in boot loader mode instead of a module")
+	}
+
+	c, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	switch args[0] {
+	case "upload", "install", "verify":
+		if err := f.Parse(args[1:]); err != nil {
+			return err
+		} else if f.NArg() != 1 {
+			return fmt.Errorf("got %v arguments, want a firmware file", f.NArg())
+		}
+	case "disable":
+		if err := f.Parse(args[1:]); err != nil {
+			return err
+		} else if f.NArg() != 0 {
+			return fmt.Errorf("got %v arguments, want no arguments", f.NArg())
+		}
+	default:
+		return fmt.Errorf("got subcommand %q, want upload, install, verify, or disable", args[0])
+	}
+
+	var p ztex.Progress
+	if !*quiet {
+		p = progressBar(os.Stderr, args[0])
+	}
+
+	if bootLoader {
+		r, err := os.Open(f.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+
+		dev, err := ztex.OpenGousbDevice(ctx, fx3BootLoaderVendor, fx3BootLoaderProduct)
+		if errors.Is(err, ztex.ErrNoDevice) {
+			return fmt.Errorf("got no FX3 in boot loader mode, want an FX3 in boot loader mode: %w", err)
+		} else if err != nil {
+			return err
+		}
+		defer dev.Close()
+
+		if err := ztex.UploadFX3Firmware(c, dev, r, p); err != nil || !jsonOutput {
+			return err
+		}
+		return writeJSON("firmware", firmwareResult{"", args[0], f.Arg(0)})
+	}
+
+	d, err := openDevice(ctx)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	if args[0] == "disable" {
+		if err := d.DisableFirmware(c); err != nil || !jsonOutput {
+			return err
+		}
+		return writeJSON("firmware", firmwareResult{d.Serial().String(), args[0], ""})
+	}
+
+	r, err := os.Open(f.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	switch args[0] {
+	case "upload":
+		err = d.UploadFirmware(c, r, p)
+	case "install":
+		err = d.InstallFirmware(c, r, p)
+	default:
+		ok := false
+		if ok, err = d.VerifyFirmware(c, r, p); err == nil && !ok {
+			err = fmt.Errorf("got different firmware, want firmware from %v", f.Arg(0))
+		} else if err == nil && !jsonOutput {
+			fmt.Printf("%v: firmware matches %v\n", d.Serial(), f.Arg(0))
+		}
+	}
+	if err != nil || !jsonOutput {
+		return err
+	}
+	return writeJSON("firmware", firmwareResult{d.Serial().String(), args[0], f.Arg(0)})
+}
+
+// firmwareResult describes a completed firmware operation in the JSON
+// output of "ztex firmware".
+type firmwareResult struct {
+	Serial    string `json:"serial"`
+	Operation string `json:"operation"`
+	File      string `json:"file,omitempty"`
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/aljumi/ztex"
+	"github.com/google/gousb"
+)
+
+var flashCommand = &command{
+	name:    "flash",
+	summary: "read, write, erase, dump, or restore the flash of a module",
+	run:     runFlash,
+}
+
+// flashFlags holds the flags shared by the flash subcommands.
+type flashFlags struct {
+	*flag.FlagSet
+	sector uint
+	count  uint
+	quiet  bool
+}
+
+// newFlashFlags returns the flags of a flash subcommand.  Sector ranges are
+// only accepted by subcommands that operate on part of the flash.
+func newFlashFlags(name string, ranged bool) *flashFlags {
+	f := &flashFlags{FlagSet: flag.NewFlagSet("ztex flash "+name, flag.ContinueOnError)}
+	addSelectionFlags(f.FlagSet, true)
+	f.BoolVar(&f.quiet, "quiet", false, "do not show a progress bar")
+	if ranged {
+		f.UintVar(&f.sector, "sector", 0, "first sector")
+		f.UintVar(&f.count, "count", 0, "number of sectors (default: up to the end of the flash)")
+	}
+	return f
+}
+
+// progress returns the progress reporter selected by the flags.
+func (f *flashFlags) progress(label string) ztex.Progress {
+	if f.quiet {
+		return nil
+	}
+	return progressBar(os.Stderr, label)
+}
+
+// open opens the selected module and returns it along with the sector
+// size and the range of sectors selected by the flags.
+func (f *flashFlags) open(ctx *gousb.Context) (*ztex.Device, int, uint32, uint32, error) {
+	d, err := openDevice(ctx)
+	if err != nil {
+		return nil, 0, 0, 0, err
+	}
+
+	s, err := d.FlashStatus()
+	if err != nil {
+		d.Close()
+		return nil, 0, 0, 0, err
+	}
+
+	n := s.FlashCount.Number()
+	if uint64(f.sector) >= uint64(n) {
+		d.Close()
+		return nil, 0, 0, 0, fmt.Errorf("got sector %v, want sector in [0, %v)", f.sector, n)
+	}
+	c := n - uint32(f.sector)
+	if f.count != 0 {
+		if uint64(f.sector)+uint64(f.count) > uint64(n) {
+			d.Close()
+			return nil, 0, 0, 0, fmt.Errorf("got sectors [%v, %v), want sectors in [0, %v)", f.sector, uint64(f.sector)+uint64(f.count), n)
+		}
+		c = uint32(f.count)
+	}
+
+	return d, int(s.FlashSector.Number()), uint32(f.sector), c, nil
+}
+
+// flashResult describes a completed flash operation in the JSON output of
+// "ztex flash".
+type flashResult struct {
+	Serial     string `json:"serial"`
+	Operation  string `json:"operation"`
+	Sector     uint32 `json:"sector"`
+	Sectors    uint32 `json:"sectors"`
+	SectorSize int    `json:"sector_size"`
+	Bytes      int    `json:"bytes"`
+}
+
+// report prints the result of a flash operation if JSON output is
+// selected.
+func (f *flashFlags) report(d *ztex.Device, z int, s, n uint32, b int) error {
+	if !jsonOutput {
+		return nil
+	}
+	return writeJSON("flash", flashResult{d.Serial().String(), f.Name()[len("ztex flash "):], s, n, z, b})
+}
+
+func runFlash(ctx *gousb.Context, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("got no subcommand, want read, write, erase, dump, or restore")
+	}
+
+	c, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	switch args[0] {
+	case "read":
+		return runFlashRead(c, ctx, newFlashFlags("read", true), args[1:])
+	case "dump":
+		return runFlashRead(c, ctx, newFlashFlags("dump", false), args[1:])
+	case "write":
+		return runFlashWrite(c, ctx, newFlashFlags("write", true), args[1:])
+	case "restore":
+		return runFlashWrite(c, ctx, newFlashFlags("restore", false), args[1:])
+	case "erase":
+		return runFlashErase(c, ctx, newFlashFlags("erase", true), args[1:])
+	default:
+		return fmt.Errorf("got subcommand %q, want read, write, erase, dump, or restore", args[0])
+	}
+}
+
+// runFlashRead reads a range of sectors, or the whole flash, into a file.
+func runFlashRead(c context.Context, ctx *gousb.Context, f *flashFlags, args []string) error {
+	if err := f.Parse(args); err != nil {
+		return err
+	} else if f.NArg() != 1 {
+		return fmt.Errorf("got %v arguments, want an output file", f.NArg())
+	}
+
+	if f.Arg(0) == "-" && jsonOutput {
+		return fmt.Errorf("got output file -, want a file with -json")
+	}
+
+	d, z, s, n, err := f.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	// The sectors are written to the output while the next ones are
+	// read, in buffers of up to 1 MiB each.
+	buf := make([]byte, 2*max(z, 1<<20))
+	if f.Arg(0) == "-" {
+		return d.ReadFlashInto(c, os.Stdout, s, n, buf, f.progress("read"))
+	}
+
+	o, err := os.Create(f.Arg(0))
+	if err != nil {
+		return err
+	}
+	if err := d.ReadFlashInto(c, o, s, n, buf, f.progress("read")); err != nil {
+		o.Close()
+		os.Remove(f.Arg(0))
+		return err
+	} else if err := o.Close(); err != nil {
+		return err
+	}
+	return f.report(d, z, s, n, int(n)*z)
+}
+
+// runFlashWrite writes a file to the flash, starting at the selected
+// sector.  The last sector is padded with erased bytes.  Restoring an
+// image requires it to match the size of the flash exactly.
+func runFlashWrite(c context.Context, ctx *gousb.Context, f *flashFlags, args []string) error {
+	if err := f.Parse(args); err != nil {
+		return err
+	} else if f.NArg() != 1 {
+		return fmt.Errorf("got %v arguments, want an input file", f.NArg())
+	}
+
+	b, err := os.ReadFile(f.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	d, z, s, n, err := f.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	if f.Name() == "ztex flash restore" && len(b) != int(n)*z {
+		return fmt.Errorf("got image of %v bytes, want image of %v bytes", len(b), int(n)*z)
+	} else if len(b) > int(n)*z {
+		return fmt.Errorf("got %v bytes, want at most %v bytes", len(b), int(n)*z)
+	}
+	if r := len(b) % z; r != 0 {
+		b = append(b, bytes.Repeat([]byte{0xff}, z-r)...)
+	}
+
+	if err := d.WriteFlash(c, s, b, f.progress("write")); err != nil {
+		return err
+	}
+	return f.report(d, z, s, uint32(len(b)/z), len(b))
+}
+
+// runFlashErase erases a range of sectors, or the whole flash, by writing
+// erased bytes to them.
+func runFlashErase(c context.Context, ctx *gousb.Context, f *flashFlags, args []string) error {
+	if err := f.Parse(args); err != nil {
+		return err
+	} else if f.NArg() != 0 {
+		return fmt.Errorf("got %v arguments, want no arguments", f.NArg())
+	}
+
+	d, z, s, n, err := f.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	if err := d.WriteFlash(c, s, bytes.Repeat([]byte{0xff}, int(n)*z), f.progress("erase")); err != nil {
+		return err
+	}
+	return f.report(d, z, s, n, int(n)*z)
+}
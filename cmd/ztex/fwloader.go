@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+
+	"github.com/aljumi/ztex"
+	"github.com/aljumi/ztex/fwloader"
+	"github.com/google/gousb"
+)
+
+var fwloaderCommand = &command{
+	name:    "fwloader",
+	summary: "run FWLoader options of the ZTEX SDK, such as -d 0 -uf <bitstream>",
+	run:     runFWLoader,
+}
+
+// runFWLoader takes the options of FWLoader instead of the selection
+// flags, so that FWLoader invocations in existing scripts work by
+// replacing "FWLoader" with "ztex fwloader".
+func runFWLoader(ctx *gousb.Context, args []string) error {
+	o, err := fwloader.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	c, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	return o.Run(c, ztex.GousbBackend(ctx), os.Stdout)
+}
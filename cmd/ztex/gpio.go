@@ -0,0 +1,138 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/gousb"
+)
+
+var gpioCommand = &command{
+	name:    "gpio",
+	summary: "get or set the GPIO pins of the default firmware interface",
+	run:     runGPIO,
+}
+
+// gpioPins lists the symbolic names of the GPIO pins of the default
+// firmware interface, indexed by bit.
+var gpioPins = []string{"gpio0", "gpio1", "gpio2", "gpio3"}
+
+// parseGPIOPin returns the bit of a GPIO pin, given by its symbolic name
+// or by its number.
+func parseGPIOPin(s string) (uint, error) {
+	for i, p := range gpioPins {
+		if strings.EqualFold(s, p) {
+			return uint(i), nil
+		}
+	}
+	if i, err := strconv.ParseUint(s, 0, 8); err == nil && i < uint64(len(gpioPins)) {
+		return uint(i), nil
+	}
+	return 0, fmt.Errorf("got pin %q, want one of %v or a number in [0, %v)", s, strings.Join(gpioPins, ", "), len(gpioPins))
+}
+
+func runGPIO(ctx *gousb.Context, args []string) error {
+	if len(args) < 1 || (args[0] != "get" && args[0] != "set") {
+		return fmt.Errorf("got no subcommand, want get or set")
+	}
+
+	f := flag.NewFlagSet("ztex gpio "+args[0], flag.ContinueOnError)
+	addSelectionFlags(f, true)
+	pulse := time.Duration(0)
+	if args[0] == "set" {
+		f.DurationVar(&pulse, "pulse", 0, "restore the previous state of the pins after this duration")
+	}
+	if err := f.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	if args[0] == "get" {
+		pins := []uint{}
+		for _, s := range f.Args() {
+			i, err := parseGPIOPin(s)
+			if err != nil {
+				return err
+			}
+			pins = append(pins, i)
+		}
+		if len(pins) == 0 {
+			for i := range gpioPins {
+				pins = append(pins, uint(i))
+			}
+		}
+
+		d, err := openDevice(ctx)
+		if err != nil {
+			return err
+		}
+		defer d.Close()
+
+		v, err := d.GPIO(0, 0)
+		if err != nil {
+			return err
+		}
+		if jsonOutput {
+			x := map[string]uint8{}
+			for _, i := range pins {
+				x[gpioPins[i]] = v >> i & 1
+			}
+			return writeJSON("gpio", x)
+		}
+		for _, i := range pins {
+			fmt.Printf("%v=%v\n", gpioPins[i], v>>i&1)
+		}
+		return nil
+	}
+
+	if f.NArg() == 0 {
+		return fmt.Errorf("got no arguments, want PIN=VALUE arguments")
+	}
+	mask, value := uint8(0), uint8(0)
+	for _, s := range f.Args() {
+		p, v, ok := strings.Cut(s, "=")
+		if !ok || (v != "0" && v != "1") {
+			return fmt.Errorf("got argument %q, want PIN=0 or PIN=1", s)
+		}
+		i, err := parseGPIOPin(p)
+		if err != nil {
+			return err
+		}
+		mask |= 1 << i
+		if v == "1" {
+			value |= 1 << i
+		}
+	}
+
+	d, err := openDevice(ctx)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	prev, err := d.GPIO(0, 0)
+	if err != nil {
+		return err
+	}
+	if _, err := d.GPIO(mask, value); err != nil {
+		return err
+	}
+	if pulse > 0 {
+		time.Sleep(pulse)
+		if _, err := d.GPIO(mask, prev); err != nil {
+			return err
+		}
+	}
+	if jsonOutput {
+		x := map[string]uint8{}
+		for i := range gpioPins {
+			if mask>>i&1 != 0 {
+				x[gpioPins[i]] = value >> i & 1
+			}
+		}
+		return writeJSON("gpio", x)
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestParseGPIOPin(t *testing.T) {
+	for _, tt := range []struct {
+		s    string
+		want uint
+		ok   bool
+	}{
+		{"gpio0", 0, true},
+		{"GPIO3", 3, true},
+		{"2", 2, true},
+		{"0x1", 1, true},
+		{"4", 0, false},
+		{"gpio4", 0, false},
+		{"", 0, false},
+	} {
+		if i, err := parseGPIOPin(tt.s); (err == nil) != tt.ok || i != tt.want {
+			t.Errorf("parseGPIOPin(%q): got %v and %v, want %v and error %v", tt.s, i, err, tt.want, !tt.ok)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/aljumi/ztex"
+	"github.com/google/gousb"
+)
+
+var infoCommand = &command{
+	name:    "info",
+	summary: "print the configuration and status of a module",
+	run:     runInfo,
+}
+
+func runInfo(ctx *gousb.Context, args []string) error {
+	f := flag.NewFlagSet("ztex info", flag.ContinueOnError)
+	addSelectionFlags(f, true)
+	if err := f.Parse(args); err != nil {
+		return err
+	} else if f.NArg() != 0 {
+		return fmt.Errorf("got %v arguments, want no arguments", f.NArg())
+	}
+
+	d, err := openDevice(ctx)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	if jsonOutput {
+		return writeJSON("info", newInfoEntry(d))
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	writeInfo(w, d)
+	return w.Flush()
+}
+
+// infoEntry describes a module in the JSON output of "ztex info".
+// Statuses which the module does not support are omitted.
+type infoEntry struct {
+	listEntry
+	Speed        string                `json:"speed"`
+	Capabilities map[string]bool       `json:"capabilities"`
+	FPGAPackage  string                `json:"fpga_package"`
+	FPGAGrade    string                `json:"fpga_grade"`
+	RAMSize      string                `json:"ram_size"`
+	RAMType      string                `json:"ram_type"`
+	Bitstream    infoBitstream         `json:"bitstream"`
+	MultiFPGA    *ztex.MultiFPGAConfig `json:"multi_fpga,omitempty"`
+	FlashStatus  *monitorFlash         `json:"flash_status,omitempty"`
+	FPGAStatus   *monitorFPGA          `json:"fpga_status,omitempty"`
+}
+
+// infoBitstream describes the bitstream configuration in bytes.
+type infoBitstream struct {
+	Size     int `json:"size"`
+	Capacity int `json:"capacity"`
+	Start    int `json:"start"`
+}
+
+func newInfoEntry(d *ztex.Device) infoEntry {
+	c := d.Capability()
+	e := infoEntry{
+		listEntry: newListEntry(d),
+		Speed:     d.Desc.Speed,
+		Capabilities: map[string]bool{
+			"eeprom":                        c.EEPROM(),
+			"fpga_configuration":            c.FPGAConfiguration(),
+			"flash_memory":                  c.FlashMemory(),
+			"debug_helper":                  c.DebugHelper(),
+			"xmega":                         c.XMEGA(),
+			"high_speed_fpga_configuration": c.HighSpeedFPGAConfiguration(),
+			"mac_eeprom":                    c.MACEEPROM(),
+			"multi_fpga":                    c.MultiFPGA(),
+			"temperature_sensor":            c.TemperatureSensor(),
+			"flash_memory_2":                c.FlashMemory2(),
+			"fx3_firmware":                  c.FX3Firmware(),
+			"debug_helper_2":                c.DebugHelper2(),
+			"default_firmware":              c.DefaultFirmware(),
+		},
+		FPGAPackage: d.FPGAConfig.FPGAPackage.String(),
+		FPGAGrade:   d.FPGAConfig.FPGAGrade.String(),
+		RAMSize:     d.RAMConfig.RAMSize.String(),
+		RAMType:     d.RAMConfig.RAMType.String(),
+		Bitstream: infoBitstream{
+			Size:     int(d.BitstreamConfig.BitstreamSize.Bytes()),
+			Capacity: int(d.BitstreamConfig.BitstreamCapacity.Bytes()),
+			Start:    int(d.BitstreamConfig.BitstreamStart.Bytes()),
+		},
+	}
+	if c.MultiFPGA() {
+		m := d.MultiFPGAConfig
+		e.MultiFPGA = &m
+	}
+	if s, err := d.FlashStatus(); err == nil {
+		e.FlashStatus = newMonitorFlash(s)
+	}
+	if s, err := d.FPGAStatus(); err == nil {
+		e.FPGAStatus = newMonitorFPGA(s)
+	}
+	return e
+}
+
+// writeInfo writes the configuration and status of a device, one section
+// after the other.
+func writeInfo(w io.Writer, d *ztex.Device) {
+	c := d.Capability()
+
+	fmt.Fprintf(w, "USB\n")
+	fmt.Fprintf(w, "  Bus:\t%03d\n", d.Desc.Bus)
+	fmt.Fprintf(w, "  Address:\t%03d\n", d.Desc.Address)
+	fmt.Fprintf(w, "  Speed:\t%v\n", d.Desc.Speed)
+
+	fmt.Fprintf(w, "Descriptor\n")
+	fmt.Fprintf(w, "  Size:\t%v\n", d.DescriptorConfig.DescriptorSize)
+	fmt.Fprintf(w, "  Version:\t%v\n", d.DescriptorConfig.DescriptorVersion)
+	fmt.Fprintf(w, "  Magic:\t%v\n", d.DescriptorConfig.DescriptorMagic)
+	fmt.Fprintf(w, "  Product:\t%v\n", d.DescriptorConfig.DescriptorProduct)
+	fmt.Fprintf(w, "  Firmware:\t%v\n", d.DescriptorConfig.DescriptorFirmware)
+	fmt.Fprintf(w, "  Interface:\t%v\n", d.DescriptorConfig.DescriptorInterface)
+	fmt.Fprintf(w, "  Module:\t%v\n", d.DescriptorConfig.Module())
+	fmt.Fprintf(w, "  Serial:\t%v\n", d.Serial())
+
+	fmt.Fprintf(w, "Capabilities\n")
+	fmt.Fprintf(w, "  EEPROM:\t%v\n", c.EEPROM())
+	fmt.Fprintf(w, "  FPGA Configuration:\t%v\n", c.FPGAConfiguration())
+	fmt.Fprintf(w, "  Flash Memory:\t%v\n", c.FlashMemory())
+	fmt.Fprintf(w, "  Debug Helper:\t%v\n", c.DebugHelper())
+	fmt.Fprintf(w, "  XMEGA:\t%v\n", c.XMEGA())
+	fmt.Fprintf(w, "  High Speed FPGA Configuration:\t%v\n", c.HighSpeedFPGAConfiguration())
+	fmt.Fprintf(w, "  MAC EEPROM:\t%v\n", c.MACEEPROM())
+	fmt.Fprintf(w, "  MultiFPGA:\t%v\n", c.MultiFPGA())
+	fmt.Fprintf(w, "  Temperature Sensor:\t%v\n", c.TemperatureSensor())
+	fmt.Fprintf(w, "  Flash Memory 2:\t%v\n", c.FlashMemory2())
+	fmt.Fprintf(w, "  FX3 Firmware:\t%v\n", c.FX3Firmware())
+	fmt.Fprintf(w, "  Debug Helper 2:\t%v\n", c.DebugHelper2())
+	fmt.Fprintf(w, "  Default Firmware:\t%v\n", c.DefaultFirmware())
+
+	fmt.Fprintf(w, "Board\n")
+	fmt.Fprintf(w, "  Type:\t%v\n", d.BoardConfig.BoardType)
+	fmt.Fprintf(w, "  Version:\t%v\n", d.BoardConfig.BoardVersion)
+
+	fmt.Fprintf(w, "FPGA\n")
+	fmt.Fprintf(w, "  Type:\t%v\n", d.FPGAConfig.FPGAType)
+	fmt.Fprintf(w, "  Package:\t%v\n", d.FPGAConfig.FPGAPackage)
+	fmt.Fprintf(w, "  Grade:\t%v\n", d.FPGAConfig.FPGAGrade)
+	if c.MultiFPGA() {
+		fmt.Fprintf(w, "  Count:\t%v\n", d.MultiFPGAConfig.MultiFPGACount)
+		fmt.Fprintf(w, "  Selected:\t%v\n", d.MultiFPGAConfig.MultiFPGASelected)
+		fmt.Fprintf(w, "  Parallel:\t%v\n", d.MultiFPGAConfig.MultiFPGAParallel)
+	}
+
+	fmt.Fprintf(w, "RAM\n")
+	fmt.Fprintf(w, "  Size:\t%v\n", d.RAMConfig.RAMSize)
+	fmt.Fprintf(w, "  Type:\t%v\n", d.RAMConfig.RAMType)
+
+	fmt.Fprintf(w, "Bitstream\n")
+	fmt.Fprintf(w, "  Size:\t%v\n", d.BitstreamConfig.BitstreamSize)
+	fmt.Fprintf(w, "  Capacity:\t%v\n", d.BitstreamConfig.BitstreamCapacity)
+	fmt.Fprintf(w, "  Start:\t%v\n", d.BitstreamConfig.BitstreamStart)
+
+	fmt.Fprintf(w, "Flash Status\n")
+	if s, err := d.FlashStatus(); err != nil {
+		fmt.Fprintf(w, "  Error:\t%v\n", err)
+	} else {
+		fmt.Fprintf(w, "  Enabled:\t%v\n", s.FlashEnabled)
+		fmt.Fprintf(w, "  Sector:\t%v\n", s.FlashSector)
+		fmt.Fprintf(w, "  Count:\t%v\n", s.FlashCount)
+		fmt.Fprintf(w, "  Error:\t%v\n", s.FlashError)
+	}
+
+	fmt.Fprintf(w, "FPGA Status\n")
+	if s, err := d.FPGAStatus(); err != nil {
+		fmt.Fprintf(w, "  Error:\t%v\n", err)
+	} else {
+		fmt.Fprintf(w, "  Configured:\t%v\n", s.FPGAConfigured)
+		fmt.Fprintf(w, "  Checksum:\t%v\n", s.FPGAChecksum)
+		fmt.Fprintf(w, "  Transferred:\t%v\n", s.FPGATransferred)
+		fmt.Fprintf(w, "  Init:\t%v\n", s.FPGAInit)
+		fmt.Fprintf(w, "  Result:\t%v\n", s.FPGAResult)
+		fmt.Fprintf(w, "  Swapped:\t%v\n", s.FPGASwapped)
+	}
+}
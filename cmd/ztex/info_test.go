@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/aljumi/ztex"
+	"github.com/aljumi/ztex/ztextest"
+)
+
+func TestInfo(t *testing.T) {
+	f := ztextest.NewFakeDevice("fake000001")
+	f.Descriptor[12] |= 0x80
+	f.FPGAs = 2
+	ds, err := ztex.OpenBackendDevices(ztextest.Backend{f})
+	if err != nil {
+		t.Fatalf("ztex.OpenBackendDevices: %v", err)
+	}
+	defer ds[0].Close()
+
+	w := &bytes.Buffer{}
+	writeInfo(w, ds[0])
+	for _, want := range []string{"  Serial:\tfake000001\n", "  Flash Memory:\ttrue\n", "  XMEGA:\tfalse\n", "  MultiFPGA:\ttrue\n"} {
+		if !strings.Contains(w.String(), want) {
+			t.Errorf("writeInfo: got %q, want %q in it", w.String(), want)
+		}
+	}
+
+	e := newInfoEntry(ds[0])
+	if e.Serial != "fake000001" || !e.Capabilities["flash_memory"] || e.Capabilities["xmega"] || e.MultiFPGA == nil || e.FlashStatus == nil || e.FPGAStatus == nil {
+		t.Errorf("newInfoEntry: got %+v, want flash memory and multi-FPGA support with their status", e)
+	} else if n := e.MultiFPGA.MultiFPGACount.Number(); n != 2 {
+		t.Errorf("newInfoEntry: got %v FPGAs, want 2", n)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/aljumi/ztex"
+	"github.com/google/gousb"
+)
+
+var listCommand = &command{
+	name:    "list",
+	summary: "list attached modules",
+	run:     runList,
+}
+
+// listEntry describes an attached module in the output of "ztex list".
+type listEntry struct {
+	Bus       int    `json:"bus"`
+	Address   int    `json:"address"`
+	Serial    string `json:"serial"`
+	Product   string `json:"product"`
+	Firmware  uint8  `json:"firmware"`
+	Interface uint8  `json:"interface"`
+	Board     string `json:"board"`
+	FPGA      string `json:"fpga"`
+}
+
+func newListEntry(d *ztex.Device) listEntry {
+	return listEntry{
+		Bus:       d.Desc.Bus,
+		Address:   d.Desc.Address,
+		Serial:    d.Serial().String(),
+		Product:   d.DescriptorConfig.DescriptorProduct.String(),
+		Firmware:  uint8(d.DescriptorConfig.DescriptorFirmware),
+		Interface: uint8(d.DescriptorConfig.DescriptorInterface),
+		Board:     d.BoardConfig.BoardVersion.String(),
+		FPGA:      d.FPGAConfig.FPGAType.String(),
+	}
+}
+
+func runList(ctx *gousb.Context, args []string) error {
+	f := flag.NewFlagSet("ztex list", flag.ContinueOnError)
+	addSelectionFlags(f, true)
+	if err := f.Parse(args); err != nil {
+		return err
+	} else if f.NArg() != 0 {
+		return fmt.Errorf("got %v arguments, want no arguments", f.NArg())
+	}
+
+	ds, err := selectDevices(ctx)
+	for _, d := range ds {
+		defer d.Close()
+	}
+
+	x := []listEntry{}
+	for _, d := range ds {
+		x = append(x, newListEntry(d))
+	}
+
+	if jsonOutput {
+		if err := writeJSON("list", x); err != nil {
+			return err
+		}
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintf(w, "BUS\tADDRESS\tSERIAL\tPRODUCT\tFIRMWARE\tINTERFACE\tBOARD\tFPGA\n")
+		for _, e := range x {
+			fmt.Fprintf(w, "%03d\t%03d\t%v\t%v\t%v\t%v\t%v\t%v\n", e.Bus, e.Address, e.Serial, e.Product, e.Firmware, e.Interface, e.Board, e.FPGA)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aljumi/ztex"
+	"github.com/aljumi/ztex/ztextest"
+)
+
+// captureStdout returns what f prints to the standard output.
+func captureStdout(t *testing.T, f func() error) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("os.Pipe: %v", err)
+	}
+	defer r.Close()
+
+	stdout := os.Stdout
+	os.Stdout = w
+	err = f()
+	os.Stdout = stdout
+	w.Close()
+	if err != nil {
+		t.Fatalf("got error %v, want no error", err)
+	}
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("io.ReadAll: %v", err)
+	}
+	return string(b)
+}
+
+func TestListJSON(t *testing.T) {
+	ds, err := ztex.OpenBackendDevices(ztextest.Backend{ztextest.NewFakeDevice("fake000001")})
+	if err != nil {
+		t.Fatalf("ztex.OpenBackendDevices: %v", err)
+	}
+	defer ds[0].Close()
+
+	// The list is printed in the envelope of every command, on one line.
+	s := captureStdout(t, func() error { return writeJSON("list", []listEntry{newListEntry(ds[0])}) })
+	if strings.Count(s, "\n") != 1 {
+		t.Errorf("writeJSON: got %q, want a single line", s)
+	}
+
+	var x struct {
+		Schema  int         `json:"schema"`
+		Command string      `json:"command"`
+		Result  []listEntry `json:"result"`
+	}
+	if err := json.Unmarshal([]byte(s), &x); err != nil {
+		t.Fatalf("json.Unmarshal: %v", err)
+	} else if x.Schema != schemaVersion || x.Command != "list" || len(x.Result) != 1 {
+		t.Fatalf("writeJSON: got %+v, want envelope of schema %v for list with one module", x, schemaVersion)
+	} else if e := x.Result[0]; e.Serial != "fake000001" || e.Product != "10.17.0.0 [ZTEX USB-FPGA Module 2.13]" || e.Interface != 1 {
+		t.Errorf("newListEntry: got %+v, want serial fake000001 of a 2.13 with interface 1", e)
+	}
+}
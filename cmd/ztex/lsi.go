@@ -0,0 +1,136 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/google/gousb"
+	"gopkg.in/yaml.v3"
+)
+
+var lsiCommand = &command{
+	name:    "lsi",
+	summary: "read or write LSI registers of the FPGA",
+	run:     runLSI,
+}
+
+// lsiMap maps register names to LSI addresses, as read from a register
+// map file, which is a YAML mapping such as "status: 0x01".
+type lsiMap map[string]uint8
+
+// address returns the address of a register, given by its name in the
+// map or by its number.
+func (m lsiMap) address(s string) (uint8, error) {
+	if a, ok := m[s]; ok {
+		return a, nil
+	}
+	a, err := strconv.ParseUint(s, 0, 8)
+	if err != nil {
+		return 0, fmt.Errorf("got register %q, want a register name or an address in [0, 256)", s)
+	}
+	return uint8(a), nil
+}
+
+// name returns the name of the register at an address, or the address
+// itself if the map does not name it.
+func (m lsiMap) name(a uint8) string {
+	for k, v := range m {
+		if v == a {
+			return k
+		}
+	}
+	return fmt.Sprintf("%#02x", a)
+}
+
+// lsiRegister describes a register in the JSON output of "ztex lsi read".
+type lsiRegister struct {
+	Name    string `json:"name"`
+	Address uint8  `json:"address"`
+	Value   uint32 `json:"value"`
+}
+
+func runLSI(ctx *gousb.Context, args []string) error {
+	if len(args) < 1 || (args[0] != "read" && args[0] != "write") {
+		return fmt.Errorf("got no subcommand, want read or write")
+	}
+
+	f := flag.NewFlagSet("ztex lsi "+args[0], flag.ContinueOnError)
+	addSelectionFlags(f, true)
+	fpga := f.Int("fpga", -1, "index of the FPGA on a multi-FPGA module")
+	regs := f.String("map", "", "register map file naming the registers")
+	count := 1
+	if args[0] == "read" {
+		f.IntVar(&count, "count", 1, "number of consecutive registers to read")
+	}
+	if err := f.Parse(args[1:]); err != nil {
+		return err
+	} else if args[0] == "read" && f.NArg() != 1 {
+		return fmt.Errorf("got %v arguments, want ADDR", f.NArg())
+	} else if args[0] == "write" && f.NArg() != 2 {
+		return fmt.Errorf("got %v arguments, want ADDR VALUE", f.NArg())
+	} else if count < 1 || count > 256 {
+		return fmt.Errorf("got count %v, want count in [1, 256]", count)
+	}
+
+	m := lsiMap{}
+	if *regs != "" {
+		b, err := os.ReadFile(*regs)
+		if err != nil {
+			return err
+		} else if err := yaml.Unmarshal(b, &m); err != nil {
+			return fmt.Errorf("yaml.Unmarshal: %w", err)
+		}
+	}
+
+	a, err := m.address(f.Arg(0))
+	if err != nil {
+		return err
+	} else if int(a)+count > 256 {
+		// The addresses of consecutive registers would wrap around.
+		return fmt.Errorf("got registers [%v, %v), want registers within [0, 256)", a, int(a)+count)
+	}
+
+	d, err := openDevice(ctx)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	read, write := d.LSIRead, d.LSIWrite
+	if *fpga >= 0 {
+		h, err := d.FPGA(*fpga)
+		if err != nil {
+			return err
+		}
+		read, write = h.LSIRead, h.LSIWrite
+	}
+
+	if args[0] == "write" {
+		v, err := strconv.ParseUint(f.Arg(1), 0, 32)
+		if err != nil {
+			return fmt.Errorf("got value %q, want a 32-bit value", f.Arg(1))
+		}
+		if err := write(a, []uint32{uint32(v)}); err != nil || !jsonOutput {
+			return err
+		}
+		return writeJSON("lsi", []lsiRegister{{m.name(a), a, uint32(v)}})
+	}
+
+	v := make([]uint32, count)
+	if err := read(a, v); err != nil {
+		return err
+	}
+	if jsonOutput {
+		x := []lsiRegister{}
+		for i, r := range v {
+			x = append(x, lsiRegister{m.name(a + uint8(i)), a + uint8(i), r})
+		}
+		return writeJSON("lsi", x)
+	}
+	for i, x := range v {
+		fmt.Printf("%v=%#08x\n", m.name(a+uint8(i)), x)
+	}
+	return nil
+}
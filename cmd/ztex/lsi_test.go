@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestLSIMap(t *testing.T) {
+	m := lsiMap{"status": 0x01, "10": 0x20}
+	for _, tt := range []struct {
+		s    string
+		want uint8
+		ok   bool
+	}{
+		{"status", 0x01, true},
+		{"0x7f", 0x7f, true},
+		{"255", 0xff, true},
+		{"10", 0x20, true},
+		{"256", 0, false},
+		{"control", 0, false},
+		{"-1", 0, false},
+	} {
+		if a, err := m.address(tt.s); (err == nil) != tt.ok || a != tt.want {
+			t.Errorf("(lsiMap).address(%q): got %#02x and %v, want %#02x and error %v", tt.s, a, err, tt.want, !tt.ok)
+		}
+	}
+
+	if got := m.name(0x01); got != "status" {
+		t.Errorf("(lsiMap).name(0x01): got %q, want %q", got, "status")
+	}
+	if got := m.name(0x05); got != "0x05" {
+		t.Errorf("(lsiMap).name(0x05): got %q, want %q", got, "0x05")
+	}
+}
@@ -0,0 +1,111 @@
+// Command ztex manages ZTEX modules from the command line.
+//
+// Usage:
+//
+//	ztex [-json] [selection flags] <command> [flags] [arguments]
+//
+// Run "ztex help" for the list of commands, and "ztex <command> -h" for
+// the flags of a command.  The -json flag makes every command print its
+// results as JSON objects, one per line, each of which carries the
+// version of the output schema.
+//
+// The modules a command operates on are selected with the -serial, -bus,
+// -port, -index, -d, and -all flags, which may be given before the command
+// name or among the flags of any command.  Commands which operate on a
+// single module fail if the selection is ambiguous.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/google/gousb"
+)
+
+// command represents a subcommand of ztex.
+type command struct {
+	name    string
+	summary string
+	run     func(ctx *gousb.Context, args []string) error
+}
+
+// commands lists the subcommands of ztex, in the order in which they are
+// listed by "ztex help".
+var commands = []*command{
+	listCommand,
+	infoCommand,
+	reportCommand,
+	programCommand,
+	flashCommand,
+	firmwareCommand,
+	eepromCommand,
+	monitorCommand,
+	resetCommand,
+	benchCommand,
+	debugCommand,
+	provisionCommand,
+	gpioCommand,
+	lsiCommand,
+	daemonCommand,
+	fwloaderCommand,
+	udevCommand,
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: ztex [-json] [selection flags] <command> [flags] [arguments]\n\nCommands:\n")
+	w := tabwriter.NewWriter(os.Stderr, 0, 8, 2, ' ', 0)
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %v\t%v\n", c.name, c.summary)
+	}
+	w.Flush()
+}
+
+func main() {
+	f := flag.NewFlagSet("ztex", flag.ContinueOnError)
+	f.Usage = usage
+	f.BoolVar(&jsonOutput, "json", false, "print results as JSON")
+	addSelectionFlags(f, true)
+	if err := f.Parse(os.Args[1:]); err == flag.ErrHelp {
+		return
+	} else if err != nil {
+		os.Exit(2)
+	} else if f.NArg() < 1 {
+		usage()
+		os.Exit(2)
+	}
+
+	name, args := f.Arg(0), f.Args()[1:]
+	if name == "help" || name == "-h" || name == "-help" || name == "--help" {
+		usage()
+		return
+	}
+
+	for _, c := range commands {
+		if c.name != name {
+			continue
+		}
+
+		ctx := gousb.NewContext()
+		err := c.run(ctx, args)
+		ctx.Close()
+
+		switch {
+		case errors.Is(err, flag.ErrHelp):
+			return
+		case err != nil && jsonOutput:
+			writeJSONError(name, err)
+			os.Exit(1)
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "ztex %v: %v\n", name, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "ztex: unknown command %q\n", name)
+	usage()
+	os.Exit(2)
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/aljumi/ztex"
+	"github.com/google/gousb"
+)
+
+var monitorCommand = &command{
+	name:    "monitor",
+	summary: "continuously display the sensors and status of modules",
+	run:     runMonitor,
+}
+
+// monitorSensor describes a sensor reading in the output of "ztex monitor".
+type monitorSensor struct {
+	Channel uint8   `json:"channel"`
+	Kind    string  `json:"kind"`
+	Value   float64 `json:"value"`
+	Unit    string  `json:"unit"`
+}
+
+// monitorFPGA describes the FPGA status in the output of "ztex monitor".
+type monitorFPGA struct {
+	Configured  bool   `json:"configured"`
+	Checksum    uint8  `json:"checksum"`
+	Transferred uint32 `json:"transferred"`
+	Result      string `json:"result"`
+}
+
+func newMonitorFPGA(s *ztex.FPGAStatus) *monitorFPGA {
+	return &monitorFPGA{
+		Configured:  s.FPGAConfigured.Bool(),
+		Checksum:    uint8(s.FPGAChecksum),
+		Transferred: s.FPGATransferred.Number(),
+		Result:      s.FPGAResult.String(),
+	}
+}
+
+// monitorFlash describes the flash status in the output of "ztex monitor".
+type monitorFlash struct {
+	Enabled bool   `json:"enabled"`
+	Error   string `json:"error"`
+}
+
+func newMonitorFlash(s *ztex.FlashStatus) *monitorFlash {
+	return &monitorFlash{
+		Enabled: s.FlashEnabled == 1,
+		Error:   s.FlashError.String(),
+	}
+}
+
+// monitorEntry describes the state of a module at one point in time in the
+// output of "ztex monitor".  Statuses which the module does not support
+// are omitted.
+type monitorEntry struct {
+	Time    time.Time       `json:"time"`
+	Serial  string          `json:"serial"`
+	Sensors []monitorSensor `json:"sensors,omitempty"`
+	FPGA    *monitorFPGA    `json:"fpga,omitempty"`
+	Flash   *monitorFlash   `json:"flash,omitempty"`
+	Errors  []string        `json:"errors,omitempty"`
+}
+
+func newMonitorEntry(t time.Time, d *ztex.Device) monitorEntry {
+	e := monitorEntry{Time: t, Serial: d.Serial().String()}
+	c, _ := d.CombinedStatus()
+
+	for _, err := range []error{c.SensorsErr, c.FPGAErr, c.FlashErr} {
+		if err != nil {
+			e.Errors = append(e.Errors, err.Error())
+		}
+	}
+	if c.Sensors != nil {
+		for _, r := range c.Sensors.SensorReadings {
+			e.Sensors = append(e.Sensors, monitorSensor{
+				Channel: r.SensorChannel.Number(),
+				Kind:    r.SensorKind.String(),
+				Value:   r.SensorValue.Number(),
+				Unit:    r.SensorKind.Unit(),
+			})
+		}
+	}
+	if c.FPGA != nil {
+		e.FPGA = newMonitorFPGA(c.FPGA)
+	}
+	if c.Flash != nil {
+		e.Flash = newMonitorFlash(c.Flash)
+	}
+
+	return e
+}
+
+// String returns the entry as a single line of human-readable text.
+func (e monitorEntry) String() string {
+	x := []string{e.Time.Format(time.RFC3339), e.Serial}
+	for _, s := range e.Sensors {
+		x = append(x, fmt.Sprintf("%v[%v]=%.2f%v", s.Kind, s.Channel, s.Value, s.Unit))
+	}
+	if e.FPGA != nil {
+		x = append(x, fmt.Sprintf("configured=%v", e.FPGA.Configured))
+	}
+	if e.Flash != nil {
+		x = append(x, fmt.Sprintf("flash=%q", e.Flash.Error))
+	}
+	for _, err := range e.Errors {
+		x = append(x, fmt.Sprintf("error=%q", err))
+	}
+	return strings.Join(x, " ")
+}
+
+func runMonitor(ctx *gousb.Context, args []string) error {
+	f := flag.NewFlagSet("ztex monitor", flag.ContinueOnError)
+	addSelectionFlags(f, true)
+	interval := f.Duration("interval", time.Second, "interval between readings")
+	jsonLines := f.Bool("json-lines", false, "print one JSON object per module and reading")
+	if err := f.Parse(args); err != nil {
+		return err
+	} else if f.NArg() != 0 {
+		return fmt.Errorf("got %v arguments, want no arguments", f.NArg())
+	} else if *interval <= 0 {
+		return fmt.Errorf("got interval %v, want positive interval", *interval)
+	}
+
+	ds, err := openDevices(ctx)
+	if err != nil {
+		return err
+	}
+	for _, d := range ds {
+		defer d.Close()
+	}
+
+	c, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	t := time.NewTicker(*interval)
+	defer t.Stop()
+
+	e := json.NewEncoder(os.Stdout)
+	for {
+		now := time.Now()
+		for _, d := range ds {
+			x := newMonitorEntry(now, d)
+			if jsonOutput {
+				if err := writeJSON("monitor", x); err != nil {
+					return err
+				}
+			} else if *jsonLines {
+				if err := e.Encode(x); err != nil {
+					return err
+				}
+			} else {
+				fmt.Println(x)
+			}
+		}
+
+		select {
+		case <-c.Done():
+			return nil
+		case <-t.C:
+		}
+	}
+}
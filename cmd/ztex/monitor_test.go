@@ -0,0 +1,36 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aljumi/ztex/ztextest"
+)
+
+func TestMonitorEntry(t *testing.T) {
+	f := ztextest.NewFakeDevice("fake000001")
+	f.Descriptor[13] |= 0x01
+	f.Sensors = []byte{2, 1, 0x80, 0x2a, 2, 0xe4, 0x0c}
+	d, err := ztextest.Open(f)
+	if err != nil {
+		t.Fatalf("ztextest.Open: %v", err)
+	}
+	defer d.Close()
+
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	e := newMonitorEntry(now, d)
+	if len(e.Sensors) != 2 || e.Sensors[0].Kind != "Temperature" || e.Sensors[0].Value != 42.5 || e.Sensors[1].Unit != "V" || e.FPGA == nil || e.Flash == nil || len(e.Errors) != 0 {
+		t.Errorf("newMonitorEntry: got %+v, want two sensors, the FPGA and flash status, and no errors", e)
+	}
+	if s := e.String(); !strings.HasPrefix(s, "2024-01-02T03:04:05Z fake000001 Temperature[0]=42.50°C Voltage[1]=3.30V configured=false") {
+		t.Errorf("(monitorEntry).String: got %q, want the time, serial, sensors, and FPGA state", s)
+	}
+
+	// Statuses which fail are reported as errors, and the others are
+	// still shown.
+	f.Sensors = []byte{2, 1}
+	if e = newMonitorEntry(now, d); len(e.Errors) != 1 || e.Sensors != nil || e.FPGA == nil {
+		t.Errorf("newMonitorEntry with broken sensors: got %+v, want one error and the FPGA status", e)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// schemaVersion is the version of the JSON output of ztex.  It is
+// incremented whenever a field is removed or changes its meaning; fields
+// may be added without changing it.
+const schemaVersion = 1
+
+// jsonOutput is set by the global -json flag, and makes every command
+// print its results as JSON instead of human-readable text.
+var jsonOutput bool
+
+// jsonMu serializes the JSON output of commands which report results
+// from several goroutines.
+var jsonMu sync.Mutex
+
+// jsonEnvelope wraps every JSON object printed by ztex.
+type jsonEnvelope struct {
+	Schema  int    `json:"schema"`
+	Command string `json:"command"`
+	Result  any    `json:"result,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// writeJSON prints the result of a command as a single line of JSON.
+// Commands that print a sequence of results call it once per result, and
+// may do so from several goroutines.
+func writeJSON(name string, v any) error {
+	jsonMu.Lock()
+	defer jsonMu.Unlock()
+	return json.NewEncoder(os.Stdout).Encode(jsonEnvelope{Schema: schemaVersion, Command: name, Result: v})
+}
+
+// writeJSONError prints the error of a command as a single line of JSON.
+func writeJSONError(name string, err error) error {
+	jsonMu.Lock()
+	defer jsonMu.Unlock()
+	return json.NewEncoder(os.Stdout).Encode(jsonEnvelope{Schema: schemaVersion, Command: name, Error: err.Error()})
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestWriteJSONError(t *testing.T) {
+	s := captureStdout(t, func() error { return writeJSONError("info", errors.New("no module")) })
+	var x map[string]any
+	if err := json.Unmarshal([]byte(s), &x); err != nil {
+		t.Fatalf("json.Unmarshal: %v", err)
+	} else if len(x) != 3 || x["schema"] != float64(schemaVersion) || x["command"] != "info" || x["error"] != "no module" {
+		t.Errorf("writeJSONError: got %v, want schema, command, and error only", x)
+	}
+}
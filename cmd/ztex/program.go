@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+
+	"github.com/aljumi/ztex"
+	"github.com/aljumi/ztex/bitstream"
+	"github.com/google/gousb"
+)
+
+var programCommand = &command{
+	name:    "program",
+	summary: "configure the FPGA of one or more modules with a bitstream",
+	run:     runProgram,
+}
+
+// programResult describes a configured FPGA in the JSON output of "ztex
+// program".
+type programResult struct {
+	Serial string       `json:"serial"`
+	Method string       `json:"method"`
+	Bytes  int          `json:"bytes"`
+	Status *monitorFPGA `json:"status"`
+}
+
+func runProgram(ctx *gousb.Context, args []string) error {
+	f := flag.NewFlagSet("ztex program", flag.ContinueOnError)
+	addSelectionFlags(f, true)
+	fpga := f.Int("fpga", -1, "index of the FPGA to configure on a multi-FPGA module")
+	lowSpeed := f.Bool("low-speed", false, "use low-speed configuration even if high-speed configuration is available")
+	quiet := f.Bool("quiet", false, "do not show a progress bar")
+	jobs := f.Int("jobs", 4, "with -all, number of modules to configure at once, or 0 for no limit")
+	perBus := f.Int("per-bus", 2, "with -all, number of modules to configure at once on each USB bus, or 0 for no limit")
+	if err := f.Parse(args); err != nil {
+		return err
+	} else if f.NArg() != 1 {
+		return fmt.Errorf("got %v arguments, want a bitstream file, build directory, or project file", f.NArg())
+	}
+
+	name, part, err := programBitstream(f.Arg(0))
+	if err != nil {
+		return err
+	}
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+
+	c, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if selection.all {
+		ds, err := openDevices(ctx)
+		if err != nil {
+			return err
+		}
+		defer closeDevices(ds)
+
+		s := &ztex.Scheduler{Limit: *jobs, PerBus: *perBus}
+		e := []error{}
+		for _, r := range s.Run(c, ds, b, programUpload(*fpga, *lowSpeed, part)) {
+			if r.Err == nil {
+				r.Err = reportProgram(r.Device, programMethod(r.Device, *lowSpeed), len(b))
+			}
+			if r.Err != nil {
+				e = append(e, fmt.Errorf("%v: %w", r.Device.Serial(), r.Err))
+			}
+		}
+		return errors.Join(e...)
+	}
+
+	d, err := openDevice(ctx)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	var p ztex.Progress
+	if !*quiet {
+		p = progressBar(os.Stderr, "program")
+	}
+
+	if err := programUpload(*fpga, *lowSpeed, part)(d, c, bytes.NewReader(b), p); err != nil {
+		return err
+	}
+	return reportProgram(d, programMethod(d, *lowSpeed), len(b))
+}
+
+// programBitstream returns the bitstream file named by the argument of
+// "ztex program" and the part it must be built for.  A file is used as is,
+// without a part.  For a build directory, such as the runs directory of a
+// Vivado project, or a Vivado or ISE project file, the most recent build is
+// used, whose part must be known; see bitstream.Find.
+func programBitstream(name string) (string, string, error) {
+	if i, err := os.Stat(name); err != nil {
+		return "", "", err
+	} else if !i.IsDir() && filepath.Ext(name) != ".xpr" && filepath.Ext(name) != ".xise" {
+		return name, "", nil
+	}
+
+	b, err := bitstream.Find(name)
+	if err != nil {
+		return "", "", err
+	} else if b.Part == "" {
+		return "", "", fmt.Errorf("got no part for %v, want a .bit file or a project file naming the part", b.Path)
+	}
+	if !jsonOutput {
+		fmt.Fprintf(os.Stderr, "using %v (%v, built %v)\n", b.Path, b.Part, b.ModTime.Format("2006-01-02 15:04:05"))
+	}
+	return b.Path, b.Part, nil
+}
+
+// programMethod returns the configuration method used for a device.
+func programMethod(d *ztex.Device, lowSpeed bool) string {
+	if !lowSpeed && d.Capability().HighSpeedFPGAConfiguration() {
+		return "high-speed"
+	}
+	return "low-speed"
+}
+
+// programUpload returns an upload which selects the given FPGA, unless it
+// is negative, checks that it is the given part, unless it is empty, and
+// configures it with the method chosen by programMethod.
+func programUpload(fpga int, lowSpeed bool, part string) ztex.Upload {
+	return func(d *ztex.Device, ctx context.Context, r io.Reader, p ztex.Progress) error {
+		if fpga >= 0 {
+			if err := d.SelectFPGA(fpga); err != nil {
+				return err
+			}
+		}
+		if part != "" {
+			if err := d.CheckPart(part); err != nil {
+				return err
+			}
+		}
+		if programMethod(d, lowSpeed) == "high-speed" {
+			return d.ConfigureFPGAHighSpeed(ctx, r, p)
+		}
+		return d.ConfigureFPGA(ctx, r, p)
+	}
+}
+
+// reportProgram prints the FPGA status of a configured device.
+func reportProgram(d *ztex.Device, method string, n int) error {
+	s, err := d.FPGAStatus()
+	if err != nil {
+		return err
+	}
+	if jsonOutput {
+		return writeJSON("program", programResult{d.Serial().String(), method, n, newMonitorFPGA(s)})
+	}
+	fmt.Printf("%v: %v\n", d.Serial(), s)
+
+	return nil
+}
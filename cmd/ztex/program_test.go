@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aljumi/ztex"
+	"github.com/aljumi/ztex/ztextest"
+)
+
+// testBitstream returns a raw bitstream with the synchronization word,
+// which the fake device accepts.
+func testBitstream() []byte {
+	b := bytes.Repeat([]byte{0xff}, 1<<12)
+	copy(b[16:], []byte{0xaa, 0x99, 0x55, 0x66})
+	return b
+}
+
+func TestProgramUpload(t *testing.T) {
+	f := ztextest.NewFakeDevice("fake000001")
+	f.Descriptor[12] |= 0x20
+	ds, err := ztex.OpenBackendDevices(ztextest.Backend{f})
+	if err != nil {
+		t.Fatalf("ztex.OpenBackendDevices: %v", err)
+	}
+	d := ds[0]
+	defer d.Close()
+
+	// The part is checked before anything is transferred.
+	ctx := context.Background()
+	if err := programUpload(-1, false, "xc7a100t")(d, ctx, bytes.NewReader(testBitstream()), nil); err == nil {
+		t.Errorf("programUpload for another part: got no error, want error")
+	} else if n := f.Calls(0x32) + f.Calls(0x34); n != 0 {
+		t.Errorf("programUpload for another part: got %v configuration requests, want 0", n)
+	}
+
+	for _, lowSpeed := range []bool{false, true} {
+		want := map[bool]string{false: "high-speed", true: "low-speed"}[lowSpeed]
+		if got := programMethod(d, lowSpeed); got != want {
+			t.Errorf("programMethod(%v): got %v, want %v", lowSpeed, got, want)
+		}
+		if err := programUpload(-1, lowSpeed, "")(d, ctx, bytes.NewReader(testBitstream()), nil); err != nil {
+			t.Errorf("programUpload(%v): %v", want, err)
+		} else if s, err := d.FPGAStatus(); err != nil || !s.FPGAConfigured.Bool() {
+			t.Errorf("programUpload(%v): got status %v and %v, want configured FPGA", want, s, err)
+		}
+	}
+	if f.Calls(0x34) != 1 || f.Calls(0x32) == 0 {
+		t.Errorf("programUpload: got %v high-speed and %v low-speed transfers, want both methods used", f.Calls(0x34), f.Calls(0x32))
+	}
+
+	// The FPGA of a module with a single FPGA cannot be selected.
+	if err := programUpload(1, true, "")(d, ctx, bytes.NewReader(testBitstream()), nil); err == nil {
+		t.Errorf("programUpload for FPGA 1: got no error, want error")
+	}
+}
+
+func TestProgramBitstream(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "top.bit")
+	if err := os.WriteFile(p, testBitstream(), 0600); err != nil {
+		t.Fatalf("os.WriteFile: %v", err)
+	}
+	if name, part, err := programBitstream(p); err != nil || name != p || part != "" {
+		t.Errorf("programBitstream(%q): got %q, %q, and %v, want the file without a part", p, name, part, err)
+	}
+	if _, _, err := programBitstream(p + ".missing"); err == nil {
+		t.Errorf("programBitstream of a missing file: got no error, want error")
+	}
+}
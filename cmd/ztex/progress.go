@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// progressBar returns a function which draws a progress bar labelled with
+// label on w, suitable as a ztex.Progress.  The bar is redrawn in place
+// and ends with a newline once the operation is complete.
+func progressBar(w io.Writer, label string) func(done, total int64) {
+	const width = 40
+	return func(done, total int64) {
+		n := width
+		p := int64(100)
+		if total > 0 {
+			n = int(done * width / total)
+			p = done * 100 / total
+		}
+		fmt.Fprintf(w, "\r%v [%v%v] %3d%% %v/%v bytes", label, strings.Repeat("=", n), strings.Repeat(" ", width-n), p, done, total)
+		if done >= total {
+			fmt.Fprintln(w)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/aljumi/ztex"
+	"github.com/google/gousb"
+	"gopkg.in/yaml.v3"
+)
+
+var provisionCommand = &command{
+	name:    "provision",
+	summary: "write the configuration, serial number, firmware, and bitstream of a new module",
+	run:     runProvision,
+}
+
+// provisionTemplate describes a board in the template file read by "ztex
+// provision".  Sizes and offsets of the bitstream are given in 4 kiB
+// sectors, and file names are relative to the template file.
+type provisionTemplate struct {
+	Board struct {
+		Type    uint8  `yaml:"type"`
+		Series  uint8  `yaml:"series"`
+		Number  uint8  `yaml:"number"`
+		Variant string `yaml:"variant"`
+	} `yaml:"board"`
+	FPGA struct {
+		Type    uint16 `yaml:"type"`
+		Package uint8  `yaml:"package"`
+		Grade   string `yaml:"grade"`
+	} `yaml:"fpga"`
+	RAM struct {
+		Size uint8 `yaml:"size"`
+		Type uint8 `yaml:"type"`
+	} `yaml:"ram"`
+	Bitstream struct {
+		Capacity uint16 `yaml:"capacity"`
+		Start    uint16 `yaml:"start"`
+		File     string `yaml:"file"`
+	} `yaml:"bitstream"`
+	Serial   string `yaml:"serial"`
+	Firmware string `yaml:"firmware"`
+}
+
+// config returns the configuration data described by the template, with
+// the given serial number and bitstream size.
+func (t *provisionTemplate) config(serial string, size ztex.BitstreamSize) (*ztex.DeviceConfig, error) {
+	if len(t.Board.Variant) > 2 {
+		return nil, fmt.Errorf("got board variant %q, want at most %v characters", t.Board.Variant, 2)
+	} else if len(t.FPGA.Grade) > 3 {
+		return nil, fmt.Errorf("got FPGA grade %q, want at most %v characters", t.FPGA.Grade, 3)
+	} else if len(serial) != 10 {
+		return nil, fmt.Errorf("got serial number %q, want %v characters", serial, 10)
+	}
+
+	c := &ztex.DeviceConfig{}
+	c.BoardConfig.BoardType = ztex.BoardType(t.Board.Type)
+	c.BoardConfig.BoardSeries = ztex.BoardSeries(t.Board.Series)
+	c.BoardConfig.BoardNumber = ztex.BoardNumber(t.Board.Number)
+	copy(c.BoardConfig.BoardVariant[:], t.Board.Variant)
+	c.FPGAConfig.FPGAType = ztex.MakeFPGAType(t.FPGA.Type)
+	c.FPGAConfig.FPGAPackage = ztex.FPGAPackage(t.FPGA.Package)
+	copy(c.FPGAConfig.FPGAGrade[:], t.FPGA.Grade)
+	c.RAMConfig.RAMSize = ztex.RAMSize(t.RAM.Size)
+	c.RAMConfig.RAMType = ztex.RAMType(t.RAM.Type)
+	copy(c.DescriptorSerial[:], serial)
+	c.BitstreamConfig.BitstreamSize = size
+	c.BitstreamConfig.BitstreamCapacity = ztex.BitstreamCapacity{uint8(t.Bitstream.Capacity), uint8(t.Bitstream.Capacity >> 8)}
+	c.BitstreamConfig.BitstreamStart = ztex.BitstreamStart{uint8(t.Bitstream.Start), uint8(t.Bitstream.Start >> 8)}
+	if err := c.Validate().Err(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// provisionResult describes a provisioned module in the JSON output of
+// "ztex provision".  Firmware and bitstream are the installed files, if
+// any.
+type provisionResult struct {
+	Bus       int    `json:"bus"`
+	Address   int    `json:"address"`
+	Serial    string `json:"serial"`
+	Board     string `json:"board"`
+	FPGA      string `json:"fpga"`
+	Firmware  string `json:"firmware,omitempty"`
+	Bitstream string `json:"bitstream,omitempty"`
+}
+
+func runProvision(ctx *gousb.Context, args []string) error {
+	f := flag.NewFlagSet("ztex provision", flag.ContinueOnError)
+	template := f.String("template", "", "board template file")
+	addSelectionFlags(f, true)
+	assign := f.String("assign", "", "serial number to assign (default: the serial number in the template)")
+	quiet := f.Bool("quiet", false, "do not show progress bars")
+	if err := f.Parse(args); err != nil {
+		return err
+	} else if f.NArg() != 0 {
+		return fmt.Errorf("got %v arguments, want no arguments", f.NArg())
+	} else if *template == "" {
+		return fmt.Errorf("got no template, want -template")
+	}
+
+	b, err := os.ReadFile(*template)
+	if err != nil {
+		return err
+	}
+	t := &provisionTemplate{}
+	if err := yaml.Unmarshal(b, t); err != nil {
+		return fmt.Errorf("yaml.Unmarshal: %w", err)
+	}
+	if *assign != "" {
+		t.Serial = *assign
+	}
+
+	// path resolves a file name in the template relative to the template.
+	path := func(p string) string {
+		if filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(filepath.Dir(*template), p)
+	}
+
+	d, err := openDevice(ctx)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	c, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	progress := func(label string) ztex.Progress {
+		if *quiet {
+			return nil
+		}
+		return progressBar(os.Stderr, label)
+	}
+
+	x, err := t.config(t.Serial, d.BitstreamConfig.BitstreamSize)
+	if err != nil {
+		return err
+	}
+	if err := d.WriteDeviceConfig(*x); err != nil {
+		return err
+	}
+
+	if t.Firmware != "" {
+		r, err := os.Open(path(t.Firmware))
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		if err := d.InstallFirmware(c, r, progress("firmware")); err != nil {
+			return err
+		}
+	}
+
+	if t.Bitstream.File != "" {
+		r, err := os.Open(path(t.Bitstream.File))
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		if err := d.InstallBitstream(c, r, progress("bitstream")); err != nil {
+			return err
+		}
+	}
+
+	if jsonOutput {
+		return writeJSON("provision", provisionResult{
+			Bus:       d.Desc.Bus,
+			Address:   d.Desc.Address,
+			Serial:    t.Serial,
+			Board:     d.BoardConfig.BoardVersion.String(),
+			FPGA:      d.FPGAConfig.FPGAType.String(),
+			Firmware:  t.Firmware,
+			Bitstream: t.Bitstream.File,
+		})
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "Module:\t%03d/%03d\n", d.Desc.Bus, d.Desc.Address)
+	fmt.Fprintf(w, "Serial:\t%v\n", t.Serial)
+	fmt.Fprintf(w, "Board:\t%v %v\n", d.BoardConfig.BoardType, d.BoardConfig.BoardVersion)
+	fmt.Fprintf(w, "FPGA:\t%v\n", d.FPGAConfig)
+	fmt.Fprintf(w, "RAM:\t%v\n", d.RAMConfig)
+	fmt.Fprintf(w, "Bitstream:\t%v\n", d.BitstreamConfig)
+	fmt.Fprintf(w, "Firmware:\t%v\n", orNone(t.Firmware))
+	fmt.Fprintf(w, "Boot Bitstream:\t%v\n", orNone(t.Bitstream.File))
+	return w.Flush()
+}
+
+// orNone returns s, or "none" if s is empty.
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
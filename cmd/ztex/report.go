@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/aljumi/ztex"
+	"github.com/google/gousb"
+)
+
+var reportCommand = &command{
+	name:    "report",
+	summary: "print a report on a module for attaching to support requests",
+	run:     runReport,
+}
+
+func runReport(ctx *gousb.Context, args []string) error {
+	f := flag.NewFlagSet("ztex report", flag.ContinueOnError)
+	addSelectionFlags(f, true)
+	format := ztex.ReportText
+	f.TextVar(&format, "format", ztex.ReportText, "report format: text, markdown, or json")
+	if err := f.Parse(args); err != nil {
+		return err
+	} else if f.NArg() != 0 {
+		return fmt.Errorf("got %v arguments, want no arguments", f.NArg())
+	}
+	if jsonOutput {
+		format = ztex.ReportJSON
+	}
+
+	d, err := openDevice(ctx)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Report(os.Stdout, format)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/google/gousb"
+)
+
+var resetCommand = &command{
+	name:    "reset",
+	summary: "reset the FPGA, FX3, or default firmware of a module",
+	run:     runReset,
+}
+
+// resetResult describes which parts of a module were reset in the JSON
+// output of "ztex reset".
+type resetResult struct {
+	Serial   string `json:"serial"`
+	Firmware bool   `json:"firmware"`
+	FPGA     bool   `json:"fpga"`
+	FX3      bool   `json:"fx3"`
+}
+
+func runReset(ctx *gousb.Context, args []string) error {
+	f := flag.NewFlagSet("ztex reset", flag.ContinueOnError)
+	addSelectionFlags(f, false)
+	fpga := f.Bool("fpga", false, "reset the FPGAs, discarding their configuration")
+	fx3 := f.Bool("fx3", false, "reset the FX3, which reboots from flash and reconnects")
+	firmware := f.Bool("firmware", false, "reset the default firmware interface")
+	all := f.Bool("all", false, "reset the default firmware and the FX3, if present, and the FPGAs, in that order")
+	yes := f.Bool("yes", false, "do not ask for confirmation")
+	if err := f.Parse(args); err != nil {
+		return err
+	} else if f.NArg() != 0 {
+		return fmt.Errorf("got %v arguments, want no arguments", f.NArg())
+	}
+
+	if *all {
+		*fpga, *fx3, *firmware = true, true, true
+	}
+	if !*fpga && !*fx3 && !*firmware {
+		return fmt.Errorf("got nothing to reset, want -fpga, -fx3, -firmware, or -all")
+	}
+
+	d, err := openDevice(ctx)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	// With -all, the parts which the module lacks are skipped rather than
+	// failing the command.
+	if *all {
+		*firmware = d.Capability().DefaultFirmware()
+		*fx3 = d.Capability().FX3Firmware()
+	}
+
+	// Resetting the FPGAs discards the running design, and resetting the
+	// FX3 additionally disconnects the module, so both are confirmed.
+	if (*fpga || *fx3) && !*yes {
+		x := []string{}
+		if *fpga {
+			x = append(x, "FPGAs")
+		}
+		if *fx3 {
+			x = append(x, "FX3")
+		}
+		fmt.Fprintf(os.Stderr, "Reset the %v of module %v? [y/N] ", strings.Join(x, " and "), d.Serial())
+		s, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if a := strings.ToLower(strings.TrimSpace(s)); a != "y" && a != "yes" {
+			return fmt.Errorf("got no confirmation, want confirmation")
+		}
+	}
+
+	if *firmware {
+		if err := d.ResetDefaultFirmware(); err != nil {
+			return err
+		}
+	}
+	if *fpga {
+		reset := d.ResetFPGA
+		if d.Capability().MultiFPGA() {
+			reset = d.ResetAllFPGAs
+		}
+		if err := reset(); err != nil {
+			return err
+		}
+	}
+	if *fx3 {
+		if err := d.ResetFX3(); err != nil {
+			return err
+		}
+	}
+
+	if jsonOutput {
+		return writeJSON("reset", resetResult{d.Serial().String(), *firmware, *fpga, *fx3})
+	}
+	return nil
+}
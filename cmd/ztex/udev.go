@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/aljumi/ztex"
+	"github.com/google/gousb"
+)
+
+var udevCommand = &command{
+	name:    "udev",
+	summary: "print or install the udev rule granting access to modules on Linux",
+	run:     runUdev,
+}
+
+// udevResult describes the udev rule in the JSON output of "ztex udev".
+type udevResult struct {
+	Rule string `json:"rule"`
+	Path string `json:"path,omitempty"`
+}
+
+func runUdev(ctx *gousb.Context, args []string) error {
+	f := flag.NewFlagSet("ztex udev", flag.ContinueOnError)
+	group := f.String("group", "", "also grant access to the members of this group, such as plugdev, besides the user at the seat")
+	write := f.Bool("write", false, "install the rule, which requires root privileges")
+	path := f.String("path", ztex.UdevRulePath, "with -write, the file to install the rule in")
+	if err := f.Parse(args); err != nil {
+		return err
+	} else if f.NArg() != 0 {
+		return fmt.Errorf("got %v arguments, want no arguments", f.NArg())
+	}
+
+	rule, err := ztex.UdevRule(*group)
+	if err != nil {
+		return err
+	}
+	r := udevResult{Rule: rule}
+	if *write {
+		if err := ztex.WriteUdevRule(*path, *group); err != nil {
+			return err
+		}
+		r.Path = *path
+	}
+
+	if jsonOutput {
+		return writeJSON("udev", r)
+	}
+	fmt.Println(r.Rule)
+	if *write {
+		fmt.Printf("installed in %v; run \"udevadm control --reload-rules && udevadm trigger\" and reconnect the modules\n", *path)
+	}
+	return nil
+}
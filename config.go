@@ -0,0 +1,132 @@
+package ztex
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// DeviceConfig represents the configuration data area at the start of the
+// MAC EEPROM, which describes the hardware of the device.
+type DeviceConfig struct {
+	BoardConfig      BoardConfig
+	FPGAConfig       FPGAConfig
+	RAMConfig        RAMConfig
+	DescriptorSerial DescriptorSerial
+	BitstreamConfig  BitstreamConfig
+}
+
+// String returns a human-readable description of the configuration data.
+func (d DeviceConfig) String() string {
+	x := []string{}
+	x = append(x, fmt.Sprintf("Board(%v)", d.BoardConfig))
+	x = append(x, fmt.Sprintf("FPGA(%v)", d.FPGAConfig))
+	x = append(x, fmt.Sprintf("RAM(%v)", d.RAMConfig))
+	x = append(x, fmt.Sprintf("Serial(%v)", d.DescriptorSerial))
+	x = append(x, fmt.Sprintf("Bitstream(%v)", d.BitstreamConfig))
+	return strings.Join(x, ", ")
+}
+
+// MarshalJSON returns a JSON representation of the configuration data.
+func (d DeviceConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Board     BoardConfig     `json:"board"`
+		FPGA      FPGAConfig      `json:"fpga"`
+		RAM       RAMConfig       `json:"ram"`
+		Serial    string          `json:"serial"`
+		Bitstream BitstreamConfig `json:"bitstream"`
+	}{
+		d.BoardConfig,
+		d.FPGAConfig,
+		d.RAMConfig,
+		d.DescriptorSerial.String(),
+		d.BitstreamConfig,
+	})
+}
+
+// ParseDeviceConfig decodes the 128-byte configuration data area, which
+// starts with the signature "CD0".
+func ParseDeviceConfig(b []byte) (*DeviceConfig, error) {
+	if len(b) != 128 {
+		return nil, fmt.Errorf("%w: got %v bytes, want %v bytes", ErrBadConfig, len(b), 128)
+	} else if b[0] != 'C' || b[1] != 'D' || b[2] != '0' {
+		return nil, fmt.Errorf("%w: got signature %v, want signature %v", ErrBadConfig, b[:3], []byte{'C', 'D', '0'})
+	}
+
+	return &DeviceConfig{
+		BoardConfig{
+			BoardType(b[3]),
+			BoardVersion{
+				BoardSeries(b[4]),
+				BoardNumber(b[5]),
+				BoardVariant([2]byte{b[6], b[7]}),
+			},
+		},
+		FPGAConfig{
+			FPGAType([2]byte{b[8], b[9]}),
+			FPGAPackage(b[10]),
+			FPGAGrade([3]byte{b[11], b[12], b[13]}),
+		},
+		RAMConfig{
+			RAMSize(b[14]),
+			RAMType(b[15]),
+		},
+		DescriptorSerial([10]uint8(b[16:26])),
+		BitstreamConfig{
+			BitstreamSize([2]byte{b[26], b[27]}),
+			BitstreamCapacity([2]byte{b[28], b[29]}),
+			BitstreamStart([2]byte{b[30], b[31]}),
+		},
+	}, nil
+}
+
+// Bytes returns the first 32 bytes of the configuration data area, which
+// hold the signature and the fields of the configuration.  The user data
+// area which follows is not included.
+func (d DeviceConfig) Bytes() []byte {
+	b := make([]byte, 0, 32)
+	b = append(b, 'C', 'D', '0', uint8(d.BoardConfig.BoardType), uint8(d.BoardConfig.BoardSeries), uint8(d.BoardConfig.BoardNumber))
+	b = append(b, d.BoardConfig.BoardVariant[:]...)
+	b = append(b, d.FPGAConfig.FPGAType[:]...)
+	b = append(b, uint8(d.FPGAConfig.FPGAPackage))
+	b = append(b, d.FPGAConfig.FPGAGrade[:]...)
+	b = append(b, uint8(d.RAMConfig.RAMSize), uint8(d.RAMConfig.RAMType))
+	b = append(b, d.DescriptorSerial[:]...)
+	b = append(b, d.BitstreamConfig.BitstreamSize[:]...)
+	b = append(b, d.BitstreamConfig.BitstreamCapacity[:]...)
+	b = append(b, d.BitstreamConfig.BitstreamStart[:]...)
+	return b
+}
+
+// BoardConfigBlock represents the whole 128-byte configuration data area,
+// including the reserved bytes and the user data area which follow the
+// configuration itself.
+type BoardConfigBlock struct {
+	DeviceConfig DeviceConfig
+	Reserved     [macEEPROMUserStart - 32]byte
+	UserData     [macEEPROMUserSize]byte
+}
+
+// ParseBoardConfigBlock decodes the 128-byte configuration data area,
+// keeping the bytes which are not part of the configuration.
+func ParseBoardConfigBlock(b []byte) (*BoardConfigBlock, error) {
+	c, err := ParseDeviceConfig(b)
+	if err != nil {
+		return nil, err
+	}
+
+	x := &BoardConfigBlock{DeviceConfig: *c}
+	copy(x.Reserved[:], b[32:macEEPROMUserStart])
+	copy(x.UserData[:], b[macEEPROMUserStart:])
+	return x, nil
+}
+
+// Bytes returns the 128-byte configuration data area, which can be decoded
+// again by ParseBoardConfigBlock.
+func (b BoardConfigBlock) Bytes() []byte {
+	x := make([]byte, 128)
+	copy(x, b.DeviceConfig.Bytes())
+	copy(x[32:], b.Reserved[:])
+	copy(x[macEEPROMUserStart:], b.UserData[:])
+	return x
+}
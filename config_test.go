@@ -0,0 +1,64 @@
+package ztex
+
+import (
+	"bytes"
+	"testing"
+)
+
+// configArea returns a 128-byte configuration data area starting with the
+// given 32 bytes and followed by a recognizable pattern.
+func configArea(head []byte) []byte {
+	b := make([]byte, 128)
+	copy(b, head)
+	for i := 32; i < len(b); i++ {
+		b[i] = uint8(i)
+	}
+	return b
+}
+
+func TestDeviceConfigBytes(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		head []byte
+	}{
+		{"USB-FPGA 2.13a", []byte{'C', 'D', '0', 2, 2, 13, 'a', 0, 8, 0, 2, '1', 'C', 0, 0x18, 10, '0', '4', 'A', '3', '2', 'E', '4', '3', 'B', '7', 0x40, 0x0a, 0x80, 0x0f, 0, 0}},
+		{"USB-FPGA 2.16b", []byte{'C', 'D', '0', 2, 2, 16, 'b', 0, 9, 0, 3, '2', 0, 0, 0x19, 10, '1', '2', '3', '4', '5', '6', '7', '8', '9', 'A', 0, 0, 0, 0, 0, 0}},
+		{"two-byte variant, three-byte grade", []byte{'C', 'D', '0', 1, 1, 15, 'y', 'z', 3, 0, 1, '2', 'L', 'E', 0, 0, 'a', 'b', 'c', 'd', 'e', 'f', 'g', 'h', 'i', 'j', 1, 2, 3, 4, 5, 6}},
+		{"empty fields", []byte{'C', 'D', '0', 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := ParseDeviceConfig(configArea(tc.head))
+			if err != nil {
+				t.Fatalf("ParseDeviceConfig: %v", err)
+			}
+			if got := c.Bytes(); !bytes.Equal(got, tc.head) {
+				t.Errorf("(DeviceConfig).Bytes: got %v (%v bytes), want %v", got, len(got), tc.head)
+			}
+
+			x, err := ParseBoardConfigBlock(configArea(tc.head))
+			if err != nil {
+				t.Fatalf("ParseBoardConfigBlock: %v", err)
+			}
+			if got := x.Bytes(); !bytes.Equal(got, configArea(tc.head)) {
+				t.Errorf("(BoardConfigBlock).Bytes: got %v (%v bytes), want %v", got, len(got), configArea(tc.head))
+			}
+		})
+	}
+}
+
+func TestParseDeviceConfigErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		b    []byte
+	}{
+		{"short", make([]byte, 32)},
+		{"no signature", make([]byte, 128)},
+		{"wrong signature", configArea([]byte{'C', 'D', '1'})},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := ParseDeviceConfig(tc.b); err == nil {
+				t.Errorf("ParseDeviceConfig: got no error, want error")
+			}
+		})
+	}
+}
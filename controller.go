@@ -0,0 +1,91 @@
+package ztex
+
+import "io"
+
+// Controller performs the control transfers through which a device is
+// operated.  USB devices opened through a backend implement it, and so
+// does *gousb.Device; other implementations can stand in for hardware,
+// such as the simulator in package ztextest.
+type Controller interface {
+	Control(rType, request uint8, val, idx uint16, data []byte) (int, error)
+}
+
+// adaptController turns controllers which are USB devices of a known
+// library, such as *gousb.Device, into USBDevices.  It is set by the
+// backends which are built.
+var adaptController = func(c Controller) Controller { return c }
+
+// NewDevice returns a device operated through c, closing c if the device
+// cannot be initialized.  If c is a USBDevice or a *gousb.Device, then it
+// is also the USB device of the device, as if given to USBBackend.
+// Otherwise, operations which need bulk endpoints, such as high-speed FPGA
+// configuration and streams, are not supported unless the USB device is
+// given by USBBackend.
+func NewDevice(c Controller, opt ...DeviceOption) (*Device, error) {
+	c = adaptController(c)
+	d := &Device{ctrl: c, clock: SystemClock}
+	if dev, ok := c.(USBDevice); ok {
+		opt = append([]DeviceOption{USBBackend(dev)}, opt...)
+	}
+
+	if err := d.init(opt...); err != nil {
+		if x, ok := c.(io.Closer); ok {
+			x.Close()
+		}
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// USBBackend sets the USB device through which the bulk endpoints of the
+// device are reached, and which describes its location, for devices whose
+// controller is not the USB device itself, such as a recording or remote
+// controller.  It must precede ControlTimeout among the options.
+func USBBackend(u USBDevice) DeviceOption {
+	return func(d *Device) error {
+		desc := u.Describe()
+		d.usb, d.Desc = u, &desc
+		if x, ok := u.(controlTimeouter); ok {
+			d.timeout = x.controlTimeout()
+		}
+		return nil
+	}
+}
+
+// USB returns the USB device of the device, or nil if the device was not
+// opened through a backend nor given one by USBBackend.
+func (d *Device) USB() USBDevice { return d.usb }
+
+// WrapController replaces the controller of the device by the one
+// returned by wrap, which is typically a wrapper that forwards to the
+// original controller, for instance to trace or to fail transfers.  The
+// wrapper is used for all transfers, including those which read the
+// descriptor and the configuration when the device is initialized.
+func WrapController(wrap func(Controller) Controller) DeviceOption {
+	return func(d *Device) error {
+		d.ctrl = wrap(d.ctrl)
+		return nil
+	}
+}
+
+// Control performs a control transfer through the controller of the
+// device and counts it in the metrics of the device.  Errors of the
+// transfer are returned as a *CommandError.
+func (d *Device) Control(rType, request uint8, val, idx uint16, data []byte) (int, error) {
+	t := d.clock.Now()
+	n, err := d.ctrl.Control(rType, request, val, idx, data)
+	d.record(request, n, d.clock.Now().Sub(t), err)
+	if err != nil {
+		return n, &CommandError{rType, request, val, idx, len(data), n, err}
+	}
+	return n, nil
+}
+
+// Close closes the controller of the device, if it can be closed.
+func (d *Device) Close() error {
+	if x, ok := d.ctrl.(io.Closer); ok {
+		return x.Close()
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package ztex_test
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/aljumi/ztex"
+	"github.com/aljumi/ztex/ztextest"
+)
+
+func TestWrapControllerInit(t *testing.T) {
+	// The descriptor, the configuration, and the multi-FPGA configuration
+	// are read through the wrapped controller.
+	for _, tt := range []struct {
+		name    string
+		request uint8
+		fpgas   int
+	}{
+		{"descriptor", 0x22, 1},
+		{"configuration", 0x3b, 1},
+		{"multi-FPGA configuration", 0x50, 2},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			f := ztextest.NewFakeDevice("fake000001")
+			if tt.fpgas > 1 {
+				f.Descriptor[12] |= 0x80
+				f.FPGAs = tt.fpgas
+			}
+
+			d, err := ztextest.Open(f, ztextest.Faults(ztextest.Fault{Requests: []uint8{tt.request}, Err: ztextest.ErrStall}))
+			if err == nil {
+				d.Close()
+				t.Fatalf("ztextest.Open: got no error, want error of the faulted request %#02x", tt.request)
+			} else if !errors.Is(err, ztextest.ErrStall) {
+				t.Errorf("ztextest.Open: got %v, want %v", err, ztextest.ErrStall)
+			}
+
+			d, err = ztextest.Open(f, ztextest.Faults(ztextest.Fault{Requests: []uint8{tt.request}, Short: 1}))
+			var ce *ztex.CommandError
+			if err == nil {
+				d.Close()
+				t.Fatalf("ztextest.Open: got no error, want error of the short request %#02x", tt.request)
+			} else if !errors.As(err, &ce) {
+				t.Errorf("ztextest.Open: got %v, want *ztex.CommandError", err)
+			}
+		})
+	}
+}
+
+func TestWithLoggerInit(t *testing.T) {
+	b := &bytes.Buffer{}
+	l := slog.New(slog.NewTextHandler(b, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	d, err := ztextest.Open(ztextest.NewFakeDevice("fake000001"), ztex.WithLogger(l))
+	if err != nil {
+		t.Fatalf("ztextest.Open: %v", err)
+	}
+	defer d.Close()
+
+	for _, want := range []string{"request=0x22", "serial=fake000001 type=0xc0 request=0x3b"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("ztex.WithLogger: got log %q, want record with %q", b.String(), want)
+		}
+	}
+}
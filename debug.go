@@ -0,0 +1,228 @@
+package ztex
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// DebugCounter indicates the number of messages written to the debug
+// stack since the firmware started.
+type DebugCounter [2]uint8
+
+// String returns a human-readable description of the debug counter.
+func (d DebugCounter) String() string { return fmt.Sprintf("%v", d.Number()) }
+
+// Number returns the number of messages written to the debug stack.
+func (d DebugCounter) Number() uint16 { return bytesToUint16(d) }
+
+// DebugStackSize indicates the number of messages the debug stack holds.
+type DebugStackSize uint16
+
+// Number returns the number of messages the debug stack holds.
+func (d DebugStackSize) Number() uint16 { return uint16(d) }
+
+// DebugMessageSize indicates the number of bytes in a debug message.
+type DebugMessageSize uint16
+
+// String returns a human-readable description of the debug message size.
+func (d DebugMessageSize) String() string { return binaryPrefix(uint64(d), "B") }
+
+// Number returns the number of bytes in a debug message.
+func (d DebugMessageSize) Number() uint16 { return uint16(d) }
+
+// DebugSequence indicates the position of a message among all messages
+// written to the debug stack since the firmware started, starting at 0.
+// Sequence numbers wrap around after 65535.
+type DebugSequence uint16
+
+// Number returns the raw numeric representation of the debug sequence.
+func (d DebugSequence) Number() uint16 { return uint16(d) }
+
+// Before returns true if and only if the message with sequence d was
+// written before the message with sequence e, taking wrap-around into
+// account.
+func (d DebugSequence) Before(e DebugSequence) bool { return int16(d-e) < 0 }
+
+// DebugPayload represents the contents of a debug message.
+type DebugPayload []byte
+
+// String returns a human-readable description of the debug payload.
+func (d DebugPayload) String() string { return fmt.Sprintf("% x", []byte(d)) }
+
+// Text returns the debug payload as text if it consists of printable ASCII
+// characters padded with zero bytes, which is how firmware usually writes
+// strings to the debug stack, and as hexadecimal bytes otherwise.
+func (d DebugPayload) Text() string {
+	b := bytes.TrimRight(d, "\x00")
+	for _, c := range b {
+		if c < 0x20 || c > 0x7e {
+			return d.String()
+		}
+	}
+	return string(b)
+}
+
+// DebugMessage represents a message read from the debug stack.
+type DebugMessage struct {
+	DebugSequence
+	DebugPayload
+}
+
+// String returns a human-readable description of the debug message.
+func (d DebugMessage) String() string {
+	x := []string{}
+	x = append(x, fmt.Sprintf("Sequence(%v)", d.DebugSequence))
+	x = append(x, fmt.Sprintf("Payload(%v)", d.DebugPayload))
+	return strings.Join(x, ", ")
+}
+
+// DebugMessages represents the messages held by the debug stack, oldest
+// first.
+type DebugMessages []DebugMessage
+
+// String returns a human-readable description of the debug messages.
+func (d DebugMessages) String() string {
+	x := []string{}
+	for _, m := range d {
+		x = append(x, fmt.Sprintf("Message(%v)", m))
+	}
+	return strings.Join(x, ", ")
+}
+
+// Since returns the messages with sequence s or later.
+func (d DebugMessages) Since(s DebugSequence) DebugMessages {
+	x := DebugMessages{}
+	for _, m := range d {
+		if !m.DebugSequence.Before(s) {
+			x = append(x, m)
+		}
+	}
+	return x
+}
+
+// DebugStack represents the contents of the debug stack.
+type DebugStack struct {
+	DebugCounter
+	DebugStackSize
+	DebugMessageSize
+	DebugMessages
+}
+
+// String returns a human-readable description of the debug stack.
+func (d DebugStack) String() string {
+	x := []string{}
+	x = append(x, fmt.Sprintf("Counter(%v)", d.DebugCounter))
+	x = append(x, fmt.Sprintf("StackSize(%v)", d.DebugStackSize))
+	x = append(x, fmt.Sprintf("MessageSize(%v)", d.DebugMessageSize))
+	x = append(x, fmt.Sprintf("Messages(%v)", d.DebugMessages))
+	return strings.Join(x, ", ")
+}
+
+// Oldest returns the sequence of the oldest message still held by the
+// debug stack.
+func (d DebugStack) Oldest() DebugSequence {
+	c, n := d.DebugCounter.Number(), d.DebugStackSize.Number()
+	if c < n {
+		n = c
+	}
+	return DebugSequence(c - n)
+}
+
+// Lost returns the number of messages with sequence s or later which were
+// overwritten by newer messages before they could be read, because more
+// messages than the stack holds were written since s.
+func (d DebugStack) Lost(s DebugSequence) uint16 {
+	if o := d.Oldest(); s.Before(o) {
+		return uint16(o - s)
+	}
+	return 0
+}
+
+// decodeDebugStack decodes the debug stack returned by the debug helper:
+// the message counter, the stack size, the message size, and the most
+// recent messages, newest first.
+func decodeDebugStack(b []byte) (*DebugStack, error) {
+	if len(b) < 4 {
+		return nil, fmt.Errorf("got %v bytes, want at least %v bytes", len(b), 4)
+	}
+
+	d := &DebugStack{
+		DebugCounter([2]uint8{b[0], b[1]}),
+		DebugStackSize(b[2]),
+		DebugMessageSize(b[3]),
+		DebugMessages{},
+	}
+
+	n := int(d.DebugStackSize)
+	if c := int(d.DebugCounter.Number()); c < n {
+		n = c
+	}
+	if k := 4 + n*int(d.DebugMessageSize); len(b) < k {
+		return nil, fmt.Errorf("got %v bytes, want %v bytes", len(b), k)
+	}
+
+	for i := n - 1; i >= 0; i-- {
+		s := 4 + i*int(d.DebugMessageSize)
+		d.DebugMessages = append(d.DebugMessages, DebugMessage{
+			DebugSequence(d.DebugCounter.Number() - 1 - uint16(i)),
+			DebugPayload(append([]byte{}, b[s:s+int(d.DebugMessageSize)]...)),
+		})
+	}
+
+	return d, nil
+}
+
+// decodeDebugStack2 decodes the debug stack returned by the advanced debug
+// helper: the message counter, the stack size, the message size, and the
+// sequence of the first message returned, each as a little-endian 16-bit
+// number, followed by as many consecutive messages as fit in the transfer,
+// oldest first.
+func decodeDebugStack2(b []byte) (*DebugStack, error) {
+	if len(b) < 8 {
+		return nil, fmt.Errorf("got %v bytes, want at least %v bytes", len(b), 8)
+	}
+
+	d := &DebugStack{
+		DebugCounter([2]uint8{b[0], b[1]}),
+		DebugStackSize(bytesToUint16([2]uint8{b[2], b[3]})),
+		DebugMessageSize(bytesToUint16([2]uint8{b[4], b[5]})),
+		DebugMessages{},
+	}
+
+	k := int(d.DebugMessageSize)
+	if k == 0 || (len(b)-8)%k != 0 {
+		return nil, fmt.Errorf("got %v bytes of messages, want a multiple of %v bytes", len(b)-8, k)
+	}
+
+	q := bytesToUint16([2]uint8{b[6], b[7]})
+	for s := 8; s < len(b); s += k {
+		d.DebugMessages = append(d.DebugMessages, DebugMessage{
+			DebugSequence(q),
+			DebugPayload(append([]byte{}, b[s:s+k]...)),
+		})
+		q++
+	}
+
+	return d, nil
+}
+
+// DebugTail delivers debug messages as they are written by the firmware.
+type DebugTail struct {
+	// C delivers the debug messages in order.  It is closed when the
+	// context passed to TailDebug is done or reading the debug stack fails.
+	C <-chan DebugMessage
+
+	err  error
+	lost atomic.Uint64
+}
+
+// Err returns the error that stopped the tail.  It must only be called
+// after C has been closed.
+func (t *DebugTail) Err() error { return t.err }
+
+// Lost returns the number of messages which were overwritten in the debug
+// stack before the tail could read them.  Such messages also show up as
+// gaps in the sequence of the delivered messages.
+func (t *DebugTail) Lost() uint64 { return t.lost.Load() }
@@ -0,0 +1,168 @@
+package ztex_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aljumi/ztex"
+	"github.com/aljumi/ztex/ztextest"
+)
+
+// openDebug opens a fake device with a debug stack of three messages of
+// eight bytes, announced for the debug helper with the given capability
+// bits in Descriptor[12] and Descriptor[13], and writes n messages to it.
+func openDebug(t *testing.T, cap12, cap13 uint8, n int, opt ...ztex.DeviceOption) (*ztex.Device, *ztextest.FakeDevice) {
+	t.Helper()
+	f := ztextest.NewFakeDevice("fake000001")
+	f.Descriptor[12] |= cap12
+	f.Descriptor[13] |= cap13
+	f.Debug = ztextest.NewFakeDebug(3, 8)
+	for i := 0; i < n; i++ {
+		f.WriteDebug([]byte(fmt.Sprintf("msg %v", i)))
+	}
+
+	d, err := ztextest.Open(f, opt...)
+	if err != nil {
+		t.Fatalf("ztextest.Open: %v", err)
+	}
+	t.Cleanup(func() { d.Close() })
+	return d, f
+}
+
+// checkDebugStack checks the counter of a debug stack and the sequences
+// and texts of its messages, which are written by openDebug.
+func checkDebugStack(t *testing.T, s *ztex.DebugStack, counter uint16, seq ...uint16) {
+	t.Helper()
+	if s.DebugCounter.Number() != counter || s.DebugStackSize != 3 || s.DebugMessageSize != 8 {
+		t.Errorf("got stack %v, want counter %v, stack size 3, and message size 8", s, counter)
+	}
+	if len(s.DebugMessages) != len(seq) {
+		t.Fatalf("got messages %v, want messages %v", s.DebugMessages, seq)
+	}
+	for i, m := range s.DebugMessages {
+		if want := fmt.Sprintf("msg %v", seq[i]); m.DebugSequence.Number() != seq[i] || m.Text() != want {
+			t.Errorf("got message %v with text %q, want message %v with text %q", m, m.Text(), seq[i], want)
+		}
+	}
+}
+
+func TestDebugMessages(t *testing.T) {
+	for _, tt := range []struct {
+		name         string
+		cap12, cap13 uint8
+	}{
+		{"debug helper", 0x08, 0},
+		{"advanced debug helper", 0, 0x08},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			d, f := openDebug(t, tt.cap12, tt.cap13, 5)
+
+			// Only the three most recent messages are held, oldest first.
+			s, err := d.DebugMessages()
+			if err != nil {
+				t.Fatalf("(*ztex.Device).DebugMessages: %v", err)
+			}
+			checkDebugStack(t, s, 5, 2, 3, 4)
+			if s.Oldest() != 2 || s.Lost(0) != 2 || s.Lost(3) != 0 {
+				t.Errorf("(*ztex.Device).DebugMessages: got oldest %v and %v and %v lost, want oldest 2 and 2 and 0 lost", s.Oldest(), s.Lost(0), s.Lost(3))
+			}
+
+			if s, err = d.DebugMessagesSince(3); err != nil {
+				t.Fatalf("(*ztex.Device).DebugMessagesSince: %v", err)
+			}
+			checkDebugStack(t, s, 5, 3, 4)
+
+			if err := d.ResetDebug(); err != nil {
+				t.Fatalf("(*ztex.Device).ResetDebug: %v", err)
+			}
+			f.WriteDebug([]byte("msg 0"))
+			if s, err = d.DebugMessages(); err != nil {
+				t.Fatalf("(*ztex.Device).DebugMessages: %v", err)
+			}
+			checkDebugStack(t, s, 1, 0)
+		})
+	}
+}
+
+func TestDebugMessagesChunks(t *testing.T) {
+	f := ztextest.NewFakeDevice("fake000001")
+	f.Descriptor[13] |= 0x08
+	f.Debug = ztextest.NewFakeDebug(1000, 8)
+	for i := 0; i < 700; i++ {
+		f.WriteDebug([]byte(fmt.Sprintf("msg %v", i)))
+	}
+	d, err := ztextest.Open(f)
+	if err != nil {
+		t.Fatalf("ztextest.Open: %v", err)
+	}
+	defer d.Close()
+
+	// The header is followed by two chunks of up to 511 messages each.
+	s, err := d.DebugMessages()
+	if err != nil {
+		t.Fatalf("(*ztex.Device).DebugMessages: %v", err)
+	} else if n := f.Calls(0x2a); n != 3 {
+		t.Errorf("(*ztex.Device).DebugMessages: got %v requests, want 3", n)
+	}
+	if len(s.DebugMessages) != 700 {
+		t.Fatalf("(*ztex.Device).DebugMessages: got %v messages, want 700", len(s.DebugMessages))
+	}
+	for i, m := range s.DebugMessages {
+		if want := fmt.Sprintf("msg %v", i); m.DebugSequence.Number() != uint16(i) || m.Text() != want {
+			t.Fatalf("(*ztex.Device).DebugMessages: got message %v with text %q, want message %v with text %q", m, m.Text(), i, want)
+		}
+	}
+
+	// Only the requested messages are transferred.
+	if s, err = d.DebugMessagesSince(698); err != nil {
+		t.Fatalf("(*ztex.Device).DebugMessagesSince: %v", err)
+	} else if n := f.Calls(0x2a); n != 5 || len(s.DebugMessages) != 2 {
+		t.Errorf("(*ztex.Device).DebugMessagesSince: got %v messages after %v requests, want 2 messages after 5 requests", len(s.DebugMessages), n)
+	}
+}
+
+func TestDebugMessagesErrors(t *testing.T) {
+	// A debug stack which is shorter than its header announces is
+	// rejected.
+	d, _ := openDebug(t, 0x08, 0, 5, ztextest.Faults(ztextest.Fault{Requests: []uint8{0x28}, Short: 1}))
+	if s, err := d.DebugMessages(); err == nil {
+		t.Errorf("(*ztex.Device).DebugMessages: got %v, want error", s)
+	}
+
+	// A chunk of messages which ends within a message is rejected.
+	d, _ = openDebug(t, 0, 0x08, 5, ztextest.Faults(ztextest.Fault{Requests: []uint8{0x2a}, Call: 2, Short: 1}))
+	if s, err := d.DebugMessages(); err == nil {
+		t.Errorf("(*ztex.Device).DebugMessages: got %v, want error", s)
+	}
+
+	// A header which is cut short is reported as a failed request.
+	d, _ = openDebug(t, 0, 0x08, 5, ztextest.Faults(ztextest.Fault{Requests: []uint8{0x2a}, Call: 1, Short: 1}))
+	var ce *ztex.CommandError
+	if s, err := d.DebugMessages(); !errors.As(err, &ce) {
+		t.Errorf("(*ztex.Device).DebugMessages: got %v and %v, want *ztex.CommandError", s, err)
+	} else if ce.Request != 0x2a || ce.Want != 8 || ce.Got != 7 {
+		t.Errorf("(*ztex.Device).DebugMessages: got %v, want request 0x2a short by one of 8 bytes", ce)
+	}
+
+	d, _ = openDebug(t, 0, 0, 5)
+	if s, err := d.DebugMessages(); err != ztex.ErrNotSupported {
+		t.Errorf("(*ztex.Device).DebugMessages without debug helper: got %v and %v, want %v", s, err, ztex.ErrNotSupported)
+	}
+}
+
+func TestDebugPayloadText(t *testing.T) {
+	for _, tt := range []struct {
+		b    []byte
+		want string
+	}{
+		{[]byte("ready\x00\x00\x00"), "ready"},
+		{[]byte{}, ""},
+		{[]byte{'o', 'k', 0, 1}, "6f 6b 00 01"},
+		{[]byte{0x80, 'a'}, "80 61"},
+	} {
+		if got := ztex.DebugPayload(tt.b).Text(); got != tt.want {
+			t.Errorf("(ztex.DebugPayload).Text(% x): got %q, want %q", tt.b, got, tt.want)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package ztex
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 )
@@ -25,59 +26,63 @@ type DescriptorProduct [4]uint8
 
 // String returns a human-readable description of the ZTEX product ID.
 func (d DescriptorProduct) String() string {
-	p := "Unknown"
-	switch {
-	case d[0] == 0 && d[1] == 0 && d[2] == 0 && d[3] == 0:
-		p = "Default"
-	case d[0] == 1:
-		p = "Experimental"
-	case d[0] == 10 && d[1] == 0 && d[2] == 1 && d[3] == 1:
-		p = "ZTEX BTCMiner"
-	case d[0] == 10 && d[1] == 11:
-		p = "ZTEX USB-FPGA Module 1.2"
-	case d[0] == 10 && d[1] == 12 && d[2] == 2 && (1 <= d[3] && d[3] <= 4):
-		p = "NIT"
-	case d[0] == 10 && d[1] == 12:
-		p = "ZTEX USB-FPGA Module 1.11"
-	case d[0] == 10 && d[1] == 13:
-		p = "ZTEX USB-FPGA Module 1.15"
-	case d[0] == 10 && d[1] == 14:
-		p = "ZTEX USB-FPGA Module 1.15x"
-	case d[0] == 10 && d[1] == 15:
-		p = "ZTEX USB-FPGA Module 1.15y"
-	case d[0] == 10 && d[1] == 16:
-		p = "ZTEX USB-FPGA Module 2.16"
-	case d[0] == 10 && d[1] == 17:
-		p = "ZTEX USB-FPGA Module 2.13"
-	case d[0] == 10 && d[1] == 18:
-		p = "ZTEX USB-FPGA Module 2.01"
-	case d[0] == 10 && d[1] == 19:
-		p = "ZTEX USB-FPGA Module 2.04"
-	case d[0] == 10 && d[1] == 20:
-		p = "ZTEX USB Module 1.0"
-	case d[0] == 10 && d[1] == 30:
-		p = "ZTEX USB-XMEGA Module 1.0"
-	case d[0] == 10 && d[1] == 40:
-		p = "ZTEX USB-FPGA Module 2.02"
-	case d[0] == 10 && d[1] == 41:
-		p = "ZTEX USB-FPGA Module 2.14"
-	case d[0] == 10 && d[1] == 42:
-		p = "ZTEX USB3-FPGA Module 2.18"
-	case d[0] == 10:
-		p = "ZTEX"
-	}
-	return fmt.Sprintf("%v.%v.%v.%v [%v]", d[0], d[1], d[2], d[3], p)
+	return fmt.Sprintf("%v.%v.%v.%v [%v]", d[0], d[1], d[2], d[3], d.Name())
 }
 
+// IsKnown returns true if and only if the ZTEX product ID has a known
+// name.
+func (d DescriptorProduct) IsKnown() bool { return isKnown(d.Name()) }
+
+// Name returns the name of the product with the ZTEX product ID.
+func (d DescriptorProduct) Name() string { return productName(d) }
+
 // Bytes returns a raw representation of the ZTEX product ID.
 func (d DescriptorProduct) Bytes() []byte { return []byte{d[0], d[1], d[2], d[3]} }
 
 // DescriptorFirmware indicates the version of the ZTEX firmware.
 type DescriptorFirmware uint8
 
+// Number returns the firmware version.
+func (d DescriptorFirmware) Number() uint8 { return uint8(d) }
+
+// Compare returns -1, 0, or +1 depending on whether the firmware version
+// is older than, the same as, or newer than v.
+func (d DescriptorFirmware) Compare(v DescriptorFirmware) int {
+	return compareVersion(uint8(d), uint8(v))
+}
+
+// AtLeast returns true if and only if the firmware version is v or newer.
+func (d DescriptorFirmware) AtLeast(v uint8) bool { return uint8(d) >= v }
+
 // DescriptorInterface indicates the version of the ZTEX interface.
 type DescriptorInterface uint8
 
+// Number returns the interface version.
+func (d DescriptorInterface) Number() uint8 { return uint8(d) }
+
+// Compare returns -1, 0, or +1 depending on whether the interface version
+// is older than, the same as, or newer than v.
+func (d DescriptorInterface) Compare(v DescriptorInterface) int {
+	return compareVersion(uint8(d), uint8(v))
+}
+
+// AtLeast returns true if and only if the interface version is v or newer.
+// Features added in later revisions of the ZTEX interface are gated on
+// it.
+func (d DescriptorInterface) AtLeast(v uint8) bool { return uint8(d) >= v }
+
+// compareVersion compares the versions a and b.
+func compareVersion(a, b uint8) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return +1
+	default:
+		return 0
+	}
+}
+
 // DescriptorCapability indicates the capabilities supported by the ZTEX device.
 type DescriptorCapability [6]uint8
 
@@ -85,22 +90,31 @@ type DescriptorCapability [6]uint8
 // supported by the device.
 func (d DescriptorCapability) String() string {
 	x := []string{}
-	x = append(x, fmt.Sprintf("EEPROM(%v)", d.EEPROM()))
-	x = append(x, fmt.Sprintf("FPGA Configuration(%v)", d.FPGAConfiguration()))
-	x = append(x, fmt.Sprintf("Flash Memory(%v)", d.FlashMemory()))
-	x = append(x, fmt.Sprintf("Debug Helper(%v)", d.DebugHelper()))
-	x = append(x, fmt.Sprintf("XMEGA(%v)", d.XMEGA()))
-	x = append(x, fmt.Sprintf("High Speed FPGA Configuration(%v)", d.HighSpeedFPGAConfiguration()))
-	x = append(x, fmt.Sprintf("MAC EEPROM(%v)", d.MACEEPROM()))
-	x = append(x, fmt.Sprintf("MultiFPGA(%v)", d.MultiFPGA()))
-	x = append(x, fmt.Sprintf("Temperature Sensor(%v)", d.TemperatureSensor()))
-	x = append(x, fmt.Sprintf("Flash Memory 2(%v)", d.FlashMemory2()))
-	x = append(x, fmt.Sprintf("FX3 Firmware(%v)", d.FX3Firmware()))
-	x = append(x, fmt.Sprintf("Debug Helper 2(%v)", d.DebugHelper2()))
-	x = append(x, fmt.Sprintf("Default Firmware(%v)", d.DefaultFirmware()))
+	for _, f := range d.fields() {
+		x = append(x, fmt.Sprintf("%v(%v)", f.name, f.value))
+	}
 	return strings.Join(x, ", ")
 }
 
+// fields returns whether or not each capability is supported.
+func (d DescriptorCapability) fields() []field {
+	return []field{
+		{"EEPROM", d.EEPROM()},
+		{"FPGA Configuration", d.FPGAConfiguration()},
+		{"Flash Memory", d.FlashMemory()},
+		{"Debug Helper", d.DebugHelper()},
+		{"XMEGA", d.XMEGA()},
+		{"High Speed FPGA Configuration", d.HighSpeedFPGAConfiguration()},
+		{"MAC EEPROM", d.MACEEPROM()},
+		{"MultiFPGA", d.MultiFPGA()},
+		{"Temperature Sensor", d.TemperatureSensor()},
+		{"Flash Memory 2", d.FlashMemory2()},
+		{"FX3 Firmware", d.FX3Firmware()},
+		{"Debug Helper 2", d.DebugHelper2()},
+		{"Default Firmware", d.DefaultFirmware()},
+	}
+}
+
 // Function cap returns true if and only if ZTEX capability i.j is
 // supported by the device.
 func (d DescriptorCapability) cap(i, j uint) bool { return d[i]&(1<<j) != 0 }
@@ -152,7 +166,8 @@ func (d DescriptorCapability) DebugHelper2() bool { return d.cap(1, 3) }
 // default firmware interface.
 func (d DescriptorCapability) DefaultFirmware() bool { return d.cap(1, 4) }
 
-// DescriptorModule represents product specific configuration.
+// DescriptorModule represents product specific configuration.  Module
+// decodes it for the firmware families with a known layout.
 type DescriptorModule [12]uint8
 
 // DescriptorSerial represents the device serial number.
@@ -189,7 +204,76 @@ func (d DescriptorConfig) String() string {
 	x = append(x, fmt.Sprintf("Firmware(%v)", d.DescriptorFirmware))
 	x = append(x, fmt.Sprintf("Interface(%v)", d.DescriptorInterface))
 	x = append(x, fmt.Sprintf("Capability(%v)", d.DescriptorCapability))
-	x = append(x, fmt.Sprintf("Module(%v)", d.DescriptorModule))
+	x = append(x, fmt.Sprintf("Module(%v)", d.Module()))
 	x = append(x, fmt.Sprintf("Serial(%v)", d.DescriptorSerial))
 	return strings.Join(x, ", ")
 }
+
+// MarshalJSON returns a JSON representation of a ZTEX device descriptor,
+// with both the raw product and capability bytes and their meanings.
+func (d DescriptorConfig) MarshalJSON() ([]byte, error) {
+	c := d.DescriptorCapability
+	return json.Marshal(struct {
+		Size         uint8           `json:"size"`
+		Version      uint8           `json:"version"`
+		Magic        string          `json:"magic"`
+		Product      [4]uint8        `json:"product"`
+		ProductName  string          `json:"product_name"`
+		Firmware     uint8           `json:"firmware"`
+		Interface    uint8           `json:"interface"`
+		Capability   [6]uint8        `json:"capability"`
+		Capabilities map[string]bool `json:"capabilities"`
+		Module       [12]uint8       `json:"module"`
+		Serial       string          `json:"serial"`
+	}{
+		uint8(d.DescriptorSize),
+		uint8(d.DescriptorVersion),
+		d.DescriptorMagic.String(),
+		d.DescriptorProduct,
+		d.DescriptorProduct.Name(),
+		uint8(d.DescriptorFirmware),
+		uint8(d.DescriptorInterface),
+		c,
+		map[string]bool{
+			"eeprom":                        c.EEPROM(),
+			"fpga_configuration":            c.FPGAConfiguration(),
+			"flash_memory":                  c.FlashMemory(),
+			"debug_helper":                  c.DebugHelper(),
+			"xmega":                         c.XMEGA(),
+			"high_speed_fpga_configuration": c.HighSpeedFPGAConfiguration(),
+			"mac_eeprom":                    c.MACEEPROM(),
+			"multi_fpga":                    c.MultiFPGA(),
+			"temperature_sensor":            c.TemperatureSensor(),
+			"flash_memory_2":                c.FlashMemory2(),
+			"fx3_firmware":                  c.FX3Firmware(),
+			"debug_helper_2":                c.DebugHelper2(),
+			"default_firmware":              c.DefaultFirmware(),
+		},
+		d.DescriptorModule,
+		d.DescriptorSerial.String(),
+	})
+}
+
+// ParseDescriptorConfig decodes the 40-byte ZTEX descriptor, as returned by
+// the device or stored in a firmware image.
+func ParseDescriptorConfig(b []byte) (DescriptorConfig, error) {
+	if len(b) != 40 {
+		return DescriptorConfig{}, fmt.Errorf("%w: got %v bytes, want %v bytes", ErrBadDescriptor, len(b), 40)
+	} else if b[0] != 40 {
+		return DescriptorConfig{}, fmt.Errorf("%w: got size %v, want size %v", ErrBadDescriptor, b[0], 40)
+	} else if b[1] != 1 {
+		return DescriptorConfig{}, fmt.Errorf("%w: got version %v, want version %v", ErrBadDescriptor, b[1], 1)
+	}
+
+	return DescriptorConfig{
+		DescriptorSize(b[0]),
+		DescriptorVersion(b[1]),
+		DescriptorMagic([4]uint8(b[2:6])),
+		DescriptorProduct([4]uint8(b[6:10])),
+		DescriptorFirmware(b[10]),
+		DescriptorInterface(b[11]),
+		DescriptorCapability([6]uint8(b[12:18])),
+		DescriptorModule([12]uint8(b[18:30])),
+		DescriptorSerial([10]uint8(b[30:40])),
+	}, nil
+}
@@ -1,74 +1,303 @@
 package ztex
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/google/gousb"
+	"github.com/aljumi/ztex/bitstream"
+	"github.com/aljumi/ztex/firmware"
+	"github.com/aljumi/ztex/flash"
+	"github.com/aljumi/ztex/ihx"
 )
 
 // Device represents a ZTEX USB device.
 type Device struct {
-	*gousb.Device
+	// Desc describes the USB device, if the device was opened through a
+	// backend, and is nil otherwise.
+	Desc *USBDesc
 
-	DescriptorConfig
-	BoardConfig
-	FPGAConfig
-	RAMConfig
-	BitstreamConfig
+	// The descriptor and configuration read when the device was opened,
+	// and again by Refresh.  They are named fields rather than embedded,
+	// so that their fields and methods do not collide; Capability and
+	// Serial are shorthands for the most commonly used descriptor fields.
+	DescriptorConfig DescriptorConfig
+	BoardConfig      BoardConfig
+	FPGAConfig       FPGAConfig
+	RAMConfig        RAMConfig
+	BitstreamConfig  BitstreamConfig
+	MultiFPGAConfig  MultiFPGAConfig
+
+	// rawConfig holds the MAC EEPROM contents read when the device was
+	// opened, kept up to date by writes through the device.
+	rawConfig [128]byte
+
+	// configRead records whether or not the configuration data area has
+	// been read, which deferConfig, as set by DeferDeviceConfig, postpones
+	// until it is needed.
+	configRead  bool
+	deferConfig bool
+
+	// geometry caches the geometry of the flash once it has been read,
+	// until Refresh.
+	geometry flash.Geometry
+
+	ctrl         Controller
+	usb          USBDevice
+	clock        Clock
+	timeout      time.Duration
+	dataTimeout  time.Duration
+	opTimeout    time.Duration
+	callTimeout  time.Duration
+	force        bool
+	xferSize     int
+	xferCount    int
+	tracer       Tracer
+	calibrations SensorCalibrations
+
+	// mu serializes LSI accesses.
+	mu sync.Mutex
+
+	// fpgas pins the selected FPGA during streams and LSI accesses.
+	fpgas fpgaLease
+
+	metricsMu sync.Mutex
+	metrics   Metrics
+	bulkOut   BulkMetrics
+	bulkIn    BulkMetrics
 }
 
 // String returns a human-readable representation of the device.
 func (d *Device) String() string {
 	x := []string{}
-	x = append(x, fmt.Sprintf("Device(%v)", d.Device))
+	x = append(x, fmt.Sprintf("USB(%v)", d.Desc))
 	x = append(x, fmt.Sprintf("Descriptor(%v)", d.DescriptorConfig))
 	x = append(x, fmt.Sprintf("Board(%v)", d.BoardConfig))
 	x = append(x, fmt.Sprintf("FPGA(%v)", d.FPGAConfig))
 	x = append(x, fmt.Sprintf("RAM(%v)", d.RAMConfig))
 	x = append(x, fmt.Sprintf("Bitstream(%v)", d.BitstreamConfig))
+	x = append(x, fmt.Sprintf("MultiFPGA(%v)", d.MultiFPGAConfig))
 	return strings.Join(x, ", ")
 }
 
+// Capability returns the capabilities announced by the ZTEX descriptor of
+// the device.
+func (d *Device) Capability() DescriptorCapability { return d.DescriptorConfig.DescriptorCapability }
+
+// Serial returns the serial number of the device.
+func (d *Device) Serial() DescriptorSerial { return d.DescriptorConfig.DescriptorSerial }
+
+// RawConfig returns the first 128 bytes of the MAC EEPROM as read when the
+// device was opened or refreshed, including the bytes which are not
+// decoded into the configuration, such as the interface-specific bytes 16
+// to 25.  Writes to the MAC EEPROM through the device are reflected in the
+// result.  For devices opened with DeferDeviceConfig, the bytes are zero
+// until LoadDeviceConfig has been called.
+func (d *Device) RawConfig() [128]byte { return d.rawConfig }
+
 // DeviceOption represents a device option.
 type DeviceOption func(*Device) error
 
 // ControlTimeout sets the timeout for control commands for the device.
+// It does not apply to bulk transfers, whose timeout is set by
+// DataTimeout, nor to commands which start long operations, whose timeout
+// is set by OperationTimeout.
 func ControlTimeout(timeout time.Duration) DeviceOption {
 	return func(d *Device) error {
-		d.ControlTimeout = timeout
+		if d.usb != nil {
+			d.usb.SetControlTimeout(timeout)
+		}
+		d.timeout = timeout
 		return nil
 	}
 }
 
-// OpenDevice opens a ZTEX USB-FPGA module and returns its device handle.
-// If there are multiple modules present, then one is chosen arbitrarily.
-func OpenDevice(ctx *gousb.Context, opt ...DeviceOption) (*Device, error) {
-	d := &Device{}
-	if dev, err := ctx.OpenDeviceWithVIDPID(VendorID, ProductID); err != nil {
-		return nil, fmt.Errorf("(*gousb.Context).OpenDeviceWithVIDPID: %v", err)
-	} else if dev == nil {
-		return nil, fmt.Errorf("(*gousb.Context).OpenDeviceWithVIDPID: got nil device, want non-nil device")
-	} else {
-		d.Device = dev
+// DataTimeout sets the timeout for each bulk transfer of high-speed FPGA
+// configuration and streams, which is independent of the timeout for
+// control commands.  If zero, which is the default, then bulk transfers
+// are only bounded by the context of the operation.
+func DataTimeout(timeout time.Duration) DeviceOption {
+	return func(d *Device) error {
+		d.dataTimeout = timeout
+		return nil
 	}
+}
 
-	if err := d.readDescriptorConfig(); err != nil {
-		return nil, err
+// OperationTimeout sets the timeout for control commands which the
+// firmware answers only once a long operation has finished, such as
+// writing a flash sector, which erases it first, or erasing the XMEGA
+// application section.  If zero, which is the default, then the timeout
+// for control commands applies.
+func OperationTimeout(timeout time.Duration) DeviceOption {
+	return func(d *Device) error {
+		d.opTimeout = timeout
+		return nil
 	}
+}
 
-	if err := d.readDeviceConfig(); err != nil {
-		return nil, err
+// DeferDeviceConfig defers reading the configuration data area from the
+// MAC EEPROM, which costs a control transfer per device, until it is
+// needed.  It is meant for enumerating many devices in order to select
+// one of them by serial number or location, which are known without the
+// configuration.  The board, FPGA, RAM, and bitstream configurations of
+// the device are zero until LoadDeviceConfig or Refresh has been called;
+// operations which depend on them, such as InstallBitstream, read them
+// first.
+func DeferDeviceConfig() DeviceOption {
+	return func(d *Device) error {
+		d.deferConfig = true
+		return nil
+	}
+}
+
+// CalibrateSensors sets the corrections applied to the sensor readings of
+// the device.
+func CalibrateSensors(c SensorCalibrations) DeviceOption {
+	return func(d *Device) error {
+		d.calibrations = c
+		return nil
+	}
+}
+
+// DeviceFilter reports whether or not a device is selected.
+type DeviceFilter func(*Device) bool
+
+// SerialFilter selects the device with the given serial number.
+func SerialFilter(serial string) DeviceFilter {
+	return func(d *Device) bool { return d.Serial().String() == serial }
+}
+
+// BusFilter selects the devices attached to the given USB bus.
+func BusFilter(bus int) DeviceFilter {
+	return func(d *Device) bool { return d.Desc != nil && d.Desc.Bus == bus }
+}
+
+// PortFilter selects the devices attached to the given port of their hub.
+func PortFilter(port int) DeviceFilter {
+	return func(d *Device) bool { return d.Desc != nil && d.Desc.Port == port }
+}
+
+// location returns the USB bus and address of the device, which are zero
+// for devices that were not opened through a backend.
+func (d *Device) location() (int, int) {
+	if d.Desc == nil {
+		return 0, 0
+	}
+	return d.Desc.Bus, d.Desc.Address
+}
+
+// FilterDevices returns the devices selected by all filters, ordered by
+// bus and address, and closes the other devices.
+func FilterDevices(ds []*Device, filter ...DeviceFilter) []*Device {
+	x := FilterDevicesInScanOrder(ds, filter...)
+
+	sort.Slice(x, func(i, j int) bool {
+		bi, ai := x[i].location()
+		bj, aj := x[j].location()
+		return bi < bj || (bi == bj && ai < aj)
+	})
+
+	return x
+}
+
+// FilterDevicesInScanOrder returns the devices selected by all filters,
+// like FilterDevices, but keeps them in the order in which the backend
+// listed them.  The ZTEX SDK numbers modules in the order in which libusb
+// lists them, which the gousb backend keeps, so the index of a module
+// among all devices returned by OpenDevices, kept in this order, is its
+// device number in the SDK, as given to FWLoader with -d.  Modules
+// which could not be opened are left out, and shift the numbers of the
+// modules listed after them.
+func FilterDevicesInScanOrder(ds []*Device, filter ...DeviceFilter) []*Device {
+	x := []*Device{}
+	for _, d := range ds {
+		ok := true
+		for _, f := range filter {
+			ok = ok && f(d)
+		}
+		if ok {
+			x = append(x, d)
+		} else {
+			d.Close()
+		}
 	}
+	return x
+}
 
+func (d *Device) init(opt ...DeviceOption) error {
+	// The options are applied first, so that the descriptor and the
+	// configuration are read through the controller they install, and the
+	// transfers are faulted, logged, and counted like any other.
 	for _, o := range opt {
 		if err := o(d); err != nil {
-			return nil, err
+			return err
+		}
+	}
+
+	if err := d.readDescriptorConfig(); err != nil {
+		return err
+	}
+
+	if !d.deferConfig {
+		if err := d.readDeviceConfig(); err != nil {
+			return err
 		}
 	}
 
-	return d, nil
+	return d.readMultiFPGAConfig()
+}
+
+// Refresh reads the descriptor, the configuration data area, and the
+// multi-FPGA configuration of the device again, and forgets the cached
+// flash geometry.  These are read when the device is opened and cached
+// afterwards, so that String, Info, and the operations which depend on
+// them do not repeat the control transfers; Refresh is only needed after
+// the device was changed by other means, such as by another program or
+// by new firmware.  It must not be called concurrently with other
+// methods of the device.
+func (d *Device) Refresh() error {
+	if err := d.readDescriptorConfig(); err != nil {
+		return err
+	}
+
+	if err := d.readDeviceConfig(); err != nil {
+		return err
+	}
+
+	if err := d.readMultiFPGAConfig(); err != nil {
+		return err
+	}
+
+	d.geometry = flash.Geometry{}
+
+	return nil
+}
+
+// LoadDeviceConfig reads the configuration data area from the MAC EEPROM
+// unless it has been read already, which is only the case for devices
+// opened with DeferDeviceConfig.
+func (d *Device) LoadDeviceConfig() error {
+	if d.configRead {
+		return nil
+	}
+	return d.readDeviceConfig()
+}
+
+// readMultiFPGAConfig reads the multi-FPGA configuration if the device
+// supports it.
+func (d *Device) readMultiFPGAConfig() error {
+	if !d.Capability().MultiFPGA() {
+		return nil
+	}
+	_, err := d.ReadMultiFPGAConfig()
+	return err
 }
 
 func (d *Device) readDescriptorConfig() error {
@@ -76,26 +305,16 @@ func (d *Device) readDescriptorConfig() error {
 
 	// VR 0x22: ZTEX descriptor: read ZTEX descriptor
 	if nbr, err := d.Control(0xc0, 0x22, 0, 0, b); err != nil {
-		return fmt.Errorf("(*ztex.Device).Control: ZTEX descriptor: read ZTEX descriptor: %v", err)
+		return fmt.Errorf("(*ztex.Device).Control: ZTEX descriptor: read ZTEX descriptor: %w", err)
 	} else if nbr != 40 {
-		return fmt.Errorf("(*ztex.Device).Control: ZTEX descriptor: read ZTEX descriptor: got %v bytes, want %v bytes", nbr, 40)
-	} else if b[0] != 40 {
-		return fmt.Errorf("(*ztex.Device).Control: ZTEX descriptor: read ZTEX descriptor: got size %v, want size %v", b[0], 40)
-	} else if b[1] != 1 {
-		return fmt.Errorf("(*ztex.Device).Control: ZTEX descriptor: read ZTEX descriptor: got version %v, want version %v", b[0], 1)
+		return fmt.Errorf("(*ztex.Device).Control: ZTEX descriptor: read ZTEX descriptor: %w", &CommandError{0xc0, 0x22, 0, 0, 40, nbr, nil})
 	}
 
-	d.DescriptorConfig = DescriptorConfig{
-		DescriptorSize(b[0]),
-		DescriptorVersion(b[1]),
-		DescriptorMagic([4]uint8{b[2], b[3], b[4], b[5]}),
-		DescriptorProduct([4]uint8{b[6], b[7], b[8], b[9]}),
-		DescriptorFirmware(b[10]),
-		DescriptorInterface(b[11]),
-		DescriptorCapability([6]uint8{b[12], b[13], b[14], b[15], b[16], b[17]}),
-		DescriptorModule([12]uint8{b[18], b[19], b[20], b[21], b[22], b[23], b[24], b[25], b[26], b[27], b[28], b[29]}),
-		DescriptorSerial([10]uint8{b[30], b[31], b[32], b[33], b[34], b[35], b[36], b[37], b[38], b[39]}),
+	c, err := ParseDescriptorConfig(b)
+	if err != nil {
+		return fmt.Errorf("(*ztex.Device).Control: ZTEX descriptor: read ZTEX descriptor: %w", err)
 	}
+	d.DescriptorConfig = c
 
 	return nil
 }
@@ -105,132 +324,2057 @@ func (d *Device) readDeviceConfig() error {
 
 	// VR 0x3b: MAC EEPROM support: read from MAC EEPROM
 	if nbr, err := d.Control(0xc0, 0x3b, 0, 0, b); err != nil {
-		return fmt.Errorf("(*ztex.Device).Control: MAC EEPROM support: read from MAC EEPROM: %v", err)
+		return fmt.Errorf("(*ztex.Device).Control: MAC EEPROM support: read from MAC EEPROM: %w", err)
 	} else if nbr != 128 {
-		return fmt.Errorf("(*ztex.Device).Control: MAC EEPROM support: read from MAC EEPROM: got %v bytes, want %v bytes", nbr, 128)
-	} else if b[0] != 'C' || b[1] != 'D' || b[2] != '0' {
-		return fmt.Errorf("(*ztex.Device).Control: MAC EEPROM support: read from MAC EEPROM: got signature %v, want signature %v", b[:3], []byte{'C', 'D', '0'})
+		return fmt.Errorf("(*ztex.Device).Control: MAC EEPROM support: read from MAC EEPROM: %w", &CommandError{0xc0, 0x3b, 0, 0, 128, nbr, nil})
+	}
+
+	c, err := ParseDeviceConfig(b)
+	if err != nil {
+		return fmt.Errorf("(*ztex.Device).Control: MAC EEPROM support: read from MAC EEPROM: %w", err)
+	}
+	copy(d.rawConfig[:], b)
+	d.configRead = true
+
+	d.BoardConfig = c.BoardConfig
+	d.FPGAConfig = c.FPGAConfig
+	d.RAMConfig = c.RAMConfig
+	d.BitstreamConfig = c.BitstreamConfig
+
+	return nil
+}
+
+// WriteDeviceConfig writes the configuration data area at the start of
+// the MAC EEPROM, leaving the user data area unchanged, and updates the
+// configuration of the device accordingly.
+func (d *Device) WriteDeviceConfig(c DeviceConfig) error {
+	if err := d.WriteMACEEPROM(0, c.Bytes()); err != nil {
+		return err
+	}
+
+	d.BoardConfig = c.BoardConfig
+	d.FPGAConfig = c.FPGAConfig
+	d.RAMConfig = c.RAMConfig
+	d.BitstreamConfig = c.BitstreamConfig
+
+	return nil
+}
+
+// ReadEEPROM reads len(b) bytes from the firmware EEPROM, starting at the
+// given address.
+func (d *Device) ReadEEPROM(addr uint16, b []byte, opt ...CallOption) error {
+	return d.call(opt, func(callOptions) error { return d.readEEPROM(addr, b) })
+}
+
+func (d *Device) readEEPROM(addr uint16, b []byte) error {
+	if !d.Capability().EEPROM() {
+		return ErrNotSupported
+	}
+
+	// VR 0x38: EEPROM support: read from EEPROM
+	if nbr, err := d.Control(0xc0, 0x38, addr, 0, b); err != nil {
+		return fmt.Errorf("(*gousb.Device).Control: EEPROM support: read from EEPROM: %w", err)
+	} else if nbr != len(b) {
+		return fmt.Errorf("(*gousb.Device).Control: EEPROM support: read from EEPROM: %w", &CommandError{0xc0, 0x38, addr, 0, len(b), nbr, nil})
+	}
+
+	return nil
+}
+
+// WriteEEPROM writes b to the firmware EEPROM, starting at the given
+// address.  The data is written in blocks of up to 64 bytes, waiting for
+// each block to be committed before writing the next.
+func (d *Device) WriteEEPROM(addr uint16, b []byte, opt ...CallOption) error {
+	return d.call(opt, func(o callOptions) error { return d.writeEEPROM(addr, b, o.progress) })
+}
+
+func (d *Device) writeEEPROM(addr uint16, b []byte, progress Progress) error {
+	if !d.Capability().EEPROM() {
+		return ErrNotSupported
+	}
+
+	done, total := int64(0), int64(len(b))
+	for len(b) > 0 {
+		n := 64 - int(addr)%64
+		if n > len(b) {
+			n = len(b)
+		}
+
+		// VC 0x39: EEPROM support: write to EEPROM
+		if nbw, err := d.Control(0x40, 0x39, addr, 0, b[:n]); err != nil {
+			return fmt.Errorf("(*gousb.Device).Control: EEPROM support: write to EEPROM: %w", err)
+		} else if nbw != n {
+			return fmt.Errorf("(*gousb.Device).Control: EEPROM support: write to EEPROM: %w", &CommandError{0x40, 0x39, addr, 0, n, nbw, nil})
+		}
+
+		if err := d.waitEEPROM(); err != nil {
+			return err
+		}
+
+		addr, b = addr+uint16(n), b[n:]
+		done += int64(n)
+		progress.report(done, total)
+	}
+
+	return nil
+}
+
+// waitEEPROM waits until the firmware EEPROM has finished writing.
+func (d *Device) waitEEPROM() error {
+	b := make([]byte, 4)
+	for i := 0; ; i++ {
+		// VR 0x3a: EEPROM support: get EEPROM state
+		if nbr, err := d.Control(0xc0, 0x3a, 0, 0, b); err != nil {
+			return fmt.Errorf("(*gousb.Device).Control: EEPROM support: get EEPROM state: %w", err)
+		} else if nbr != 4 {
+			return fmt.Errorf("(*gousb.Device).Control: EEPROM support: get EEPROM state: %w", &CommandError{0xc0, 0x3a, 0, 0, 4, nbr, nil})
+		} else if b[3] == 0 {
+			return nil
+		} else if i == 100 {
+			return fmt.Errorf("(*gousb.Device).Control: EEPROM support: get EEPROM state: %w: still busy after %v attempts", ErrTimeout, i+1)
+		}
+		d.clock.Sleep(10 * time.Millisecond)
+	}
+}
+
+// UploadFirmware uploads an FX2 firmware image in the Intel HEX format,
+// read from r, into the RAM of the device and starts it.  The device
+// disconnects and reconnects with the new firmware, so it must be closed
+// and opened again afterwards.  Progress is reported if progress is not
+// nil.  FX3 devices must be in boot loader mode to accept firmware, see
+// UploadFX3Firmware.
+func (d *Device) UploadFirmware(ctx context.Context, r io.Reader, progress Progress) (err error) {
+	if d.Capability().FX3Firmware() {
+		return ErrNotSupported
+	}
+
+	ctx, op := d.startSpan(ctx, "UploadFirmware")
+	defer func() { op.end(err) }()
+
+	i, err := ihx.Parse(r)
+	if err != nil {
+		return fmt.Errorf("ihx.Parse: %w", err)
+	}
+	n := 0
+	for _, x := range i.Segments {
+		n += len(x.Data)
+	}
+	op.SetAttributes(slog.Int("bytes", n))
+
+	if err := writeCypressRAM(d, firmware.FX2CPUCS, []byte{1}); err != nil {
+		return err
+	}
+	if err := uploadCypressRAM(ctx, d, i.Segments, 1024, op.progress(progress)); err != nil {
+		return err
+	}
+	return writeCypressRAM(d, firmware.FX2CPUCS, []byte{0})
+}
+
+// UploadFX3Firmware uploads an FX3 firmware image, read from r, into the
+// RAM of an FX3 in boot loader mode and starts it.  Progress is reported
+// if progress is not nil.
+func UploadFX3Firmware(ctx context.Context, dev Controller, r io.Reader, progress Progress) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("(io.Reader).Read: %w", err)
+	}
+	x, err := firmware.ParseFX3Image(b)
+	if err != nil {
+		return fmt.Errorf("ztex.UploadFX3Firmware: %w", err)
 	}
 
-	d.BoardConfig = BoardConfig{
-		BoardType(b[3]),
-		BoardVersion{
-			BoardSeries(b[4]),
-			BoardNumber(b[5]),
-			BoardVariant([2]byte{b[6], b[7]}),
-		},
+	if err := uploadCypressRAM(ctx, dev, x.Segments, 4096, progress); err != nil {
+		return err
 	}
-	d.FPGAConfig = FPGAConfig{
-		FPGAType([2]byte{b[8], b[9]}),
-		FPGAPackage(b[10]),
-		FPGAGrade([3]byte{b[11], b[12], b[13]}),
+	return writeCypressRAM(dev, x.Entry, nil)
+}
+
+// uploadCypressRAM writes segments into the RAM of an EZ-USB device
+// through the boot loader, in chunks of the given size.
+func uploadCypressRAM(ctx context.Context, dev Controller, s []ihx.Segment, chunk int, progress Progress) error {
+	t := int64(0)
+	for _, x := range s {
+		t += int64(len(x.Data))
+	}
+
+	c := int64(0)
+	for _, x := range s {
+		for a, b := x.Address, x.Data; len(b) > 0; {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			n := len(b)
+			if n > chunk {
+				n = chunk
+			}
+			if err := writeCypressRAM(dev, a, b[:n]); err != nil {
+				return err
+			}
+
+			a, b, c = a+uint32(n), b[n:], c+int64(n)
+			progress.report(c, t)
+		}
+	}
+
+	return nil
+}
+
+// writeCypressRAM writes b into the RAM of an EZ-USB device through the
+// boot loader, starting at the given address.
+func writeCypressRAM(dev Controller, addr uint32, b []byte) error {
+	// VC 0xa0: boot loader: write to RAM
+	if nbw, err := dev.Control(0x40, 0xa0, uint16(addr), uint16(addr>>16), b); err != nil {
+		// Transfers through a Device already fail with a CommandError.
+		if _, ok := err.(*CommandError); !ok {
+			err = &CommandError{0x40, 0xa0, uint16(addr), uint16(addr >> 16), len(b), nbw, err}
+		}
+		return fmt.Errorf("(*gousb.Device).Control: boot loader: write to RAM: %w", err)
+	} else if nbw != len(b) {
+		return fmt.Errorf("(*gousb.Device).Control: boot loader: write to RAM: %w", &CommandError{0x40, 0xa0, uint16(addr), uint16(addr >> 16), len(b), nbw, nil})
+	}
+
+	return nil
+}
+
+// firmwareImage reads a firmware image from r and returns it in the form
+// in which it is installed on the device: a boot image for the EEPROM of
+// FX2 devices, or the unmodified image for the flash of FX3 devices.
+func (d *Device) firmwareImage(r io.Reader) ([]byte, error) {
+	if d.Capability().FX3Firmware() {
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("(io.Reader).Read: %w", err)
+		} else if _, err := firmware.ParseFX3Image(b); err != nil {
+			return nil, fmt.Errorf("(*ztex.Device).InstallFirmware: %w", err)
+		}
+		return b, nil
+	}
+
+	i, err := ihx.Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("ihx.Parse: %w", err)
+	}
+	v, p := uint16(VendorID), uint16(ProductID)
+	if d.Desc != nil {
+		v, p = d.Desc.Vendor, d.Desc.Product
+	}
+	b, err := firmware.FX2BootImage(i, v, p)
+	if err != nil {
+		return nil, fmt.Errorf("(*ztex.Device).InstallFirmware: %w", err)
+	}
+	return b, nil
+}
+
+// InstallFirmware installs a firmware image, read from r, so that the
+// device boots it on power-up.  FX2 firmware in the Intel HEX format is
+// written to the EEPROM, and FX3 firmware images are written to the start
+// of the flash, where they must not overlap the bitstream.  Progress is
+// reported if progress is not nil.
+func (d *Device) InstallFirmware(ctx context.Context, r io.Reader, progress Progress) error {
+	b, err := d.firmwareImage(r)
+	if err != nil {
+		return err
+	}
+
+	if !d.Capability().FX3Firmware() {
+		if !d.Capability().EEPROM() {
+			return ErrNotSupported
+		}
+		t := int64(len(b))
+		for i := 0; i < len(b); i += 1024 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			n := min(len(b)-i, 1024)
+			if err := d.WriteEEPROM(uint16(i), b[i:i+n]); err != nil {
+				return err
+			}
+			progress.report(int64(i+n), t)
+		}
+		return nil
+	}
+
+	g, err := d.flashGeometry()
+	if err != nil {
+		return err
+	} else if err := d.LoadDeviceConfig(); err != nil {
+		return err
+	}
+	if s := int(d.BitstreamConfig.BitstreamStart.Bytes()); s != 0 && len(b) > s {
+		return fmt.Errorf("(*ztex.Device).InstallFirmware: got %v bytes, want at most %v bytes before the bitstream", len(b), s)
+	}
+	return d.WriteFlash(ctx, 0, g.Pad(b), progress)
+}
+
+// VerifyFirmware reads a firmware image from r and reports whether or not
+// it is installed on the device, by comparing it with the contents of the
+// EEPROM or flash.  Progress is reported if progress is not nil.
+func (d *Device) VerifyFirmware(ctx context.Context, r io.Reader, progress Progress) (bool, error) {
+	b, err := d.firmwareImage(r)
+	if err != nil {
+		return false, err
+	}
+
+	if !d.Capability().FX3Firmware() {
+		x := make([]byte, len(b))
+		t := int64(len(b))
+		for i := 0; i < len(b); i += 1024 {
+			if err := ctx.Err(); err != nil {
+				return false, err
+			}
+			n := min(len(b)-i, 1024)
+			if err := d.ReadEEPROM(uint16(i), x[i:i+n]); err != nil {
+				return false, err
+			}
+			progress.report(int64(i+n), t)
+		}
+		return bytes.Equal(b, x), nil
+	}
+
+	g, err := d.flashGeometry()
+	if err != nil {
+		return false, err
+	}
+	x := g.Pad(make([]byte, len(b)))
+	if err := d.ReadFlash(ctx, 0, x, progress); err != nil {
+		return false, err
+	}
+	return bytes.Equal(b, x[:len(b)]), nil
+}
+
+// DisableFirmware prevents the device from booting the installed firmware
+// on power-up, without erasing more than necessary: the signature byte of
+// the EEPROM is cleared on FX2 devices, and the first sector of the flash
+// is erased on FX3 devices.  The device then enumerates as an unconfigured
+// EZ-USB device, to which firmware can be uploaded.
+func (d *Device) DisableFirmware(ctx context.Context) error {
+	if !d.Capability().FX3Firmware() {
+		return d.WriteEEPROM(0, []byte{0})
+	}
+
+	g, err := d.flashGeometry()
+	if err != nil {
+		return err
+	}
+	return d.WriteFlash(ctx, 0, g.Erased(), nil)
+}
+
+// ReadMACEEPROM reads len(b) bytes from the MAC EEPROM, starting at the
+// given address.
+func (d *Device) ReadMACEEPROM(addr uint16, b []byte, opt ...CallOption) error {
+	return d.call(opt, func(callOptions) error { return d.readMACEEPROM(addr, b) })
+}
+
+func (d *Device) readMACEEPROM(addr uint16, b []byte) error {
+	if !d.Capability().MACEEPROM() {
+		return ErrNotSupported
+	}
+
+	// VR 0x3b: MAC EEPROM support: read from MAC EEPROM
+	if nbr, err := d.Control(0xc0, 0x3b, addr, 0, b); err != nil {
+		return fmt.Errorf("(*gousb.Device).Control: MAC EEPROM support: read from MAC EEPROM: %w", err)
+	} else if nbr != len(b) {
+		return fmt.Errorf("(*gousb.Device).Control: MAC EEPROM support: read from MAC EEPROM: %w", &CommandError{0xc0, 0x3b, addr, 0, len(b), nbr, nil})
+	}
+
+	return nil
+}
+
+// WriteMACEEPROM writes b to the MAC EEPROM, starting at the given
+// address.  The data is written one EEPROM page at a time, waiting for
+// each page to be committed before writing the next.
+func (d *Device) WriteMACEEPROM(addr uint16, b []byte, opt ...CallOption) error {
+	return d.call(opt, func(o callOptions) error { return d.writeMACEEPROM(addr, b, o.progress) })
+}
+
+func (d *Device) writeMACEEPROM(addr uint16, b []byte, progress Progress) error {
+	if !d.Capability().MACEEPROM() {
+		return ErrNotSupported
+	}
+
+	done, total := int64(0), int64(len(b))
+	for len(b) > 0 {
+		n := macEEPROMPageSize - int(addr)%macEEPROMPageSize
+		if n > len(b) {
+			n = len(b)
+		}
+
+		// VC 0x3c: MAC EEPROM support: write to MAC EEPROM
+		if nbw, err := d.Control(0x40, 0x3c, addr, 0, b[:n]); err != nil {
+			return fmt.Errorf("(*gousb.Device).Control: MAC EEPROM support: write to MAC EEPROM: %w", err)
+		} else if nbw != n {
+			return fmt.Errorf("(*gousb.Device).Control: MAC EEPROM support: write to MAC EEPROM: %w", &CommandError{0x40, 0x3c, addr, 0, n, nbw, nil})
+		}
+
+		if err := d.waitMACEEPROM(); err != nil {
+			return err
+		}
+
+		if int(addr) < len(d.rawConfig) {
+			copy(d.rawConfig[addr:], b[:n])
+		}
+		addr, b = addr+uint16(n), b[n:]
+		done += int64(n)
+		progress.report(done, total)
+	}
+
+	return nil
+}
+
+// waitMACEEPROM waits until the MAC EEPROM has finished writing.
+func (d *Device) waitMACEEPROM() error {
+	b := make([]byte, 4)
+	for i := 0; ; i++ {
+		// VR 0x3d: MAC EEPROM support: get MAC EEPROM state
+		if nbr, err := d.Control(0xc0, 0x3d, 0, 0, b); err != nil {
+			return fmt.Errorf("(*gousb.Device).Control: MAC EEPROM support: get MAC EEPROM state: %w", err)
+		} else if nbr != 4 {
+			return fmt.Errorf("(*gousb.Device).Control: MAC EEPROM support: get MAC EEPROM state: %w", &CommandError{0xc0, 0x3d, 0, 0, 4, nbr, nil})
+		} else if b[3] == 0 {
+			return nil
+		} else if i == 100 {
+			return fmt.Errorf("(*gousb.Device).Control: MAC EEPROM support: get MAC EEPROM state: %w: still busy after %v attempts", ErrTimeout, i+1)
+		}
+		d.clock.Sleep(10 * time.Millisecond)
+	}
+}
+
+// ReadMultiFPGAConfig reads the number of FPGAs on the device, which of
+// them is selected, and whether or not they can be configured in
+// parallel, and stores the result in the MultiFPGAConfig of the device.
+func (d *Device) ReadMultiFPGAConfig() (*MultiFPGAConfig, error) {
+	if !d.Capability().MultiFPGA() {
+		return nil, ErrNotSupported
+	}
+
+	b := make([]byte, 3)
+
+	// VR 0x50: multi-FPGA support: get multi-FPGA information
+	if nbr, err := d.Control(0xc0, 0x50, 0, 0, b); err != nil {
+		return nil, fmt.Errorf("(*gousb.Device).Control: multi-FPGA support: get multi-FPGA information: %w", err)
+	} else if nbr != 3 {
+		return nil, fmt.Errorf("(*gousb.Device).Control: multi-FPGA support: get multi-FPGA information: %w", &CommandError{0xc0, 0x50, 0, 0, 3, nbr, nil})
+	}
+
+	m := MultiFPGAConfig{
+		MultiFPGACount(b[0]),
+		MultiFPGASelected(b[1]),
+		MultiFPGAParallel(b[2]),
+	}
+	d.MultiFPGAConfig = m
+
+	return &m, nil
+}
+
+// SelectFPGA selects the FPGA with the given index on a multi-FPGA device.
+// Subsequent FPGA operations apply to the selected FPGA.  The selection is
+// recorded in the MultiFPGAConfig of the device and restored after resets
+// performed through the device.  If streams or LSI accesses through the
+// handle of another FPGA are in progress, SelectFPGA waits for them to
+// finish.
+func (d *Device) SelectFPGA(i int) error {
+	if !d.Capability().MultiFPGA() {
+		return ErrNotSupported
+	} else if i < 0 {
+		return fmt.Errorf("(*ztex.Device).SelectFPGA: got index %v, want index in [0, %v)", i, d.MultiFPGAConfig.MultiFPGACount.Number())
 	}
-	d.RAMConfig = RAMConfig{
-		RAMSize(b[14]),
-		RAMType(b[15]),
+	release, err := d.acquireFPGA(i, true)
+	if err != nil {
+		return err
 	}
-	d.BitstreamConfig = BitstreamConfig{
-		BitstreamSize([2]byte{b[26], b[27]}),
-		BitstreamCapacity([2]byte{b[28], b[29]}),
-		BitstreamStart([2]byte{b[30], b[31]}),
+	release()
+	return nil
+}
+
+// selectFPGA sends the command selecting FPGA i.  Callers other than
+// reselectFPGA go through acquireFPGA, which keeps the selection from
+// changing under transfers in progress.
+func (d *Device) selectFPGA(i int) error {
+	// VC 0x51: multi-FPGA support: select FPGA
+	if nbr, err := d.Control(0x40, 0x51, uint16(i), 0, nil); err != nil {
+		return fmt.Errorf("(*gousb.Device).Control: multi-FPGA support: select FPGA: %w", err)
+	} else if nbr != 0 {
+		return fmt.Errorf("(*gousb.Device).Control: multi-FPGA support: select FPGA: %w", &CommandError{0x40, 0x51, uint16(i), 0, 0, nbr, nil})
 	}
 
+	d.MultiFPGAConfig.MultiFPGASelected = MultiFPGASelected(i)
 	return nil
 }
 
+// reselectFPGA selects the FPGA recorded in the MultiFPGAConfig of the
+// device again, in case a reset changed the selection in the firmware.
+func (d *Device) reselectFPGA() error {
+	if !d.Capability().MultiFPGA() {
+		return nil
+	}
+	return d.selectFPGA(int(d.MultiFPGAConfig.MultiFPGASelected))
+}
+
 // ResetFX3 resets the Cypress CYUSB3033 EZ-USB FX3S controller on the
 // device, if one is present.
 func (d *Device) ResetFX3() error {
-	if !d.DescriptorCapability.FX3Firmware() {
-		return fmt.Errorf("operation not supported")
+	if !d.Capability().FX3Firmware() {
+		return ErrNotSupported
 	}
 
 	// VC 0xa1: FX3 support: reset FX3 controller
 	if nbr, err := d.Control(0x40, 0xa1, 1, 0, nil); err != nil {
-		return fmt.Errorf("(*gousb.Device).Control: FX3 firmware: reset and boot from flash: %v", err)
+		return fmt.Errorf("(*gousb.Device).Control: FX3 firmware: reset and boot from flash: %w", err)
 	} else if nbr != 0 {
-		return fmt.Errorf("(*gousb.Device).Control: FX3 firmware: reset and boot from flash: got %v bytes, want %v bytes", nbr, 0)
+		return fmt.Errorf("(*gousb.Device).Control: FX3 firmware: reset and boot from flash: %w", &CommandError{0x40, 0xa1, 1, 0, 0, nbr, nil})
 	}
 
 	return nil
 }
 
+// StatusBufferSize is the size of the buffers passed to FPGAStatusInto,
+// FlashStatusInto, SensorStatusInto, and XMEGAStatusInto, which is enough
+// for the largest status response.
+const StatusBufferSize = 64
+
+// checkStatusBuffer returns an error if a buffer is too small for the
+// status responses.
+func checkStatusBuffer(b []byte) error {
+	if len(b) < StatusBufferSize {
+		return fmt.Errorf("got %v bytes of buffer, want at least %v bytes", len(b), StatusBufferSize)
+	}
+	return nil
+}
+
 // FPGAStatus retrieves the current FPGA status.
-func (d *Device) FPGAStatus() (*FPGAStatus, error) {
-	if !d.DescriptorCapability.FPGAConfiguration() {
-		return nil, fmt.Errorf("operation not supported")
+func (d *Device) FPGAStatus(opt ...CallOption) (*FPGAStatus, error) {
+	s := &FPGAStatus{}
+	if err := d.FPGAStatusInto(s, make([]byte, StatusBufferSize), opt...); err != nil {
+		return nil, err
 	}
+	return s, nil
+}
+
+// FPGAStatusInto retrieves the current FPGA status into s, using buf for
+// the transfer, so that polling the status does not allocate.
+func (d *Device) FPGAStatusInto(s *FPGAStatus, buf []byte, opt ...CallOption) error {
+	if err := checkStatusBuffer(buf); err != nil {
+		return fmt.Errorf("(*ztex.Device).FPGAStatusInto: %w", err)
+	}
+	return d.call(opt, func(callOptions) error { return d.fpgaStatus(s, buf[:9]) })
+}
 
-	b := make([]byte, 9)
+func (d *Device) fpgaStatus(s *FPGAStatus, b []byte) error {
+	if !d.Capability().FPGAConfiguration() {
+		return ErrNotSupported
+	}
 
 	// VR 0x30: FPGA configuration: get FPGA state
 	if nbr, err := d.Control(0xc0, 0x30, 0, 0, b); err != nil {
-		return nil, fmt.Errorf("(*gousb.Device).Control: FPGA configuration: get FPGA state: %v", err)
+		return fmt.Errorf("(*gousb.Device).Control: FPGA configuration: get FPGA state: %w", err)
 	} else if nbr != 9 {
-		return nil, fmt.Errorf("(*gousb.Device).Control: FPGA configuration: get FPGA state: got %v bytes, want %v bytes", nbr, 9)
+		return fmt.Errorf("(*gousb.Device).Control: FPGA configuration: get FPGA state: %w", &CommandError{0xc0, 0x30, 0, 0, 9, nbr, nil})
 	}
 
-	return &FPGAStatus{
+	*s = FPGAStatus{
 		FPGAConfigured(b[0]),
 		FPGAChecksum(b[1]),
 		FPGATransferred([4]uint8{b[2], b[3], b[4], b[5]}),
 		FPGAInit(b[6]),
 		FPGAResult(b[7]),
 		FPGASwapped(b[8]),
-	}, nil
+	}
+	return nil
+}
+
+// AllFPGAStatus retrieves the current status of every FPGA on the device,
+// indexed like SelectFPGA.  On a multi-FPGA device, each FPGA is selected
+// in turn, and the previous selection is restored afterwards.
+func (d *Device) AllFPGAStatus() (_ []*FPGAStatus, err error) {
+	if !d.Capability().MultiFPGA() {
+		s, err := d.FPGAStatus()
+		if err != nil {
+			return nil, err
+		}
+		return []*FPGAStatus{s}, nil
+	}
+
+	defer func(i int) {
+		if e := d.SelectFPGA(i); err == nil && e != nil {
+			err = e
+		}
+	}(int(d.MultiFPGAConfig.MultiFPGASelected))
+
+	x := []*FPGAStatus{}
+	for i := 0; i < d.MultiFPGAConfig.MultiFPGACount.Number(); i++ {
+		if err := d.SelectFPGA(i); err != nil {
+			return nil, err
+		}
+		s, err := d.FPGAStatus()
+		if err != nil {
+			return nil, err
+		}
+		x = append(x, s)
+	}
+
+	return x, nil
 }
 
 // ResetFPGA resets the FPGA on the device.
 func (d *Device) ResetFPGA() error {
-	if !d.DescriptorCapability.FPGAConfiguration() {
-		return fmt.Errorf("operation not supported")
+	if !d.Capability().FPGAConfiguration() {
+		return ErrNotSupported
 	}
 
 	// VC 0x31: FPGA configuration: reset FPGA
 	if nbr, err := d.Control(0x40, 0x31, 0, 0, nil); err != nil {
-		return fmt.Errorf("(*gousb.Device).Control: FPGA configuration: reset FPGA: %v", err)
+		return fmt.Errorf("(*gousb.Device).Control: FPGA configuration: reset FPGA: %w", err)
 	} else if nbr != 0 {
-		return fmt.Errorf("(*gousb.Device).Control: FPGA configuration: reset FPGA: got %v bytes, want %v bytes", nbr, 0)
+		return fmt.Errorf("(*gousb.Device).Control: FPGA configuration: reset FPGA: %w", &CommandError{0x40, 0x31, 0, 0, 0, nbr, nil})
 	}
 
-	return nil
+	return d.reselectFPGA()
 }
 
-// FlashStatus retrieves the current flash memory status.
-func (d *Device) FlashStatus() (*FlashStatus, error) {
-	if !d.DescriptorCapability.FlashMemory() {
-		return nil, fmt.Errorf("operation not supported")
+// ConfigureFPGA configures the selected FPGA with the bitstream read from
+// r.  The FPGA is reset, the bitstream is transferred through the control
+// endpoint, and the FPGA status is checked afterwards.  Progress of the
+// transfer is reported if progress is not nil.
+func (d *Device) ConfigureFPGA(ctx context.Context, r io.Reader, progress Progress) (err error) {
+	ctx, op := d.startSpan(ctx, "ConfigureFPGA")
+	defer func() { op.end(err) }()
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("(io.Reader).Read: %w", err)
 	}
+	op.SetAttributes(slog.Int("bytes", len(b)))
 
-	b := make([]byte, 8)
+	return d.configureFPGA(ctx, b, op.progress(progress))
+}
+
+func (d *Device) configureFPGA(ctx context.Context, b []byte, progress Progress) error {
+	if !d.Capability().FPGAConfiguration() {
+		return ErrNotSupported
+	}
+
+	b, err := bitstream.Swap(b)
+	if err != nil {
+		return fmt.Errorf("(*ztex.Device).ConfigureFPGA: %w", err)
+	}
+
+	if err := d.ResetFPGA(); err != nil {
+		return err
+	}
+
+	t := int64(len(b))
+	for len(b) > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		n := len(b)
+		if n > 2048 {
+			n = 2048
+		}
+
+		// VC 0x32: FPGA configuration: send FPGA configuration data
+		if nbw, err := d.Control(0x40, 0x32, 0, 0, b[:n]); err != nil {
+			return fmt.Errorf("(*gousb.Device).Control: FPGA configuration: send FPGA configuration data: %w", err)
+		} else if nbw != n {
+			return fmt.Errorf("(*gousb.Device).Control: FPGA configuration: send FPGA configuration data: %w", &CommandError{0x40, 0x32, 0, 0, n, nbw, nil})
+		}
+
+		b = b[n:]
+		progress.report(t-int64(len(b)), t)
+	}
+
+	s, err := d.FPGAStatus()
+	if err != nil {
+		return err
+	} else if !s.FPGAConfigured.Bool() {
+		return fmt.Errorf("(*ztex.Device).ConfigureFPGA: got status %v, want configured FPGA", s)
+	}
+
+	return nil
+}
+
+// ConfigureFPGAHighSpeed configures the selected FPGA with the bitstream
+// read from r, like ConfigureFPGA, but transfers the bitstream through the
+// bulk endpoint announced by the firmware, which is considerably faster
+// than the control endpoint.  Unless set with BulkTransfers, the transfer
+// size is chosen by probing several sizes with the start of the bitstream.
+func (d *Device) ConfigureFPGAHighSpeed(ctx context.Context, r io.Reader, progress Progress) (err error) {
+	ctx, op := d.startSpan(ctx, "ConfigureFPGAHighSpeed")
+	defer func() { op.end(err) }()
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("(io.Reader).Read: %w", err)
+	}
+	op.SetAttributes(slog.Int("bytes", len(b)))
+
+	return d.configureFPGAHighSpeed(ctx, b, op.progress(progress))
+}
+
+func (d *Device) configureFPGAHighSpeed(ctx context.Context, b []byte, progress Progress) error {
+	if !d.Capability().HighSpeedFPGAConfiguration() || d.usb == nil {
+		return ErrNotSupported
+	}
+
+	b, err := bitstream.Swap(b)
+	if err != nil {
+		return fmt.Errorf("(*ztex.Device).ConfigureFPGAHighSpeed: %w", err)
+	}
+
+	e := make([]byte, 2)
+
+	// VR 0x33: high-speed FPGA configuration: get endpoint and interface
+	if nbr, err := d.Control(0xc0, 0x33, 0, 0, e); err != nil {
+		return fmt.Errorf("(*gousb.Device).Control: high-speed FPGA configuration: get endpoint and interface: %w", err)
+	} else if nbr != 2 {
+		return fmt.Errorf("(*gousb.Device).Control: high-speed FPGA configuration: get endpoint and interface: %w", &CommandError{0xc0, 0x33, 0, 0, 2, nbr, nil})
+	}
+
+	hs := FPGAHighSpeedConfig{}
+	if err := hs.UnmarshalBinary(e); err != nil {
+		return fmt.Errorf("(*gousb.Device).Control: high-speed FPGA configuration: get endpoint and interface: %w", err)
+	}
+
+	intf, err := d.usb.Interface(int(hs.FPGAHighSpeedInterface))
+	if err != nil {
+		return fmt.Errorf("(ztex.USBDevice).Interface: %w", err)
+	}
+	defer intf.Close()
+	out, err := intf.OutEndpoint(int(hs.FPGAHighSpeedEndpoint))
+	if err != nil {
+		return fmt.Errorf("(ztex.USBInterface).OutEndpoint: %w", err)
+	}
+
+	if err := d.ResetFPGA(); err != nil {
+		return err
+	}
+
+	// VC 0x34: high-speed FPGA configuration: start
+	if nbr, err := d.Control(0x40, 0x34, 0, 0, nil); err != nil {
+		return fmt.Errorf("(*gousb.Device).Control: high-speed FPGA configuration: start: %w", err)
+	} else if nbr != 0 {
+		return fmt.Errorf("(*gousb.Device).Control: high-speed FPGA configuration: start: %w", &CommandError{0x40, 0x34, 0, 0, 0, nbr, nil})
+	}
+
+	if _, err := d.writeBulkTuned(ctx, out, b, progress); err != nil {
+		return err
+	}
+
+	// VC 0x35: high-speed FPGA configuration: finish
+	if nbr, err := d.Control(0x40, 0x35, 0, 0, nil); err != nil {
+		return fmt.Errorf("(*gousb.Device).Control: high-speed FPGA configuration: finish: %w", err)
+	} else if nbr != 0 {
+		return fmt.Errorf("(*gousb.Device).Control: high-speed FPGA configuration: finish: %w", &CommandError{0x40, 0x35, 0, 0, 0, nbr, nil})
+	}
+
+	s, err := d.FPGAStatus()
+	if err != nil {
+		return err
+	} else if !s.FPGAConfigured.Bool() {
+		return fmt.Errorf("(*ztex.Device).ConfigureFPGAHighSpeed: got status %v, want configured FPGA", s)
+	}
+
+	return nil
+}
+
+// ConfigureFPGAs configures several FPGAs of a multi-FPGA device, where
+// bitstreams maps the index of each FPGA to configure to its bitstream.
+// The same bitstream may be used for several FPGAs.  A failure to
+// configure one FPGA does not prevent the others from being configured;
+// the failures are returned as FPGAErrors.  If the context is done before
+// all FPGAs are configured, then its error is returned joined with the
+// FPGAErrors of the FPGAs which failed before.  Progress is reported per
+// FPGA if progress is not nil, and the previous selection is restored
+// afterwards.
+func (d *Device) ConfigureFPGAs(ctx context.Context, bitstreams map[int][]byte, progress func(i int, done, total int64)) (err error) {
+	if !d.Capability().MultiFPGA() {
+		return ErrNotSupported
+	}
+
+	i, n := []int{}, 0
+	for k := range bitstreams {
+		if k < 0 || k >= d.MultiFPGAConfig.MultiFPGACount.Number() {
+			return fmt.Errorf("(*ztex.Device).ConfigureFPGAs: got index %v, want index in [0, %v)", k, d.MultiFPGAConfig.MultiFPGACount.Number())
+		}
+		i, n = append(i, k), n+len(bitstreams[k])
+	}
+	sort.Ints(i)
+
+	ctx, op := d.startSpan(ctx, "ConfigureFPGAs", slog.Int("fpgas", len(i)), slog.Int("bytes", n))
+	defer func() { op.end(err) }()
+
+	defer func(k int) {
+		if e := d.SelectFPGA(k); err == nil && e != nil {
+			err = e
+		}
+	}(int(d.MultiFPGAConfig.MultiFPGASelected))
+
+	f := FPGAErrors{}
+	for _, k := range i {
+		if err := ctx.Err(); err != nil && len(f) != 0 {
+			return errors.Join(err, f)
+		} else if err != nil {
+			return err
+		}
+
+		var p Progress
+		if progress != nil {
+			p = func(done, total int64) { progress(k, done, total) }
+		}
+
+		if release, err := d.acquireFPGA(k, true); err != nil {
+			f[k] = err
+		} else if err := d.configureFPGA(ctx, bitstreams[k], p); err != nil {
+			release()
+			f[k] = err
+		} else {
+			release()
+			op.done += int64(len(bitstreams[k]))
+		}
+	}
+
+	if len(f) != 0 {
+		return f
+	}
+
+	return nil
+}
+
+// ResetAllFPGAs resets every FPGA on the device.  If the device supports
+// parallel configuration, all FPGAs are selected at once and reset with a
+// single command; otherwise each FPGA is selected and reset in turn.  The
+// previous selection is restored afterwards.
+func (d *Device) ResetAllFPGAs() (err error) {
+	if !d.Capability().MultiFPGA() {
+		return d.ResetFPGA()
+	}
+
+	defer func(i int) {
+		if e := d.SelectFPGA(i); err == nil && e != nil {
+			err = e
+		}
+	}(int(d.MultiFPGAConfig.MultiFPGASelected))
+
+	if d.MultiFPGAConfig.MultiFPGAParallel.Bool() {
+		// VC 0x51: multi-FPGA support: select all FPGAs
+		if nbr, err := d.Control(0x40, 0x51, 0, 1, nil); err != nil {
+			return fmt.Errorf("(*gousb.Device).Control: multi-FPGA support: select all FPGAs: %w", err)
+		} else if nbr != 0 {
+			return fmt.Errorf("(*gousb.Device).Control: multi-FPGA support: select all FPGAs: %w", &CommandError{0x40, 0x51, 0, 1, 0, nbr, nil})
+		}
+		return d.ResetFPGA()
+	}
+
+	for i := 0; i < d.MultiFPGAConfig.MultiFPGACount.Number(); i++ {
+		if err := d.SelectFPGA(i); err != nil {
+			return err
+		} else if err := d.ResetFPGA(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FlashStatus retrieves the current flash memory status.
+func (d *Device) FlashStatus(opt ...CallOption) (*FlashStatus, error) {
+	s := &FlashStatus{}
+	if err := d.FlashStatusInto(s, make([]byte, StatusBufferSize), opt...); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// FlashStatusInto retrieves the current flash memory status into s, using
+// buf for the transfer, so that polling the status does not allocate.
+func (d *Device) FlashStatusInto(s *FlashStatus, buf []byte, opt ...CallOption) error {
+	if err := checkStatusBuffer(buf); err != nil {
+		return fmt.Errorf("(*ztex.Device).FlashStatusInto: %w", err)
+	}
+	return d.call(opt, func(callOptions) error { return d.flashStatus(s, buf[:8]) })
+}
+
+func (d *Device) flashStatus(s *FlashStatus, b []byte) error {
+	if !d.Capability().FlashMemory() {
+		return ErrNotSupported
+	}
 
 	// VR 0x40: flash memory support: get flash state
 	if nbr, err := d.Control(0xc0, 0x40, 0, 0, b); err != nil {
-		return nil, fmt.Errorf("(*gousb.Device).Control: flash memory support: get flash state: %v", err)
+		return fmt.Errorf("(*gousb.Device).Control: flash memory support: get flash state: %w", err)
 	} else if nbr != 8 {
-		return nil, fmt.Errorf("(*gousb.Device).Control: flash memory support: get flash state: got %v bytes, want %v bytes", nbr, 8)
+		return fmt.Errorf("(*gousb.Device).Control: flash memory support: get flash state: %w", &CommandError{0xc0, 0x40, 0, 0, 8, nbr, nil})
 	}
 
-	return &FlashStatus{
+	*s = FlashStatus{
 		FlashEnabled(b[0]),
 		FlashSector([2]uint8{b[1], b[2]}),
 		FlashCount([4]uint8{b[3], b[4], b[5], b[6]}),
 		FlashError(b[7]),
-	}, nil
+	}
+	return nil
+}
+
+// flashGeometry returns the geometry of the flash, and an error if the
+// flash is not enabled.  The geometry is read once and cached.
+func (d *Device) flashGeometry() (flash.Geometry, error) {
+	if d.geometry.SectorSize != 0 {
+		return d.geometry, nil
+	}
+
+	s, err := d.FlashStatus()
+	if err != nil {
+		return flash.Geometry{}, err
+	} else if s.FlashEnabled != 1 {
+		return flash.Geometry{}, fmt.Errorf("(*ztex.Device).FlashStatus: got status %v, want enabled flash", s)
+	}
+	d.geometry = flash.Geometry{SectorSize: int(s.FlashSector.Number()), Sectors: s.FlashCount.Number()}
+	return d.geometry, nil
+}
+
+// ReadFlash reads whole sectors of the flash, starting at the given
+// sector, into b, whose length must be a multiple of the sector size.
+// Progress is reported if progress is not nil.
+func (d *Device) ReadFlash(ctx context.Context, sector uint32, b []byte, progress Progress) error {
+	g, err := d.flashGeometry()
+	if err != nil {
+		return err
+	} else if err := g.Check(sector, len(b)); err != nil {
+		return fmt.Errorf("(*ztex.Device).ReadFlash: %w", err)
+	}
+	return d.readFlash(ctx, g, sector, b, progress)
+}
+
+// ReadFlashInto reads count sectors of the flash, starting at the given
+// sector, and writes them to w.  The sectors are read into buf, which must
+// hold at least one sector, so that large ranges can be read without
+// allocating memory for all of them.  If buf holds at least two sectors,
+// then it is split in halves, and the sectors read into one half are
+// written to w while the next sectors are read into the other, so that
+// slow writers and the round trips of the control transfers overlap.
+// Progress is reported if progress is not nil.
+func (d *Device) ReadFlashInto(ctx context.Context, w io.Writer, sector, count uint32, buf []byte, progress Progress) error {
+	g, err := d.flashGeometry()
+	if err != nil {
+		return err
+	}
+	z := g.SectorSize
+	if err := g.Check(sector, int(count)*z); err != nil {
+		return fmt.Errorf("(*ztex.Device).ReadFlashInto: %w", err)
+	} else if len(buf) < z {
+		return fmt.Errorf("(*ztex.Device).ReadFlashInto: got %v bytes of buffer, want at least %v bytes", len(buf), z)
+	}
+
+	x := [][]byte{buf[:len(buf)/z*z]}
+	if k := len(buf) / z / 2 * z; k > 0 {
+		x = [][]byte{buf[:k], buf[k : 2*k]}
+	}
+
+	// errc holds the result of the write in progress, if any.
+	var errc chan error
+	wait := func() error {
+		if errc == nil {
+			return nil
+		}
+		err := <-errc
+		errc = nil
+		return err
+	}
+	defer wait()
+
+	t := int64(count) * int64(z)
+	for i, j := uint32(0), 0; i < count; j = (j + 1) % len(x) {
+		n := min(count-i, uint32(len(x[j])/z))
+		b := x[j][:int(n)*z]
+		if len(x) == 1 {
+			// The only buffer must be written before it is reused.
+			if err := wait(); err != nil {
+				return err
+			}
+		}
+		if err := d.readFlash(ctx, g, sector+i, b, progress.offset(int64(i)*int64(z), t)); err != nil {
+			return err
+		}
+		if err := wait(); err != nil {
+			return err
+		}
+		errc = make(chan error, 1)
+		go func(b []byte, errc chan<- error) {
+			if _, err := w.Write(b); err != nil {
+				errc <- fmt.Errorf("(io.Writer).Write: %w", err)
+			}
+			close(errc)
+		}(b, errc)
+		i += n
+	}
+
+	return wait()
+}
+
+// DumpFlash reads the whole flash and writes it to w, overlapping the
+// reads from the flash with the writes to w, as described for
+// ReadFlashInto.  Progress is reported if progress is not nil.
+func (d *Device) DumpFlash(ctx context.Context, w io.Writer, progress Progress) error {
+	g, err := d.flashGeometry()
+	if err != nil {
+		return err
+	}
+	return d.ReadFlashInto(ctx, w, 0, g.Sectors, make([]byte, 2*max(g.SectorSize, 1<<20)), progress)
+}
+
+// readFlash reads whole sectors of a flash with geometry g, which have
+// been checked to exist, into b.
+func (d *Device) readFlash(ctx context.Context, g flash.Geometry, sector uint32, b []byte, progress Progress) error {
+	z := g.SectorSize
+
+	t := int64(len(b))
+	for i := 0; i < len(b); i, sector = i+z, sector+1 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// VR 0x41: flash memory support: read sector
+		if nbr, err := d.Control(0xc0, 0x41, uint16(sector), uint16(sector>>16), b[i:i+z]); err != nil {
+			return fmt.Errorf("(*gousb.Device).Control: flash memory support: read sector: %w", err)
+		} else if nbr != z {
+			return fmt.Errorf("(*gousb.Device).Control: flash memory support: read sector: %w", &CommandError{0xc0, 0x41, uint16(sector), uint16(sector >> 16), z, nbr, nil})
+		}
+
+		progress.report(int64(i+z), t)
+	}
+
+	return nil
+}
+
+// WriteFlash writes whole sectors of the flash, starting at the given
+// sector, from b, whose length must be a multiple of the sector size.
+// Progress is reported if progress is not nil.
+func (d *Device) WriteFlash(ctx context.Context, sector uint32, b []byte, progress Progress) (err error) {
+	ctx, op := d.startSpan(ctx, "WriteFlash", slog.Int("bytes", len(b)), slog.Int64("sector", int64(sector)))
+	defer func() { op.end(err) }()
+	progress = op.progress(progress)
+
+	g, err := d.flashGeometry()
+	if err != nil {
+		return err
+	} else if err := g.Check(sector, len(b)); err != nil {
+		return fmt.Errorf("(*ztex.Device).WriteFlash: %w", err)
+	}
+	z := g.SectorSize
+
+	defer d.longOperation()()
+
+	t := int64(len(b))
+	for i := 0; i < len(b); i, sector = i+z, sector+1 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// VC 0x42: flash memory support: write sector
+		if nbw, err := d.Control(0x40, 0x42, uint16(sector), uint16(sector>>16), b[i:i+z]); err != nil {
+			return fmt.Errorf("(*gousb.Device).Control: flash memory support: write sector: %w", err)
+		} else if nbw != z {
+			return fmt.Errorf("(*gousb.Device).Control: flash memory support: write sector: %w", &CommandError{0x40, 0x42, uint16(sector), uint16(sector >> 16), z, nbw, nil})
+		}
+
+		progress.report(int64(i+z), t)
+	}
+
+	return nil
+}
+
+// InstallBitstream writes the bitstream read from r to the flash, so that
+// the firmware configures the FPGA with it on power-up.  The bitstream is
+// stored at the start and within the capacity given by the configuration
+// data area, whose size field is updated afterwards.  Progress is
+// reported if progress is not nil.
+func (d *Device) InstallBitstream(ctx context.Context, r io.Reader, progress Progress) (err error) {
+	ctx, op := d.startSpan(ctx, "InstallBitstream")
+	defer func() { op.end(err) }()
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("(io.Reader).Read: %w", err)
+	}
+	op.SetAttributes(slog.Int("bytes", len(b)))
+	b, err = bitstream.SwapInto(b, b)
+	if err != nil {
+		return fmt.Errorf("(*ztex.Device).InstallBitstream: %w", err)
+	}
+
+	g, err := d.flashGeometry()
+	if err != nil {
+		return err
+	} else if err := d.LoadDeviceConfig(); err != nil {
+		return err
+	}
+
+	s := int(d.BitstreamConfig.BitstreamStart.Bytes())
+	n := bitstream.Sectors(len(b))
+	if n > int(d.BitstreamConfig.BitstreamCapacity.Number()) {
+		return fmt.Errorf("(*ztex.Device).InstallBitstream: got %v bytes, want at most %v bytes", len(b), d.BitstreamConfig.BitstreamCapacity.Bytes())
+	} else if s%g.SectorSize != 0 {
+		return fmt.Errorf("(*ztex.Device).InstallBitstream: got start %v, want start aligned to sectors of %v bytes", s, g.SectorSize)
+	}
+
+	if err := d.WriteFlash(ctx, uint32(s/g.SectorSize), g.Pad(b), op.progress(progress)); err != nil {
+		return err
+	}
+
+	return d.SetBitstreamConfig(BitstreamSize{uint8(n), uint8(n >> 8)}, d.BitstreamConfig.BitstreamStart)
+}
+
+// SetBitstreamConfig updates the size and start of the bitstream in the
+// configuration data area, keeping the capacity.  The bitstream must fit
+// within the capacity, and the area reserved for it must be aligned to
+// sectors of the flash and lie within the flash.
+func (d *Device) SetBitstreamConfig(size BitstreamSize, start BitstreamStart) error {
+	g, err := d.flashGeometry()
+	if err != nil {
+		return err
+	} else if err := d.LoadDeviceConfig(); err != nil {
+		return err
+	}
+
+	c := d.BitstreamConfig.BitstreamCapacity
+	if size.Number() > c.Number() {
+		return fmt.Errorf("(*ztex.Device).SetBitstreamConfig: got size %v, want at most the capacity %v", size, c)
+	} else if start.Bytes()%uint64(g.SectorSize) != 0 {
+		return fmt.Errorf("(*ztex.Device).SetBitstreamConfig: got start %v, want start aligned to sectors of %v bytes", start, g.SectorSize)
+	} else if n := uint64(g.SectorSize) * uint64(g.Sectors); start.Bytes()+c.Bytes() > n {
+		return fmt.Errorf("(*ztex.Device).SetBitstreamConfig: got start %v and capacity %v, want them within the flash of %v bytes", start, c, n)
+	}
+
+	if err := d.WriteMACEEPROM(26, []byte{size[0], size[1], c[0], c[1], start[0], start[1]}); err != nil {
+		return err
+	}
+	d.BitstreamConfig.BitstreamSize = size
+	d.BitstreamConfig.BitstreamStart = start
+
+	return nil
+}
+
+// SensorStatus retrieves the current readings of the temperature sensors
+// and, with newer firmware, the supply voltage and current sensors.
+func (d *Device) SensorStatus(opt ...CallOption) (*SensorStatus, error) {
+	s := &SensorStatus{SensorReadings: SensorReadings{}}
+	if err := d.SensorStatusInto(s, make([]byte, StatusBufferSize), opt...); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SensorStatusInto retrieves the current sensor readings into s, reusing
+// the storage of its readings, and using buf for the transfer, so that
+// polling the sensors does not allocate.
+func (d *Device) SensorStatusInto(s *SensorStatus, buf []byte, opt ...CallOption) error {
+	if err := checkStatusBuffer(buf); err != nil {
+		return fmt.Errorf("(*ztex.Device).SensorStatusInto: %w", err)
+	}
+	return d.call(opt, func(callOptions) error { return d.sensorStatus(s, buf[:64]) })
+}
+
+func (d *Device) sensorStatus(s *SensorStatus, b []byte) error {
+	if !d.Capability().TemperatureSensor() {
+		return ErrNotSupported
+	}
+
+	// VR 0x58: temperature sensor support: read sensors
+	nbr, err := d.Control(0xc0, 0x58, 0, 0, b)
+	if err != nil {
+		return fmt.Errorf("(*gousb.Device).Control: temperature sensor support: read sensors: %w", err)
+	}
+
+	if err := decodeSensorStatusInto(s, b[:nbr]); err != nil {
+		return fmt.Errorf("(*gousb.Device).Control: temperature sensor support: read sensors: %w", err)
+	}
+
+	for i, r := range s.SensorReadings {
+		if c, ok := d.calibrations[r.SensorChannel]; ok {
+			s.SensorReadings[i].SensorValue = c.apply(r.SensorValue)
+		}
+	}
+
+	return nil
+}
+
+// SensorCalibrations returns the corrections currently applied to the
+// sensor readings of the device.
+func (d *Device) SensorCalibrations() SensorCalibrations { return d.calibrations }
+
+// SetSensorCalibrations sets the corrections applied to the sensor
+// readings of the device.  The corrections are not persisted; see
+// SaveSensorCalibrations.
+func (d *Device) SetSensorCalibrations(c SensorCalibrations) { d.calibrations = c }
+
+// LoadSensorCalibrations loads the sensor corrections stored in the user
+// area of the MAC EEPROM, as cached by the device, and applies them to
+// subsequent readings.
+func (d *Device) LoadSensorCalibrations() error {
+	if !d.Capability().MACEEPROM() {
+		return ErrNotSupported
+	} else if err := d.LoadDeviceConfig(); err != nil {
+		return err
+	}
+
+	c, err := decodeSensorCalibrations(d.rawConfig[macEEPROMUserStart:])
+	if err != nil {
+		return fmt.Errorf("(*ztex.Device).LoadSensorCalibrations: %w", err)
+	}
+
+	d.calibrations = c
+	return nil
+}
+
+// SaveSensorCalibrations stores the sensor corrections currently applied
+// to the device in the user area of the MAC EEPROM, so that they can be
+// restored with LoadSensorCalibrations.
+func (d *Device) SaveSensorCalibrations() error {
+	b, err := encodeSensorCalibrations(d.calibrations)
+	if err != nil {
+		return fmt.Errorf("(*ztex.Device).SaveSensorCalibrations: %w", err)
+	}
+
+	return d.WriteMACEEPROM(macEEPROMUserStart, b)
+}
+
+// DebugMessages retrieves the messages currently held by the debug stack
+// of the firmware.  The advanced debug helper is used if the firmware
+// supports it, and the basic debug helper otherwise.
+func (d *Device) DebugMessages() (*DebugStack, error) {
+	switch {
+	case d.Capability().DebugHelper2():
+		return d.readDebugStack2(nil)
+	case d.Capability().DebugHelper():
+		return d.readDebugStack()
+	default:
+		return nil, ErrNotSupported
+	}
+}
+
+// DebugMessagesSince retrieves the messages held by the debug stack of the
+// firmware whose sequence is s or later.  With the advanced debug helper,
+// only those messages are transferred.
+func (d *Device) DebugMessagesSince(s DebugSequence) (*DebugStack, error) {
+	switch {
+	case d.Capability().DebugHelper2():
+		return d.readDebugStack2(&s)
+	case d.Capability().DebugHelper():
+		x, err := d.readDebugStack()
+		if err != nil {
+			return nil, err
+		}
+		x.DebugMessages = x.DebugMessages.Since(s)
+		return x, nil
+	default:
+		return nil, ErrNotSupported
+	}
+}
+
+// ResetDebug clears the debug stack of the firmware and resets its message
+// counter, so that subsequent reads only return messages written after
+// the reset.
+func (d *Device) ResetDebug() error {
+	switch {
+	case d.Capability().DebugHelper2():
+		// VC 0x2b: advanced debug helper: reset debug messages
+		if nbr, err := d.Control(0x40, 0x2b, 0, 0, nil); err != nil {
+			return fmt.Errorf("(*gousb.Device).Control: advanced debug helper: reset debug messages: %w", err)
+		} else if nbr != 0 {
+			return fmt.Errorf("(*gousb.Device).Control: advanced debug helper: reset debug messages: %w", &CommandError{0x40, 0x2b, 0, 0, 0, nbr, nil})
+		}
+	case d.Capability().DebugHelper():
+		// VC 0x29: debug helper: reset debug stack
+		if nbr, err := d.Control(0x40, 0x29, 0, 0, nil); err != nil {
+			return fmt.Errorf("(*gousb.Device).Control: debug helper: reset debug stack: %w", err)
+		} else if nbr != 0 {
+			return fmt.Errorf("(*gousb.Device).Control: debug helper: reset debug stack: %w", &CommandError{0x40, 0x29, 0, 0, 0, nbr, nil})
+		}
+	default:
+		return ErrNotSupported
+	}
+
+	return nil
+}
+
+// TailDebug polls the debug stack of the firmware every 100 ms and
+// delivers each message exactly once, starting with the messages already
+// held by the stack, until the context is done.
+func (d *Device) TailDebug(ctx context.Context) (*DebugTail, error) {
+	s, err := d.DebugMessages()
+	if err != nil {
+		return nil, err
+	}
+
+	c := make(chan DebugMessage)
+	t := &DebugTail{C: c}
+	go func() {
+		defer close(c)
+		for {
+			for _, m := range s.DebugMessages {
+				select {
+				case c <- m:
+				case <-ctx.Done():
+					t.err = ctx.Err()
+					return
+				}
+			}
+
+			q := DebugSequence(s.DebugCounter.Number())
+			select {
+			case <-d.clock.After(100 * time.Millisecond):
+			case <-ctx.Done():
+				t.err = ctx.Err()
+				return
+			}
+
+			if s, t.err = d.DebugMessagesSince(q); t.err != nil {
+				return
+			}
+
+			// The debug stack was reset, so start over from its beginning.
+			if DebugSequence(s.DebugCounter.Number()).Before(q) {
+				if s, t.err = d.DebugMessages(); t.err != nil {
+					return
+				}
+				q = s.Oldest()
+			}
+			t.lost.Add(uint64(s.Lost(q)))
+		}
+	}()
+
+	return t, nil
+}
+
+// WriteDebug tails the debug stack of the firmware and writes each message
+// to w on a line of its own, prefixed with the time at which it was read
+// and the serial number of the device, until the context is done.
+func (d *Device) WriteDebug(ctx context.Context, w io.Writer) error {
+	t, err := d.TailDebug(ctx)
+	if err != nil {
+		return err
+	}
+
+	for m := range t.C {
+		if _, err := fmt.Fprintf(w, "%v %v [%v] %v\n", d.clock.Now().Format(time.RFC3339Nano), d.Serial(), m.DebugSequence, m.DebugPayload.Text()); err != nil {
+			return fmt.Errorf("(io.Writer).Write: %w", err)
+		}
+	}
+
+	return t.Err()
+}
+
+// LogDebug tails the debug stack of the firmware and logs each message to
+// l at debug level, with the serial number of the device and the sequence
+// of the message as attributes, until the context is done.
+func (d *Device) LogDebug(ctx context.Context, l *slog.Logger) error {
+	t, err := d.TailDebug(ctx)
+	if err != nil {
+		return err
+	}
+
+	for m := range t.C {
+		l.LogAttrs(ctx, slog.LevelDebug, m.DebugPayload.Text(),
+			slog.String("serial", d.Serial().String()),
+			slog.Int("sequence", int(m.DebugSequence)))
+	}
+
+	return t.Err()
+}
+
+func (d *Device) readDebugStack() (*DebugStack, error) {
+	b := make([]byte, 4)
+
+	// VR 0x28: debug helper: read debug stack
+	if nbr, err := d.Control(0xc0, 0x28, 0, 0, b); err != nil {
+		return nil, fmt.Errorf("(*gousb.Device).Control: debug helper: read debug stack: %w", err)
+	} else if nbr != 4 {
+		return nil, fmt.Errorf("(*gousb.Device).Control: debug helper: read debug stack: %w", &CommandError{0xc0, 0x28, 0, 0, 4, nbr, nil})
+	}
+
+	b = make([]byte, 4+int(b[2])*int(b[3]))
+
+	// VR 0x28: debug helper: read debug stack
+	nbr, err := d.Control(0xc0, 0x28, 0, 0, b)
+	if err != nil {
+		return nil, fmt.Errorf("(*gousb.Device).Control: debug helper: read debug stack: %w", err)
+	}
+
+	s, err := decodeDebugStack(b[:nbr])
+	if err != nil {
+		return nil, fmt.Errorf("(*gousb.Device).Control: debug helper: read debug stack: %w", err)
+	}
+
+	return s, nil
+}
+
+// readDebugStack2 reads the messages with sequence *s or later, or all
+// messages if s is nil, from the advanced debug helper.  The messages are
+// transferred in chunks of up to 4 kiB, each continuing where the previous
+// one ended, until the newest message has been read.
+func (d *Device) readDebugStack2(s *DebugSequence) (*DebugStack, error) {
+	b := make([]byte, 8)
+
+	// VR 0x2a: advanced debug helper: read debug messages
+	if nbr, err := d.Control(0xc0, 0x2a, 0, 0, b); err != nil {
+		return nil, fmt.Errorf("(*gousb.Device).Control: advanced debug helper: read debug messages: %w", err)
+	} else if nbr < 8 {
+		return nil, fmt.Errorf("(*gousb.Device).Control: advanced debug helper: read debug messages: %w", &CommandError{0xc0, 0x2a, 0, 0, 8, nbr, nil})
+	}
+
+	x, err := decodeDebugStack2(b[:8])
+	if err != nil {
+		return nil, fmt.Errorf("(*gousb.Device).Control: advanced debug helper: read debug messages: %w", err)
+	}
+
+	q := x.Oldest()
+	if s != nil && q.Before(*s) {
+		q = *s
+	}
+
+	k := 8 + int(x.DebugMessageSize)
+	if k < 4096 {
+		k = 4096 - (4096-8)%int(x.DebugMessageSize)
+	}
+	b = make([]byte, k)
+
+	for q.Before(DebugSequence(x.DebugCounter.Number())) {
+		// VR 0x2a: advanced debug helper: read debug messages
+		nbr, err := d.Control(0xc0, 0x2a, q.Number(), 0, b)
+		if err != nil {
+			return nil, fmt.Errorf("(*gousb.Device).Control: advanced debug helper: read debug messages: %w", err)
+		}
+
+		y, err := decodeDebugStack2(b[:nbr])
+		if err != nil {
+			return nil, fmt.Errorf("(*gousb.Device).Control: advanced debug helper: read debug messages: %w", err)
+		} else if len(y.DebugMessages) == 0 {
+			break
+		}
+
+		x.DebugCounter = y.DebugCounter
+		x.DebugMessages = append(x.DebugMessages, y.DebugMessages.Since(q)...)
+		q = y.DebugMessages[len(y.DebugMessages)-1].DebugSequence + 1
+	}
+
+	return x, nil
+}
+
+// XMEGAStatus retrieves the current status of the XMEGA.
+func (d *Device) XMEGAStatus(opt ...CallOption) (*XMEGAStatus, error) {
+	s := &XMEGAStatus{}
+	if err := d.XMEGAStatusInto(s, make([]byte, StatusBufferSize), opt...); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// XMEGAStatusInto retrieves the current status of the XMEGA into s, using
+// buf for the transfer, so that polling the status does not allocate.
+func (d *Device) XMEGAStatusInto(s *XMEGAStatus, buf []byte, opt ...CallOption) error {
+	if err := checkStatusBuffer(buf); err != nil {
+		return fmt.Errorf("(*ztex.Device).XMEGAStatusInto: %w", err)
+	}
+	return d.call(opt, func(callOptions) error { return d.xmegaStatus(s, buf[:9]) })
+}
+
+func (d *Device) xmegaStatus(s *XMEGAStatus, b []byte) error {
+	if !d.Capability().XMEGA() {
+		return ErrNotSupported
+	}
+
+	// VR 0x48: XMEGA support: get XMEGA state
+	if nbr, err := d.Control(0xc0, 0x48, 0, 0, b); err != nil {
+		return fmt.Errorf("(*gousb.Device).Control: XMEGA support: get XMEGA state: %w", err)
+	} else if nbr != 9 {
+		return fmt.Errorf("(*gousb.Device).Control: XMEGA support: get XMEGA state: %w", &CommandError{0xc0, 0x48, 0, 0, 9, nbr, nil})
+	}
+
+	*s = XMEGAStatus{
+		XMEGAError(b[0]),
+		XMEGABusy(b[1]),
+		XMEGASignature([3]uint8{b[2], b[3], b[4]}),
+		XMEGAFlashPage([2]uint8{b[5], b[6]}),
+		XMEGAEEPROMPage([2]uint8{b[7], b[8]}),
+	}
+	return nil
+}
+
+// ResetXMEGA resets the XMEGA on the device.
+func (d *Device) ResetXMEGA() error {
+	if !d.Capability().XMEGA() {
+		return ErrNotSupported
+	}
+
+	// VC 0x49: XMEGA support: reset XMEGA
+	if nbr, err := d.Control(0x40, 0x49, 0, 0, nil); err != nil {
+		return fmt.Errorf("(*gousb.Device).Control: XMEGA support: reset XMEGA: %w", err)
+	} else if nbr != 0 {
+		return fmt.Errorf("(*gousb.Device).Control: XMEGA support: reset XMEGA: %w", &CommandError{0x40, 0x49, 0, 0, 0, nbr, nil})
+	}
+
+	return nil
+}
+
+// XMEGAPart identifies the XMEGA on the device from its signature, with
+// the flash and EEPROM page sizes reported by the firmware.
+func (d *Device) XMEGAPart() (*XMEGAPart, error) {
+	s, err := d.XMEGAStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	p, ok := s.XMEGASignature.Part()
+	if !ok {
+		return nil, fmt.Errorf("(*ztex.Device).XMEGAPart: got signature %v, want signature of a supported XMEGA", s.XMEGASignature)
+	}
+
+	p.FlashPage, p.EEPROMPage = s.XMEGAFlashPage.Number(), s.XMEGAEEPROMPage.Number()
+	if p.FlashPage == 0 || p.EEPROMPage == 0 {
+		return nil, fmt.Errorf("(*ztex.Device).XMEGAPart: got flash page size %v and EEPROM page size %v, want nonzero page sizes", s.XMEGAFlashPage, s.XMEGAEEPROMPage)
+	}
+
+	return &p, nil
+}
+
+// ReadXMEGAFlash reads len(b) bytes from the flash memory of the XMEGA,
+// starting at the given byte address.  Progress is reported after every
+// flash page if progress is not nil.
+func (d *Device) ReadXMEGAFlash(ctx context.Context, addr uint32, b []byte, progress Progress) error {
+	p, err := d.XMEGAPart()
+	if err != nil {
+		return err
+	} else if uint64(addr)+uint64(len(b)) > uint64(p.Flash()) {
+		return fmt.Errorf("(*ztex.Device).ReadXMEGAFlash: got range [%v, %v), want range within [0, %v)", addr, uint64(addr)+uint64(len(b)), p.Flash())
+	}
+
+	t := int64(len(b))
+	for len(b) > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		n := len(b)
+		if n > int(p.FlashPage) {
+			n = int(p.FlashPage)
+		}
+
+		// VR 0x4a: XMEGA support: read from XMEGA flash
+		if nbr, err := d.Control(0xc0, 0x4a, uint16(addr), uint16(addr>>16), b[:n]); err != nil {
+			return fmt.Errorf("(*gousb.Device).Control: XMEGA support: read from XMEGA flash: %w", err)
+		} else if nbr != n {
+			return fmt.Errorf("(*gousb.Device).Control: XMEGA support: read from XMEGA flash: %w", &CommandError{0xc0, 0x4a, uint16(addr), uint16(addr >> 16), n, nbr, nil})
+		}
+
+		addr, b = addr+uint32(n), b[n:]
+		progress.report(t-int64(len(b)), t)
+	}
+
+	return nil
+}
+
+// ReadXMEGAEEPROM reads len(b) bytes from the EEPROM of the XMEGA,
+// starting at the given byte address.  Progress is reported after every
+// EEPROM page if progress is not nil.
+func (d *Device) ReadXMEGAEEPROM(ctx context.Context, addr uint32, b []byte, progress Progress) error {
+	p, err := d.XMEGAPart()
+	if err != nil {
+		return err
+	} else if uint64(addr)+uint64(len(b)) > uint64(p.EEPROM) {
+		return fmt.Errorf("(*ztex.Device).ReadXMEGAEEPROM: got range [%v, %v), want range within [0, %v)", addr, uint64(addr)+uint64(len(b)), p.EEPROM)
+	}
+
+	t := int64(len(b))
+	for len(b) > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		n := len(b)
+		if n > int(p.EEPROMPage) {
+			n = int(p.EEPROMPage)
+		}
+
+		// VR 0x4c: XMEGA support: read from XMEGA EEPROM
+		if nbr, err := d.Control(0xc0, 0x4c, uint16(addr), 0, b[:n]); err != nil {
+			return fmt.Errorf("(*gousb.Device).Control: XMEGA support: read from XMEGA EEPROM: %w", err)
+		} else if nbr != n {
+			return fmt.Errorf("(*gousb.Device).Control: XMEGA support: read from XMEGA EEPROM: %w", &CommandError{0xc0, 0x4c, uint16(addr), 0, n, nbr, nil})
+		}
+
+		addr, b = addr+uint32(n), b[n:]
+		progress.report(t-int64(len(b)), t)
+	}
+
+	return nil
+}
+
+// XMEGAEEPROM returns the EEPROM of the XMEGA as an io.ReaderAt and
+// io.WriterAt.
+func (d *Device) XMEGAEEPROM() (*XMEGAEEPROM, error) {
+	p, err := d.XMEGAPart()
+	if err != nil {
+		return nil, err
+	}
+
+	return &XMEGAEEPROM{d, int64(p.EEPROM)}, nil
+}
+
+// WriteXMEGAFlash programs b into the flash memory of the XMEGA, starting
+// at the given byte address.  The data is programmed one flash page at a
+// time; the unchanged contents of partially written pages are read back
+// and programmed along with the new data.  The target range must have been
+// erased beforehand.  Progress is reported after every flash page if
+// progress is not nil.
+func (d *Device) WriteXMEGAFlash(ctx context.Context, addr uint32, b []byte, progress Progress) error {
+	p, err := d.XMEGAPart()
+	if err != nil {
+		return err
+	} else if uint64(addr)+uint64(len(b)) > uint64(p.Flash()) {
+		return fmt.Errorf("(*ztex.Device).WriteXMEGAFlash: got range [%v, %v), want range within [0, %v)", addr, uint64(addr)+uint64(len(b)), p.Flash())
+	}
+
+	k, t := uint32(p.FlashPage), int64(len(b))
+	for len(b) > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		s, o := addr-addr%k, int(addr%k)
+		n := int(k) - o
+		if n > len(b) {
+			n = len(b)
+		}
+
+		x := b[:n]
+		if n != int(k) {
+			x = make([]byte, k)
+			if err := d.ReadXMEGAFlash(ctx, s, x, nil); err != nil {
+				return err
+			}
+			copy(x[o:], b[:n])
+		}
+
+		// VC 0x4b: XMEGA support: write XMEGA flash page
+		if nbw, err := d.Control(0x40, 0x4b, uint16(s), uint16(s>>16), x); err != nil {
+			return fmt.Errorf("(*gousb.Device).Control: XMEGA support: write XMEGA flash page: %w", err)
+		} else if nbw != int(k) {
+			return fmt.Errorf("(*gousb.Device).Control: XMEGA support: write XMEGA flash page: %w", &CommandError{0x40, 0x4b, uint16(s), uint16(s >> 16), int(k), nbw, nil})
+		}
+
+		if err := d.waitXMEGA(ctx); err != nil {
+			return err
+		}
+
+		addr, b = addr+uint32(n), b[n:]
+		progress.report(t-int64(len(b)), t)
+	}
+
+	return nil
+}
+
+// WriteXMEGAEEPROM writes b to the EEPROM of the XMEGA, starting at the
+// given byte address.  The data is written one EEPROM page at a time, and
+// progress is reported after every page if progress is not nil.
+func (d *Device) WriteXMEGAEEPROM(ctx context.Context, addr uint32, b []byte, progress Progress) error {
+	p, err := d.XMEGAPart()
+	if err != nil {
+		return err
+	} else if uint64(addr)+uint64(len(b)) > uint64(p.EEPROM) {
+		return fmt.Errorf("(*ztex.Device).WriteXMEGAEEPROM: got range [%v, %v), want range within [0, %v)", addr, uint64(addr)+uint64(len(b)), p.EEPROM)
+	}
+
+	k, t := uint32(p.EEPROMPage), int64(len(b))
+	for len(b) > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		n := int(k - addr%k)
+		if n > len(b) {
+			n = len(b)
+		}
+
+		// VC 0x4d: XMEGA support: write XMEGA EEPROM page
+		if nbw, err := d.Control(0x40, 0x4d, uint16(addr), 0, b[:n]); err != nil {
+			return fmt.Errorf("(*gousb.Device).Control: XMEGA support: write XMEGA EEPROM page: %w", err)
+		} else if nbw != n {
+			return fmt.Errorf("(*gousb.Device).Control: XMEGA support: write XMEGA EEPROM page: %w", &CommandError{0x40, 0x4d, uint16(addr), 0, n, nbw, nil})
+		}
+
+		if err := d.waitXMEGA(ctx); err != nil {
+			return err
+		}
+
+		addr, b = addr+uint32(n), b[n:]
+		progress.report(t-int64(len(b)), t)
+	}
+
+	return nil
+}
+
+// EraseXMEGAApplication erases the application section of the flash
+// memory of the XMEGA.
+func (d *Device) EraseXMEGAApplication(ctx context.Context) error {
+	if !d.Capability().XMEGA() {
+		return ErrNotSupported
+	}
+
+	restore := d.longOperation()
+	// VC 0x47: XMEGA support: erase XMEGA application section
+	nbr, err := d.Control(0x40, 0x47, 0, 0, nil)
+	restore()
+	if err != nil {
+		return fmt.Errorf("(*gousb.Device).Control: XMEGA support: erase XMEGA application section: %w", err)
+	} else if nbr != 0 {
+		return fmt.Errorf("(*gousb.Device).Control: XMEGA support: erase XMEGA application section: %w", &CommandError{0x40, 0x47, 0, 0, 0, nbr, nil})
+	}
+
+	return d.waitXMEGA(ctx)
+}
+
+// ProgramXMEGA reads an application firmware image in the Intel HEX format
+// from r, erases the application section of the XMEGA flash memory,
+// programs the image, and verifies it by reading it back.  Progress of
+// programming and verification is reported if progress is not nil.
+func (d *Device) ProgramXMEGA(ctx context.Context, r io.Reader, progress Progress) error {
+	i, err := ihx.Parse(r)
+	if err != nil {
+		return err
+	}
+
+	p, err := d.XMEGAPart()
+	if err != nil {
+		return err
+	} else if i.End() > p.Application {
+		return fmt.Errorf("(*ztex.Device).ProgramXMEGA: got image ending at %v, want image within application section [0, %v)", i.End(), p.Application)
+	}
+
+	if err := d.EraseXMEGAApplication(ctx); err != nil {
+		return err
+	}
+
+	n, t := int64(0), int64(0)
+	for _, s := range i.Segments {
+		t += 2 * int64(len(s.Data))
+	}
+	for _, s := range i.Segments {
+		if err := d.WriteXMEGAFlash(ctx, s.Address, s.Data, progress.offset(n, t)); err != nil {
+			return err
+		}
+		n += int64(len(s.Data))
+	}
+
+	v, err := d.verifyXMEGA(ctx, i, p.Signature, progress.offset(n, t))
+	if err != nil {
+		return err
+	} else if !v.Bool() {
+		return &XMEGAVerificationError{v}
+	}
+
+	return nil
+}
+
+// VerifyXMEGA reads a firmware image in the Intel HEX format from r and
+// compares it with the flash memory of the XMEGA.  It also checks that the
+// signature of the XMEGA matches the signature of the part for which the
+// image was built.  Differences are reported in the result rather than as
+// an error.  Progress is reported if progress is not nil.
+func (d *Device) VerifyXMEGA(ctx context.Context, r io.Reader, want XMEGASignature, progress Progress) (*XMEGAVerification, error) {
+	i, err := ihx.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.verifyXMEGA(ctx, i, want, progress)
+}
+
+func (d *Device) verifyXMEGA(ctx context.Context, i *ihx.Image, want XMEGASignature, progress Progress) (*XMEGAVerification, error) {
+	s, err := d.XMEGAStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	v := &XMEGAVerification{Signature: s.XMEGASignature, WantSignature: want}
+	if v.Signature != v.WantSignature {
+		return v, nil
+	}
+
+	n, t := int64(0), int64(0)
+	for _, s := range i.Segments {
+		t += int64(len(s.Data))
+	}
+	for _, s := range i.Segments {
+		b := make([]byte, len(s.Data))
+		if err := d.ReadXMEGAFlash(ctx, s.Address, b, progress.offset(n, t)); err != nil {
+			return nil, err
+		}
+		v.compare(s.Address, b, s.Data)
+		n += int64(len(s.Data))
+	}
+
+	return v, nil
+}
+
+// waitXMEGA waits until the XMEGA has finished programming its
+// non-volatile memory and checks that programming succeeded.
+func (d *Device) waitXMEGA(ctx context.Context) error {
+	for i := 0; ; i++ {
+		s, err := d.XMEGAStatus()
+		if err != nil {
+			return err
+		} else if s.XMEGAError != 0 {
+			return fmt.Errorf("(*ztex.Device).XMEGAStatus: got error %v, want error %v", s.XMEGAError, XMEGAError(0))
+		} else if !s.XMEGABusy.Bool() {
+			return nil
+		} else if i == 1000 {
+			return fmt.Errorf("(*ztex.Device).XMEGAStatus: %w: still busy after %v attempts", ErrTimeout, i+1)
+		}
+
+		select {
+		case <-d.clock.After(time.Millisecond):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// ReadXMEGAFuses reads the fuse bytes of the XMEGA.  The result holds
+// fuse bytes 0 to 5 at the corresponding indices, followed by a reserved
+// byte and the lock bits.
+func (d *Device) ReadXMEGAFuses() ([]byte, error) {
+	if _, err := d.XMEGAPart(); err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, 8)
+
+	// VR 0x4e: XMEGA support: read XMEGA fuses
+	if nbr, err := d.Control(0xc0, 0x4e, 0, 0, b); err != nil {
+		return nil, fmt.Errorf("(*gousb.Device).Control: XMEGA support: read XMEGA fuses: %w", err)
+	} else if nbr != 8 {
+		return nil, fmt.Errorf("(*gousb.Device).Control: XMEGA support: read XMEGA fuses: %w", &CommandError{0xc0, 0x4e, 0, 0, 8, nbr, nil})
+	}
+
+	return b, nil
+}
+
+// XMEGAFuses retrieves the fuse bytes and lock bits of the XMEGA.
+func (d *Device) XMEGAFuses() (*XMEGAFuses, error) {
+	b, err := d.ReadXMEGAFuses()
+	if err != nil {
+		return nil, err
+	}
+
+	x := XMEGAFuses{}
+	copy(x[:], b)
+	return &x, nil
+}
+
+// WriteXMEGAFuses programs those of fuse bytes 0 to 5 which differ from
+// their current values.  The lock bits cannot be changed this way; a
+// change to them is rejected, see WriteXMEGALockBits.
+func (d *Device) WriteXMEGAFuses(ctx context.Context, x XMEGAFuses) error {
+	y, err := d.XMEGAFuses()
+	if err != nil {
+		return err
+	} else if x[7] != y[7] {
+		return fmt.Errorf("(*ztex.Device).WriteXMEGAFuses: got lock bits %#02x, want unchanged lock bits %#02x", x[7], y[7])
+	}
+
+	for i := 0; i < 6; i++ {
+		if x[i] == y[i] {
+			continue
+		}
+		if err := d.writeXMEGAFuse(ctx, uint16(i), x[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteXMEGALockBits programs the lock bits of the XMEGA.  Since lock bits
+// can only be cleared again by a chip erase, which destroys the firmware,
+// the call must be confirmed explicitly by passing true for confirm.
+func (d *Device) WriteXMEGALockBits(ctx context.Context, x XMEGALockBits, confirm bool) error {
+	if !confirm {
+		return fmt.Errorf("(*ztex.Device).WriteXMEGALockBits: got no confirmation, want confirmation")
+	} else if _, err := d.XMEGAPart(); err != nil {
+		return err
+	}
+
+	return d.writeXMEGAFuse(ctx, 7, uint8(x))
+}
+
+func (d *Device) writeXMEGAFuse(ctx context.Context, i uint16, v uint8) error {
+	// VC 0x4f: XMEGA support: write XMEGA fuse
+	if nbr, err := d.Control(0x40, 0x4f, uint16(v), i, nil); err != nil {
+		return fmt.Errorf("(*gousb.Device).Control: XMEGA support: write XMEGA fuse: %w", err)
+	} else if nbr != 0 {
+		return fmt.Errorf("(*gousb.Device).Control: XMEGA support: write XMEGA fuse: %w", &CommandError{0x40, 0x4f, uint16(v), i, 0, nbr, nil})
+	}
+
+	return d.waitXMEGA(ctx)
+}
+
+// LSIRead reads len(v) consecutive registers of the selected FPGA through
+// the low-speed interface of the default firmware, starting at the given
+// address.
+func (d *Device) LSIRead(addr uint8, v []uint32, opt ...CallOption) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.call(opt, func(callOptions) error { return d.lsiRead(addr, v) })
+}
+
+func (d *Device) lsiRead(addr uint8, v []uint32) error {
+	if err := d.requireDefault(2, "LSI read"); err != nil {
+		return err
+	} else if int(addr)+len(v) > 256 {
+		return fmt.Errorf("(*ztex.Device).LSIRead: got registers [%v, %v), want registers within [0, %v)", addr, int(addr)+len(v), 256)
+	}
+
+	b := make([]byte, 4*len(v))
+
+	// VR 0x63: default firmware interface: LSI read
+	if nbr, err := d.Control(0xc0, 0x63, uint16(addr), 0, b); err != nil {
+		return fmt.Errorf("(*gousb.Device).Control: default firmware interface: LSI read: %w", err)
+	} else if nbr != len(b) {
+		return fmt.Errorf("(*gousb.Device).Control: default firmware interface: LSI read: %w", &CommandError{0xc0, 0x63, uint16(addr), 0, len(b), nbr, nil})
+	}
+
+	for i := range v {
+		v[i] = bytesToUint32([4]uint8{b[4*i], b[4*i+1], b[4*i+2], b[4*i+3]})
+	}
+
+	return nil
+}
+
+// LSIWrite writes v to consecutive registers of the selected FPGA through
+// the low-speed interface of the default firmware, starting at the given
+// address.
+func (d *Device) LSIWrite(addr uint8, v []uint32, opt ...CallOption) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.call(opt, func(callOptions) error { return d.lsiWrite(addr, v) })
+}
+
+func (d *Device) lsiWrite(addr uint8, v []uint32) error {
+	if err := d.requireDefault(2, "LSI write"); err != nil {
+		return err
+	} else if int(addr)+len(v) > 256 {
+		return fmt.Errorf("(*ztex.Device).LSIWrite: got registers [%v, %v), want registers within [0, %v)", addr, int(addr)+len(v), 256)
+	}
+
+	b := make([]byte, 0, 5*len(v))
+	for i, x := range v {
+		b = append(b, uint8(x>>0), uint8(x>>8), uint8(x>>16), uint8(x>>24), addr+uint8(i))
+	}
+
+	// VC 0x62: default firmware interface: LSI write
+	if nbw, err := d.Control(0x40, 0x62, 0, 0, b); err != nil {
+		return fmt.Errorf("(*gousb.Device).Control: default firmware interface: LSI write: %w", err)
+	} else if nbw != len(b) {
+		return fmt.Errorf("(*gousb.Device).Control: default firmware interface: LSI write: %w", &CommandError{0x40, 0x62, 0, 0, len(b), nbw, nil})
+	}
+
+	return nil
+}
+
+// GPIO sets the bits of the general purpose I/O pins of the default
+// firmware interface selected by mask to the corresponding bits of value,
+// and returns the state of all pins afterwards.  A zero mask reads the
+// pins without changing them.
+func (d *Device) GPIO(mask, value uint8) (uint8, error) {
+	if err := d.requireDefault(1, "GPIO"); err != nil {
+		return 0, err
+	}
+
+	b := make([]byte, 1)
+
+	// VR 0x61: default firmware interface: set and get GPIO
+	if nbr, err := d.Control(0xc0, 0x61, uint16(mask), uint16(value), b); err != nil {
+		return 0, fmt.Errorf("(*gousb.Device).Control: default firmware interface: set and get GPIO: %w", err)
+	} else if nbr != 1 {
+		return 0, fmt.Errorf("(*gousb.Device).Control: default firmware interface: set and get GPIO: %w", &CommandError{0xc0, 0x61, uint16(mask), uint16(value), 1, nbr, nil})
+	}
+
+	return b[0], nil
+}
+
+// OpenStream opens a stream to the selected FPGA through the bulk
+// endpoints of the default firmware interface.  The stream claims the
+// default USB interface until it is closed.
+func (d *Device) OpenStream() (*Stream, error) {
+	return d.openStream(-1)
+}
+
+// openStream opens a stream to FPGA i, or to the selected FPGA if i is
+// negative.
+func (d *Device) openStream(i int) (*Stream, error) {
+	if d.usb == nil {
+		return nil, ErrNotSupported
+	} else if err := d.requireDefault(1, "streams"); err != nil {
+		return nil, err
+	}
+
+	intf, err := d.usb.Interface(0)
+	if err != nil {
+		return nil, fmt.Errorf("(ztex.USBDevice).Interface: %w", err)
+	}
+
+	// The endpoints announced by the descriptor take precedence over those
+	// of the board catalog, which firmware may not follow.
+	b, _ := LookupBoard(d.DescriptorConfig.DescriptorProduct)
+	m := d.DescriptorConfig.Module().(DefaultModule)
+	o, n := m.OutEndpoint, m.InEndpoint
+	if o == 0 || n == 0 {
+		o, n = b.Stream.OutEndpoint, b.Stream.InEndpoint
+	}
+	out, err := intf.OutEndpoint(int(o))
+	if err != nil {
+		intf.Close()
+		return nil, fmt.Errorf("(ztex.USBInterface).OutEndpoint: %w", err)
+	}
+	in, err := intf.InEndpoint(int(n))
+	if err != nil {
+		intf.Close()
+		return nil, fmt.Errorf("(ztex.USBInterface).InEndpoint: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Stream{d: d, fpga: i, fifo: b.Stream.FIFOSize, intf: intf, in: in, out: out, ctx: ctx, cancel: cancel}, nil
+}
+
+// FPGA returns a handle which directs LSI accesses and streams to the FPGA
+// with the given index on a multi-FPGA device.  Selecting FPGAs with
+// SelectFPGA while handles are in use is not safe.
+func (d *Device) FPGA(i int) (*FPGAHandle, error) {
+	if !d.Capability().MultiFPGA() {
+		return nil, ErrNotSupported
+	} else if i < 0 || i >= d.MultiFPGAConfig.MultiFPGACount.Number() {
+		return nil, fmt.Errorf("(*ztex.Device).FPGA: got index %v, want index in [0, %v)", i, d.MultiFPGAConfig.MultiFPGACount.Number())
+	}
+
+	return &FPGAHandle{d, i}, nil
 }
 
 // ResetDefaultFirmware resets the default firmware, if it is present.
 func (d *Device) ResetDefaultFirmware() error {
-	if !d.DescriptorCapability.DefaultFirmware() {
-		return fmt.Errorf("operation not supported")
+	if err := d.requireDefault(1, "reset"); err != nil {
+		return err
 	}
 
 	// VC 0x60: default firmware interface: reset
 	if nbr, err := d.Control(0x40, 0x60, 0, 0, nil); err != nil {
-		return fmt.Errorf("(*gousb.Device).Control: default firmware interface: reset: %v", err)
+		return fmt.Errorf("(*gousb.Device).Control: default firmware interface: reset: %w", err)
 	} else if nbr != 0 {
-		return fmt.Errorf("(*gousb.Device).Control: default firmware interface: reset: got %v bytes, want %v bytes", nbr, 0)
+		return fmt.Errorf("(*gousb.Device).Control: default firmware interface: reset: %w", &CommandError{0x40, 0x60, 0, 0, 0, nbr, nil})
 	}
 
-	return nil
+	return d.reselectFPGA()
 }
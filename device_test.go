@@ -0,0 +1,80 @@
+package ztex_test
+
+import (
+	"testing"
+
+	"github.com/aljumi/ztex"
+	"github.com/aljumi/ztex/ztextest"
+)
+
+// openSensors opens a fake device with sensors reporting the given data.
+func openSensors(t *testing.T, b []byte, opt ...ztex.DeviceOption) (*ztex.Device, *ztextest.FakeDevice) {
+	t.Helper()
+	f := ztextest.NewFakeDevice("fake000001")
+	f.Descriptor[13] |= 0x01
+	f.Sensors = b
+	d, err := ztextest.Open(f, opt...)
+	if err != nil {
+		t.Fatalf("ztextest.Open: %v", err)
+	}
+	t.Cleanup(func() { d.Close() })
+	return d, f
+}
+
+func TestSensorStatus(t *testing.T) {
+	// A temperature of 42.5 °C, 3.3 V, and 0.5 A.
+	d, _ := openSensors(t, []byte{2, 1, 0x80, 0x2a, 2, 0xe4, 0x0c, 3, 0xf4, 0x01})
+
+	s, err := d.SensorStatus()
+	if err != nil {
+		t.Fatalf("(*ztex.Device).SensorStatus: %v", err)
+	}
+	if got, want := s.String(), "Protocol(Temperature, Voltage, and Current), Readings(Reading(Channel(0), Kind(Temperature), Value(42.50°C)), Reading(Channel(1), Kind(Voltage), Value(3.30V)), Reading(Channel(2), Kind(Current), Value(0.50A)))"; got != want {
+		t.Errorf("(*ztex.Device).SensorStatus: got %v, want %v", got, want)
+	}
+
+	d, _ = openSensors(t, []byte{2, 1, 0x80})
+	if s, err := d.SensorStatus(); err == nil {
+		t.Errorf("(*ztex.Device).SensorStatus with truncated data: got %v, want error", s)
+	}
+
+	d, _ = openSensors(t, nil)
+	if s, err := d.SensorStatus(); err == nil {
+		t.Errorf("(*ztex.Device).SensorStatus without sensors: got %v, want error", s)
+	}
+}
+
+func TestSensorCalibrations(t *testing.T) {
+	c := ztex.SensorCalibrations{0: {Offset: -2}, 1: {Scale: 2}}
+	d, f := openSensors(t, []byte{1, 42, 20}, ztex.CalibrateSensors(c))
+
+	s, err := d.SensorStatus()
+	if err != nil {
+		t.Fatalf("(*ztex.Device).SensorStatus: %v", err)
+	} else if s.SensorReadings[0].SensorValue != 40 || s.SensorReadings[1].SensorValue != 40 {
+		t.Errorf("(*ztex.Device).SensorStatus: got %v, want corrected readings of 40 °C", s.SensorReadings)
+	}
+
+	// The corrections survive reopening the device through the MAC
+	// EEPROM.
+	if err := d.SaveSensorCalibrations(); err != nil {
+		t.Fatalf("(*ztex.Device).SaveSensorCalibrations: %v", err)
+	}
+	e, err := ztextest.Open(f)
+	if err != nil {
+		t.Fatalf("ztextest.Open: %v", err)
+	}
+	defer e.Close()
+	want := ztex.SensorCalibrations{0: {Offset: -2, Scale: 1}, 1: {Scale: 2}}
+	if err := e.LoadSensorCalibrations(); err != nil {
+		t.Fatalf("(*ztex.Device).LoadSensorCalibrations: %v", err)
+	} else if got := e.SensorCalibrations(); got.String() != want.String() {
+		t.Errorf("(*ztex.Device).LoadSensorCalibrations: got %v, want %v", got, want)
+	}
+
+	// A MAC EEPROM without corrections is rejected.
+	d, _ = openSensors(t, []byte{1, 42})
+	if err := d.LoadSensorCalibrations(); err == nil {
+		t.Errorf("(*ztex.Device).LoadSensorCalibrations without corrections: got no error, want error")
+	}
+}
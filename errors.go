@@ -0,0 +1,69 @@
+package ztex
+
+import (
+	"errors"
+	"fmt"
+)
+
+// These errors are returned, possibly wrapped, by the functions and
+// methods of the package, so that callers can tell failures apart with
+// errors.Is.
+var (
+	// ErrNotSupported indicates that the firmware of the device does not
+	// support an operation, according to the capabilities announced in
+	// its ZTEX descriptor, or that the device was not opened in a way
+	// which supports it.
+	ErrNotSupported = errors.New("operation not supported")
+
+	// ErrBadDescriptor indicates that a ZTEX descriptor is malformed.
+	ErrBadDescriptor = errors.New("bad ZTEX descriptor")
+
+	// ErrBadConfig indicates that the configuration data area of the MAC
+	// EEPROM is malformed or inconsistent.
+	ErrBadConfig = errors.New("bad configuration data")
+
+	// ErrNoDevice indicates that no matching device is present.
+	ErrNoDevice = errors.New("no device")
+
+	// ErrTimeout indicates that the device did not finish an operation in
+	// time.
+	ErrTimeout = errors.New("timeout")
+)
+
+// CommandError describes a failed vendor request or command: the setup
+// packet of the control transfer, the number of bytes which should have
+// been and were transferred, and the underlying error of the transfer, if
+// any.  It is returned, possibly wrapped, by the methods of Device.  The
+// underlying error is not flattened into a string, so that conditions of
+// the backend, such as gousb.ErrorPipe or gousb.ErrorNoDevice, can be
+// detected with errors.Is and errors.As.
+type CommandError struct {
+	RequestType uint8
+	Request     uint8
+	Value       uint16
+	Index       uint16
+	Want        int
+	Got         int
+	Err         error
+}
+
+// In returns true if and only if the request transfers data from the
+// device to the host, which is the case for vendor requests, as opposed to
+// vendor commands.
+func (e *CommandError) In() bool { return e.RequestType&0x80 != 0 }
+
+// Error returns a description of the failed request.
+func (e *CommandError) Error() string {
+	k := "VC"
+	if e.In() {
+		k = "VR"
+	}
+	s := fmt.Sprintf("%v %#02x (value %#04x, index %#04x)", k, e.Request, e.Value, e.Index)
+	if e.Err != nil {
+		return fmt.Sprintf("%v: %v", s, e.Err)
+	}
+	return fmt.Sprintf("%v: got %v bytes, want %v bytes", s, e.Got, e.Want)
+}
+
+// Unwrap returns the underlying error of the transfer.
+func (e *CommandError) Unwrap() error { return e.Err }
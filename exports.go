@@ -0,0 +1,68 @@
+package ztex
+
+import (
+	"io"
+
+	"github.com/aljumi/ztex/bitstream"
+	"github.com/aljumi/ztex/firmware"
+	"github.com/aljumi/ztex/flash"
+	"github.com/aljumi/ztex/ihx"
+)
+
+// The types and functions of the subpackages which programs operating
+// modules commonly need are re-exported here, so that such programs need
+// only import this package.
+
+// BitstreamSectorSize is the size of the sectors in which bitstreams are
+// stored in flash.
+const BitstreamSectorSize = bitstream.SectorSize
+
+// BitstreamHeader holds the fields of the header of a .bit file.
+type BitstreamHeader = bitstream.Header
+
+// BitstreamBuild describes a bitstream written by Vivado or ISE.
+type BitstreamBuild = bitstream.Build
+
+// FX3Image represents a firmware image for the EZ-USB FX3.
+type FX3Image = firmware.FX3Image
+
+// FlashGeometry describes the sectors of a flash memory.
+type FlashGeometry = flash.Geometry
+
+// IHXImage is a firmware image read from an Intel HEX file.
+type IHXImage = ihx.Image
+
+// IHXSegment is a contiguous block of data of an IHXImage.
+type IHXSegment = ihx.Segment
+
+// SwapBitstream prepares a bitstream for transfer to the firmware, as
+// bitstream.Swap does.
+func SwapBitstream(b []byte) ([]byte, error) { return bitstream.Swap(b) }
+
+// ParseBitstreamHeader parses the header at the start of a .bit file, as
+// bitstream.ParseHeader does.
+func ParseBitstreamHeader(b []byte) (*BitstreamHeader, error) { return bitstream.ParseHeader(b) }
+
+// FindBitstream returns the most recent bitstream under a directory or
+// project file, as bitstream.Find does.
+func FindBitstream(path string) (*BitstreamBuild, error) { return bitstream.Find(path) }
+
+// NormalizePart returns the device and package of an FPGA part in the
+// form used by .bit headers, as bitstream.NormalizePart does.
+func NormalizePart(part string) string { return bitstream.NormalizePart(part) }
+
+// FX2BootImage encodes a firmware image in the format expected by the
+// EZ-USB FX2 boot loader in EEPROM, as firmware.FX2BootImage does.
+func FX2BootImage(i *IHXImage, vid, pid uint16) ([]byte, error) {
+	return firmware.FX2BootImage(i, vid, pid)
+}
+
+// ParseFX3Image decodes a firmware image for the EZ-USB FX3, as
+// firmware.ParseFX3Image does.
+func ParseFX3Image(b []byte) (*FX3Image, error) { return firmware.ParseFX3Image(b) }
+
+// ParseIHX reads an Intel HEX file, as ihx.Parse does.
+func ParseIHX(r io.Reader) (*IHXImage, error) { return ihx.Parse(r) }
+
+// WriteIHX writes an image in the Intel HEX format, as ihx.Write does.
+func WriteIHX(w io.Writer, i *IHXImage) error { return ihx.Write(w, i) }
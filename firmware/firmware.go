@@ -0,0 +1,90 @@
+// Package firmware encodes and decodes firmware images for the Cypress
+// EZ-USB FX2 and FX3 microcontrollers of ZTEX modules.
+package firmware
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/aljumi/ztex/ihx"
+)
+
+// FX2CPUCS is the address of the CPU control and status register of the
+// EZ-USB FX2, through which the CPU is held in reset during uploads.
+const FX2CPUCS = 0xe600
+
+// FX2BootImage encodes a firmware image in the format expected by the
+// EZ-USB FX2 boot loader in EEPROM: a 0xc2 signature byte, the vendor,
+// product, and device IDs, a configuration byte, a sequence of data
+// records, each consisting of a big-endian length and address followed by
+// the data, and a final record that releases the CPU from reset.
+func FX2BootImage(i *ihx.Image, vid, pid uint16) ([]byte, error) {
+	b := []byte{0xc2, uint8(vid), uint8(vid >> 8), uint8(pid), uint8(pid >> 8), 0, 0, 0}
+	for _, s := range i.Segments {
+		if s.End() > FX2CPUCS {
+			return nil, fmt.Errorf("got segment %v, want segments below %#04x", s, FX2CPUCS)
+		}
+		for a, d := s.Address, s.Data; len(d) > 0; {
+			n := len(d)
+			if n > 1023 {
+				n = 1023
+			}
+			b = append(b, uint8(n>>8), uint8(n), uint8(a>>8), uint8(a))
+			b = append(b, d[:n]...)
+			a, d = a+uint32(n), d[n:]
+		}
+	}
+	return append(b, 0x80, 0x01, FX2CPUCS>>8, FX2CPUCS&0xff, 0x00), nil
+}
+
+// FX3Image represents a firmware image in the format expected by the
+// EZ-USB FX3 boot loader.
+type FX3Image struct {
+	Segments []ihx.Segment
+	Entry    uint32
+}
+
+// ParseFX3Image decodes a firmware image for the EZ-USB FX3: the "CY"
+// signature, a control byte, an image type of 0xb0, a sequence of sections,
+// each consisting of a little-endian length in 32-bit words and address
+// followed by the data, a terminating section of length 0 whose address is
+// the entry point, and a checksum over the data of all sections.
+func ParseFX3Image(b []byte) (*FX3Image, error) {
+	if len(b) < 4 || !bytes.Equal(b[:2], []byte("CY")) {
+		return nil, fmt.Errorf("got no signature, want an FX3 firmware image")
+	} else if b[3] != 0xb0 {
+		return nil, fmt.Errorf("got image type %#02x, want image type %#02x", b[3], 0xb0)
+	}
+
+	x, p, c := &FX3Image{}, 4, uint32(0)
+	for {
+		if len(b) < p+8 {
+			return nil, fmt.Errorf("got %v bytes, want at least %v bytes", len(b), p+8)
+		}
+		n := int(binary.LittleEndian.Uint32(b[p:])) * 4
+		a := binary.LittleEndian.Uint32(b[p+4:])
+		p += 8
+
+		if n == 0 {
+			x.Entry = a
+			break
+		} else if len(b) < p+n {
+			return nil, fmt.Errorf("got %v bytes, want at least %v bytes", len(b), p+n)
+		}
+
+		for i := p; i < p+n; i += 4 {
+			c += binary.LittleEndian.Uint32(b[i:])
+		}
+		x.Segments = append(x.Segments, ihx.Segment{Address: a, Data: b[p : p+n]})
+		p += n
+	}
+
+	if len(b) < p+4 {
+		return nil, fmt.Errorf("got %v bytes, want at least %v bytes", len(b), p+4)
+	} else if s := binary.LittleEndian.Uint32(b[p:]); s != c {
+		return nil, fmt.Errorf("got checksum %#08x, want checksum %#08x", s, c)
+	}
+
+	return x, nil
+}
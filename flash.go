@@ -1,8 +1,8 @@
 package ztex
 
 import (
+	"encoding/json"
 	"fmt"
-	"strings"
 )
 
 // FlashEnabled indicates whether or not the flash is enabled.
@@ -17,10 +17,14 @@ func (f FlashEnabled) String() string {
 	case 1:
 		return "Enabled"
 	default:
-		return "Unknown"
+		return Unknown
 	}
 }
 
+// IsKnown returns true if and only if the flash indicator has a known
+// name.
+func (f FlashEnabled) IsKnown() bool { return isKnown(f.String()) }
+
 // FlashSector represents the size of a sector in the flash.
 type FlashSector [2]uint8
 
@@ -72,10 +76,14 @@ func (f FlashError) String() string {
 	case 8:
 		return "Runtime Error"
 	default:
-		return fmt.Sprintf("Unknown Error [%v]", uint8(f))
+		return fmt.Sprintf("%v Error [%v]", Unknown, uint8(f))
 	}
 }
 
+// IsKnown returns true if and only if the flash error code has a known
+// name.
+func (f FlashError) IsKnown() bool { return isKnown(f.String()) }
+
 // FlashStatus indicates the current status of the flash.
 type FlashStatus struct {
 	FlashEnabled
@@ -86,10 +94,34 @@ type FlashStatus struct {
 
 // String returns a human-readable description of the flash status.
 func (f FlashStatus) String() string {
-	x := []string{}
-	x = append(x, fmt.Sprintf("Enabled(%v)", f.FlashEnabled))
-	x = append(x, fmt.Sprintf("Sector(%v)", f.FlashSector))
-	x = append(x, fmt.Sprintf("Count(%v)", f.FlashCount))
-	x = append(x, fmt.Sprintf("Error(%v)", f.FlashError))
-	return strings.Join(x, ", ")
+	b, _ := f.AppendText(nil)
+	return string(b)
+}
+
+// AppendText appends the description returned by String to b, so that
+// monitors which log the status repeatedly can reuse a buffer.
+func (f FlashStatus) AppendText(b []byte) ([]byte, error) {
+	return fmt.Appendf(b, "Enabled(%v), Sector(%v), Count(%v), Error(%v)",
+		f.FlashEnabled, f.FlashSector, f.FlashCount, f.FlashError), nil
+}
+
+// MarshalJSON returns a JSON representation of the flash status.
+func (f FlashStatus) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Enabled   bool   `json:"enabled"`
+		Sector    uint64 `json:"sector"`
+		Count     uint32 `json:"count"`
+		Size      uint64 `json:"size"`
+		SizeName  string `json:"size_name"`
+		Error     uint8  `json:"error"`
+		ErrorName string `json:"error_name"`
+	}{
+		f.FlashEnabled == 1,
+		f.FlashSector.Number(),
+		f.FlashCount.Number(),
+		f.FlashSector.Number() * uint64(f.FlashCount.Number()),
+		binaryPrefix(f.FlashSector.Number()*uint64(f.FlashCount.Number()), "B"),
+		uint8(f.FlashError),
+		f.FlashError.String(),
+	})
 }
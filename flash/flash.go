@@ -0,0 +1,41 @@
+// Package flash describes the layout of the flash memory of ZTEX modules,
+// which is read and written in whole sectors.
+package flash
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Geometry describes the sectors of a flash memory.
+type Geometry struct {
+	SectorSize int
+	Sectors    uint32
+}
+
+// String returns a human-readable description of the geometry.
+func (g Geometry) String() string {
+	return fmt.Sprintf("%v sectors of %v bytes", g.Sectors, g.SectorSize)
+}
+
+// Check returns an error unless n bytes, starting at the given sector, are
+// whole sectors within the flash.
+func (g Geometry) Check(sector uint32, n int) error {
+	if n%g.SectorSize != 0 {
+		return fmt.Errorf("got %v bytes, want a multiple of %v bytes", n, g.SectorSize)
+	} else if end := uint64(sector) + uint64(n/g.SectorSize); end > uint64(g.Sectors) {
+		return fmt.Errorf("got sectors [%v, %v), want sectors in [0, %v)", sector, end, g.Sectors)
+	}
+	return nil
+}
+
+// Pad pads b with erased bytes to a multiple of the sector size.
+func (g Geometry) Pad(b []byte) []byte {
+	if r := len(b) % g.SectorSize; r != 0 {
+		b = append(b, bytes.Repeat([]byte{0xff}, g.SectorSize-r)...)
+	}
+	return b
+}
+
+// Erased returns one erased sector.
+func (g Geometry) Erased() []byte { return bytes.Repeat([]byte{0xff}, g.SectorSize) }
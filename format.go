@@ -0,0 +1,163 @@
+package ztex
+
+import (
+	"fmt"
+	"io"
+)
+
+// The device and the status types implement fmt.Formatter.  The verb %v
+// gives a compact summary which fits on a log line, %+v a breakdown with
+// one field per line for reports, %s the full description returned by
+// String, and %#v the Go syntax representation.
+
+// field is a named value in the breakdown printed by %+v.
+type field struct {
+	name  string
+	value any
+}
+
+// format implements fmt.Formatter for a type with the given full
+// description, compact summary, breakdown, and Go syntax value, which
+// must be of a type that does not implement fmt.Formatter.  Only the
+// representation selected by the verb is built, so that values logged
+// with %v do not pay for the others.
+func format(s fmt.State, verb rune, full fmt.Stringer, compact func(io.Writer), fields func() []field, raw any) {
+	switch {
+	case verb == 'v' && s.Flag('#'):
+		fmt.Fprintf(s, "%#v", raw)
+	case verb == 'v' && s.Flag('+'):
+		f := fields()
+		w := 0
+		for _, x := range f {
+			w = max(w, len(x.name))
+		}
+		for i, x := range f {
+			if i > 0 {
+				io.WriteString(s, "\n")
+			}
+			fmt.Fprintf(s, "%-*s %v", w+1, x.name+":", x.value)
+		}
+	case verb == 'v':
+		compact(s)
+	case verb == 's':
+		io.WriteString(s, full.String())
+	case verb == 'q':
+		fmt.Fprintf(s, "%q", full.String())
+	default:
+		fmt.Fprintf(s, "%%!%c(%s)", verb, full.String())
+	}
+}
+
+// stringer adapts a function to fmt.Stringer.
+type stringer func() string
+
+func (f stringer) String() string { return f() }
+
+// Format implements fmt.Formatter.  The compact summary holds the serial
+// number, the board version, the FPGA, and the USB bus and address.
+func (d *Device) Format(s fmt.State, verb rune) {
+	type plain Device
+	format(s, verb, d, func(w io.Writer) {
+		fmt.Fprintf(w, "Serial(%v), Board(%v), FPGA(%v)", d.Serial(), d.BoardConfig.BoardVersion, d.FPGAConfig.FPGAType)
+		if d.Desc != nil {
+			fmt.Fprintf(w, ", USB(%03d/%03d)", d.Desc.Bus, d.Desc.Address)
+		}
+	}, func() []field {
+		return []field{
+			{"USB", d.Desc},
+			{"Descriptor", d.DescriptorConfig},
+			{"Board", d.BoardConfig},
+			{"FPGA", d.FPGAConfig},
+			{"RAM", d.RAMConfig},
+			{"Bitstream", d.BitstreamConfig},
+			{"MultiFPGA", d.MultiFPGAConfig},
+		}
+	}, (*plain)(d))
+}
+
+// Format implements fmt.Formatter.  The compact summary holds whether or
+// not the FPGA is configured, the number of bytes transferred, and the
+// result of the last configuration.
+func (f FPGAStatus) Format(s fmt.State, verb rune) {
+	type plain FPGAStatus
+	format(s, verb, stringer(f.String), func(w io.Writer) {
+		fmt.Fprintf(w, "%v, Transferred(%v), Result(%v)", f.FPGAConfigured, f.FPGATransferred, f.FPGAResult)
+	}, f.fields, plain(f))
+}
+
+// fields returns the breakdown of the FPGA status.
+func (f FPGAStatus) fields() []field {
+	return []field{
+		{"Configured", f.FPGAConfigured},
+		{"Checksum", f.FPGAChecksum},
+		{"Transferred", f.FPGATransferred},
+		{"Init", f.FPGAInit},
+		{"Result", f.FPGAResult},
+		{"Swapped", f.FPGASwapped},
+	}
+}
+
+// Format implements fmt.Formatter.  The compact summary holds whether or
+// not the flash is enabled, its size, and the error code.
+func (f FlashStatus) Format(s fmt.State, verb rune) {
+	type plain FlashStatus
+	format(s, verb, stringer(f.String), func(w io.Writer) {
+		fmt.Fprintf(w, "%v, Size(%v), Error(%v)", f.FlashEnabled, binaryPrefix(f.FlashSector.Number()*uint64(f.FlashCount.Number()), "B"), f.FlashError)
+	}, f.fields, plain(f))
+}
+
+// fields returns the breakdown of the flash status.
+func (f FlashStatus) fields() []field {
+	return []field{
+		{"Enabled", f.FlashEnabled},
+		{"Sector", f.FlashSector},
+		{"Count", f.FlashCount},
+		{"Error", f.FlashError},
+	}
+}
+
+// Format implements fmt.Formatter.  The compact summary holds the value
+// of every sensor, named after its kind and channel.
+func (s SensorStatus) Format(st fmt.State, verb rune) {
+	type plain SensorStatus
+	format(st, verb, stringer(s.String), func(w io.Writer) {
+		for i, r := range s.SensorReadings {
+			if i > 0 {
+				io.WriteString(w, ", ")
+			}
+			fmt.Fprintf(w, "%v%v(%.2f%v)", r.SensorKind, r.SensorChannel.Number(), r.SensorValue.Number(), r.SensorKind.Unit())
+		}
+	}, s.fields, plain(s))
+}
+
+// fields returns the breakdown of the sensor status: the protocol,
+// followed by the value of every sensor, named after its kind and channel.
+func (s SensorStatus) fields() []field {
+	f := []field{{"Protocol", s.SensorProtocol}}
+	for _, r := range s.SensorReadings {
+		n := fmt.Sprintf("%v%v", r.SensorKind, r.SensorChannel.Number())
+		v := fmt.Sprintf("%.2f%v", r.SensorValue.Number(), r.SensorKind.Unit())
+		f = append(f, field{n, v})
+	}
+	return f
+}
+
+// Format implements fmt.Formatter.  The compact summary holds the
+// signature, whether or not the XMEGA is busy, and the error code.
+func (x XMEGAStatus) Format(s fmt.State, verb rune) {
+	type plain XMEGAStatus
+	format(s, verb, stringer(x.String), func(w io.Writer) {
+		fmt.Fprintf(w, "Signature(%v), %v, Error(%v)", x.XMEGASignature, x.XMEGABusy, x.XMEGAError)
+	}, x.fields, plain(x))
+}
+
+// fields returns the breakdown of the XMEGA status.
+func (x XMEGAStatus) fields() []field {
+	return []field{
+		{"Error", x.XMEGAError},
+		{"Busy", x.XMEGABusy},
+		{"Signature", x.XMEGASignature},
+		{"FlashPage", x.XMEGAFlashPage},
+		{"EEPROMPage", x.XMEGAEEPROMPage},
+	}
+}
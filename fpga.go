@@ -1,48 +1,44 @@
 package ztex
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
+
+	"github.com/aljumi/ztex/bitstream"
 )
 
 // FPGAType indicates which FPGA device is present.
 type FPGAType [2]byte
 
+// The numbers of the FPGA types, as returned by (FPGAType).Number.
+// FPGAType is an array, which cannot be constant, so the types are named
+// by their numbers and made with MakeFPGAType.
+const (
+	FPGAXC6SLX9     uint16 = 1
+	FPGAXC6SLX16    uint16 = 2
+	FPGAXC6SLX25    uint16 = 3
+	FPGAXC6SLX45    uint16 = 4
+	FPGAXC6SLX75    uint16 = 5
+	FPGAXC6SLX100   uint16 = 6
+	FPGAXC6SLX150   uint16 = 7
+	FPGAXC7A35T     uint16 = 8
+	FPGAXC7A50T     uint16 = 9
+	FPGAXC7A75T     uint16 = 10
+	FPGAXC7A100T    uint16 = 11
+	FPGAXC7A200T    uint16 = 12
+	FPGAXC6SLX150x4 uint16 = 13
+	FPGAXC7A15T     uint16 = 14
+)
+
+// MakeFPGAType returns the FPGA type with the given number.
+func MakeFPGAType(n uint16) FPGAType { return FPGAType{uint8(n), uint8(n >> 8)} }
+
 // String returns a human-readable representation of an FPGA type.
-func (f FPGAType) String() string {
-	switch f.Number() {
-	case 1:
-		return "Xilinx Spartan-6 XC6SLX9"
-	case 2:
-		return "Xilinx Spartan-6 XC6SLX16"
-	case 3:
-		return "Xilinx Spartan-6 XC6SLX25"
-	case 4:
-		return "Xilinx Spartan-6 XC6SLX45"
-	case 5:
-		return "Xilinx Spartan-6 XC6SLX75"
-	case 6:
-		return "Xilinx Spartan-6 XC6SLX100"
-	case 7:
-		return "Xilinx Spartan-6 XC6SLX150"
-	case 8:
-		return "Xilinx Artix-7 XC7A35T"
-	case 9:
-		return "Xilinx Artix-7 XC7A50T"
-	case 10:
-		return "Xilinx Artix-7 XC7A75T"
-	case 11:
-		return "Xilinx Artix-7 XC7A100T"
-	case 12:
-		return "Xilinx Artix-7 XC7A200T"
-	case 13:
-		return "Xilinx Spartan-6 XC6SLX150 [x4]"
-	case 14:
-		return "Xilinx Artix-7 XC7A15T"
-	default:
-		return "Unknown"
-	}
-}
+func (f FPGAType) String() string { return fpgaTypeName(f) }
+
+// IsKnown returns true if and only if an FPGA type has a known name.
+func (f FPGAType) IsKnown() bool { return isKnown(f.String()) }
 
 // Bytes returns a raw representation of an FPGA type.
 func (f FPGAType) Bytes() []byte { return []byte{f[0], f[1]} }
@@ -53,22 +49,33 @@ func (f FPGAType) Number() uint16 { return (uint16(f[0]) << 0) | (uint16(f[1]) <
 // FPGAPackage indicates the mechanical packaging of the FPGA.
 type FPGAPackage uint8
 
+// The FPGA packages.
+const (
+	PackageFTG256 FPGAPackage = 1
+	PackageCSG324 FPGAPackage = 2
+	PackageCSG484 FPGAPackage = 3
+	PackageFBG484 FPGAPackage = 4
+)
+
 // String returns a human-readable representation of the FPGA package.
 func (f FPGAPackage) String() string {
 	switch f {
-	case 1:
+	case PackageFTG256:
 		return "FTG256"
-	case 2:
+	case PackageCSG324:
 		return "CSG324"
-	case 3:
+	case PackageCSG484:
 		return "CSG484"
-	case 4:
+	case PackageFBG484:
 		return "FBG484"
 	default:
-		return "Unknown"
+		return Unknown
 	}
 }
 
+// IsKnown returns true if and only if the FPGA package has a known name.
+func (f FPGAPackage) IsKnown() bool { return isKnown(f.String()) }
+
 // Number returns the raw numeric representation of an FPGA package.
 func (f FPGAPackage) Number() uint8 { return uint8(f) }
 
@@ -110,6 +117,42 @@ func (f FPGAConfig) String() string {
 	return strings.Join(x, ", ")
 }
 
+// Part returns the device and package of the FPGA in the form used by
+// the headers of .bit files, such as "7a35tcsg324", or an empty string if
+// the FPGA type is not known.  The package is left out if it is not known.
+func (f FPGAConfig) Part() string {
+	if !f.FPGAType.IsKnown() {
+		return ""
+	}
+	p := ""
+	for _, x := range strings.Fields(f.FPGAType.String()) {
+		if strings.HasPrefix(x, "XC") {
+			p = bitstream.NormalizePart(x)
+		}
+	}
+	if p != "" && f.FPGAPackage.IsKnown() {
+		p += strings.ToLower(f.FPGAPackage.String())
+	}
+	return p
+}
+
+// MarshalJSON returns a JSON representation of the FPGA configuration.
+func (f FPGAConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type        uint16 `json:"type"`
+		TypeName    string `json:"type_name"`
+		Package     uint8  `json:"package"`
+		PackageName string `json:"package_name"`
+		Grade       string `json:"grade"`
+	}{
+		f.FPGAType.Number(),
+		f.FPGAType.String(),
+		f.FPGAPackage.Number(),
+		f.FPGAPackage.String(),
+		f.FPGAGrade.String(),
+	})
+}
+
 // FPGAConfigured indicates whether or not the FPGA is configured.
 type FPGAConfigured uint8
 
@@ -122,10 +165,14 @@ func (f FPGAConfigured) String() string {
 	case 1:
 		return "Unconfigured"
 	default:
-		return "Unknown"
+		return Unknown
 	}
 }
 
+// IsKnown returns true if and only if the FPGA configuration indicator has
+// a known name.
+func (f FPGAConfigured) IsKnown() bool { return isKnown(f.String()) }
+
 // Number returns the raw numeric representation of the FPGA configuration
 // indicator.
 func (f FPGAConfigured) Number() uint8 { return uint8(f) }
@@ -169,10 +216,14 @@ func (f FPGAResult) String() string {
 	case 4:
 		return "Configuration Error"
 	default:
-		return "Unknown"
+		return Unknown
 	}
 }
 
+// IsKnown returns true if and only if the FPGA configuration result has a
+// known name.
+func (f FPGAResult) IsKnown() bool { return isKnown(f.String()) }
+
 // Bool returns true if and only if the result indicates that
 // configuration was successful.
 func (f FPGAResult) Bool() bool { return f == 0 }
@@ -188,16 +239,50 @@ func (f FPGASwapped) String() string {
 	case 1:
 		return "Swapped"
 	default:
-		return "Unknown"
+		return Unknown
 	}
 }
 
+// IsKnown returns true if and only if the bitstream bit order has a known
+// name.
+func (f FPGASwapped) IsKnown() bool { return isKnown(f.String()) }
+
 // Number returns the raw numeric representation of the bitstream bit order.
 func (f FPGASwapped) Number() uint8 { return uint8(f) }
 
 // Bool returns true if and only if the bitstream bit order is swapped.
 func (f FPGASwapped) Bool() bool { return f == 1 }
 
+// FPGAHighSpeedEndpoint indicates the bulk OUT endpoint through which the
+// bitstream is sent during high-speed FPGA configuration.
+type FPGAHighSpeedEndpoint uint8
+
+// Number returns the endpoint number.
+func (f FPGAHighSpeedEndpoint) Number() uint8 { return uint8(f) }
+
+// FPGAHighSpeedInterface indicates the USB interface which holds the
+// endpoint used for high-speed FPGA configuration.
+type FPGAHighSpeedInterface uint8
+
+// Number returns the interface number.
+func (f FPGAHighSpeedInterface) Number() uint8 { return uint8(f) }
+
+// FPGAHighSpeedConfig indicates the settings for high-speed FPGA
+// configuration.
+type FPGAHighSpeedConfig struct {
+	FPGAHighSpeedEndpoint
+	FPGAHighSpeedInterface
+}
+
+// String returns a human-readable description of the high-speed FPGA
+// configuration settings.
+func (f FPGAHighSpeedConfig) String() string {
+	x := []string{}
+	x = append(x, fmt.Sprintf("Endpoint(%v)", f.FPGAHighSpeedEndpoint))
+	x = append(x, fmt.Sprintf("Interface(%v)", f.FPGAHighSpeedInterface))
+	return strings.Join(x, ", ")
+}
+
 // FPGAStatus indicates the status of the FPGA.
 type FPGAStatus struct {
 	FPGAConfigured
@@ -210,12 +295,34 @@ type FPGAStatus struct {
 
 // String returns a human-readable description of the FPGA status.
 func (f FPGAStatus) String() string {
-	x := []string{}
-	x = append(x, fmt.Sprintf("Configured(%v)", f.FPGAConfigured))
-	x = append(x, fmt.Sprintf("Checksum(%v)", f.FPGAChecksum))
-	x = append(x, fmt.Sprintf("Transferred(%v)", f.FPGATransferred))
-	x = append(x, fmt.Sprintf("Init(%v)", f.FPGAInit))
-	x = append(x, fmt.Sprintf("Result(%v)", f.FPGAResult))
-	x = append(x, fmt.Sprintf("Swapped(%v)", f.FPGASwapped))
-	return strings.Join(x, ", ")
+	b, _ := f.AppendText(nil)
+	return string(b)
+}
+
+// AppendText appends the description returned by String to b, so that
+// monitors which log the status repeatedly can reuse a buffer.
+func (f FPGAStatus) AppendText(b []byte) ([]byte, error) {
+	return fmt.Appendf(b, "Configured(%v), Checksum(%v), Transferred(%v), Init(%v), Result(%v), Swapped(%v)",
+		f.FPGAConfigured, f.FPGAChecksum, f.FPGATransferred, f.FPGAInit, f.FPGAResult, f.FPGASwapped), nil
+}
+
+// MarshalJSON returns a JSON representation of the FPGA status.
+func (f FPGAStatus) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Configured  bool   `json:"configured"`
+		Checksum    uint8  `json:"checksum"`
+		Transferred uint32 `json:"transferred"`
+		Init        uint8  `json:"init"`
+		Result      uint8  `json:"result"`
+		ResultName  string `json:"result_name"`
+		Swapped     bool   `json:"swapped"`
+	}{
+		f.FPGAConfigured.Bool(),
+		uint8(f.FPGAChecksum),
+		f.FPGATransferred.Number(),
+		uint8(f.FPGAInit),
+		uint8(f.FPGAResult),
+		f.FPGAResult.String(),
+		f.FPGASwapped.Bool(),
+	})
 }
@@ -0,0 +1,263 @@
+// Package fwloader runs the operations of FWLoader, the command-line tool
+// of the ZTEX SDK, with the same options, so that scripts and
+// documentation written for FWLoader carry over.  The options are:
+//
+//	-c          scan the bus and list the modules
+//	-d <number> select the module with the given number (default 0)
+//	-s <serial> select the module with the given serial number
+//	-f          force commands which the firmware does not announce
+//	-p          print the bus location of the selected module
+//	-i          print information about the selected module
+//	-ii         print information about the selected module and its capabilities
+//	-uu <file>  upload firmware into the RAM of the USB controller
+//	-ue <file>  upload firmware to the EEPROM or flash
+//	-re         reset (disable) the firmware in the EEPROM or flash
+//	-rv <file>  verify the firmware in the EEPROM or flash against a file
+//	-uf <file>  upload a bitstream to the FPGA
+//	-rf         read the FPGA state
+//
+// Modules are numbered like in the SDK, in the order in which the backend
+// lists them; see ztex.FilterDevicesInScanOrder.
+//
+// Options of FWLoader which have no counterpart here, such as -bs, whose
+// bit order is detected from the bitstream instead, are rejected.
+package fwloader
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/aljumi/ztex"
+)
+
+// Options holds the options of an FWLoader invocation.
+type Options struct {
+	Scan          bool
+	Index         int
+	Serial        string
+	Force         bool
+	PrintBus      bool
+	Info          bool
+	Capabilities  bool
+	UploadRAM     string
+	UploadEEPROM  string
+	ResetEEPROM   bool
+	VerifyEEPROM  string
+	UploadFPGA    string
+	ReadFPGAState bool
+}
+
+// Parse parses FWLoader options.
+func Parse(args []string) (*Options, error) {
+	o := &Options{}
+	f := flag.NewFlagSet("FWLoader", flag.ContinueOnError)
+	f.SetOutput(io.Discard)
+	f.BoolVar(&o.Scan, "c", false, "")
+	f.IntVar(&o.Index, "d", 0, "")
+	f.StringVar(&o.Serial, "s", "", "")
+	f.BoolVar(&o.Force, "f", false, "")
+	f.BoolVar(&o.PrintBus, "p", false, "")
+	f.BoolVar(&o.Info, "i", false, "")
+	f.BoolVar(&o.Capabilities, "ii", false, "")
+	f.StringVar(&o.UploadRAM, "uu", "", "")
+	f.StringVar(&o.UploadEEPROM, "ue", "", "")
+	f.BoolVar(&o.ResetEEPROM, "re", false, "")
+	f.StringVar(&o.VerifyEEPROM, "rv", "", "")
+	f.StringVar(&o.UploadFPGA, "uf", "", "")
+	f.BoolVar(&o.ReadFPGAState, "rf", false, "")
+	if err := f.Parse(args); err != nil {
+		return nil, fmt.Errorf("fwloader.Parse: %w", err)
+	} else if f.NArg() != 0 {
+		return nil, fmt.Errorf("fwloader.Parse: got argument %q, want options only", f.Arg(0))
+	} else if o.Index < 0 {
+		return nil, fmt.Errorf("fwloader.Parse: got device number %v, want a non-negative device number", o.Index)
+	}
+	return o, nil
+}
+
+// selected reports whether or not the options operate on a module, as
+// opposed to only scanning the bus.
+func (o *Options) selected() bool {
+	return o.PrintBus || o.Info || o.Capabilities || o.UploadRAM != "" || o.UploadEEPROM != "" || o.ResetEEPROM ||
+		o.VerifyEEPROM != "" || o.UploadFPGA != "" || o.ReadFPGAState
+}
+
+// reconnectTimeout is how long Run waits for a module to reconnect after
+// firmware has been uploaded into its RAM.
+const reconnectTimeout = 10 * time.Second
+
+// Run performs the operations selected by the options on the modules
+// opened through the backend, printing their results to w.  The operations
+// run in the order of FWLoader: the bus is scanned, firmware is uploaded
+// into RAM, after which the module is opened again once it has
+// reconnected, firmware is uploaded to, reset in, or verified against the
+// EEPROM or flash, information is printed, and the FPGA is configured and
+// its state read.
+func (o *Options) Run(ctx context.Context, b ztex.Backend, w io.Writer) error {
+	var opt []ztex.DeviceOption
+	if o.Force {
+		opt = append(opt, ztex.ForceUnsupportedCommands())
+	}
+
+	ds, err := ztex.OpenBackendDevices(b, opt...)
+	if len(ds) == 0 && err != nil {
+		return err
+	}
+	ds = ztex.FilterDevicesInScanOrder(ds)
+	defer func() {
+		for _, d := range ds {
+			d.Close()
+		}
+	}()
+
+	if o.Scan {
+		for i, d := range ds {
+			bus, addr := 0, 0
+			if d.Desc != nil {
+				bus, addr = d.Desc.Bus, d.Desc.Address
+			}
+			fmt.Fprintf(w, "%v: bus=%03d device=%03d SerialNumber=%q Product=%q\n", i, bus, addr, d.Serial(), d.DescriptorConfig.DescriptorProduct)
+		}
+	}
+	if !o.selected() {
+		return nil
+	}
+
+	d, err := o.selectDevice(ds)
+	if err != nil {
+		return err
+	}
+
+	if o.PrintBus && d.Desc != nil {
+		fmt.Fprintf(w, "bus=%03d device=%03d port=%v\n", d.Desc.Bus, d.Desc.Address, d.Desc.Port)
+	}
+
+	if o.UploadRAM != "" {
+		if err := upload(ctx, o.UploadRAM, d.UploadFirmware); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "firmware uploaded into RAM from %v\n", o.UploadRAM)
+		if d, err = reconnect(ctx, b, d.Serial().String(), opt); err != nil {
+			return err
+		}
+		ds = append(ds, d)
+	}
+
+	if o.UploadEEPROM != "" {
+		if err := upload(ctx, o.UploadEEPROM, d.InstallFirmware); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "firmware uploaded to EEPROM or flash from %v\n", o.UploadEEPROM)
+	}
+
+	if o.ResetEEPROM {
+		if err := d.DisableFirmware(ctx); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "firmware in EEPROM or flash disabled\n")
+	}
+
+	if o.VerifyEEPROM != "" {
+		r, err := os.Open(o.VerifyEEPROM)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		if ok, err := d.VerifyFirmware(ctx, r, nil); err != nil {
+			return err
+		} else if !ok {
+			return fmt.Errorf("got different firmware in EEPROM or flash, want firmware from %v", o.VerifyEEPROM)
+		}
+		fmt.Fprintf(w, "firmware in EEPROM or flash matches %v\n", o.VerifyEEPROM)
+	}
+
+	if o.Capabilities {
+		if err := d.Report(w, ztex.ReportText); err != nil {
+			return err
+		}
+	} else if o.Info {
+		fmt.Fprintf(w, "%+v\n", d)
+	}
+
+	if o.UploadFPGA != "" {
+		configure := d.ConfigureFPGA
+		if d.Capability().HighSpeedFPGAConfiguration() {
+			configure = d.ConfigureFPGAHighSpeed
+		}
+		if err := upload(ctx, o.UploadFPGA, configure); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "FPGA configured from %v\n", o.UploadFPGA)
+	}
+
+	if o.ReadFPGAState {
+		s, err := d.FPGAStatus()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%+v\n", s)
+	}
+
+	return nil
+}
+
+// selectDevice returns the module selected by serial number or, failing
+// that, by number.
+func (o *Options) selectDevice(ds []*ztex.Device) (*ztex.Device, error) {
+	if o.Serial != "" {
+		for _, d := range ds {
+			if d.Serial().String() == o.Serial {
+				return d, nil
+			}
+		}
+		return nil, fmt.Errorf("%w: got no module with serial number %q, want a module", ztex.ErrNoDevice, o.Serial)
+	}
+
+	if o.Index >= len(ds) {
+		return nil, fmt.Errorf("%w: got device number %v, want device number in [0, %v)", ztex.ErrNoDevice, o.Index, len(ds))
+	}
+	return ds[o.Index], nil
+}
+
+// upload runs u with the contents of the named file.
+func upload(ctx context.Context, name string, u func(context.Context, io.Reader, ztex.Progress) error) error {
+	r, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return u(ctx, r, nil)
+}
+
+// reconnect waits until the module with the given serial number has
+// reconnected after a firmware upload into RAM, and opens it again.
+func reconnect(ctx context.Context, b ztex.Backend, serial string, opt []ztex.DeviceOption) (*ztex.Device, error) {
+	ctx, cancel := context.WithTimeout(ctx, reconnectTimeout)
+	defer cancel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w: got no module with serial number %q after the upload, want the module to reconnect: %w", ztex.ErrNoDevice, serial, ctx.Err())
+		case <-time.After(500 * time.Millisecond):
+		}
+
+		ds, _ := ztex.OpenBackendDevices(b, append(opt, ztex.DeferDeviceConfig())...)
+		ds = ztex.FilterDevices(ds, ztex.SerialFilter(serial))
+		if len(ds) == 0 {
+			continue
+		}
+		for _, d := range ds[1:] {
+			d.Close()
+		}
+		if err := ds[0].LoadDeviceConfig(); err != nil {
+			ds[0].Close()
+			return nil, err
+		}
+		return ds[0], nil
+	}
+}
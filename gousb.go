@@ -0,0 +1,214 @@
+//go:build cgo && !nolibusb
+
+package ztex
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/google/gousb"
+)
+
+func init() {
+	adaptController = func(c Controller) Controller {
+		if dev, ok := c.(*gousb.Device); ok {
+			return gousbDevice{dev}
+		}
+		return c
+	}
+}
+
+// OpenDevice opens a ZTEX USB-FPGA module and returns its device handle.
+// If there are multiple modules present, then one is chosen arbitrarily.
+func OpenDevice(ctx *gousb.Context, opt ...DeviceOption) (*Device, error) {
+	r, _ := UdevRule("")
+	dev, err := openGousbDevice(ctx, VendorID, ProductID, r)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewDevice(gousbDevice{dev}, opt...)
+}
+
+// OpenGousbDevice opens the first USB device with the given vendor and
+// product IDs through gousb, like (*gousb.Context).OpenDeviceWithVIDPID,
+// for devices which are not operated as ZTEX modules, such as an EZ-USB
+// FX3 in boot loader mode.  Unlike it, OpenGousbDevice reports a lack of
+// permission as a *PermissionError naming the device node and a udev rule
+// for the device, and the absence of the device as ErrNoDevice.
+func OpenGousbDevice(ctx *gousb.Context, vendor, product uint16) (*gousb.Device, error) {
+	r, _ := udevRule(fmt.Sprintf(`ATTR{idVendor}=="%04x", ATTR{idProduct}=="%04x"`, vendor, product), "")
+	return openGousbDevice(ctx, vendor, product, r)
+}
+
+// openGousbDevice opens the first USB device with the given vendor and
+// product IDs, and suggests the given udev rule if permission is denied.
+func openGousbDevice(ctx *gousb.Context, vendor, product uint16, rule string) (*gousb.Device, error) {
+	found := map[string]bool{}
+	devs, err := ctx.OpenDevices(func(desc *gousb.DeviceDesc) bool {
+		if len(found) != 0 || desc.Vendor != gousb.ID(vendor) || desc.Product != gousb.ID(product) {
+			return false
+		}
+		found[usbPath(desc.Bus, desc.Address)] = true
+		return true
+	})
+	if len(devs) == 0 {
+		if err != nil {
+			return nil, fmt.Errorf("(*gousb.Context).OpenDevices: %w", permissionError(err, rule, found))
+		}
+		return nil, fmt.Errorf("(*gousb.Context).OpenDevices: %w: got no device %04x:%04x, want a device", ErrNoDevice, vendor, product)
+	}
+	for _, dev := range devs[1:] {
+		dev.Close()
+	}
+	return devs[0], nil
+}
+
+// OpenDevices opens all ZTEX USB-FPGA modules present and returns their
+// device handles.  If some of the modules cannot be opened, then the
+// handles of the others are returned along with an error describing the
+// failures.
+func OpenDevices(ctx *gousb.Context, opt ...DeviceOption) ([]*Device, error) {
+	return OpenBackendDevices(GousbBackend(ctx), opt...)
+}
+
+// GousbBackend returns a backend which opens devices through gousb, and
+// thus libusb, in the given context.
+func GousbBackend(ctx *gousb.Context) Backend { return gousbBackend{ctx} }
+
+type gousbBackend struct{ ctx *gousb.Context }
+
+func (b gousbBackend) OpenDevices(vendor, product uint16) ([]USBDevice, error) {
+	found := map[string]bool{}
+	devs, err := b.ctx.OpenDevices(func(desc *gousb.DeviceDesc) bool {
+		ok := desc.Vendor == gousb.ID(vendor) && desc.Product == gousb.ID(product)
+		if ok {
+			found[usbPath(desc.Bus, desc.Address)] = true
+		}
+		return ok
+	})
+
+	x := []USBDevice{}
+	for _, dev := range devs {
+		x = append(x, gousbDevice{dev})
+		delete(found, usbPath(dev.Desc.Bus, dev.Desc.Address))
+	}
+
+	if err != nil {
+		r, _ := UdevRule("")
+		return x, fmt.Errorf("(*gousb.Context).OpenDevices: %w", permissionError(err, r, found))
+	}
+	return x, nil
+}
+
+// permissionError returns a *PermissionError wrapping err if err denotes a
+// lack of permission on Linux, where it is fixed by the given udev rule,
+// and err otherwise.  The paths are those of the devices which were not
+// opened.
+func permissionError(err error, rule string, paths map[string]bool) error {
+	if runtime.GOOS != "linux" || !errors.Is(err, gousb.ErrorAccess) {
+		return err
+	}
+	e := &PermissionError{Rule: rule, Err: err}
+	for p := range paths {
+		e.Paths = append(e.Paths, p)
+	}
+	sort.Strings(e.Paths)
+	return e
+}
+
+// GousbDevice returns the *gousb.Device through which the device is
+// operated, or nil if the device was not opened through gousb.
+func (d *Device) GousbDevice() *gousb.Device {
+	if g, ok := d.usb.(gousbDevice); ok {
+		return g.Device
+	}
+	return nil
+}
+
+// gousbDevice adapts a *gousb.Device to USBDevice.
+type gousbDevice struct{ *gousb.Device }
+
+func (g gousbDevice) Describe() USBDesc {
+	return USBDesc{
+		Bus:     g.Desc.Bus,
+		Address: g.Desc.Address,
+		Port:    g.Desc.Port,
+		Vendor:  uint16(g.Desc.Vendor),
+		Product: uint16(g.Desc.Product),
+		Speed:   g.Desc.Speed.String(),
+	}
+}
+
+func (g gousbDevice) SetControlTimeout(timeout time.Duration) { g.ControlTimeout = timeout }
+
+func (g gousbDevice) controlTimeout() time.Duration { return g.ControlTimeout }
+
+func (g gousbDevice) Interface(num int) (USBInterface, error) {
+	n, err := g.ActiveConfigNum()
+	if err != nil {
+		return nil, fmt.Errorf("(*gousb.Device).ActiveConfigNum: %w", err)
+	}
+	cfg, err := g.Config(n)
+	if err != nil {
+		return nil, fmt.Errorf("(*gousb.Device).Config: %w", err)
+	}
+	intf, err := cfg.Interface(num, 0)
+	if err != nil {
+		cfg.Close()
+		return nil, fmt.Errorf("(*gousb.Config).Interface: %w", err)
+	}
+	return gousbInterface{cfg, intf}, nil
+}
+
+// gousbInterface adapts a *gousb.Interface to USBInterface.
+type gousbInterface struct {
+	cfg  *gousb.Config
+	intf *gousb.Interface
+}
+
+func (g gousbInterface) InEndpoint(n int) (BulkIn, error) {
+	e, err := g.intf.InEndpoint(n)
+	if err != nil {
+		return nil, fmt.Errorf("(*gousb.Interface).InEndpoint: %w", err)
+	}
+	return gousbInEndpoint{e}, nil
+}
+
+func (g gousbInterface) OutEndpoint(n int) (BulkOut, error) {
+	e, err := g.intf.OutEndpoint(n)
+	if err != nil {
+		return nil, fmt.Errorf("(*gousb.Interface).OutEndpoint: %w", err)
+	}
+	return gousbOutEndpoint{e}, nil
+}
+
+func (g gousbInterface) Close() {
+	g.intf.Close()
+	g.cfg.Close()
+}
+
+// gousbInEndpoint adapts a *gousb.InEndpoint to StreamingBulkIn.
+type gousbInEndpoint struct{ *gousb.InEndpoint }
+
+func (g gousbInEndpoint) NewStream(size, count int) (BulkInStream, error) {
+	s, err := g.InEndpoint.NewStream(size, count)
+	if err != nil {
+		return nil, fmt.Errorf("(*gousb.InEndpoint).NewStream: %w", err)
+	}
+	return s, nil
+}
+
+// gousbOutEndpoint adapts a *gousb.OutEndpoint to StreamingBulkOut.
+type gousbOutEndpoint struct{ *gousb.OutEndpoint }
+
+func (g gousbOutEndpoint) NewStream(size, count int) (BulkOutStream, error) {
+	s, err := g.OutEndpoint.NewStream(size, count)
+	if err != nil {
+		return nil, fmt.Errorf("(*gousb.OutEndpoint).NewStream: %w", err)
+	}
+	return s, nil
+}
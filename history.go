@@ -0,0 +1,154 @@
+package ztex
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SensorSample represents a sensor reading taken at a particular time.
+type SensorSample struct {
+	Time time.Time
+	SensorReading
+}
+
+// String returns a human-readable description of the sensor sample.
+func (s SensorSample) String() string {
+	x := []string{}
+	x = append(x, fmt.Sprintf("Time(%v)", s.Time.Format(time.RFC3339)))
+	x = append(x, fmt.Sprintf("Reading(%v)", s.SensorReading))
+	return strings.Join(x, ", ")
+}
+
+// SensorHistory keeps the most recent readings of each sensor channel of a
+// device in memory, so that short excursions can be inspected after the
+// fact.  It is safe for concurrent use.
+type SensorHistory struct {
+	mu      sync.Mutex
+	size    int
+	samples map[SensorChannel]*sensorRing
+	clock   Clock
+}
+
+// sensorRing is a fixed-size ring buffer of samples for one channel.
+type sensorRing struct {
+	buf  []SensorSample
+	next int
+	full bool
+}
+
+// NewSensorHistory returns a history that keeps up to size samples per
+// sensor channel.
+func NewSensorHistory(size int) (*SensorHistory, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("got size %v, want positive size", size)
+	}
+	return &SensorHistory{size: size, samples: map[SensorChannel]*sensorRing{}, clock: SystemClock}, nil
+}
+
+// SetClock sets the clock which determines the end of the windows of Min,
+// Max, and Avg.  It should be the clock of the device whose readings are
+// recorded.
+func (h *SensorHistory) SetClock(c Clock) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.clock = c
+}
+
+// now returns the current time of the clock of the history.
+func (h *SensorHistory) now() time.Time {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.clock.Now()
+}
+
+// Add records the readings of a sensor status taken at time t, discarding
+// the oldest samples of any channel whose history is full.
+func (h *SensorHistory) Add(t time.Time, s *SensorStatus) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, r := range s.SensorReadings {
+		x, ok := h.samples[r.SensorChannel]
+		if !ok {
+			x = &sensorRing{buf: make([]SensorSample, h.size)}
+			h.samples[r.SensorChannel] = x
+		}
+		x.buf[x.next] = SensorSample{t, r}
+		x.next = (x.next + 1) % h.size
+		x.full = x.full || x.next == 0
+	}
+}
+
+// Samples returns the samples of a sensor channel taken at or after the
+// given time, oldest first.
+func (h *SensorHistory) Samples(c SensorChannel, since time.Time) []SensorSample {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	x, ok := h.samples[c]
+	if !ok {
+		return nil
+	}
+
+	y := []SensorSample{}
+	n, i := x.next, 0
+	if x.full {
+		n, i = h.size, x.next
+	}
+	for ; n > 0; n, i = n-1, (i+1)%h.size {
+		if !x.buf[i].Time.Before(since) {
+			y = append(y, x.buf[i])
+		}
+	}
+	return y
+}
+
+// Min returns the lowest reading of a sensor channel within the given
+// window before now, and false if there are no readings in the window.
+func (h *SensorHistory) Min(c SensorChannel, window time.Duration) (SensorValue, bool) {
+	s := h.Samples(c, h.now().Add(-window))
+	if len(s) == 0 {
+		return 0, false
+	}
+	v := s[0].SensorValue
+	for _, x := range s[1:] {
+		if x.SensorValue < v {
+			v = x.SensorValue
+		}
+	}
+	return v, true
+}
+
+// Max returns the highest reading of a sensor channel within the given
+// window before now, and false if there are no readings in the window.
+func (h *SensorHistory) Max(c SensorChannel, window time.Duration) (SensorValue, bool) {
+	s := h.Samples(c, h.now().Add(-window))
+	if len(s) == 0 {
+		return 0, false
+	}
+	v := s[0].SensorValue
+	for _, x := range s[1:] {
+		if x.SensorValue > v {
+			v = x.SensorValue
+		}
+	}
+	return v, true
+}
+
+// Avg returns the mean reading of a sensor channel within the given window
+// before now, and false if there are no readings in the window.
+func (h *SensorHistory) Avg(c SensorChannel, window time.Duration) (SensorValue, bool) {
+	s := h.Samples(c, h.now().Add(-window))
+	if len(s) == 0 {
+		return 0, false
+	}
+	v := SensorValue(0)
+	for _, x := range s {
+		v += x.SensorValue
+	}
+	return v / SensorValue(len(s)), true
+}
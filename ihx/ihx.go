@@ -0,0 +1,168 @@
+// Package ihx reads and writes firmware images in the Intel HEX format.
+package ihx
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Segment represents a contiguous block of data in an image.
+type Segment struct {
+	Address uint32
+	Data    []byte
+}
+
+// String returns a human-readable description of the segment.
+func (s Segment) String() string {
+	return fmt.Sprintf("[%#x, %#x)", s.Address, s.End())
+}
+
+// End returns the address following the last byte of the segment.
+func (s Segment) End() uint32 { return s.Address + uint32(len(s.Data)) }
+
+// Image represents the contents of an Intel HEX file.
+type Image struct {
+	// Segments holds the data of the image, sorted by address.  Adjacent
+	// data is merged into a single segment, and segments do not overlap.
+	Segments []Segment
+
+	// Start holds the start address given by the file, if any.
+	Start *uint32
+}
+
+// String returns a human-readable description of the image.
+func (i *Image) String() string {
+	x := []string{}
+	for _, s := range i.Segments {
+		x = append(x, fmt.Sprintf("Segment(%v)", s))
+	}
+	return strings.Join(x, ", ")
+}
+
+// End returns the address following the last byte of the image.
+func (i *Image) End() uint32 {
+	if len(i.Segments) == 0 {
+		return 0
+	}
+	return i.Segments[len(i.Segments)-1].End()
+}
+
+// Bytes returns the contents of the image from address 0 to End, with the
+// gaps between segments filled with the given byte.
+func (i *Image) Bytes(fill byte) []byte {
+	b := make([]byte, i.End())
+	for j := range b {
+		b[j] = fill
+	}
+	for _, s := range i.Segments {
+		copy(b[s.Address:], s.Data)
+	}
+	return b
+}
+
+// Parse reads an Intel HEX file.  Data records, end-of-file records, and
+// the extended segment, extended linear, start segment, and start linear
+// address records are supported.  The checksum of every record is
+// verified, and data records which overlap earlier ones are rejected.
+func Parse(r io.Reader) (*Image, error) {
+	i := &Image{}
+	base := uint32(0)
+	eof := false
+
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		l := strings.TrimSpace(s.Text())
+		if l == "" {
+			continue
+		} else if eof {
+			return nil, fmt.Errorf("ihx: line %v: got record after end of file", n)
+		} else if l[0] != ':' {
+			return nil, fmt.Errorf("ihx: line %v: got start code %q, want start code %q", n, l[0], ':')
+		}
+
+		b, err := hex.DecodeString(l[1:])
+		if err != nil {
+			return nil, fmt.Errorf("ihx: line %v: %w", n, err)
+		} else if len(b) < 5 || len(b) != 5+int(b[0]) {
+			return nil, fmt.Errorf("ihx: line %v: got %v bytes, want %v bytes", n, len(b), 5+int(b[0]))
+		}
+
+		c := uint8(0)
+		for _, x := range b {
+			c += x
+		}
+		if c != 0 {
+			return nil, fmt.Errorf("ihx: line %v: got checksum %#02x, want checksum %#02x", n, b[len(b)-1], b[len(b)-1]-c)
+		}
+
+		a, t, d := uint32(b[1])<<8|uint32(b[2]), b[3], b[4:len(b)-1]
+		switch {
+		case t == 0:
+			if err := i.add(base+a, d); err != nil {
+				return nil, fmt.Errorf("ihx: line %v: %w", n, err)
+			}
+		case t == 1 && len(d) == 0:
+			eof = true
+		case t == 2 && len(d) == 2:
+			base = (uint32(d[0])<<8 | uint32(d[1])) << 4
+		case t == 3 && len(d) == 4:
+			x := (uint32(d[0])<<8|uint32(d[1]))<<4 + (uint32(d[2])<<8 | uint32(d[3]))
+			i.Start = &x
+		case t == 4 && len(d) == 2:
+			base = (uint32(d[0])<<8 | uint32(d[1])) << 16
+		case t == 5 && len(d) == 4:
+			x := uint32(d[0])<<24 | uint32(d[1])<<16 | uint32(d[2])<<8 | uint32(d[3])
+			i.Start = &x
+		default:
+			return nil, fmt.Errorf("ihx: line %v: got record type %v with %v bytes of data, want a supported record", n, t, len(d))
+		}
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("ihx: %w", err)
+	} else if !eof {
+		return nil, fmt.Errorf("ihx: got end of input, want end of file record")
+	}
+
+	return i, nil
+}
+
+// add adds data at the given address, merging it with adjacent segments.
+func (i *Image) add(a uint32, d []byte) error {
+	if len(d) == 0 {
+		return nil
+	}
+
+	e := a + uint32(len(d))
+	k := sort.Search(len(i.Segments), func(j int) bool { return i.Segments[j].End() >= a })
+	if k < len(i.Segments) && i.Segments[k].Address < e && a < i.Segments[k].End() {
+		return fmt.Errorf("got data at [%#x, %#x), which overlaps segment %v", a, e, i.Segments[k])
+	}
+
+	switch {
+	case k < len(i.Segments) && i.Segments[k].End() == a:
+		i.Segments[k].Data = append(i.Segments[k].Data, d...)
+	case k < len(i.Segments) && i.Segments[k].Address == e:
+		i.Segments[k] = Segment{a, append(append([]byte{}, d...), i.Segments[k].Data...)}
+		return nil
+	default:
+		i.Segments = append(i.Segments, Segment{})
+		copy(i.Segments[k+1:], i.Segments[k:])
+		i.Segments[k] = Segment{a, append([]byte{}, d...)}
+		return nil
+	}
+
+	// Appending to segment k may have closed the gap to segment k+1.
+	if k+1 < len(i.Segments) && i.Segments[k+1].Address < i.Segments[k].End() {
+		return fmt.Errorf("got data at [%#x, %#x), which overlaps segment %v", a, e, i.Segments[k+1])
+	} else if k+1 < len(i.Segments) && i.Segments[k+1].Address == i.Segments[k].End() {
+		i.Segments[k].Data = append(i.Segments[k].Data, i.Segments[k+1].Data...)
+		i.Segments = append(i.Segments[:k+1], i.Segments[k+2:]...)
+	}
+
+	return nil
+}
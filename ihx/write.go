@@ -0,0 +1,74 @@
+package ihx
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// RecordSize is the maximum number of data bytes in the data records
+// written by Write.
+const RecordSize = 16
+
+// FromBytes returns an image holding a copy of b at the given address, such
+// as a binary firmware image which is to be written in the Intel HEX
+// format.
+func FromBytes(addr uint32, b []byte) *Image {
+	i := &Image{}
+	i.add(addr, b)
+	return i
+}
+
+// Add adds a copy of b to the image at the given address, merging it with
+// adjacent segments.  Data which overlaps the data of the image is
+// rejected; patches which replace data are applied to the segments
+// directly.
+func (i *Image) Add(addr uint32, b []byte) error {
+	if err := i.add(addr, b); err != nil {
+		return fmt.Errorf("ihx: %w", err)
+	}
+	return nil
+}
+
+// Write writes an image to w in the Intel HEX format, which Parse reads
+// back.  Data records hold at most RecordSize bytes and do not cross 64 kiB
+// boundaries; extended linear address records precede the data above
+// 64 kiB, and a start linear address record is written if the image has a
+// start address.
+func Write(w io.Writer, i *Image) error {
+	b := bufio.NewWriter(w)
+	base := uint32(0)
+	for _, s := range i.Segments {
+		for a, d := s.Address, s.Data; len(d) > 0; {
+			if a&^0xffff != base {
+				base = a &^ 0xffff
+				writeRecord(b, 0, 4, []byte{byte(base >> 24), byte(base >> 16)})
+			}
+			n := min(len(d), RecordSize, int(0x10000-a&0xffff))
+			writeRecord(b, uint16(a), 0, d[:n])
+			a, d = a+uint32(n), d[n:]
+		}
+	}
+	if i.Start != nil {
+		x := *i.Start
+		writeRecord(b, 0, 5, []byte{byte(x >> 24), byte(x >> 16), byte(x >> 8), byte(x)})
+	}
+	writeRecord(b, 0, 1, nil)
+
+	if err := b.Flush(); err != nil {
+		return fmt.Errorf("ihx: %w", err)
+	}
+	return nil
+}
+
+// writeRecord writes a record of the given type with its checksum.  Errors
+// are left to the flush of the writer.
+func writeRecord(w *bufio.Writer, addr uint16, t uint8, d []byte) {
+	c := uint8(len(d)) + uint8(addr>>8) + uint8(addr) + t
+	fmt.Fprintf(w, ":%02X%04X%02X", len(d), addr, t)
+	for _, x := range d {
+		fmt.Fprintf(w, "%02X", x)
+		c += x
+	}
+	fmt.Fprintf(w, "%02X\n", -c)
+}
@@ -0,0 +1,88 @@
+package ihx
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	start := uint32(0x12345678)
+	for _, tc := range []struct {
+		name  string
+		image *Image
+		want  string
+	}{
+		{"empty", &Image{}, ":00000001FF\n"},
+		{"one record", FromBytes(0, []byte{0x02, 0x00, 0x06}), ":03000000020006F5\n:00000001FF\n"},
+		{
+			"split records",
+			FromBytes(0x100, bytes.Repeat([]byte{0xaa}, RecordSize+1)),
+			":10010000AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA4F\n:01011000AA44\n:00000001FF\n",
+		},
+		{
+			"64 kiB boundary",
+			FromBytes(0xfffe, []byte{1, 2, 3, 4}),
+			":02FFFE000102FE\n:020000040001F9\n:020000000304F7\n:00000001FF\n",
+		},
+		{
+			"start address",
+			&Image{Segments: []Segment{{0x10000, []byte{0x55}}}, Start: &start},
+			":020000040001F9\n:0100000055AA\n:0400000512345678E3\n:00000001FF\n",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			b := &strings.Builder{}
+			if err := Write(b, tc.image); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			if b.String() != tc.want {
+				t.Errorf("Write: got\n%v\nwant\n%v", b, tc.want)
+			}
+
+			i, err := Parse(strings.NewReader(b.String()))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if len(tc.image.Segments) == 0 {
+				i.Segments = tc.image.Segments
+			}
+			if !reflect.DeepEqual(i, tc.image) {
+				t.Errorf("Parse: got %v, want %v", i, tc.image)
+			}
+		})
+	}
+}
+
+func TestAdd(t *testing.T) {
+	i := FromBytes(0x10, []byte{1, 2})
+	for _, tc := range []struct {
+		name string
+		addr uint32
+		data []byte
+		ok   bool
+	}{
+		{"after", 0x12, []byte{3}, true},
+		{"before", 0x0f, []byte{0}, true},
+		{"apart", 0x20, []byte{9}, true},
+		{"overlapping", 0x11, []byte{7, 7}, false},
+	} {
+		if err := i.Add(tc.addr, tc.data); (err == nil) != tc.ok {
+			t.Errorf("(*Image).Add(%#x, % x): got error %v, want error %v", tc.addr, tc.data, err, !tc.ok)
+		}
+	}
+	want := []Segment{{0x0f, []byte{0, 1, 2, 3}}, {0x20, []byte{9}}}
+	if !reflect.DeepEqual(i.Segments, want) {
+		t.Errorf("(*Image).Add: got segments %v, want %v", i.Segments, want)
+	}
+}
+
+func TestFromBytes(t *testing.T) {
+	b := []byte{1, 2, 3}
+	i := FromBytes(0x2000, b)
+	b[0] = 9
+	if want := []Segment{{0x2000, []byte{1, 2, 3}}}; !reflect.DeepEqual(i.Segments, want) {
+		t.Errorf("FromBytes: got segments %v, want %v", i.Segments, want)
+	}
+}
@@ -0,0 +1,56 @@
+package ztex
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DeviceInfo is a snapshot of the identity and configuration of a device.
+// It holds no handles, so it can be copied, compared, sent over RPC, or
+// stored in an inventory database.  The USB location is zero for devices
+// that were not opened through a backend.
+type DeviceInfo struct {
+	Serial  string `json:"serial"`
+	Bus     int    `json:"bus"`
+	Address int    `json:"address"`
+	Port    int    `json:"port"`
+	Speed   string `json:"speed,omitempty"`
+
+	Descriptor DescriptorConfig `json:"descriptor"`
+	Board      BoardConfig      `json:"board"`
+	FPGA       FPGAConfig       `json:"fpga"`
+	RAM        RAMConfig        `json:"ram"`
+	Bitstream  BitstreamConfig  `json:"bitstream"`
+	MultiFPGA  MultiFPGAConfig  `json:"multi_fpga"`
+}
+
+// String returns a human-readable description of the device information.
+func (d DeviceInfo) String() string {
+	x := []string{}
+	x = append(x, fmt.Sprintf("Serial(%v)", d.Serial))
+	x = append(x, fmt.Sprintf("USB(%03d/%03d)", d.Bus, d.Address))
+	x = append(x, fmt.Sprintf("Descriptor(%v)", d.Descriptor))
+	x = append(x, fmt.Sprintf("Board(%v)", d.Board))
+	x = append(x, fmt.Sprintf("FPGA(%v)", d.FPGA))
+	x = append(x, fmt.Sprintf("RAM(%v)", d.RAM))
+	x = append(x, fmt.Sprintf("Bitstream(%v)", d.Bitstream))
+	x = append(x, fmt.Sprintf("MultiFPGA(%v)", d.MultiFPGA))
+	return strings.Join(x, ", ")
+}
+
+// Info returns a snapshot of the identity and configuration of the device.
+func (d *Device) Info() DeviceInfo {
+	x := DeviceInfo{
+		Serial:     d.Serial().String(),
+		Descriptor: d.DescriptorConfig,
+		Board:      d.BoardConfig,
+		FPGA:       d.FPGAConfig,
+		RAM:        d.RAMConfig,
+		Bitstream:  d.BitstreamConfig,
+		MultiFPGA:  d.MultiFPGAConfig,
+	}
+	if d.Desc != nil {
+		x.Bus, x.Address, x.Port, x.Speed = d.Desc.Bus, d.Desc.Address, d.Desc.Port, d.Desc.Speed
+	}
+	return x
+}
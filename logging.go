@@ -0,0 +1,185 @@
+package ztex
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// WithLogger logs every control transfer and every bulk transfer of the
+// device at debug level, with the command, the number of bytes requested
+// and transferred, the duration, and the error, if any.  Like
+// WrapController, it applies to all transfers, including those which
+// initialize the device.  The records carry the serial number of the
+// device, which is empty while the descriptor is read.
+func WithLogger(l *slog.Logger) DeviceOption {
+	return func(d *Device) error {
+		d.ctrl = &logController{d.ctrl, d, l}
+		if d.usb != nil {
+			d.usb = logUSBDevice{d.usb, d, l}
+		}
+		return nil
+	}
+}
+
+// logController logs the control transfers performed through a
+// controller.
+type logController struct {
+	Controller
+	d *Device
+	l *slog.Logger
+}
+
+func (c *logController) Control(rType, request uint8, val, idx uint16, data []byte) (int, error) {
+	t := c.d.clock.Now()
+	n, err := c.Controller.Control(rType, request, val, idx, data)
+	// The attributes are only built if the record is logged, as control
+	// transfers are frequent in monitoring loops.
+	if ctx := context.Background(); c.l.Enabled(ctx, slog.LevelDebug) {
+		logTransfer(ctx, c.d, c.l, "control transfer", c.d.clock.Now().Sub(t), len(data), n, err,
+			slog.String("type", fmt.Sprintf("0x%02x", rType)),
+			slog.String("request", fmt.Sprintf("0x%02x", request)),
+			slog.String("value", fmt.Sprintf("0x%04x", val)),
+			slog.String("index", fmt.Sprintf("0x%04x", idx)))
+	}
+	return n, err
+}
+
+// Close closes the logged controller, if it can be closed.
+func (c *logController) Close() error {
+	if x, ok := c.Controller.(io.Closer); ok {
+		return x.Close()
+	}
+	return nil
+}
+
+// logUSBDevice logs the bulk transfers through the interfaces of a USB
+// device.
+type logUSBDevice struct {
+	USBDevice
+	d *Device
+	l *slog.Logger
+}
+
+func (u logUSBDevice) Interface(num int) (USBInterface, error) {
+	i, err := u.USBDevice.Interface(num)
+	if err != nil {
+		return nil, err
+	}
+	return logUSBInterface{i, u.d, u.l.With(slog.Int("interface", num))}, nil
+}
+
+// logUSBInterface logs the bulk transfers through the endpoints of an
+// interface.
+type logUSBInterface struct {
+	USBInterface
+	d *Device
+	l *slog.Logger
+}
+
+func (u logUSBInterface) InEndpoint(n int) (BulkIn, error) {
+	e, err := u.USBInterface.InEndpoint(n)
+	if err != nil {
+		return nil, err
+	}
+	b := logBulkIn{e, u.d, u.l.With(slog.Int("endpoint", n))}
+	if _, ok := e.(StreamingBulkIn); ok {
+		return logStreamingBulkIn{b}, nil
+	}
+	return b, nil
+}
+
+func (u logUSBInterface) OutEndpoint(n int) (BulkOut, error) {
+	e, err := u.USBInterface.OutEndpoint(n)
+	if err != nil {
+		return nil, err
+	}
+	b := logBulkOut{e, u.d, u.l.With(slog.Int("endpoint", n))}
+	if _, ok := e.(StreamingBulkOut); ok {
+		return logStreamingBulkOut{b}, nil
+	}
+	return b, nil
+}
+
+// logBulkIn logs the transfers from a bulk IN endpoint.
+type logBulkIn struct {
+	BulkIn
+	d *Device
+	l *slog.Logger
+}
+
+func (b logBulkIn) ReadContext(ctx context.Context, p []byte) (int, error) {
+	t := b.d.clock.Now()
+	n, err := b.BulkIn.ReadContext(ctx, p)
+	logTransfer(ctx, b.d, b.l, "bulk in transfer", b.d.clock.Now().Sub(t), len(p), n, err)
+	return n, err
+}
+
+// logBulkOut logs the transfers to a bulk OUT endpoint.
+type logBulkOut struct {
+	BulkOut
+	d *Device
+	l *slog.Logger
+}
+
+func (b logBulkOut) WriteContext(ctx context.Context, p []byte) (int, error) {
+	t := b.d.clock.Now()
+	n, err := b.BulkOut.WriteContext(ctx, p)
+	logTransfer(ctx, b.d, b.l, "bulk out transfer", b.d.clock.Now().Sub(t), len(p), n, err)
+	return n, err
+}
+
+// logStreamingBulkIn logs the transfers from a bulk IN endpoint which
+// supports streams, and the reads from its streams.
+type logStreamingBulkIn struct{ logBulkIn }
+
+func (b logStreamingBulkIn) NewStream(size, count int) (BulkInStream, error) {
+	s, err := b.BulkIn.(StreamingBulkIn).NewStream(size, count)
+	if err != nil {
+		return nil, err
+	}
+	return logBulkInStream{logBulkIn{s, b.d, b.l.With(slog.Int("transfers", count))}, s}, nil
+}
+
+// logBulkInStream logs the reads from a bulk IN stream.
+type logBulkInStream struct {
+	logBulkIn
+	io.Closer
+}
+
+// logStreamingBulkOut logs the transfers to a bulk OUT endpoint which
+// supports streams, and the writes to its streams.
+type logStreamingBulkOut struct{ logBulkOut }
+
+func (b logStreamingBulkOut) NewStream(size, count int) (BulkOutStream, error) {
+	s, err := b.BulkOut.(StreamingBulkOut).NewStream(size, count)
+	if err != nil {
+		return nil, err
+	}
+	return logBulkOutStream{logBulkOut{s, b.d, b.l.With(slog.Int("transfers", count))}, s}, nil
+}
+
+// logBulkOutStream logs the writes to a bulk OUT stream.
+type logBulkOutStream struct {
+	logBulkOut
+	s BulkOutStream
+}
+
+func (b logBulkOutStream) CloseContext(ctx context.Context) error { return b.s.CloseContext(ctx) }
+
+// logTransfer logs a transfer of n out of want bytes of device d at debug
+// level.  The serial number is looked up for every record, as the device
+// is logged before its descriptor has been read.
+func logTransfer(ctx context.Context, d *Device, l *slog.Logger, msg string, dur time.Duration, want, n int, err error, attr ...slog.Attr) {
+	if !l.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+	attr = append([]slog.Attr{slog.String("serial", d.Serial().String())}, attr...)
+	attr = append(attr, slog.Int("length", want), slog.Int("transferred", n), slog.Duration("duration", dur))
+	if err != nil {
+		attr = append(attr, slog.String("error", err.Error()))
+	}
+	l.LogAttrs(ctx, slog.LevelDebug, msg, attr...)
+}
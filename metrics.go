@@ -0,0 +1,146 @@
+package ztex
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CommandMetrics holds the counters of a vendor command, accumulated over
+// all its control transfers since the device was opened.
+type CommandMetrics struct {
+	Calls   uint64
+	Errors  uint64
+	Bytes   uint64
+	Latency time.Duration
+}
+
+// String returns a human-readable description of the counters.
+func (c CommandMetrics) String() string {
+	x := []string{}
+	x = append(x, fmt.Sprintf("Calls(%v)", c.Calls))
+	x = append(x, fmt.Sprintf("Errors(%v)", c.Errors))
+	x = append(x, fmt.Sprintf("Bytes(%v)", c.Bytes))
+	x = append(x, fmt.Sprintf("Latency(%v)", c.Latency))
+	return strings.Join(x, ", ")
+}
+
+// Metrics maps vendor request numbers, such as 0x30 for the FPGA status,
+// to the counters of the command.
+type Metrics map[uint8]CommandMetrics
+
+// String returns a human-readable description of the counters of all
+// commands.
+func (m Metrics) String() string {
+	x := []string{}
+	for _, r := range m.Requests() {
+		x = append(x, fmt.Sprintf("VR 0x%02x(%v)", r, m[r]))
+	}
+	return strings.Join(x, ", ")
+}
+
+// Requests returns the vendor request numbers of the commands, in
+// increasing order.
+func (m Metrics) Requests() []uint8 {
+	x := []uint8{}
+	for r := range m {
+		x = append(x, r)
+	}
+	sort.Slice(x, func(i, j int) bool { return x[i] < x[j] })
+	return x
+}
+
+// Metrics returns a snapshot of the counters of every vendor command which
+// has been issued to the device.  Only transfer errors are counted as
+// errors; commands which transferred fewer bytes than expected are not.
+func (d *Device) Metrics() Metrics {
+	d.metricsMu.Lock()
+	defer d.metricsMu.Unlock()
+
+	x := Metrics{}
+	for r, c := range d.metrics {
+		x[r] = c
+	}
+	return x
+}
+
+// record adds a control transfer of n bytes, which took dur, to the
+// counters of its vendor command.
+func (d *Device) record(request uint8, n int, dur time.Duration, err error) {
+	d.metricsMu.Lock()
+	defer d.metricsMu.Unlock()
+
+	if d.metrics == nil {
+		d.metrics = Metrics{}
+	}
+	c := d.metrics[request]
+	c.Calls++
+	if err != nil {
+		c.Errors++
+	}
+	if n > 0 {
+		c.Bytes += uint64(n)
+	}
+	c.Latency += dur
+	d.metrics[request] = c
+}
+
+// BulkMetrics holds the counters of the bulk transfers in one direction,
+// through streams and high-speed FPGA configuration, accumulated since the
+// device was opened.
+type BulkMetrics struct {
+	Transfers uint64
+	Errors    uint64
+	Bytes     uint64
+	Duration  time.Duration
+}
+
+// String returns a human-readable description of the counters.
+func (b BulkMetrics) String() string {
+	x := []string{}
+	x = append(x, fmt.Sprintf("Transfers(%v)", b.Transfers))
+	x = append(x, fmt.Sprintf("Errors(%v)", b.Errors))
+	x = append(x, fmt.Sprintf("Bytes(%v)", b.Bytes))
+	x = append(x, fmt.Sprintf("Duration(%v)", b.Duration))
+	x = append(x, fmt.Sprintf("Throughput(%.0f B/s)", b.Throughput()))
+	return strings.Join(x, ", ")
+}
+
+// Throughput returns the average rate of the bulk transfers in bytes per
+// second, over the time during which they were in progress, or zero if
+// no time was spent in bulk transfers.
+func (b BulkMetrics) Throughput() float64 {
+	if b.Duration <= 0 {
+		return 0
+	}
+	return float64(b.Bytes) / b.Duration.Seconds()
+}
+
+// BulkMetrics returns a snapshot of the counters of the bulk transfers
+// to and from the device.
+func (d *Device) BulkMetrics() (out, in BulkMetrics) {
+	d.metricsMu.Lock()
+	defer d.metricsMu.Unlock()
+	return d.bulkOut, d.bulkIn
+}
+
+// recordBulk adds a bulk transfer of n bytes, which took dur, to the
+// counters of its direction.
+func (d *Device) recordBulk(in bool, n int, dur time.Duration, err error) {
+	d.metricsMu.Lock()
+	defer d.metricsMu.Unlock()
+
+	c := &d.bulkOut
+	if in {
+		c = &d.bulkIn
+	}
+	c.Transfers++
+	if err != nil {
+		c.Errors++
+	}
+	if n > 0 {
+		c.Bytes += uint64(n)
+	}
+	c.Duration += dur
+}
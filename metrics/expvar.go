@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"expvar"
+	"fmt"
+)
+
+// Publish publishes the readings of all devices in the collector as an
+// expvar variable with the given name.  The variable is a map from serial
+// number to device readings, which are read afresh every time the
+// variable is evaluated, and to the counters of the vendor commands
+// issued to the device, keyed by request number.  Like expvar.Publish,
+// Publish panics if the name is already registered.
+func (c *Collector) Publish(name string) {
+	expvar.Publish(name, expvar.Func(c.expvar))
+}
+
+func (c *Collector) expvar() any {
+	x := map[string]any{}
+	for _, s := range c.collect() {
+		y := map[string]any{}
+		switch {
+		case s.sensorsErr != nil:
+			y["sensors_error"] = s.sensorsErr.Error()
+		case s.sensors != nil:
+			z := map[string]float64{}
+			for _, r := range s.sensors.SensorReadings {
+				z[fmt.Sprintf("%v%v", r.SensorKind, r.SensorChannel.Number())] = r.SensorValue.Number()
+			}
+			y["sensors"] = z
+		}
+		switch {
+		case s.fpgaErr != nil:
+			y["fpga_error"] = s.fpgaErr.Error()
+		case s.fpga != nil:
+			y["fpga_configured"] = s.fpga.FPGAConfigured.Bool()
+		}
+		switch {
+		case s.flashErr != nil:
+			y["flash_error"] = s.flashErr.Error()
+		case s.flash != nil:
+			y["flash_error_code"] = uint8(s.flash.FlashError)
+		}
+		if len(s.commands) != 0 {
+			z := map[string]any{}
+			for _, r := range s.commands.Requests() {
+				c := s.commands[r]
+				z[fmt.Sprintf("0x%02x", r)] = map[string]any{
+					"calls":           c.Calls,
+					"errors":          c.Errors,
+					"bytes":           c.Bytes,
+					"latency_seconds": c.Latency.Seconds(),
+				}
+			}
+			y["commands"] = z
+		}
+		if b := s.bulk(); len(b) != 0 {
+			z := map[string]any{}
+			for k, c := range b {
+				z[k] = map[string]any{
+					"transfers":                   c.Transfers,
+					"errors":                      c.Errors,
+					"bytes":                       c.Bytes,
+					"throughput_bytes_per_second": c.Throughput(),
+				}
+			}
+			y["bulk"] = z
+		}
+		x[s.serial] = y
+	}
+	return x
+}
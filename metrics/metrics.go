@@ -0,0 +1,99 @@
+// Package metrics publishes the sensor readings, status, and bulk
+// throughput of ZTEX modules through expvar and Prometheus.
+package metrics
+
+import (
+	"sync"
+
+	"github.com/aljumi/ztex"
+)
+
+// Collector gathers sensor readings and status from a set of devices.
+// Devices are keyed by their serial number.
+type Collector struct {
+	mu      sync.Mutex
+	devices map[string]*ztex.Device
+}
+
+// NewCollector returns a collector for the given devices.
+func NewCollector(d ...*ztex.Device) *Collector {
+	c := &Collector{devices: map[string]*ztex.Device{}}
+	for _, x := range d {
+		c.Add(x)
+	}
+	return c
+}
+
+// Add adds a device to the collector, replacing any device with the same
+// serial number.
+func (c *Collector) Add(d *ztex.Device) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.devices[d.Serial().String()] = d
+}
+
+// Remove removes the device with the given serial number from the
+// collector.
+func (c *Collector) Remove(serial string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.devices, serial)
+}
+
+// sample holds the readings of a single device.  Readings which are not
+// supported by the device, or which could not be read, are nil and the
+// corresponding error is recorded.
+type sample struct {
+	serial string
+
+	sensors    *ztex.SensorStatus
+	sensorsErr error
+	fpga       *ztex.FPGAStatus
+	fpgaErr    error
+	flash      *ztex.FlashStatus
+	flashErr   error
+	commands   ztex.Metrics
+	bulkOut    ztex.BulkMetrics
+	bulkIn     ztex.BulkMetrics
+}
+
+// collect reads all devices in the collector, concurrently.
+func (c *Collector) collect() []sample {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	x := make([]sample, 0, len(c.devices))
+	for k := range c.devices {
+		x = append(x, sample{serial: k})
+	}
+
+	var wg sync.WaitGroup
+	for i := range x {
+		wg.Add(1)
+		go func(s *sample, d *ztex.Device) {
+			defer wg.Done()
+			t, _ := d.CombinedStatus()
+			s.sensors, s.sensorsErr = t.Sensors, t.SensorsErr
+			s.fpga, s.fpgaErr = t.FPGA, t.FPGAErr
+			s.flash, s.flashErr = t.Flash, t.FlashErr
+			s.commands = d.Metrics()
+			s.bulkOut, s.bulkIn = d.BulkMetrics()
+		}(&x[i], c.devices[x[i].serial])
+	}
+	wg.Wait()
+
+	return x
+}
+
+// bulk returns the counters of the bulk transfers of the sample which
+// have been made, keyed by direction.
+func (s sample) bulk() map[string]ztex.BulkMetrics {
+	x := map[string]ztex.BulkMetrics{}
+	if s.bulkOut.Transfers != 0 {
+		x["out"] = s.bulkOut
+	}
+	if s.bulkIn.Transfers != 0 {
+		x["in"] = s.bulkIn
+	}
+	return x
+}
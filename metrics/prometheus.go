@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	sensorDesc = prometheus.NewDesc(
+		"ztex_sensor_value",
+		"Sensor reading in degrees Celsius, volts, or amperes.",
+		[]string{"serial", "channel", "kind"}, nil,
+	)
+	fpgaConfiguredDesc = prometheus.NewDesc(
+		"ztex_fpga_configured",
+		"Whether or not the FPGA is configured.",
+		[]string{"serial"}, nil,
+	)
+	flashErrorDesc = prometheus.NewDesc(
+		"ztex_flash_error",
+		"Error code of the last flash memory operation.",
+		[]string{"serial"}, nil,
+	)
+	bulkThroughputDesc = prometheus.NewDesc(
+		"ztex_bulk_throughput_bytes_per_second",
+		"Average rate of the bulk transfers while they were in progress.",
+		[]string{"serial", "direction"}, nil,
+	)
+)
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- sensorDesc
+	ch <- fpgaConfiguredDesc
+	ch <- flashErrorDesc
+	ch <- bulkThroughputDesc
+}
+
+// Collect implements prometheus.Collector.  Devices are read afresh on
+// every scrape.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, s := range c.collect() {
+		switch {
+		case s.sensorsErr != nil:
+			ch <- prometheus.NewInvalidMetric(sensorDesc, s.sensorsErr)
+		case s.sensors != nil:
+			for _, r := range s.sensors.SensorReadings {
+				ch <- prometheus.MustNewConstMetric(sensorDesc, prometheus.GaugeValue,
+					r.SensorValue.Number(), s.serial,
+					fmt.Sprintf("%v", r.SensorChannel.Number()), strings.ToLower(r.SensorKind.String()))
+			}
+		}
+		switch {
+		case s.fpgaErr != nil:
+			ch <- prometheus.NewInvalidMetric(fpgaConfiguredDesc, s.fpgaErr)
+		case s.fpga != nil:
+			v := 0.0
+			if s.fpga.FPGAConfigured.Bool() {
+				v = 1
+			}
+			ch <- prometheus.MustNewConstMetric(fpgaConfiguredDesc, prometheus.GaugeValue, v, s.serial)
+		}
+		switch {
+		case s.flashErr != nil:
+			ch <- prometheus.NewInvalidMetric(flashErrorDesc, s.flashErr)
+		case s.flash != nil:
+			ch <- prometheus.MustNewConstMetric(flashErrorDesc, prometheus.GaugeValue, float64(s.flash.FlashError), s.serial)
+		}
+		for k, b := range s.bulk() {
+			ch <- prometheus.MustNewConstMetric(bulkThroughputDesc, prometheus.GaugeValue, b.Throughput(), s.serial, k)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package ztex
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ModuleInfo is the decoded module-specific part of a ZTEX descriptor.  Its
+// layout depends on the firmware; see (DescriptorConfig).Module.
+type ModuleInfo interface {
+	fmt.Stringer
+
+	// Raw returns the undecoded module-specific bytes.
+	Raw() []byte
+}
+
+// String returns the module-specific bytes in hexadecimal.
+func (d DescriptorModule) String() string { return fmt.Sprintf("% x", d[:]) }
+
+// Raw returns the module-specific bytes.
+func (d DescriptorModule) Raw() []byte { return append([]byte{}, d[:]...) }
+
+// DefaultModule is the module-specific part of the ZTEX descriptor of the
+// default firmware, which announces the version of the default firmware
+// interface and the bulk endpoints of its stream.
+type DefaultModule struct {
+	Version     uint8
+	OutEndpoint uint8
+	InEndpoint  uint8
+
+	raw DescriptorModule
+}
+
+// String returns a human-readable description of the default firmware
+// interface.
+func (d DefaultModule) String() string {
+	x := []string{}
+	x = append(x, fmt.Sprintf("Version(%v)", d.Version))
+	x = append(x, fmt.Sprintf("Out Endpoint(%v)", d.OutEndpoint))
+	x = append(x, fmt.Sprintf("In Endpoint(%v)", d.InEndpoint))
+	return strings.Join(x, ", ")
+}
+
+// Raw returns the module-specific bytes.
+func (d DefaultModule) Raw() []byte { return d.raw.Raw() }
+
+// AtLeast returns true if and only if the version of the default firmware
+// interface is v or newer.
+func (d DefaultModule) AtLeast(v uint8) bool { return d.Version >= v }
+
+// Module decodes the module-specific part of the descriptor according to
+// the firmware which announced it.  Devices running the default firmware
+// yield a DefaultModule; for other firmware the layout is not documented,
+// so the DescriptorModule itself is returned.
+func (d DescriptorConfig) Module() ModuleInfo {
+	m := d.DescriptorModule
+	if d.DescriptorCapability.DefaultFirmware() {
+		return DefaultModule{m[0], m[1], m[2], m}
+	}
+	return m
+}
+
+// ForceUnsupportedCommands disables the checks of the version of the
+// default firmware interface before commands introduced in later versions,
+// for custom firmware which implements the commands without announcing a
+// recent enough version.  Capabilities are still checked.
+func ForceUnsupportedCommands() DeviceOption {
+	return func(d *Device) error {
+		d.force = true
+		return nil
+	}
+}
+
+// requireDefault returns an error wrapping ErrNotSupported unless the
+// device runs the default firmware with an interface of version v or newer,
+// which is needed for the named feature.
+func (d *Device) requireDefault(v uint8, feature string) error {
+	m, ok := d.DescriptorConfig.Module().(DefaultModule)
+	if !ok {
+		return ErrNotSupported
+	} else if !d.force && !m.AtLeast(v) {
+		return fmt.Errorf("%w: %v needs default firmware interface version %v, got version %v", ErrNotSupported, feature, v, m.Version)
+	}
+	return nil
+}
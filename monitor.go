@@ -0,0 +1,301 @@
+package ztex
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MonitorLevel indicates the severity of a sensor reading with respect to
+// its configured thresholds.
+type MonitorLevel uint8
+
+// String returns a human-readable description of the monitor level.
+func (m MonitorLevel) String() string {
+	switch m {
+	case 0:
+		return "Normal"
+	case 1:
+		return "Warning"
+	case 2:
+		return "Critical"
+	default:
+		return Unknown
+	}
+}
+
+// IsKnown returns true if and only if the monitor level has a known name.
+func (m MonitorLevel) IsKnown() bool { return isKnown(m.String()) }
+
+// Number returns the raw numeric representation of the monitor level.
+func (m MonitorLevel) Number() uint8 { return uint8(m) }
+
+// Threshold configures the warning and critical levels of a sensor.  A
+// reading enters a level when it reaches the corresponding limit and
+// leaves it only once it drops below the limit by more than Hysteresis,
+// so that readings hovering around a limit do not cause a flood of events.
+type Threshold struct {
+	Warning    float64
+	Critical   float64
+	Hysteresis float64
+}
+
+// String returns a human-readable description of the threshold.
+func (t Threshold) String() string {
+	x := []string{}
+	x = append(x, fmt.Sprintf("Warning(%v)", t.Warning))
+	x = append(x, fmt.Sprintf("Critical(%v)", t.Critical))
+	x = append(x, fmt.Sprintf("Hysteresis(%v)", t.Hysteresis))
+	return strings.Join(x, ", ")
+}
+
+// level returns the level of a reading v, given the previous level p.
+func (t Threshold) level(p MonitorLevel, v float64) MonitorLevel {
+	switch {
+	case v >= t.Critical:
+		return 2
+	case p == 2 && v > t.Critical-t.Hysteresis:
+		return 2
+	case v >= t.Warning:
+		return 1
+	case p >= 1 && v > t.Warning-t.Hysteresis:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// MonitorEvent describes a sensor reading that changed level.
+type MonitorEvent struct {
+	Time     time.Time
+	Reading  SensorReading
+	Previous MonitorLevel
+	Level    MonitorLevel
+}
+
+// String returns a human-readable description of the monitor event.
+func (m MonitorEvent) String() string {
+	x := []string{}
+	x = append(x, fmt.Sprintf("Time(%v)", m.Time.Format(time.RFC3339)))
+	x = append(x, fmt.Sprintf("Reading(%v)", m.Reading))
+	x = append(x, fmt.Sprintf("Previous(%v)", m.Previous))
+	x = append(x, fmt.Sprintf("Level(%v)", m.Level))
+	return strings.Join(x, ", ")
+}
+
+// ProtectionEvent describes a protective action taken by the monitor after
+// a temperature sensor remained at the critical level.
+type ProtectionEvent struct {
+	Time    time.Time
+	Reading SensorReading
+	Samples int
+	Action  string
+	Err     error
+}
+
+// String returns a human-readable description of the protection event.
+func (p ProtectionEvent) String() string {
+	x := []string{}
+	x = append(x, fmt.Sprintf("Time(%v)", p.Time.Format(time.RFC3339)))
+	x = append(x, fmt.Sprintf("Reading(%v)", p.Reading))
+	x = append(x, fmt.Sprintf("Samples(%v)", p.Samples))
+	x = append(x, fmt.Sprintf("Action(%v)", p.Action))
+	x = append(x, fmt.Sprintf("Error(%v)", p.Err))
+	return strings.Join(x, ", ")
+}
+
+// protection is a protective action registered with a monitor.
+type protection struct {
+	samples int
+	name    string
+	action  func(*Device) error
+}
+
+// Monitor periodically polls the sensors of a device and reports readings
+// that cross their configured thresholds.
+type Monitor struct {
+	d *Device
+
+	interval   time.Duration
+	thresholds map[SensorKind]Threshold
+	callbacks  []func(MonitorEvent)
+	history    *SensorHistory
+	levels     map[SensorChannel]MonitorLevel
+
+	protections []protection
+	observers   []func(ProtectionEvent)
+	critical    map[SensorChannel]int
+}
+
+// MonitorOption represents a monitor option.
+type MonitorOption func(*Monitor) error
+
+// MonitorInterval sets the interval at which the monitor polls the sensors.
+func MonitorInterval(interval time.Duration) MonitorOption {
+	return func(m *Monitor) error {
+		if interval <= 0 {
+			return fmt.Errorf("got interval %v, want positive interval", interval)
+		}
+		m.interval = interval
+		return nil
+	}
+}
+
+// MonitorThreshold sets the threshold applied to all sensors of a kind.
+func MonitorThreshold(kind SensorKind, threshold Threshold) MonitorOption {
+	return func(m *Monitor) error {
+		if threshold.Warning > threshold.Critical {
+			return fmt.Errorf("got warning level %v above critical level %v", threshold.Warning, threshold.Critical)
+		} else if threshold.Hysteresis < 0 {
+			return fmt.Errorf("got hysteresis %v, want non-negative hysteresis", threshold.Hysteresis)
+		}
+		m.thresholds[kind] = threshold
+		return nil
+	}
+}
+
+// MonitorCallback registers a function that is called whenever a sensor
+// reading changes level.  Callbacks are called synchronously from the
+// polling goroutine, in the order in which they were registered.
+func MonitorCallback(callback func(MonitorEvent)) MonitorOption {
+	return func(m *Monitor) error {
+		m.callbacks = append(m.callbacks, callback)
+		return nil
+	}
+}
+
+// MonitorHistory records every reading taken by the monitor in a history.
+func MonitorHistory(history *SensorHistory) MonitorOption {
+	return func(m *Monitor) error {
+		m.history = history
+		return nil
+	}
+}
+
+// MonitorProtection registers an action that is taken once a temperature
+// sensor has been at the critical level for the given number of
+// consecutive samples.  The action is taken again only after the sensor
+// has left the critical level.  Methods such as (*Device).ResetFPGA can
+// be used as actions directly.
+func MonitorProtection(samples int, name string, action func(*Device) error) MonitorOption {
+	return func(m *Monitor) error {
+		if samples <= 0 {
+			return fmt.Errorf("got %v samples, want positive number of samples", samples)
+		}
+		m.protections = append(m.protections, protection{samples, name, action})
+		return nil
+	}
+}
+
+// MonitorProtectionCallback registers a function that is called after
+// every protective action taken by the monitor, whether or not the action
+// succeeded.
+func MonitorProtectionCallback(callback func(ProtectionEvent)) MonitorOption {
+	return func(m *Monitor) error {
+		m.observers = append(m.observers, callback)
+		return nil
+	}
+}
+
+// NewMonitor returns a monitor for the sensors of a device.  The sensors
+// are polled once per second unless configured otherwise.
+func NewMonitor(d *Device, opt ...MonitorOption) (*Monitor, error) {
+	if !d.Capability().TemperatureSensor() {
+		return nil, ErrNotSupported
+	}
+
+	m := &Monitor{
+		d:          d,
+		interval:   time.Second,
+		thresholds: map[SensorKind]Threshold{},
+		levels:     map[SensorChannel]MonitorLevel{},
+		critical:   map[SensorChannel]int{},
+	}
+
+	for _, o := range opt {
+		if err := o(m); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// Device returns the device whose sensors the monitor polls.
+func (m *Monitor) Device() *Device { return m.d }
+
+// Poll reads the sensors once, calls the registered callbacks for every
+// reading that changed level, and takes any protective actions that are
+// due.
+func (m *Monitor) Poll() (*SensorStatus, error) {
+	s, err := m.d.SensorStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	now := m.d.clock.Now()
+	if m.history != nil {
+		m.history.Add(now, s)
+	}
+
+	for _, r := range s.SensorReadings {
+		t, ok := m.thresholds[r.SensorKind]
+		if !ok {
+			continue
+		}
+		p := m.levels[r.SensorChannel]
+		l := t.level(p, r.SensorValue.Number())
+		if l != p {
+			m.levels[r.SensorChannel] = l
+			for _, c := range m.callbacks {
+				c(MonitorEvent{now, r, p, l})
+			}
+		}
+		if r.SensorKind == 1 {
+			m.protect(now, r, l)
+		}
+	}
+
+	return s, nil
+}
+
+// protect counts consecutive critical samples of a temperature sensor and
+// takes the protective actions which have become due.
+func (m *Monitor) protect(now time.Time, r SensorReading, l MonitorLevel) {
+	if l != 2 {
+		delete(m.critical, r.SensorChannel)
+		return
+	}
+
+	m.critical[r.SensorChannel]++
+	n := m.critical[r.SensorChannel]
+	for _, p := range m.protections {
+		if p.samples != n {
+			continue
+		}
+		err := p.action(m.d)
+		for _, o := range m.observers {
+			o(ProtectionEvent{now, r, n, p.name, err})
+		}
+	}
+}
+
+// Run polls the sensors at the configured interval until the context is
+// done or a poll fails.
+func (m *Monitor) Run(ctx context.Context) error {
+	t := m.d.clock.NewTicker(m.interval)
+	defer t.Stop()
+
+	for {
+		if _, err := m.Poll(); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C():
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package ztex_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aljumi/ztex"
+	"github.com/aljumi/ztex/ztextest"
+)
+
+func TestMonitor(t *testing.T) {
+	d, f := openSensors(t, []byte{1, 0})
+
+	e := []ztex.MonitorEvent{}
+	m, err := ztex.NewMonitor(d,
+		ztex.MonitorThreshold(1, ztex.Threshold{Warning: 60, Critical: 80, Hysteresis: 5}),
+		ztex.MonitorCallback(func(x ztex.MonitorEvent) { e = append(e, x) }))
+	if err != nil {
+		t.Fatalf("ztex.NewMonitor: %v", err)
+	} else if m.Device() != d {
+		t.Errorf("(*ztex.Monitor).Device: got %p, want %p", m.Device(), d)
+	}
+
+	// Readings only leave a level once they drop below its limit by more
+	// than the hysteresis.
+	for _, v := range []uint8{50, 65, 62, 58, 85, 78, 74, 40} {
+		f.Sensors = []byte{1, v}
+		if _, err := m.Poll(); err != nil {
+			t.Fatalf("(*ztex.Monitor).Poll: %v", err)
+		}
+	}
+
+	want := []struct {
+		value           float64
+		previous, level ztex.MonitorLevel
+	}{{65, 0, 1}, {85, 1, 2}, {74, 2, 1}, {40, 1, 0}}
+	if len(e) != len(want) {
+		t.Fatalf("ztex.MonitorCallback: got events %v, want %v", e, want)
+	}
+	for i, x := range e {
+		if x.Reading.SensorValue.Number() != want[i].value || x.Previous != want[i].previous || x.Level != want[i].level {
+			t.Errorf("ztex.MonitorCallback: got event %v, want %+v", x, want[i])
+		}
+	}
+}
+
+func TestMonitorProtection(t *testing.T) {
+	d, f := openSensors(t, []byte{1, 90})
+
+	n, p := 0, []ztex.ProtectionEvent{}
+	m, err := ztex.NewMonitor(d,
+		ztex.MonitorThreshold(1, ztex.Threshold{Warning: 60, Critical: 80}),
+		ztex.MonitorProtection(2, "cool down", func(x *ztex.Device) error {
+			if x != d {
+				t.Errorf("ztex.MonitorProtection: got device %p, want %p", x, d)
+			}
+			n++
+			return nil
+		}),
+		ztex.MonitorProtectionCallback(func(x ztex.ProtectionEvent) { p = append(p, x) }))
+	if err != nil {
+		t.Fatalf("ztex.NewMonitor: %v", err)
+	}
+
+	// The action is taken once after two critical samples in a row, and
+	// again only after the reading has left the critical level.
+	for _, v := range []uint8{90, 90, 90, 50, 90, 90} {
+		f.Sensors = []byte{1, v}
+		if _, err := m.Poll(); err != nil {
+			t.Fatalf("(*ztex.Monitor).Poll: %v", err)
+		}
+	}
+	if n != 2 || len(p) != 2 || p[0].Action != "cool down" || p[0].Samples != 2 {
+		t.Errorf("ztex.MonitorProtection: got %v actions and events %v, want 2 actions after 2 samples each", n, p)
+	}
+}
+
+func TestNewMonitorErrors(t *testing.T) {
+	d, err := ztextest.Open(ztextest.NewFakeDevice("fake000001"))
+	if err != nil {
+		t.Fatalf("ztextest.Open: %v", err)
+	}
+	defer d.Close()
+	if _, err := ztex.NewMonitor(d); !errors.Is(err, ztex.ErrNotSupported) {
+		t.Errorf("ztex.NewMonitor without sensors: got %v, want %v", err, ztex.ErrNotSupported)
+	}
+
+	d, _ = openSensors(t, []byte{1, 0})
+	if _, err := ztex.NewMonitor(d, ztex.MonitorInterval(0)); err == nil {
+		t.Errorf("ztex.NewMonitor with interval 0: got no error, want error")
+	}
+}
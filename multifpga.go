@@ -0,0 +1,169 @@
+package ztex
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MultiFPGACount indicates the number of FPGAs on the device, minus one.
+type MultiFPGACount uint8
+
+// String returns a human-readable description of the number of FPGAs.
+func (m MultiFPGACount) String() string { return fmt.Sprintf("%v", m.Number()) }
+
+// Number returns the number of FPGAs on the device.
+func (m MultiFPGACount) Number() int { return int(m) + 1 }
+
+// MultiFPGASelected indicates the index of the currently selected FPGA.
+type MultiFPGASelected uint8
+
+// Number returns the index of the currently selected FPGA.
+func (m MultiFPGASelected) Number() uint8 { return uint8(m) }
+
+// MultiFPGAParallel indicates whether or not the device supports
+// configuring all FPGAs in parallel.
+type MultiFPGAParallel uint8
+
+// String returns a human-readable description of whether or not parallel
+// configuration is supported.
+func (m MultiFPGAParallel) String() string {
+	switch m {
+	case 0:
+		return "Unsupported"
+	case 1:
+		return "Supported"
+	default:
+		return Unknown
+	}
+}
+
+// IsKnown returns true if and only if the parallel configuration indicator
+// has a known name.
+func (m MultiFPGAParallel) IsKnown() bool { return isKnown(m.String()) }
+
+// Bool returns true if and only if parallel configuration is supported.
+func (m MultiFPGAParallel) Bool() bool { return m == 1 }
+
+// MultiFPGAConfig indicates the number of FPGAs on the device, which of
+// them is selected, and whether or not they can be configured in parallel.
+// The zero value describes a device with a single FPGA.
+type MultiFPGAConfig struct {
+	MultiFPGACount
+	MultiFPGASelected
+	MultiFPGAParallel
+}
+
+// String returns a human-readable description of the multi-FPGA
+// configuration.
+func (m MultiFPGAConfig) String() string {
+	x := []string{}
+	x = append(x, fmt.Sprintf("Count(%v)", m.MultiFPGACount))
+	x = append(x, fmt.Sprintf("Selected(%v)", m.MultiFPGASelected))
+	x = append(x, fmt.Sprintf("Parallel(%v)", m.MultiFPGAParallel))
+	return strings.Join(x, ", ")
+}
+
+// MarshalJSON returns a JSON representation of the multi-FPGA
+// configuration.
+func (m MultiFPGAConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Count    int   `json:"count"`
+		Selected uint8 `json:"selected"`
+		Parallel bool  `json:"parallel"`
+	}{
+		m.MultiFPGACount.Number(),
+		m.MultiFPGASelected.Number(),
+		m.MultiFPGAParallel.Bool(),
+	})
+}
+
+// FPGAErrors maps the indices of FPGAs to the errors which occurred while
+// operating on them.
+type FPGAErrors map[int]error
+
+// Error returns a description of the errors, ordered by FPGA index.
+func (f FPGAErrors) Error() string {
+	x := []string{}
+	for _, k := range f.indices() {
+		x = append(x, fmt.Sprintf("FPGA %v: %v", k, f[k]))
+	}
+	return strings.Join(x, "; ")
+}
+
+// Unwrap returns the errors, ordered by FPGA index, so that errors.Is and
+// errors.As find the error of any FPGA.
+func (f FPGAErrors) Unwrap() []error {
+	x := []error{}
+	for _, k := range f.indices() {
+		x = append(x, f[k])
+	}
+	return x
+}
+
+// indices returns the indices of the FPGAs which failed, in order.
+func (f FPGAErrors) indices() []int {
+	i := []int{}
+	for k := range f {
+		i = append(i, k)
+	}
+	sort.Ints(i)
+	return i
+}
+
+// fpgaLease pins the selection of an FPGA of a multi-FPGA device while it
+// is in use.  Any number of holders may use the selected FPGA at once,
+// such as a stream reading and writing concurrently, and selecting
+// another FPGA waits until they are all done, so that no transfer is
+// redirected to another FPGA midway.
+type fpgaLease struct {
+	mu    sync.Mutex
+	cond  sync.Cond
+	fpga  int
+	users int
+}
+
+// acquireFPGA selects FPGA i, or keeps the selected FPGA if i is
+// negative, and keeps it selected until the returned function is called.
+// The FPGA is selected again if force is true, unless it is in use.  On
+// devices with a single FPGA, it does nothing.
+func (d *Device) acquireFPGA(i int, force bool) (func(), error) {
+	if !d.Capability().MultiFPGA() {
+		return func() {}, nil
+	} else if i >= d.MultiFPGAConfig.MultiFPGACount.Number() {
+		return nil, fmt.Errorf("(*ztex.Device).SelectFPGA: got index %v, want index in [0, %v)", i, d.MultiFPGAConfig.MultiFPGACount.Number())
+	}
+
+	l := &d.fpgas
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.cond.L == nil {
+		l.cond.L = &l.mu
+	}
+	for l.users > 0 && i >= 0 && l.fpga != i {
+		l.cond.Wait()
+	}
+	if l.users == 0 {
+		if i < 0 {
+			i = int(d.MultiFPGAConfig.MultiFPGASelected)
+		} else if force || i != int(d.MultiFPGAConfig.MultiFPGASelected) {
+			if err := d.selectFPGA(i); err != nil {
+				return nil, err
+			}
+		}
+		l.fpga = i
+	}
+	l.users++
+
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if l.users--; l.users == 0 {
+			l.cond.Broadcast()
+		}
+	}, nil
+}
@@ -0,0 +1,62 @@
+package ztex_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/aljumi/ztex"
+	"github.com/aljumi/ztex/ztextest"
+)
+
+func TestFPGAErrorsUnwrap(t *testing.T) {
+	f := ztex.FPGAErrors{2: io.ErrUnexpectedEOF, 0: ztex.ErrTimeout, 1: io.EOF}
+	if got, want := f.Unwrap(), []error{ztex.ErrTimeout, io.EOF, io.ErrUnexpectedEOF}; len(got) != len(want) {
+		t.Fatalf("(ztex.FPGAErrors).Unwrap: got %v, want %v", got, want)
+	} else {
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("(ztex.FPGAErrors).Unwrap: got %v, want %v", got, want)
+				break
+			}
+		}
+	}
+
+	for _, err := range []error{ztex.ErrTimeout, io.EOF, io.ErrUnexpectedEOF} {
+		if !errors.Is(f, err) {
+			t.Errorf("errors.Is(%v, %v): got false, want true", f, err)
+		}
+	}
+	if errors.Is(f, ztex.ErrNotSupported) {
+		t.Errorf("errors.Is(%v, %v): got true, want false", f, ztex.ErrNotSupported)
+	}
+}
+
+func TestConfigureFPGAsErrors(t *testing.T) {
+	f := ztextest.NewFakeDevice("fake000001")
+	f.Descriptor[12] |= 0x80
+	f.FPGAs = 3
+	d, err := ztextest.Open(f)
+	if err != nil {
+		t.Fatalf("ztextest.Open: %v", err)
+	}
+	defer d.Close()
+
+	// The bitstreams of FPGAs 1 and 2 lack the synchronization word.
+	good := bytes.Repeat([]byte{0xff}, 1<<12)
+	copy(good[16:], []byte{0xaa, 0x99, 0x55, 0x66})
+	bad := make([]byte, 1<<12)
+	err = d.ConfigureFPGAs(context.Background(), map[int][]byte{0: good, 1: bad, 2: bad}, nil)
+
+	var fe ztex.FPGAErrors
+	if !errors.As(err, &fe) {
+		t.Fatalf("(*ztex.Device).ConfigureFPGAs: got %v, want ztex.FPGAErrors", err)
+	} else if len(fe) != 2 || fe[1] == nil || fe[2] == nil {
+		t.Fatalf("(*ztex.Device).ConfigureFPGAs: got %v, want errors of FPGAs 1 and 2", fe)
+	}
+	if u := fe.Unwrap(); len(u) != 2 || u[0] != fe[1] || u[1] != fe[2] {
+		t.Errorf("(ztex.FPGAErrors).Unwrap: got %v, want errors of FPGAs 1 and 2 in order", u)
+	}
+}
@@ -0,0 +1,51 @@
+package perf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Regression represents a benchmark whose throughput dropped between two
+// reports.
+type Regression struct {
+	Name   string  `json:"name"`
+	Method string  `json:"method,omitempty"`
+	Old    float64 `json:"old_bytes_per_second"`
+	New    float64 `json:"new_bytes_per_second"`
+}
+
+// Change returns the relative change in throughput, which is negative for
+// a regression.
+func (r Regression) Change() float64 { return r.New/r.Old - 1 }
+
+// String returns a human-readable description of the regression.
+func (r Regression) String() string {
+	x := []string{}
+	x = append(x, fmt.Sprintf("Name(%v)", r.Name))
+	if r.Method != "" {
+		x = append(x, fmt.Sprintf("Method(%v)", r.Method))
+	}
+	x = append(x, fmt.Sprintf("Old(%.1fMiB/s)", r.Old/(1<<20)))
+	x = append(x, fmt.Sprintf("New(%.1fMiB/s)", r.New/(1<<20)))
+	x = append(x, fmt.Sprintf("Change(%+.1f%%)", 100*r.Change()))
+	return strings.Join(x, ", ")
+}
+
+// Compare returns the benchmarks present in both reports whose throughput
+// in the new report is lower than in the old report by more than the
+// given fraction, such as 0.1 for 10%, and whose method is unchanged.
+// Loopback benchmarks which found mismatches in the new report are
+// reported regardless of their throughput.
+func Compare(old, new *Report, tolerance float64) []Regression {
+	x := []Regression{}
+	for _, n := range new.Results {
+		o, ok := old.Result(n.Name)
+		if !ok || o.Method != n.Method || o.BytesPerSecond == 0 {
+			continue
+		}
+		if n.BytesPerSecond < o.BytesPerSecond*(1-tolerance) || n.Mismatches != nil && *n.Mismatches != 0 {
+			x = append(x, Regression{n.Name, n.Method, o.BytesPerSecond, n.BytesPerSecond})
+		}
+	}
+	return x
+}
@@ -0,0 +1,309 @@
+// Package perf measures the performance of ZTEX modules: the time taken to
+// configure the FPGA, the throughput of the bulk endpoints, and the rate at
+// which the flash is read.  The benchmarks run against hardware or against
+// the simulator in package ztextest, and return structured results, so
+// that the results of different releases can be compared.
+package perf
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aljumi/ztex"
+)
+
+// Result represents the measurements of one benchmark.  Each run
+// transfers the same number of bytes; the throughput is derived from the
+// median duration of the runs.
+type Result struct {
+	Name           string        `json:"name"`
+	Method         string        `json:"method,omitempty"`
+	Bytes          int64         `json:"bytes"`
+	Runs           int           `json:"runs"`
+	Min            time.Duration `json:"min"`
+	Median         time.Duration `json:"median"`
+	Max            time.Duration `json:"max"`
+	BytesPerSecond float64       `json:"bytes_per_second"`
+	Mismatches     *int64        `json:"mismatches,omitempty"`
+}
+
+// String returns a human-readable description of the result.
+func (r Result) String() string {
+	x := []string{}
+	x = append(x, fmt.Sprintf("Name(%v)", r.Name))
+	if r.Method != "" {
+		x = append(x, fmt.Sprintf("Method(%v)", r.Method))
+	}
+	x = append(x, fmt.Sprintf("Bytes(%v)", r.Bytes))
+	x = append(x, fmt.Sprintf("Runs(%v)", r.Runs))
+	x = append(x, fmt.Sprintf("Median(%v)", r.Median))
+	x = append(x, fmt.Sprintf("Throughput(%.1fMiB/s)", r.BytesPerSecond/(1<<20)))
+	if r.Mismatches != nil {
+		x = append(x, fmt.Sprintf("Mismatches(%v)", *r.Mismatches))
+	}
+	return strings.Join(x, ", ")
+}
+
+// newResult summarizes the durations of the runs of a benchmark which
+// transfers n bytes per run.
+func newResult(name, method string, n int64, runs []time.Duration) Result {
+	x := slices.Clone(runs)
+	slices.Sort(x)
+	r := Result{
+		Name:   name,
+		Method: method,
+		Bytes:  n,
+		Runs:   len(x),
+		Min:    x[0],
+		Median: x[len(x)/2],
+		Max:    x[len(x)-1],
+	}
+	if r.Median > 0 {
+		r.BytesPerSecond = float64(n) / r.Median.Seconds()
+	}
+	return r
+}
+
+// Report represents the results of the benchmarks run on one device.
+type Report struct {
+	Serial   string   `json:"serial"`
+	Speed    string   `json:"speed"`
+	Firmware string   `json:"firmware"`
+	Results  []Result `json:"results"`
+}
+
+// Result returns the result of the benchmark with the given name.
+func (r *Report) Result(name string) (Result, bool) {
+	for _, x := range r.Results {
+		if x.Name == name {
+			return x, true
+		}
+	}
+	return Result{}, false
+}
+
+// The names of the benchmarks.
+const (
+	Configuration = "configuration"
+	Out           = "out"
+	In            = "in"
+	Loopback      = "loopback"
+	FlashRead     = "flash-read"
+)
+
+// Suite configures the benchmarks.  Only the benchmarks which are
+// requested, and for which the suite has what they need, are run.
+type Suite struct {
+	// Bitstream is used to measure the configuration time.  If nil, then
+	// the configuration benchmark is skipped.  Bitstreams are configured
+	// through the bulk endpoint if the device supports it.
+	Bitstream []byte
+
+	// Out, In, and Loopback request the bulk throughput benchmarks, which
+	// write to the FPGA, read from it, and write to and read back from a
+	// loopback design, verifying the data.
+	Out, In, Loopback bool
+
+	// Size is the number of bytes transferred in each direction by each
+	// run of the bulk throughput benchmarks.  If zero, then 64 MiB are
+	// transferred.
+	Size int64
+
+	// Chunk is the number of bytes passed to each read or write of the
+	// stream.  If zero, then chunks of 1 MiB are used.
+	Chunk int
+
+	// FlashSectors is the number of sectors read from the start of the
+	// flash by each run of the flash benchmark.  If zero, then the flash
+	// benchmark is skipped.
+	FlashSectors uint32
+
+	// Runs is the number of times each benchmark is run.  If zero, then
+	// each benchmark is run three times.
+	Runs int
+}
+
+// Run runs the requested benchmarks on the device.  Bulk transfers need a
+// device opened through a USB backend and a design which consumes and
+// produces data on the stream.
+func (s *Suite) Run(ctx context.Context, d *ztex.Device) (*Report, error) {
+	r := &Report{
+		Serial:   d.Serial().String(),
+		Firmware: fmt.Sprint(d.DescriptorConfig.DescriptorFirmware),
+	}
+	if d.Desc != nil {
+		r.Speed = d.Desc.Speed
+	}
+
+	runs := s.Runs
+	if runs == 0 {
+		runs = 3
+	}
+	size := s.Size
+	if size == 0 {
+		size = 64 << 20
+	}
+	chunk := s.Chunk
+	if chunk == 0 {
+		chunk = 1 << 20
+	}
+	if runs < 0 || size < 0 || chunk < 0 {
+		return nil, fmt.Errorf("perf: got %v runs of %v bytes in chunks of %v bytes, want positive numbers", runs, size, chunk)
+	}
+
+	if s.Bitstream != nil {
+		x, err := s.configuration(ctx, d, runs)
+		if err != nil {
+			return nil, err
+		}
+		r.Results = append(r.Results, x)
+	}
+
+	if s.Out || s.In || s.Loopback {
+		x, err := s.bulk(ctx, d, runs, size, chunk)
+		if err != nil {
+			return nil, err
+		}
+		r.Results = append(r.Results, x...)
+	}
+
+	if s.FlashSectors != 0 {
+		x, err := s.flash(ctx, d, runs)
+		if err != nil {
+			return nil, err
+		}
+		r.Results = append(r.Results, x)
+	}
+
+	return r, nil
+}
+
+// repeat times runs calls of f.
+func repeat(runs int, f func() error) ([]time.Duration, error) {
+	x := []time.Duration{}
+	for i := 0; i < runs; i++ {
+		t := time.Now()
+		if err := f(); err != nil {
+			return nil, err
+		}
+		x = append(x, time.Since(t))
+	}
+	return x, nil
+}
+
+func (s *Suite) configuration(ctx context.Context, d *ztex.Device, runs int) (Result, error) {
+	m, configure := "low-speed", d.ConfigureFPGA
+	if d.Capability().HighSpeedFPGAConfiguration() && d.Desc != nil {
+		m, configure = "high-speed", d.ConfigureFPGAHighSpeed
+	}
+	x, err := repeat(runs, func() error {
+		return configure(ctx, bytes.NewReader(s.Bitstream), nil)
+	})
+	if err != nil {
+		return Result{}, fmt.Errorf("perf: %v: %w", Configuration, err)
+	}
+	return newResult(Configuration, m, int64(len(s.Bitstream)), x), nil
+}
+
+func (s *Suite) bulk(ctx context.Context, d *ztex.Device, runs int, size int64, chunk int) ([]Result, error) {
+	st, err := d.OpenStream()
+	if err != nil {
+		return nil, fmt.Errorf("perf: %w", err)
+	}
+	defer st.Close()
+
+	b := make([]byte, chunk)
+	rand.New(rand.NewSource(1)).Read(b)
+
+	x := []Result{}
+	if s.Out {
+		t, err := repeat(runs, func() error { return transfer(ctx, st.WriteContext, b, size) })
+		if err != nil {
+			return nil, fmt.Errorf("perf: %v: %w", Out, err)
+		}
+		x = append(x, newResult(Out, "", size, t))
+	}
+	if s.In {
+		y := make([]byte, chunk)
+		t, err := repeat(runs, func() error { return transfer(ctx, st.ReadContext, y, size) })
+		if err != nil {
+			return nil, fmt.Errorf("perf: %v: %w", In, err)
+		}
+		x = append(x, newResult(In, "", size, t))
+	}
+	if s.Loopback {
+		e := int64(0)
+		t, err := repeat(runs, func() error {
+			n, err := loopback(ctx, st, b, size)
+			e += n
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("perf: %v: %w", Loopback, err)
+		}
+		r := newResult(Loopback, "", size, t)
+		r.Mismatches = &e
+		x = append(x, r)
+	}
+	return x, nil
+}
+
+func (s *Suite) flash(ctx context.Context, d *ztex.Device, runs int) (Result, error) {
+	f, err := d.FlashStatus()
+	if err != nil {
+		return Result{}, fmt.Errorf("perf: %v: %w", FlashRead, err)
+	}
+	n := int64(s.FlashSectors) * int64(f.FlashSector.Number())
+	b := make([]byte, n)
+	x, err := repeat(runs, func() error { return d.ReadFlash(ctx, 0, b, nil) })
+	if err != nil {
+		return Result{}, fmt.Errorf("perf: %v: %w", FlashRead, err)
+	}
+	return newResult(FlashRead, "", n, x), nil
+}
+
+// transfer transfers n bytes with repeated calls to f on b.
+func transfer(ctx context.Context, f func(context.Context, []byte) (int, error), b []byte, n int64) error {
+	for x := int64(0); x < n; {
+		m, err := f(ctx, b[:min(int64(len(b)), n-x)])
+		if err != nil {
+			return err
+		}
+		x += int64(m)
+	}
+	return nil
+}
+
+// loopback writes b repeatedly to a loopback design until n bytes have
+// been written, while reading them back concurrently, so that chunks
+// larger than the FIFO of the design do not block, and counts the bytes
+// which were not echoed unchanged.
+func loopback(ctx context.Context, s *ztex.Stream, b []byte, n int64) (int64, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	w := make(chan error, 1)
+	go func() { w <- transfer(ctx, s.WriteContext, b, n) }()
+
+	y, e := make([]byte, len(b)), int64(0)
+	for x := int64(0); x < n; {
+		m, err := s.ReadContext(ctx, y[:min(int64(len(y)), n-x)])
+		if err != nil {
+			cancel()
+			<-w
+			return e, err
+		}
+		for i := range y[:m] {
+			if y[i] != b[(x+int64(i))%int64(len(b))] {
+				e++
+			}
+		}
+		x += int64(m)
+	}
+	return e, <-w
+}
@@ -0,0 +1,67 @@
+package perf
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aljumi/ztex"
+	"github.com/aljumi/ztex/ztextest"
+)
+
+// open opens a fake device attached to the simulated bus, so that its bulk
+// endpoints are simulated.
+func open(t *testing.T, f *ztextest.FakeDevice) *ztex.Device {
+	t.Helper()
+	ds, err := ztex.OpenBackendDevices(ztextest.Backend{f})
+	if err != nil {
+		t.Fatalf("ztex.OpenBackendDevices: %v", err)
+	}
+	t.Cleanup(func() { ds[0].Close() })
+	return ds[0]
+}
+
+func TestBulk(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		loopback bool
+		suite    Suite
+		want     []string
+	}{
+		{"out and in", false, Suite{Out: true, In: true, Size: 1 << 20, Chunk: 64 << 10, Runs: 2}, []string{Out, In}},
+		{"loopback", true, Suite{Loopback: true, Size: 1 << 20, Chunk: 256 << 10, Runs: 2}, []string{Loopback}},
+		{"loopback with uneven chunks", true, Suite{Loopback: true, Size: 1<<20 + 12345, Chunk: 100 << 10, Runs: 1}, []string{Loopback}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f := ztextest.NewFakeDevice("fake000001")
+			f.Loopback = tc.loopback
+			d := open(t, f)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			r, err := tc.suite.Run(ctx, d)
+			if err != nil {
+				t.Fatalf("(*Suite).Run: %v", err)
+			}
+
+			for _, name := range tc.want {
+				x, ok := r.Result(name)
+				if !ok {
+					t.Errorf("(*Report).Result(%q): got no result, want result", name)
+					continue
+				}
+				if x.Bytes != tc.suite.Size || x.Runs != tc.suite.Runs {
+					t.Errorf("(*Report).Result(%q): got %v runs of %v bytes, want %v runs of %v bytes", name, x.Runs, x.Bytes, tc.suite.Runs, tc.suite.Size)
+				}
+				if x.Mismatches != nil && *x.Mismatches != 0 {
+					t.Errorf("(*Report).Result(%q): got %v mismatches, want 0", name, *x.Mismatches)
+				}
+			}
+
+			out, in := f.BulkBytes()
+			if want := tc.suite.Size * int64(tc.suite.Runs); out != want || in != want {
+				t.Errorf("(*ztextest.FakeDevice).BulkBytes: got %v bytes out and %v bytes in, want %v bytes each", out, in, want)
+			}
+		})
+	}
+}
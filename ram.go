@@ -1,6 +1,7 @@
 package ztex
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 )
@@ -10,43 +11,63 @@ type RAMSize uint8
 
 // String returns a human-readable representation of the RAM size.
 func (r RAMSize) String() string {
-	return binaryPrefix((uint64(r&0xf0))<<((uint(r&0xf))+16), "B")
+	return binaryPrefix(r.Bytes(), "B")
 }
 
+// Bytes returns the amount of RAM in bytes.
+func (r RAMSize) Bytes() uint64 { return uint64(r&0xf0) << (uint(r&0xf) + 16) }
+
 // Number returns a raw numeric representation of the RAM size.
 func (r RAMSize) Number() uint8 { return uint8(r) }
 
 // RAMType indicates the type of RAM available on the module.
 type RAMType uint8
 
+// The RAM types.
+const (
+	RAMDDR200    RAMType = 1
+	RAMDDR266    RAMType = 2
+	RAMDDR333    RAMType = 3
+	RAMDDR400    RAMType = 4
+	RAMDDR2_400  RAMType = 5
+	RAMDDR2_533  RAMType = 6
+	RAMDDR2_667  RAMType = 7
+	RAMDDR2_800  RAMType = 8
+	RAMDDR2_1066 RAMType = 9
+	RAMDDR3_800  RAMType = 10
+)
+
 // String returns a human-readable representation of the RAM type.
 func (r RAMType) String() string {
 	switch r {
-	case 1:
+	case RAMDDR200:
 		return "DDR-200 SDRAM"
-	case 2:
+	case RAMDDR266:
 		return "DDR-266 SDRAM"
-	case 3:
+	case RAMDDR333:
 		return "DDR-333 SDRAM"
-	case 4:
+	case RAMDDR400:
 		return "DDR-400 SDRAM"
-	case 5:
+	case RAMDDR2_400:
 		return "DDR2-400 SDRAM"
-	case 6:
+	case RAMDDR2_533:
 		return "DDR2-533 SDRAM"
-	case 7:
+	case RAMDDR2_667:
 		return "DDR2-667 SDRAM"
-	case 8:
+	case RAMDDR2_800:
 		return "DDR2-800 SDRAM"
-	case 9:
+	case RAMDDR2_1066:
 		return "DDR2-1066 SDRAM"
-	case 10:
+	case RAMDDR3_800:
 		return "DDR3-800 SDRAM"
 	default:
-		return "Unknown"
+		return Unknown
 	}
 }
 
+// IsKnown returns true if and only if the RAM type has a known name.
+func (r RAMType) IsKnown() bool { return isKnown(r.String()) }
+
 // RAMConfig indicates the size and type of the RAM in the module.
 type RAMConfig struct {
 	RAMSize
@@ -60,3 +81,20 @@ func (r RAMConfig) String() string {
 	x = append(x, fmt.Sprintf("Type(%v)", r.RAMType))
 	return strings.Join(x, ", ")
 }
+
+// MarshalJSON returns a JSON representation of the RAM configuration.
+func (r RAMConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Size      uint8  `json:"size"`
+		SizeName  string `json:"size_name"`
+		SizeBytes uint64 `json:"size_bytes"`
+		Type      uint8  `json:"type"`
+		TypeName  string `json:"type_name"`
+	}{
+		r.RAMSize.Number(),
+		r.RAMSize.String(),
+		r.RAMSize.Bytes(),
+		uint8(r.RAMType),
+		r.RAMType.String(),
+	})
+}
@@ -0,0 +1,144 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aljumi/ztex"
+	"google.golang.org/grpc"
+)
+
+// NewBackend returns a backend which opens the devices of the server at
+// the other end of the connection.  Closing the devices does not close
+// the connection.
+func NewBackend(cc grpc.ClientConnInterface) ztex.Backend { return backend{cc} }
+
+type backend struct{ cc grpc.ClientConnInterface }
+
+// invoke calls the method with the given name on the server.
+func invoke[Resp any](ctx context.Context, cc grpc.ClientConnInterface, name string, r any) (*Resp, error) {
+	x := new(Resp)
+	if err := cc.Invoke(ctx, "/"+ServiceName+"/"+name, r, x, grpc.CallContentSubtype(codecName)); err != nil {
+		return nil, fmt.Errorf("(grpc.ClientConnInterface).Invoke: %v: %w", name, err)
+	}
+	return x, nil
+}
+
+func (b backend) OpenDevices(vendor, product uint16) ([]ztex.USBDevice, error) {
+	x, err := invoke[ListResponse](context.Background(), b.cc, "List", &ListRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	devs := []ztex.USBDevice{}
+	for _, d := range x.Devices {
+		if d.Desc.Vendor == vendor && d.Desc.Product == product {
+			devs = append(devs, &client{cc: b.cc, id: d.ID, desc: d.Desc})
+		}
+	}
+	return devs, nil
+}
+
+// client is a device of the server.
+type client struct {
+	cc   grpc.ClientConnInterface
+	id   string
+	desc ztex.USBDesc
+
+	mu      sync.Mutex
+	timeout time.Duration
+}
+
+func (c *client) Control(rType, request uint8, val, idx uint16, data []byte) (int, error) {
+	c.mu.Lock()
+	t := c.timeout
+	c.mu.Unlock()
+
+	r := &ControlRequest{Device: c.id, RequestType: rType, Request: request, Value: val, Index: idx, Timeout: t}
+	if rType&0x80 != 0 {
+		r.Length = len(data)
+	} else {
+		r.Data = data
+	}
+
+	x, err := invoke[ControlResponse](context.Background(), c.cc, "Control", r)
+	if err != nil {
+		return 0, err
+	}
+	if rType&0x80 != 0 {
+		return copy(data, x.Data), nil
+	}
+	return x.N, nil
+}
+
+func (c *client) Close() error { return nil }
+
+func (c *client) Describe() ztex.USBDesc { return c.desc }
+
+func (c *client) SetControlTimeout(timeout time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.timeout = timeout
+}
+
+func (c *client) Interface(num int) (ztex.USBInterface, error) {
+	if _, err := invoke[InterfaceResponse](context.Background(), c.cc, "Claim", &InterfaceRequest{c.id, num}); err != nil {
+		return nil, err
+	}
+	return &clientInterface{c, num}, nil
+}
+
+// clientInterface is a claimed interface of a device of the server.
+type clientInterface struct {
+	c   *client
+	num int
+}
+
+func (i *clientInterface) InEndpoint(n int) (ztex.BulkIn, error) {
+	return clientEndpoint{i, n}, nil
+}
+
+func (i *clientInterface) OutEndpoint(n int) (ztex.BulkOut, error) {
+	return clientEndpoint{i, n}, nil
+}
+
+func (i *clientInterface) Close() {
+	invoke[InterfaceResponse](context.Background(), i.c.cc, "Release", &InterfaceRequest{i.c.id, i.num})
+}
+
+// clientEndpoint is a bulk endpoint of a claimed interface.  The context
+// of each transfer bounds the call to the server.  Transfers are split
+// into calls of at most DefaultMaxBulkLength bytes, so that servers with
+// the default limit accept them.
+type clientEndpoint struct {
+	i *clientInterface
+	n int
+}
+
+func (e clientEndpoint) ReadContext(ctx context.Context, p []byte) (int, error) {
+	p = p[:min(len(p), DefaultMaxBulkLength)]
+	x, err := invoke[BulkResponse](ctx, e.i.c.cc, "BulkRead", &BulkRequest{Device: e.i.c.id, Interface: e.i.num, Endpoint: e.n, Length: len(p)})
+	if err != nil {
+		return 0, err
+	}
+	return copy(p, x.Data), nil
+}
+
+func (e clientEndpoint) WriteContext(ctx context.Context, p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 {
+		k := min(len(p), DefaultMaxBulkLength)
+		x, err := invoke[BulkResponse](ctx, e.i.c.cc, "BulkWrite", &BulkRequest{Device: e.i.c.id, Interface: e.i.num, Endpoint: e.n, Data: p[:k]})
+		if err != nil {
+			return n, err
+		}
+		n += x.N
+		if x.N < k {
+			break
+		}
+		p = p[k:]
+	}
+	return n, nil
+}
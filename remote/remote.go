@@ -0,0 +1,145 @@
+// Package remote gives access to ZTEX modules attached to another host
+// through gRPC, such as the machines of a lab.
+//
+// A Server opens the modules attached to its host through a backend and
+// serves their control transfers and bulk transfers.  NewBackend returns a
+// backend which opens the modules of a server through a gRPC connection,
+// so that
+//
+//	ds, err := ztex.OpenBackendDevices(remote.NewBackend(cc))
+//
+// returns devices which support every operation of a local device,
+// including high-speed FPGA configuration and streams.
+//
+// The messages of the service are Go structs encoded as JSON by a codec
+// which this package registers with gRPC, so that neither the server nor
+// the client needs code generated from a protocol buffer definition.
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/aljumi/ztex"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+)
+
+// ServiceName is the name of the gRPC service.
+const ServiceName = "ztex.remote.Remote"
+
+// codecName is the content subtype under which the codec of the service
+// is registered.
+const codecName = "ztex-json"
+
+func init() { encoding.RegisterCodec(codec{}) }
+
+// codec encodes the messages of the service as JSON.
+type codec struct{}
+
+func (codec) Marshal(v any) ([]byte, error) { return json.Marshal(v) }
+
+func (codec) Unmarshal(b []byte, v any) error { return json.Unmarshal(b, v) }
+
+func (codec) Name() string { return codecName }
+
+// ListRequest requests the devices of the server.
+type ListRequest struct{}
+
+// ListResponse holds the devices of the server.
+type ListResponse struct {
+	Devices []Device `json:"devices"`
+}
+
+// Device identifies a device of the server.
+type Device struct {
+	ID   string       `json:"id"`
+	Desc ztex.USBDesc `json:"desc"`
+}
+
+// ControlRequest requests a control transfer.  For transfers from the
+// device, whose request type has the bit 0x80 set, Length bytes are read;
+// otherwise Data is written.  At most 0xffff bytes are transferred.  If
+// Timeout is positive, then it is the timeout of the transfer; otherwise
+// the ControlTimeout of the server applies.
+type ControlRequest struct {
+	Device      string        `json:"device"`
+	RequestType uint8         `json:"request_type"`
+	Request     uint8         `json:"request"`
+	Value       uint16        `json:"value"`
+	Index       uint16        `json:"index"`
+	Data        []byte        `json:"data,omitempty"`
+	Length      int           `json:"length,omitempty"`
+	Timeout     time.Duration `json:"timeout,omitempty"`
+}
+
+// ControlResponse holds the number of bytes transferred by a control
+// transfer and, for transfers from the device, the bytes read.
+type ControlResponse struct {
+	N    int    `json:"n"`
+	Data []byte `json:"data,omitempty"`
+}
+
+// InterfaceRequest requests claiming or releasing an interface of a
+// device.
+type InterfaceRequest struct {
+	Device    string `json:"device"`
+	Interface int    `json:"interface"`
+}
+
+// InterfaceResponse acknowledges an InterfaceRequest.
+type InterfaceResponse struct{}
+
+// BulkRequest requests a bulk transfer through an endpoint of a claimed
+// interface.  For reads, Length bytes are read; for writes, Data is
+// written.  At most the MaxBulkLength of the server is transferred.
+type BulkRequest struct {
+	Device    string `json:"device"`
+	Interface int    `json:"interface"`
+	Endpoint  int    `json:"endpoint"`
+	Data      []byte `json:"data,omitempty"`
+	Length    int    `json:"length,omitempty"`
+}
+
+// BulkResponse holds the number of bytes transferred by a bulk transfer
+// and, for reads, the bytes read.
+type BulkResponse struct {
+	N    int    `json:"n"`
+	Data []byte `json:"data,omitempty"`
+}
+
+// serviceDesc describes the service to gRPC.
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*Server)(nil),
+	Methods: []grpc.MethodDesc{
+		method("List", (*Server).list),
+		method("Control", (*Server).control),
+		method("Claim", (*Server).claim),
+		method("Release", (*Server).release),
+		method("BulkRead", (*Server).bulkRead),
+		method("BulkWrite", (*Server).bulkWrite),
+	},
+}
+
+// method returns the description of the unary method with the given name,
+// which is implemented by f.
+func method[Req, Resp any](name string, f func(*Server, context.Context, *Req) (*Resp, error)) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: name,
+		Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			r := new(Req)
+			if err := dec(r); err != nil {
+				return nil, err
+			}
+			if interceptor == nil {
+				return f(srv.(*Server), ctx, r)
+			}
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + ServiceName + "/" + name}
+			return interceptor(ctx, r, info, func(ctx context.Context, r any) (any, error) {
+				return f(srv.(*Server), ctx, r.(*Req))
+			})
+		},
+	}
+}
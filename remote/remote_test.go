@@ -0,0 +1,200 @@
+package remote
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aljumi/ztex"
+	"github.com/aljumi/ztex/ztextest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// conn is a grpc.ClientConnInterface which calls the methods of a server
+// in process, encoding the messages with the codec of the service.
+type conn struct{ s *Server }
+
+func (c conn) Invoke(ctx context.Context, method string, args, reply any, opts ...grpc.CallOption) error {
+	b, err := codec{}.Marshal(args)
+	if err != nil {
+		return err
+	}
+	for _, m := range serviceDesc.Methods {
+		if method != "/"+ServiceName+"/"+m.MethodName {
+			continue
+		}
+		x, err := m.Handler(c.s, ctx, func(v any) error { return codec{}.Unmarshal(b, v) }, nil)
+		if err != nil {
+			return err
+		}
+		b, err := codec{}.Marshal(x)
+		if err != nil {
+			return err
+		}
+		return codec{}.Unmarshal(b, reply)
+	}
+	return status.Errorf(codes.Unimplemented, "got method %v, want a method of the service", method)
+}
+
+func (c conn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return nil, status.Errorf(codes.Unimplemented, "got stream %v, want no streams", method)
+}
+
+// timeoutDevice records the control timeout in effect for each control
+// transfer.
+type timeoutDevice struct {
+	ztex.USBDevice
+	timeout  time.Duration
+	timeouts []time.Duration
+}
+
+func (t *timeoutDevice) SetControlTimeout(timeout time.Duration) { t.timeout = timeout }
+
+func (t *timeoutDevice) Control(rType, request uint8, val, idx uint16, data []byte) (int, error) {
+	t.timeouts = append(t.timeouts, t.timeout)
+	return t.USBDevice.Control(rType, request, val, idx, data)
+}
+
+// timeoutBackend opens timeoutDevices around the devices of a backend.
+type timeoutBackend struct {
+	ztex.Backend
+	devices []*timeoutDevice
+}
+
+func (b *timeoutBackend) OpenDevices(vendor, product uint16) ([]ztex.USBDevice, error) {
+	devs, err := b.Backend.OpenDevices(vendor, product)
+	x := []ztex.USBDevice{}
+	for _, d := range devs {
+		t := &timeoutDevice{USBDevice: d}
+		b.devices = append(b.devices, t)
+		x = append(x, t)
+	}
+	return x, err
+}
+
+func TestRemoteDevices(t *testing.T) {
+	s, err := NewServer(ztextest.Backend{ztextest.NewFakeDevice("fake000001"), ztextest.NewFakeDevice("fake000002")})
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	defer s.Close()
+
+	ds, err := ztex.OpenBackendDevices(NewBackend(conn{s}))
+	if err != nil {
+		t.Fatalf("ztex.OpenBackendDevices: %v", err)
+	} else if len(ds) != 2 {
+		t.Fatalf("ztex.OpenBackendDevices: got %v devices, want 2 devices", len(ds))
+	}
+	for i, want := range []string{"fake000001", "fake000002"} {
+		if got := ds[i].Serial().String(); got != want {
+			t.Errorf("(*ztex.Device).Serial: got %q, want %q", got, want)
+		}
+		if _, err := ds[i].FPGAStatus(); err != nil {
+			t.Errorf("(*ztex.Device).FPGAStatus: %v", err)
+		}
+	}
+}
+
+func TestControlTimeoutPerCall(t *testing.T) {
+	b := &timeoutBackend{Backend: ztextest.Backend{ztextest.NewFakeDevice("fake000001")}}
+	s, err := NewServer(b)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	defer s.Close()
+
+	r := &ControlRequest{Device: "001/001", RequestType: 0xc0, Request: 0x22, Length: 40}
+	for _, tc := range []struct {
+		timeout time.Duration
+		want    time.Duration
+	}{
+		{5 * time.Second, 5 * time.Second},
+		{0, DefaultControlTimeout},
+		{time.Minute, time.Minute},
+		{0, DefaultControlTimeout},
+	} {
+		r.Timeout = tc.timeout
+		if _, err := s.control(context.Background(), r); err != nil {
+			t.Fatalf("(*Server).control: %v", err)
+		}
+		d := b.devices[0]
+		if got := d.timeouts[len(d.timeouts)-1]; got != tc.want {
+			t.Errorf("(*Server).control with timeout %v: got timeout %v, want %v", tc.timeout, got, tc.want)
+		}
+	}
+}
+
+func TestRequestLengths(t *testing.T) {
+	s, err := NewServer(ztextest.Backend{ztextest.NewFakeDevice("fake000001")})
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	defer s.Close()
+	s.MaxBulkLength = 1024
+
+	for _, tc := range []struct {
+		name string
+		call func() error
+	}{
+		{"negative control length", func() error {
+			_, err := s.control(context.Background(), &ControlRequest{Device: "001/001", RequestType: 0xc0, Request: 0x22, Length: -1})
+			return err
+		}},
+		{"long control read", func() error {
+			_, err := s.control(context.Background(), &ControlRequest{Device: "001/001", RequestType: 0xc0, Request: 0x22, Length: 0x10000})
+			return err
+		}},
+		{"long control write", func() error {
+			_, err := s.control(context.Background(), &ControlRequest{Device: "001/001", RequestType: 0x40, Request: 0x31, Data: make([]byte, 0x10000)})
+			return err
+		}},
+		{"negative bulk length", func() error {
+			_, err := s.bulkRead(context.Background(), &BulkRequest{Device: "001/001", Length: -1})
+			return err
+		}},
+		{"long bulk read", func() error {
+			_, err := s.bulkRead(context.Background(), &BulkRequest{Device: "001/001", Length: 1025})
+			return err
+		}},
+		{"long bulk write", func() error {
+			_, err := s.bulkWrite(context.Background(), &BulkRequest{Device: "001/001", Data: make([]byte, 1025)})
+			return err
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.call()
+			if status.Code(err) != codes.InvalidArgument {
+				t.Errorf("got error %v, want code %v", err, codes.InvalidArgument)
+			}
+		})
+	}
+
+	if _, err := s.control(context.Background(), &ControlRequest{Device: "001/002"}); status.Code(err) != codes.NotFound {
+		t.Errorf("(*Server).control with unknown device: got error %v, want code %v", err, codes.NotFound)
+	}
+	if _, err := s.bulkRead(context.Background(), &BulkRequest{Device: "001/001", Length: 16}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("(*Server).bulkRead without a claimed interface: got error %v, want code %v", err, codes.FailedPrecondition)
+	}
+}
+
+func TestCodec(t *testing.T) {
+	b, err := codec{}.Marshal(&ControlRequest{Device: "001/002", RequestType: 0x40, Data: []byte{1, 2, 3}, Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("(codec).Marshal: %v", err)
+	} else if !strings.Contains(string(b), `"data":"AQID"`) {
+		t.Errorf("(codec).Marshal: got %s, want data encoded in base64", b)
+	}
+
+	r := &ControlRequest{}
+	if err := (codec{}).Unmarshal(b, r); err != nil {
+		t.Fatalf("(codec).Unmarshal: %v", err)
+	} else if r.Device != "001/002" || r.RequestType != 0x40 || len(r.Data) != 3 || r.Timeout != time.Second {
+		t.Errorf("(codec).Unmarshal: got %+v, want the marshaled request", r)
+	}
+	if err := (codec{}).Unmarshal([]byte("{"), r); err == nil {
+		t.Errorf("(codec).Unmarshal: got no error for malformed JSON, want error")
+	}
+}
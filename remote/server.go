@@ -0,0 +1,229 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aljumi/ztex"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultMaxBulkLength is the default limit on the number of bytes of a
+// bulk transfer served by a Server.  It keeps the messages of the service
+// within the default message size of gRPC.
+const DefaultMaxBulkLength = 1 << 20
+
+// DefaultControlTimeout is the default timeout of control transfers whose
+// request does not give one.
+const DefaultControlTimeout = time.Second
+
+// Server serves the devices attached to its host to remote clients.
+// Control transfers to the same device are serialized, and each runs with
+// the timeout of its own request, so that the timeout of one client does
+// not apply to the transfers of another.
+type Server struct {
+	// MaxBulkLength limits the number of bytes of a bulk transfer.  Longer
+	// transfers are rejected.
+	MaxBulkLength int
+
+	// ControlTimeout is the timeout of control transfers whose request
+	// does not give one.
+	ControlTimeout time.Duration
+
+	devices map[string]*device
+	order   []string
+}
+
+// device is a device served by the server, with the interfaces claimed
+// through it.
+type device struct {
+	mu   sync.Mutex
+	usb  ztex.USBDevice
+	intf map[int]ztex.USBInterface
+}
+
+// NewServer opens the ZTEX modules attached to the host through the
+// backend and returns a server for them.  If some of the modules cannot be
+// opened, then the server serves the others and the error describing the
+// failures is returned along with it.
+func NewServer(b ztex.Backend) (*Server, error) {
+	devs, err := b.OpenDevices(ztex.VendorID, ztex.ProductID)
+	if err != nil {
+		err = fmt.Errorf("(ztex.Backend).OpenDevices: %w", err)
+	}
+
+	s := &Server{MaxBulkLength: DefaultMaxBulkLength, ControlTimeout: DefaultControlTimeout, devices: map[string]*device{}}
+	for _, dev := range devs {
+		d := dev.Describe()
+		k := fmt.Sprintf("%03d/%03d", d.Bus, d.Address)
+		s.devices[k] = &device{usb: dev, intf: map[int]ztex.USBInterface{}}
+		s.order = append(s.order, k)
+	}
+	return s, err
+}
+
+// Register registers the service with a gRPC server, such as a
+// *grpc.Server.
+func (s *Server) Register(r grpc.ServiceRegistrar) {
+	r.RegisterService(&serviceDesc, s)
+}
+
+// Close releases the claimed interfaces and closes the devices.
+func (s *Server) Close() error {
+	var err error
+	for _, d := range s.devices {
+		d.mu.Lock()
+		for _, x := range d.intf {
+			x.Close()
+		}
+		d.intf = map[int]ztex.USBInterface{}
+		if e := d.usb.Close(); err == nil && e != nil {
+			err = e
+		}
+		d.mu.Unlock()
+	}
+	return err
+}
+
+// lookup returns the device with the given identifier.
+func (s *Server) lookup(id string) (*device, error) {
+	d, ok := s.devices[id]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "got device %q, want the identifier of a device", id)
+	}
+	return d, nil
+}
+
+// endpoint returns the claimed interface of a bulk request.
+func (s *Server) endpoint(r *BulkRequest) (ztex.USBInterface, error) {
+	if r.Length < 0 || r.Length > s.MaxBulkLength || len(r.Data) > s.MaxBulkLength {
+		return nil, status.Errorf(codes.InvalidArgument, "got %v bytes, want at most %v bytes", max(r.Length, len(r.Data)), s.MaxBulkLength)
+	}
+
+	d, err := s.lookup(r.Device)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	x, ok := d.intf[r.Interface]
+	if !ok {
+		return nil, status.Errorf(codes.FailedPrecondition, "got interface %v, want a claimed interface", r.Interface)
+	}
+	return x, nil
+}
+
+func (s *Server) list(ctx context.Context, r *ListRequest) (*ListResponse, error) {
+	x := &ListResponse{Devices: []Device{}}
+	for _, k := range s.order {
+		x.Devices = append(x.Devices, Device{k, s.devices[k].usb.Describe()})
+	}
+	return x, nil
+}
+
+func (s *Server) control(ctx context.Context, r *ControlRequest) (*ControlResponse, error) {
+	d, err := s.lookup(r.Device)
+	if err != nil {
+		return nil, err
+	} else if r.Length < 0 || r.Length > 0xffff || len(r.Data) > 0xffff {
+		return nil, status.Errorf(codes.InvalidArgument, "got %v bytes, want at most %v bytes", max(r.Length, len(r.Data)), 0xffff)
+	}
+
+	b := r.Data
+	if r.RequestType&0x80 != 0 {
+		b = make([]byte, r.Length)
+	}
+
+	t := r.Timeout
+	if t <= 0 {
+		t = s.ControlTimeout
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.usb.SetControlTimeout(t)
+	n, err := d.usb.Control(r.RequestType, r.Request, r.Value, r.Index, b)
+	if err != nil {
+		return nil, err
+	}
+
+	x := &ControlResponse{N: n}
+	if r.RequestType&0x80 != 0 {
+		x.Data = b[:n]
+	}
+	return x, nil
+}
+
+func (s *Server) claim(ctx context.Context, r *InterfaceRequest) (*InterfaceResponse, error) {
+	d, err := s.lookup(r.Device)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.intf[r.Interface]; ok {
+		return nil, status.Errorf(codes.FailedPrecondition, "got interface %v, want an interface which is not claimed", r.Interface)
+	}
+	x, err := d.usb.Interface(r.Interface)
+	if err != nil {
+		return nil, err
+	}
+	d.intf[r.Interface] = x
+	return &InterfaceResponse{}, nil
+}
+
+func (s *Server) release(ctx context.Context, r *InterfaceRequest) (*InterfaceResponse, error) {
+	d, err := s.lookup(r.Device)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if x, ok := d.intf[r.Interface]; ok {
+		x.Close()
+		delete(d.intf, r.Interface)
+	}
+	return &InterfaceResponse{}, nil
+}
+
+func (s *Server) bulkRead(ctx context.Context, r *BulkRequest) (*BulkResponse, error) {
+	x, err := s.endpoint(r)
+	if err != nil {
+		return nil, err
+	}
+	e, err := x.InEndpoint(r.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, r.Length)
+	n, err := e.ReadContext(ctx, b)
+	if err != nil {
+		return nil, err
+	}
+	return &BulkResponse{N: n, Data: b[:n]}, nil
+}
+
+func (s *Server) bulkWrite(ctx context.Context, r *BulkRequest) (*BulkResponse, error) {
+	x, err := s.endpoint(r)
+	if err != nil {
+		return nil, err
+	}
+	e, err := x.OutEndpoint(r.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := e.WriteContext(ctx, r.Data)
+	if err != nil {
+		return nil, err
+	}
+	return &BulkResponse{N: n}, nil
+}
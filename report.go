@@ -0,0 +1,202 @@
+package ztex
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ReportFormat selects the format of a device report.
+type ReportFormat uint8
+
+// The report formats.
+const (
+	ReportText     ReportFormat = 0 // aligned plain text
+	ReportMarkdown ReportFormat = 1 // a Markdown table per section
+	ReportJSON     ReportFormat = 2 // a JSON array of sections
+)
+
+// String returns a human-readable name of the report format.
+func (r ReportFormat) String() string {
+	switch r {
+	case ReportText:
+		return "Text"
+	case ReportMarkdown:
+		return "Markdown"
+	case ReportJSON:
+		return "JSON"
+	default:
+		return Unknown
+	}
+}
+
+// section is a titled part of a device report.
+type section struct {
+	title  string
+	fields []field
+}
+
+// Report writes a report on the device in the given format, covering its
+// descriptor, capabilities, configuration, and the statuses which it
+// supports.  Failures to read a status are recorded in the report, so
+// that a report can be produced for a malfunctioning device; only errors
+// writing to w, or reading a configuration deferred by DeferDeviceConfig,
+// are returned.
+func (d *Device) Report(w io.Writer, f ReportFormat) error {
+	if err := d.LoadDeviceConfig(); err != nil {
+		return err
+	}
+
+	x := d.report()
+	switch f {
+	case ReportText:
+		return writeTextReport(w, x)
+	case ReportMarkdown:
+		return writeMarkdownReport(w, x)
+	case ReportJSON:
+		return writeJSONReport(w, x)
+	default:
+		return fmt.Errorf("(*ztex.Device).Report: got format %v, want text, Markdown, or JSON", uint8(f))
+	}
+}
+
+// report collects the sections of the report on the device.
+func (d *Device) report() []section {
+	c := d.DescriptorConfig
+	x := []section{}
+
+	s := section{"Device", []field{{"Serial", strings.TrimRight(d.Serial().String(), "\x00")}}}
+	if d.Desc != nil {
+		s.fields = append(s.fields,
+			field{"USB", fmt.Sprintf("%03d/%03d", d.Desc.Bus, d.Desc.Address)},
+			field{"Port", d.Desc.Port},
+			field{"ID", fmt.Sprintf("%04x:%04x", d.Desc.Vendor, d.Desc.Product)},
+			field{"Speed", d.Desc.Speed})
+	}
+	x = append(x, s)
+
+	x = append(x, section{"Descriptor", []field{
+		{"Version", c.DescriptorVersion},
+		{"Product", c.DescriptorProduct},
+		{"Firmware", c.DescriptorFirmware},
+		{"Interface", c.DescriptorInterface},
+		{"Module", c.Module()},
+	}})
+	x = append(x, section{"Capabilities", c.DescriptorCapability.fields()})
+	x = append(x, section{"Board", []field{
+		{"Type", d.BoardConfig.BoardType},
+		{"Version", d.BoardConfig.BoardVersion},
+	}})
+	x = append(x, section{"FPGA", []field{
+		{"Type", d.FPGAConfig.FPGAType},
+		{"Package", d.FPGAConfig.FPGAPackage},
+		{"Grade", d.FPGAConfig.FPGAGrade},
+	}})
+	x = append(x, section{"RAM", []field{
+		{"Size", d.RAMConfig.RAMSize},
+		{"Type", d.RAMConfig.RAMType},
+	}})
+	x = append(x, section{"Bitstream", []field{
+		{"Size", d.BitstreamConfig.BitstreamSize},
+		{"Capacity", d.BitstreamConfig.BitstreamCapacity},
+		{"Start", d.BitstreamConfig.BitstreamStart},
+	}})
+	if d.Capability().MultiFPGA() {
+		x = append(x, section{"MultiFPGA", []field{
+			{"Count", d.MultiFPGAConfig.MultiFPGACount},
+			{"Selected", d.MultiFPGAConfig.MultiFPGASelected.Number()},
+			{"Parallel", d.MultiFPGAConfig.MultiFPGAParallel},
+		}})
+	}
+
+	cfg := DeviceConfig{d.BoardConfig, d.FPGAConfig, d.RAMConfig, d.Serial(), d.BitstreamConfig}
+	if v := cfg.Validate(); len(v) != 0 {
+		s := section{"Configuration Issues", nil}
+		for _, i := range v {
+			s.fields = append(s.fields, field{i.Severity.String(), fmt.Sprintf("%v: %v", i.Field, i.Message)})
+		}
+		x = append(x, s)
+	}
+
+	x = appendStatus(x, "FPGA Status", d.FPGAStatus)
+	x = appendStatus(x, "Flash Status", d.FlashStatus)
+	x = appendStatus(x, "Sensors", d.SensorStatus)
+	x = appendStatus(x, "XMEGA Status", d.XMEGAStatus)
+
+	return x
+}
+
+// appendStatus appends a section with the status read by get, or with the
+// error reading it, unless the status is not supported.
+func appendStatus[T interface{ fields() []field }](x []section, title string, get func(...CallOption) (T, error)) []section {
+	s, err := get()
+	switch {
+	case errors.Is(err, ErrNotSupported):
+		return x
+	case err != nil:
+		return append(x, section{title, []field{{"Error", err}}})
+	default:
+		return append(x, section{title, s.fields()})
+	}
+}
+
+func writeTextReport(w io.Writer, x []section) error {
+	b := &strings.Builder{}
+	for i, s := range x {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(b, "%v\n", s.title)
+		n := 0
+		for _, f := range s.fields {
+			n = max(n, len(f.name))
+		}
+		for _, f := range s.fields {
+			fmt.Fprintf(b, "  %-*s %v\n", n+1, f.name+":", f.value)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeMarkdownReport(w io.Writer, x []section) error {
+	b := &strings.Builder{}
+	for i, s := range x {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(b, "## %v\n\n| Field | Value |\n| --- | --- |\n", s.title)
+		for _, f := range s.fields {
+			v := strings.ReplaceAll(fmt.Sprint(f.value), "|", `\|`)
+			fmt.Fprintf(b, "| %v | %v |\n", f.name, v)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeJSONReport(w io.Writer, x []section) error {
+	type jsonField struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+	type jsonSection struct {
+		Title  string      `json:"title"`
+		Fields []jsonField `json:"fields"`
+	}
+
+	y := []jsonSection{}
+	for _, s := range x {
+		z := jsonSection{s.title, []jsonField{}}
+		for _, f := range s.fields {
+			z.Fields = append(z.Fields, jsonField{f.name, fmt.Sprint(f.value)})
+		}
+		y = append(y, z)
+	}
+
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(y)
+}
@@ -0,0 +1,131 @@
+package ztex
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Upload transfers data read from r to a device.  The method expressions
+// (*Device).ConfigureFPGA, (*Device).ConfigureFPGAHighSpeed,
+// (*Device).UploadFirmware, (*Device).InstallFirmware, and
+// (*Device).InstallBitstream are uploads.
+type Upload func(d *Device, ctx context.Context, r io.Reader, progress Progress) error
+
+// UploadResult represents the outcome of an upload to one device.
+type UploadResult struct {
+	Device   *Device
+	Err      error
+	Duration time.Duration
+}
+
+// String returns a human-readable description of the upload result.
+func (u UploadResult) String() string {
+	x := []string{}
+	x = append(x, fmt.Sprintf("Serial(%v)", u.Device.Serial()))
+	x = append(x, fmt.Sprintf("Error(%v)", u.Err))
+	x = append(x, fmt.Sprintf("Duration(%v)", u.Duration))
+	return strings.Join(x, ", ")
+}
+
+// Scheduler distributes an upload across many devices, limiting the number
+// of uploads in progress at once.  Devices on the same USB bus share the
+// bandwidth of its host controller, so that uploading to all of them at
+// once starves the bus; PerBus limits the uploads on each bus separately.
+// Devices which were not opened through a backend count as one bus.
+type Scheduler struct {
+	// Limit is the maximum number of uploads in progress at once.  If
+	// zero, then the number is not limited.
+	Limit int
+
+	// PerBus is the maximum number of uploads in progress at once on
+	// each USB bus.  If zero, then the number is not limited.
+	PerBus int
+
+	// Progress, if not nil, is called with the progress of the upload to
+	// each device.  It may be called from several goroutines at once.
+	Progress func(d *Device, done, total int64)
+}
+
+// Run uploads b to every device in ds with u, and returns the outcome of
+// each upload, in the order of ds.  Uploads which have not started when
+// the context is done fail with the error of the context.
+func (s *Scheduler) Run(ctx context.Context, ds []*Device, b []byte, u Upload) []UploadResult {
+	all := semaphore(s.Limit)
+	buses := map[int]chan struct{}{}
+	for _, d := range ds {
+		bus, _ := d.location()
+		if _, ok := buses[bus]; !ok {
+			buses[bus] = semaphore(s.PerBus)
+		}
+	}
+
+	x := make([]UploadResult, len(ds))
+	var wg sync.WaitGroup
+	for i, d := range ds {
+		wg.Add(1)
+		go func(i int, d *Device) {
+			defer wg.Done()
+			bus, _ := d.location()
+			x[i] = s.upload(ctx, all, buses[bus], d, b, u)
+		}(i, d)
+	}
+	wg.Wait()
+
+	return x
+}
+
+// upload runs an upload to one device once both the overall and the
+// per-bus semaphores admit it.
+func (s *Scheduler) upload(ctx context.Context, all, bus chan struct{}, d *Device, b []byte, u Upload) UploadResult {
+	if err := acquire(ctx, bus); err != nil {
+		return UploadResult{d, err, 0}
+	}
+	defer release(bus)
+	if err := acquire(ctx, all); err != nil {
+		return UploadResult{d, err, 0}
+	}
+	defer release(all)
+
+	var p Progress
+	if s.Progress != nil {
+		p = func(done, total int64) { s.Progress(d, done, total) }
+	}
+	t := d.clock.Now()
+	err := u(d, ctx, bytes.NewReader(b), p)
+	return UploadResult{d, err, d.clock.Now().Sub(t)}
+}
+
+// semaphore returns a semaphore admitting n holders at once, or nil, which
+// admits any number of holders, if n is not positive.
+func semaphore(n int) chan struct{} {
+	if n <= 0 {
+		return nil
+	}
+	return make(chan struct{}, n)
+}
+
+// acquire waits until the semaphore admits another holder or the context
+// is done.
+func acquire(ctx context.Context, s chan struct{}) error {
+	if s == nil {
+		return ctx.Err()
+	}
+	select {
+	case s <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release releases a semaphore acquired with acquire.
+func release(s chan struct{}) {
+	if s != nil {
+		<-s
+	}
+}
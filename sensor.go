@@ -0,0 +1,196 @@
+package ztex
+
+import (
+	"fmt"
+)
+
+// SensorProtocol indicates the encoding of the sensor data reported by
+// the firmware.
+type SensorProtocol uint8
+
+// String returns a human-readable description of the sensor protocol.
+func (s SensorProtocol) String() string {
+	switch s {
+	case 1:
+		return "Temperature"
+	case 2:
+		return "Temperature, Voltage, and Current"
+	default:
+		return Unknown
+	}
+}
+
+// IsKnown returns true if and only if the sensor protocol has a known
+// name.
+func (s SensorProtocol) IsKnown() bool { return isKnown(s.String()) }
+
+// Number returns the raw numeric representation of the sensor protocol.
+func (s SensorProtocol) Number() uint8 { return uint8(s) }
+
+// SensorChannel indicates the position of a reading in the sensor data.
+type SensorChannel uint8
+
+// Number returns the raw numeric representation of the sensor channel.
+func (s SensorChannel) Number() uint8 { return uint8(s) }
+
+// SensorKind indicates the physical quantity measured by a sensor.
+type SensorKind uint8
+
+// String returns a human-readable description of the sensor kind.
+func (s SensorKind) String() string {
+	switch s {
+	case 1:
+		return "Temperature"
+	case 2:
+		return "Voltage"
+	case 3:
+		return "Current"
+	default:
+		return Unknown
+	}
+}
+
+// IsKnown returns true if and only if the sensor kind has a known name.
+func (s SensorKind) IsKnown() bool { return isKnown(s.String()) }
+
+// Number returns the raw numeric representation of the sensor kind.
+func (s SensorKind) Number() uint8 { return uint8(s) }
+
+// Unit returns the unit in which readings of this kind are expressed.
+func (s SensorKind) Unit() string {
+	switch s {
+	case 1:
+		return "°C"
+	case 2:
+		return "V"
+	case 3:
+		return "A"
+	default:
+		return ""
+	}
+}
+
+// SensorValue represents a sensor reading in degrees Celsius, volts, or
+// amperes, depending on the kind of sensor.
+type SensorValue float64
+
+// Number returns the numeric representation of the sensor value.
+func (s SensorValue) Number() float64 { return float64(s) }
+
+// SensorReading represents a single reading from a sensor.
+type SensorReading struct {
+	SensorChannel
+	SensorKind
+	SensorValue
+}
+
+// String returns a human-readable description of the sensor reading.
+func (s SensorReading) String() string {
+	b, _ := s.AppendText(nil)
+	return string(b)
+}
+
+// AppendText appends the description returned by String to b.
+func (s SensorReading) AppendText(b []byte) ([]byte, error) {
+	return fmt.Appendf(b, "Channel(%v), Kind(%v), Value(%.2f%v)",
+		s.SensorChannel, s.SensorKind, s.SensorValue, s.SensorKind.Unit()), nil
+}
+
+// SensorReadings represents the readings of all sensors on the device.
+type SensorReadings []SensorReading
+
+// String returns a human-readable description of the sensor readings.
+func (s SensorReadings) String() string {
+	b, _ := s.AppendText(nil)
+	return string(b)
+}
+
+// AppendText appends the description returned by String to b.
+func (s SensorReadings) AppendText(b []byte) ([]byte, error) {
+	for i, r := range s {
+		if i > 0 {
+			b = append(b, ", "...)
+		}
+		b = append(b, "Reading("...)
+		b, _ = r.AppendText(b)
+		b = append(b, ')')
+	}
+	return b, nil
+}
+
+// SensorStatus indicates the current readings of the sensors on the device.
+type SensorStatus struct {
+	SensorProtocol
+	SensorReadings
+}
+
+// String returns a human-readable description of the sensor status.
+func (s SensorStatus) String() string {
+	b, _ := s.AppendText(nil)
+	return string(b)
+}
+
+// AppendText appends the description returned by String to b, so that
+// monitors which log the readings repeatedly can reuse a buffer.
+func (s SensorStatus) AppendText(b []byte) ([]byte, error) {
+	b = fmt.Appendf(b, "Protocol(%v), Readings(", s.SensorProtocol)
+	b, _ = s.SensorReadings.AppendText(b)
+	return append(b, ')'), nil
+}
+
+// decodeSensorStatus decodes the sensor data returned by the firmware.
+//
+// Protocol 1 reports one signed byte per temperature sensor, in degrees
+// Celsius.  Protocol 2, used by newer firmware, reports three bytes per
+// sensor: the sensor kind followed by a signed little-endian value in
+// 1/256 degrees Celsius, millivolts, or milliamperes.
+func decodeSensorStatus(b []byte) (*SensorStatus, error) {
+	s := &SensorStatus{SensorReadings: SensorReadings{}}
+	if err := decodeSensorStatusInto(s, b); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// decodeSensorStatusInto decodes the sensor data returned by the firmware
+// into s, reusing the storage of its readings.
+func decodeSensorStatusInto(s *SensorStatus, b []byte) error {
+	if len(b) < 1 {
+		return fmt.Errorf("got %v bytes, want at least %v bytes", len(b), 1)
+	}
+
+	s.SensorProtocol, s.SensorReadings = SensorProtocol(b[0]), s.SensorReadings[:0]
+	switch s.SensorProtocol {
+	case 1:
+		for i, v := range b[1:] {
+			s.SensorReadings = append(s.SensorReadings, SensorReading{
+				SensorChannel(i),
+				SensorKind(1),
+				SensorValue(int8(v)),
+			})
+		}
+	case 2:
+		if (len(b)-1)%3 != 0 {
+			return fmt.Errorf("got %v bytes of sensor data, want a multiple of %v bytes", len(b)-1, 3)
+		}
+		for i := 0; 1+3*i < len(b); i++ {
+			k := SensorKind(b[1+3*i])
+			v := float64(int16(bytesToUint16([2]uint8{b[2+3*i], b[3+3*i]})))
+			switch k {
+			case 1:
+				v /= 256
+			case 2, 3:
+				v /= 1000
+			}
+			s.SensorReadings = append(s.SensorReadings, SensorReading{
+				SensorChannel(i),
+				k,
+				SensorValue(v),
+			})
+		}
+	default:
+		return fmt.Errorf("got protocol %v, want protocol %v or %v", b[0], 1, 2)
+	}
+
+	return nil
+}
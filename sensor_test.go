@@ -0,0 +1,61 @@
+package ztex
+
+import (
+	"testing"
+)
+
+func TestDecodeSensorStatus(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		b    []byte
+		want SensorReadings
+	}{
+		{"temperature", []byte{1, 42, 0xf6}, SensorReadings{{0, 1, 42}, {1, 1, -10}}},
+		{"temperature, no sensors", []byte{1}, SensorReadings{}},
+		{"temperature, voltage, and current", []byte{2, 1, 0x80, 0x2a, 2, 0xe4, 0x0c, 3, 0xf4, 0x01, 1, 0x00, 0xfe},
+			SensorReadings{{0, 1, 42.5}, {1, 2, 3.3}, {2, 3, 0.5}, {3, 1, -2}}},
+		{"unknown kind", []byte{2, 9, 0x10, 0x00}, SensorReadings{{0, 9, 16}}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := decodeSensorStatus(tc.b)
+			if err != nil {
+				t.Fatalf("decodeSensorStatus(% x): %v", tc.b, err)
+			} else if s.SensorProtocol != SensorProtocol(tc.b[0]) || len(s.SensorReadings) != len(tc.want) {
+				t.Fatalf("decodeSensorStatus(% x): got %v, want protocol %v and readings %v", tc.b, s, tc.b[0], tc.want)
+			}
+			for i, r := range s.SensorReadings {
+				if d := r.SensorValue - tc.want[i].SensorValue; r.SensorChannel != tc.want[i].SensorChannel || r.SensorKind != tc.want[i].SensorKind || d < -1e-9 || d > 1e-9 {
+					t.Errorf("decodeSensorStatus(% x): got reading %v, want %v", tc.b, r, tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeSensorStatusErrors(t *testing.T) {
+	for _, b := range [][]byte{
+		{},
+		{0, 1, 2},
+		{3, 1, 2},
+		{2, 1, 0x00},
+		{2, 1, 0x00, 0x10, 2},
+	} {
+		if s, err := decodeSensorStatus(b); err == nil {
+			t.Errorf("decodeSensorStatus(% x): got %v, want error", b, s)
+		}
+	}
+}
+
+func TestDecodeSensorStatusInto(t *testing.T) {
+	// The storage of the readings is reused.
+	s := &SensorStatus{SensorReadings: make(SensorReadings, 0, 4)}
+	if err := decodeSensorStatusInto(s, []byte{1, 20, 30}); err != nil {
+		t.Fatalf("decodeSensorStatusInto: %v", err)
+	}
+	p := &s.SensorReadings[0]
+	if err := decodeSensorStatusInto(s, []byte{1, 25}); err != nil {
+		t.Fatalf("decodeSensorStatusInto: %v", err)
+	} else if len(s.SensorReadings) != 1 || s.SensorReadings[0].SensorValue != 25 || &s.SensorReadings[0] != p {
+		t.Errorf("decodeSensorStatusInto: got %v, want one reading of 25 in the same storage", s)
+	}
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// ContentType is the media type which requests with a body must declare.
+// Browsers cannot send it to another site without a preflight request,
+// which the server does not answer, so web pages cannot make the server
+// change the state of the devices.
+const ContentType = "application/octet-stream"
+
+// accessError is a request which the server refuses to serve, with the
+// status code of the response.
+type accessError struct {
+	code int
+	err  error
+}
+
+func (e *accessError) Error() string { return e.err.Error() }
+
+func (e *accessError) Unwrap() error { return e.err }
+
+// deny returns an accessError with the given status code and description.
+func deny(code int, format string, a ...any) error {
+	return &accessError{code, fmt.Errorf(format, a...)}
+}
+
+// checkAccess returns an error if the request must not be served: if its
+// Host is not one of s.Hosts, if it comes from a web page of another
+// site, if it lacks the token of the server, or if it has a body of
+// another type than ContentType.
+func (s *Server) checkAccess(r *http.Request) error {
+	if len(s.Hosts) != 0 && !slices.Contains(s.Hosts, strings.ToLower(r.Host)) {
+		return deny(http.StatusForbidden, "got host %q, want one of %v", r.Host, strings.Join(s.Hosts, ", "))
+	}
+
+	if o := r.Header.Get("Origin"); o != "" {
+		u, err := url.Parse(o)
+		if err != nil || !strings.EqualFold(u.Host, r.Host) {
+			return deny(http.StatusForbidden, "got origin %q, want origin of host %q", o, r.Host)
+		}
+	}
+
+	if s.Token != "" {
+		t, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(t), []byte(s.Token)) != 1 {
+			return deny(http.StatusUnauthorized, "got no valid bearer token, want the token of the server")
+		}
+	}
+
+	if r.Method == http.MethodPost || r.Method == http.MethodPut {
+		if t, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || t != ContentType {
+			return deny(http.StatusUnsupportedMediaType, "got content type %q, want %q", r.Header.Get("Content-Type"), ContentType)
+		}
+	}
+
+	return nil
+}
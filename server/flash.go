@@ -0,0 +1,116 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// flashBufferSize is the size of the buffer through which the flash is
+// streamed, rounded down to whole sectors.
+const flashBufferSize = 1 << 20
+
+// flashRange returns the sector size and the range of sectors selected by
+// the query parameters sector and count.  The range defaults to the rest
+// of the flash from the first sector, which defaults to zero.
+func flashRange(r *http.Request, d *device) (int, uint32, uint32, error) {
+	s, err := d.FlashStatus()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	n := uint64(s.FlashCount.Number())
+	q := r.URL.Query()
+	first, count := uint64(0), uint64(0)
+	if v := q.Get("sector"); v != "" {
+		if first, err = strconv.ParseUint(v, 0, 32); err != nil {
+			return 0, 0, 0, badRequest("got sector %q, want a sector number", v)
+		}
+	}
+	if v := q.Get("count"); v != "" {
+		if count, err = strconv.ParseUint(v, 0, 32); err != nil || count == 0 {
+			return 0, 0, 0, badRequest("got count %q, want a positive number of sectors", v)
+		}
+	}
+	if first >= n {
+		return 0, 0, 0, badRequest("got sector %v, want sector in [0, %v)", first, n)
+	} else if count == 0 {
+		count = n - first
+	} else if first+count > n {
+		return 0, 0, 0, badRequest("got sectors [%v, %v), want sectors in [0, %v)", first, first+count, n)
+	}
+	return int(s.FlashSector.Number()), uint32(first), uint32(count), nil
+}
+
+// readFlash streams the contents of a range of sectors.
+func readFlash(w http.ResponseWriter, r *http.Request, d *device) error {
+	z, first, count, err := flashRange(r, d)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.FormatInt(int64(count)*int64(z), 10))
+	c := &countingWriter{w: w}
+	if err := d.ReadFlashInto(r.Context(), c, first, count, make([]byte, 2*max(z, flashBufferSize/z*z)), nil); err != nil && c.n == 0 {
+		return err
+	}
+	// Errors after the response has started cannot be reported, but the
+	// client notices that the response is shorter than announced.
+	return nil
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// writeFlash writes the request body to the flash, starting at the sector
+// given by the query parameter sector, as it arrives.  The body must fit
+// into the range of sectors, and its last sector is padded with erased
+// bytes.
+func writeFlash(w http.ResponseWriter, r *http.Request, d *device) error {
+	z, first, count, err := flashRange(r, d)
+	if err != nil {
+		return err
+	}
+
+	size := int64(count) * int64(z)
+	if r.ContentLength > size {
+		return badRequest("got %v bytes, want at most %v bytes", r.ContentLength, size)
+	}
+
+	body := http.MaxBytesReader(w, r.Body, size)
+	b := make([]byte, max(z, flashBufferSize/z*z))
+	t, sector := int64(0), first
+	for {
+		n, err := io.ReadFull(body, b)
+		if n > 0 {
+			k := (n + z - 1) / z
+			if sector+uint32(k) > first+count {
+				return badRequest("got at least %v bytes, want at most %v bytes", t+int64(n), size)
+			}
+			copy(b[n:k*z], bytes.Repeat([]byte{0xff}, k*z-n))
+			if err := d.WriteFlash(r.Context(), sector, b[:k*z], nil); err != nil {
+				return err
+			}
+			t += int64(n)
+			sector += uint32(k)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		} else if err != nil {
+			return err
+		}
+	}
+
+	return writeJSON(w, uploadResponse{Serial: d.Serial().String(), Bytes: t})
+}
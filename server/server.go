@@ -0,0 +1,376 @@
+// Package server exposes ZTEX modules over HTTP, so that tools which are
+// not written in Go, such as scripts and web dashboards, can drive them.
+//
+// Requests and responses carry JSON, except for the contents of the flash,
+// bitstreams, firmware images, and the data of the stream, which are sent
+// as raw bytes.  The contents of the flash are streamed to and from the
+// device as they arrive, while bitstreams and firmware images, which the
+// device library needs as a whole, are held in memory and limited to
+// Server.MaxUploadSize bytes.  The API is:
+//
+//	GET  /devices                    list the devices
+//	GET  /devices/{serial}           describe a device
+//	GET  /devices/{serial}/status    read the FPGA, flash, and sensor status
+//	GET  /devices/{serial}/config    read the configuration data area
+//	POST /devices/{serial}/fpga      configure the FPGA with the bitstream in the body
+//	PUT  /devices/{serial}/bitstream install the bitstream in the body in the flash
+//	GET  /devices/{serial}/flash     read sectors of the flash
+//	PUT  /devices/{serial}/flash     write the body to sectors of the flash
+//	POST /devices/{serial}/firmware  upload the firmware in the body to RAM
+//	PUT  /devices/{serial}/firmware  install the firmware in the body
+//	GET  /devices/{serial}/stream    read up to n bytes from the stream
+//	POST /devices/{serial}/stream    write the body to the stream
+//
+// Requests which write to the devices, with methods POST and PUT, must
+// declare the content type application/octet-stream, which web pages
+// cannot send to another site without the consent of the server.  The
+// server also refuses requests from web pages of other sites, as told by
+// their Origin header, requests for other hosts than those in
+// Server.Hosts, if set, which defeats DNS rebinding, and requests without
+// Server.Token as bearer token, if set.
+//
+// Errors are reported with an HTTP status code and a JSON object whose
+// field "error" describes the error.
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/aljumi/ztex"
+)
+
+// The default limits on the requests served by a server.
+const (
+	// DefaultMaxUploadSize is the default maximum size of a bitstream or
+	// firmware image.
+	DefaultMaxUploadSize = 64 << 20
+
+	// DefaultMaxStreamSize is the default maximum number of bytes read
+	// from or written to the stream by one request.
+	DefaultMaxStreamSize = 16 << 20
+)
+
+// Server is an http.Handler which serves the API described in the package
+// documentation for a set of devices, keyed by their serial number.
+// Requests for the same device are serialized, so that clients sharing
+// the server do not interfere with each other's operations.  The server
+// does not close the devices, but Close closes the streams it opened.
+type Server struct {
+	// MaxUploadSize is the maximum size of a bitstream or firmware image
+	// in a request body.  Larger requests fail with status 413.
+	MaxUploadSize int64
+
+	// MaxStreamSize is the maximum number of bytes read from or written
+	// to the stream by one request.
+	MaxStreamSize int
+
+	// Hosts are the values of the Host header, in lower case, such as
+	// "localhost:8067", for which requests are served.  If empty, then
+	// requests for any host are served.
+	Hosts []string
+
+	// Token is the bearer token which requests must carry in their
+	// Authorization header.  If empty, then no token is required.
+	Token string
+
+	mu      sync.Mutex
+	devices map[string]*device
+	mux     *http.ServeMux
+}
+
+// device is a device served by the server.
+type device struct {
+	mu sync.Mutex
+	*ztex.Device
+
+	// stream is the stream of the device, opened on first use.  It is
+	// guarded by the mutex of the server, so that Close does not wait
+	// for requests in progress.
+	stream *ztex.Stream
+}
+
+// New returns a server for the given devices, with the default limits.
+func New(d ...*ztex.Device) *Server {
+	s := &Server{
+		MaxUploadSize: DefaultMaxUploadSize,
+		MaxStreamSize: DefaultMaxStreamSize,
+		devices:       map[string]*device{},
+		mux:           http.NewServeMux(),
+	}
+	for _, x := range d {
+		s.Add(x)
+	}
+
+	s.mux.HandleFunc("GET /devices", s.list)
+	s.mux.HandleFunc("GET /devices/{serial}", s.device(info))
+	s.mux.HandleFunc("GET /devices/{serial}/status", s.device(status))
+	s.mux.HandleFunc("GET /devices/{serial}/config", s.device(config))
+	s.mux.HandleFunc("POST /devices/{serial}/fpga", s.device(s.configureFPGA))
+	s.mux.HandleFunc("PUT /devices/{serial}/bitstream", s.device(s.installBitstream))
+	s.mux.HandleFunc("GET /devices/{serial}/flash", s.device(readFlash))
+	s.mux.HandleFunc("PUT /devices/{serial}/flash", s.device(writeFlash))
+	s.mux.HandleFunc("POST /devices/{serial}/firmware", s.device(s.uploadFirmware))
+	s.mux.HandleFunc("PUT /devices/{serial}/firmware", s.device(s.installFirmware))
+	s.mux.HandleFunc("GET /devices/{serial}/stream", s.device(s.readStream))
+	s.mux.HandleFunc("POST /devices/{serial}/stream", s.device(s.writeStream))
+
+	return s
+}
+
+// Add adds a device to the server, replacing any device with the same
+// serial number.
+func (s *Server) Add(d *ztex.Device) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.devices[d.Serial().String()] = &device{Device: d}
+}
+
+// Remove removes the device with the given serial number from the server,
+// and closes its stream, which aborts the transfers in progress on it.
+// Other requests for the device which are in progress are not interrupted.
+func (s *Server) Remove(serial string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if d, ok := s.devices[serial]; ok && d.stream != nil {
+		d.stream.Close()
+	}
+	delete(s.devices, serial)
+}
+
+// Close closes the streams opened by the server, which aborts the
+// transfers in progress on them.  Later stream requests open the streams
+// again.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var errs []error
+	for _, d := range s.devices {
+		if d.stream != nil {
+			errs = append(errs, d.stream.Close())
+			d.stream = nil
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := s.checkAccess(r); err != nil {
+		writeError(w, err)
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// lookup returns the device with the given serial number.
+func (s *Server) lookup(serial string) (*device, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.devices[serial]
+	return d, ok
+}
+
+// device returns a handler which looks up the device named in the request
+// and calls h with the device locked.
+func (s *Server) device(h func(http.ResponseWriter, *http.Request, *device) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		d, ok := s.lookup(r.PathValue("serial"))
+		if !ok {
+			writeError(w, fmt.Errorf("%w: got serial number %q, want the serial number of a device", ztex.ErrNoDevice, r.PathValue("serial")))
+			return
+		}
+
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if err := h(w, r, d); err != nil {
+			writeError(w, err)
+		}
+	}
+}
+
+// requestError is an error in the parameters of a request.
+type requestError struct{ err error }
+
+func (e *requestError) Error() string { return e.err.Error() }
+
+func (e *requestError) Unwrap() error { return e.err }
+
+// badRequest returns a requestError with the given description.
+func badRequest(format string, a ...any) error {
+	return &requestError{fmt.Errorf(format, a...)}
+}
+
+// errorResponse is the body of an error response.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// writeError writes an error response, whose status code is derived from
+// the error.  It must be called before the response has started.
+func writeError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	var e *requestError
+	var m *http.MaxBytesError
+	var a *accessError
+	switch {
+	case errors.As(err, &a):
+		code = a.code
+	case errors.As(err, &e):
+		code = http.StatusBadRequest
+	case errors.As(err, &m):
+		code = http.StatusRequestEntityTooLarge
+	case errors.Is(err, ztex.ErrNoDevice):
+		code = http.StatusNotFound
+	case errors.Is(err, ztex.ErrNotSupported):
+		code = http.StatusNotImplemented
+	}
+
+	w.Header().Del("Content-Length")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(errorResponse{err.Error()})
+}
+
+// writeJSON writes a JSON response.
+func writeJSON(w http.ResponseWriter, v any) error {
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(v)
+}
+
+func (s *Server) list(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	x := make([]*device, 0, len(s.devices))
+	for _, d := range s.devices {
+		x = append(x, d)
+	}
+	s.mu.Unlock()
+
+	y := make([]ztex.DeviceInfo, 0, len(x))
+	for _, d := range x {
+		d.mu.Lock()
+		y = append(y, d.Info())
+		d.mu.Unlock()
+	}
+	sort.Slice(y, func(i, j int) bool {
+		return y[i].Bus < y[j].Bus || (y[i].Bus == y[j].Bus && y[i].Address < y[j].Address)
+	})
+
+	writeJSON(w, y)
+}
+
+func info(w http.ResponseWriter, r *http.Request, d *device) error {
+	return writeJSON(w, d.Info())
+}
+
+// statusResponse is the body of the response to a status request.  Parts
+// which the device does not support are omitted, and parts which could
+// not be read are replaced by their error.
+type statusResponse struct {
+	FPGA         *ztex.FPGAStatus   `json:"fpga,omitempty"`
+	FPGAError    string             `json:"fpga_error,omitempty"`
+	Flash        *ztex.FlashStatus  `json:"flash,omitempty"`
+	FlashError   string             `json:"flash_error,omitempty"`
+	Sensors      map[string]float64 `json:"sensors,omitempty"`
+	SensorsError string             `json:"sensors_error,omitempty"`
+}
+
+func status(w http.ResponseWriter, r *http.Request, d *device) error {
+	c, _ := d.CombinedStatus()
+	x := statusResponse{FPGA: c.FPGA, Flash: c.Flash}
+	if c.FPGAErr != nil {
+		x.FPGAError = c.FPGAErr.Error()
+	}
+	if c.FlashErr != nil {
+		x.FlashError = c.FlashErr.Error()
+	}
+	if c.SensorsErr != nil {
+		x.SensorsError = c.SensorsErr.Error()
+	} else if c.Sensors != nil {
+		x.Sensors = map[string]float64{}
+		for _, s := range c.Sensors.SensorReadings {
+			x.Sensors[fmt.Sprintf("%v%v", s.SensorKind, s.SensorChannel.Number())] = s.SensorValue.Number()
+		}
+	}
+	return writeJSON(w, x)
+}
+
+func config(w http.ResponseWriter, r *http.Request, d *device) error {
+	if err := d.LoadDeviceConfig(); err != nil {
+		return err
+	}
+	return writeJSON(w, ztex.DeviceConfig{
+		BoardConfig:      d.BoardConfig,
+		FPGAConfig:       d.FPGAConfig,
+		RAMConfig:        d.RAMConfig,
+		DescriptorSerial: d.Serial(),
+		BitstreamConfig:  d.BitstreamConfig,
+	})
+}
+
+// uploadResponse is the body of the response to a request which uploads
+// data to a device.
+type uploadResponse struct {
+	Serial string `json:"serial"`
+	Method string `json:"method,omitempty"`
+	Bytes  int64  `json:"bytes"`
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// upload runs u with the request body, of at most s.MaxUploadSize bytes,
+// and responds with the number of bytes uploaded.
+func (s *Server) upload(w http.ResponseWriter, r *http.Request, d *device, method string, u ztex.Upload) error {
+	c := &countingReader{r: http.MaxBytesReader(w, r.Body, s.MaxUploadSize)}
+	if err := u(d.Device, r.Context(), c, nil); err != nil {
+		return err
+	}
+	return writeJSON(w, uploadResponse{d.Serial().String(), method, c.n})
+}
+
+// configureFPGA configures the FPGA with the bitstream in the request
+// body, through the bulk endpoint if the device supports it.  The query
+// parameter fpga selects the FPGA of a multi-FPGA device.
+func (s *Server) configureFPGA(w http.ResponseWriter, r *http.Request, d *device) error {
+	if v := r.URL.Query().Get("fpga"); v != "" {
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return badRequest("got fpga %q, want an index", v)
+		} else if err := d.SelectFPGA(i); err != nil {
+			return err
+		}
+	}
+
+	if d.Capability().HighSpeedFPGAConfiguration() {
+		return s.upload(w, r, d, "high-speed", (*ztex.Device).ConfigureFPGAHighSpeed)
+	}
+	return s.upload(w, r, d, "low-speed", (*ztex.Device).ConfigureFPGA)
+}
+
+func (s *Server) installBitstream(w http.ResponseWriter, r *http.Request, d *device) error {
+	return s.upload(w, r, d, "", (*ztex.Device).InstallBitstream)
+}
+
+func (s *Server) uploadFirmware(w http.ResponseWriter, r *http.Request, d *device) error {
+	return s.upload(w, r, d, "", (*ztex.Device).UploadFirmware)
+}
+
+func (s *Server) installFirmware(w http.ResponseWriter, r *http.Request, d *device) error {
+	return s.upload(w, r, d, "", (*ztex.Device).InstallFirmware)
+}
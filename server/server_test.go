@@ -0,0 +1,120 @@
+package server_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aljumi/ztex"
+	"github.com/aljumi/ztex/server"
+	"github.com/aljumi/ztex/ztextest"
+)
+
+// newServer returns a server for a fake device attached to the simulated
+// bus, whose serial number is fake000001.
+func newServer(t *testing.T) *server.Server {
+	t.Helper()
+	ds, err := ztex.OpenBackendDevices(ztextest.Backend{ztextest.NewFakeDevice("fake000001")})
+	if err != nil {
+		t.Fatalf("ztex.OpenBackendDevices: %v", err)
+	}
+	t.Cleanup(func() { ds[0].Close() })
+
+	s := server.New(ds...)
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestRequestLimits(t *testing.T) {
+	s := newServer(t)
+	s.MaxUploadSize, s.MaxStreamSize = 1<<10, 1<<10
+	for _, tc := range []struct {
+		method, target string
+		body           int
+		code           int
+	}{
+		{"POST", "/devices/fake000001/fpga", 1<<10 + 1, http.StatusRequestEntityTooLarge},
+		{"PUT", "/devices/fake000001/bitstream", 1<<10 + 1, http.StatusRequestEntityTooLarge},
+		{"POST", "/devices/fake000001/stream", 1<<10 + 1, http.StatusRequestEntityTooLarge},
+		{"GET", "/devices/fake000001/stream?n=1025", 0, http.StatusBadRequest},
+		{"GET", "/devices/fake000001/stream?n=-1", 0, http.StatusBadRequest},
+		{"GET", "/devices/fake000001/stream", 0, http.StatusBadRequest},
+		{"POST", "/devices/fake000001/fpga?fpga=x", 0, http.StatusBadRequest},
+		{"GET", "/devices/fake000001/flash?sector=x", 0, http.StatusBadRequest},
+		{"GET", "/devices/fake000002/status", 0, http.StatusNotFound},
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(tc.method, tc.target, bytes.NewReader(make([]byte, tc.body)))
+		r.Header.Set("Content-Type", server.ContentType)
+		s.ServeHTTP(w, r)
+		if w.Code != tc.code {
+			t.Errorf("%v %v with %v bytes: got status %v, want status %v (%s)", tc.method, tc.target, tc.body, w.Code, tc.code, strings.TrimSpace(w.Body.String()))
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	s := newServer(t)
+
+	want := bytes.Repeat([]byte("ztex"), 25)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/devices/fake000001/stream", bytes.NewReader(want))
+	r.Header.Set("Content-Type", server.ContentType)
+	s.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST stream: got status %v, want status %v (%s)", w.Code, http.StatusOK, strings.TrimSpace(w.Body.String()))
+	}
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/devices/fake000001/stream?n=1024", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET stream: got status %v, want status %v (%s)", w.Code, http.StatusOK, strings.TrimSpace(w.Body.String()))
+	}
+	if got, _ := io.ReadAll(w.Body); !bytes.Equal(got, want) {
+		t.Errorf("GET stream: got %q, want %q", got, want)
+	}
+}
+
+func TestAccess(t *testing.T) {
+	s := newServer(t)
+	s.Hosts, s.Token = []string{"localhost:8067"}, "secret"
+	for _, tc := range []struct {
+		name           string
+		method, target string
+		header         map[string]string
+		code           int
+	}{
+		{"read", "GET", "/devices", nil, http.StatusOK},
+		{"other host", "GET", "/devices", map[string]string{"Host": "attacker.example:8067"}, http.StatusForbidden},
+		{"same origin", "GET", "/devices", map[string]string{"Origin": "http://localhost:8067"}, http.StatusOK},
+		{"cross origin", "GET", "/devices", map[string]string{"Origin": "http://attacker.example"}, http.StatusForbidden},
+		{"null origin", "GET", "/devices", map[string]string{"Origin": "null"}, http.StatusForbidden},
+		{"no token", "GET", "/devices", map[string]string{"Authorization": ""}, http.StatusUnauthorized},
+		{"wrong token", "GET", "/devices", map[string]string{"Authorization": "Bearer guess"}, http.StatusUnauthorized},
+		{"write", "POST", "/devices/fake000001/stream", map[string]string{"Content-Type": server.ContentType}, http.StatusOK},
+		{"form", "POST", "/devices/fake000001/stream", map[string]string{"Content-Type": "application/x-www-form-urlencoded"}, http.StatusUnsupportedMediaType},
+		{"text", "PUT", "/devices/fake000001/flash", map[string]string{"Content-Type": "text/plain"}, http.StatusUnsupportedMediaType},
+		{"no content type", "POST", "/devices/fake000001/fpga", nil, http.StatusUnsupportedMediaType},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(tc.method, tc.target, strings.NewReader("ztex"))
+			r.Host = "localhost:8067"
+			r.Header.Set("Authorization", "Bearer secret")
+			for k, v := range tc.header {
+				if k == "Host" {
+					r.Host = v
+				} else {
+					r.Header.Set(k, v)
+				}
+			}
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, r)
+			if w.Code != tc.code {
+				t.Errorf("%v %v: got status %v, want status %v (%s)", tc.method, tc.target, w.Code, tc.code, strings.TrimSpace(w.Body.String()))
+			}
+		})
+	}
+}
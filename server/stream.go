@@ -0,0 +1,75 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/aljumi/ztex"
+)
+
+// openStream returns the stream of the device, which is opened on first
+// use and kept open for later requests.
+func (s *Server) openStream(d *device) (*ztex.Stream, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if d.stream == nil {
+		x, err := d.OpenStream()
+		if err != nil {
+			return nil, err
+		}
+		d.stream = x
+	}
+	return d.stream, nil
+}
+
+// streamResponse is the body of the response to a request which writes
+// to the stream.
+type streamResponse struct {
+	Serial string `json:"serial"`
+	Bytes  int    `json:"bytes"`
+}
+
+// readStream responds with up to the number of bytes given by the query
+// parameter n, which is at most s.MaxStreamSize, read from the stream.
+func (s *Server) readStream(w http.ResponseWriter, r *http.Request, d *device) error {
+	n, err := strconv.Atoi(r.URL.Query().Get("n"))
+	if err != nil || n <= 0 || n > s.MaxStreamSize {
+		return badRequest("got n %q, want a number of bytes in [1, %v]", r.URL.Query().Get("n"), s.MaxStreamSize)
+	}
+
+	x, err := s.openStream(d)
+	if err != nil {
+		return err
+	}
+
+	b := make([]byte, n)
+	m, err := x.ReadContext(r.Context(), b)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	_, err = w.Write(b[:m])
+	return err
+}
+
+// writeStream writes the request body, of at most s.MaxStreamSize bytes,
+// to the stream.
+func (s *Server) writeStream(w http.ResponseWriter, r *http.Request, d *device) error {
+	b, err := io.ReadAll(http.MaxBytesReader(w, r.Body, int64(s.MaxStreamSize)))
+	if err != nil {
+		return err
+	}
+
+	x, err := s.openStream(d)
+	if err != nil {
+		return err
+	}
+
+	n, err := x.WriteContext(r.Context(), b)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, streamResponse{d.Serial().String(), n})
+}
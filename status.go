@@ -0,0 +1,107 @@
+package ztex
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// CombinedStatus holds the FPGA status, the flash status, and the sensor
+// readings of a device, as read by (*Device).CombinedStatus.  Parts which
+// the device does not support are nil, and so are parts which could not be
+// read, whose error is recorded instead, unless they were reused by
+// CombinedStatusInto.
+type CombinedStatus struct {
+	FPGA       *FPGAStatus
+	FPGAErr    error
+	Flash      *FlashStatus
+	FlashErr   error
+	Sensors    *SensorStatus
+	SensorsErr error
+}
+
+// String returns a human-readable description of the combined status.
+func (c CombinedStatus) String() string {
+	x := []string{}
+	if c.FPGA != nil || c.FPGAErr != nil {
+		x = append(x, fmt.Sprintf("FPGA(%v)", statusOrError(c.FPGA, c.FPGAErr)))
+	}
+	if c.Flash != nil || c.FlashErr != nil {
+		x = append(x, fmt.Sprintf("Flash(%v)", statusOrError(c.Flash, c.FlashErr)))
+	}
+	if c.Sensors != nil || c.SensorsErr != nil {
+		x = append(x, fmt.Sprintf("Sensors(%v)", statusOrError(c.Sensors, c.SensorsErr)))
+	}
+	return strings.Join(x, ", ")
+}
+
+// statusOrError returns the error if it is not nil, and the status
+// otherwise.
+func statusOrError(s fmt.Stringer, err error) any {
+	if err != nil {
+		return err
+	}
+	return s
+}
+
+// Err returns the errors of the parts which could not be read, joined, or
+// nil if all supported parts were read.
+func (c CombinedStatus) Err() error {
+	return errors.Join(c.FPGAErr, c.FlashErr, c.SensorsErr)
+}
+
+// CombinedStatus reads the FPGA status, the flash status, and the sensors
+// of the device, skipping those which the device does not support, so that
+// a monitoring loop needs one call and no more control transfers than the
+// firmware requires: one for each part, since the firmware has no request
+// which returns several of them.  The transfers are issued one after the
+// other, as the control endpoint handles one request at a time; devices
+// may be polled concurrently.  The parts which were read are returned
+// along with the errors of the others.
+func (d *Device) CombinedStatus(opt ...CallOption) (*CombinedStatus, error) {
+	c := &CombinedStatus{}
+	err := d.CombinedStatusInto(c, make([]byte, StatusBufferSize), opt...)
+	return c, err
+}
+
+// CombinedStatusInto reads the combined status into c, like
+// CombinedStatus, reusing the parts of c which are not nil and using buf
+// for the transfers, so that polling does not allocate.  Parts which could
+// not be read are kept, with unspecified contents, so that the next poll
+// can reuse them.
+func (d *Device) CombinedStatusInto(c *CombinedStatus, buf []byte, opt ...CallOption) error {
+	if err := checkStatusBuffer(buf); err != nil {
+		return fmt.Errorf("(*ztex.Device).CombinedStatusInto: %w", err)
+	}
+
+	x := d.Capability()
+	c.FPGA, c.FPGAErr = readStatus(c.FPGA, x.FPGAConfiguration(), func(s *FPGAStatus) error {
+		return d.FPGAStatusInto(s, buf, opt...)
+	})
+	c.Flash, c.FlashErr = readStatus(c.Flash, x.FlashMemory(), func(s *FlashStatus) error {
+		return d.FlashStatusInto(s, buf, opt...)
+	})
+	c.Sensors, c.SensorsErr = readStatus(c.Sensors, x.TemperatureSensor(), func(s *SensorStatus) error {
+		return d.SensorStatusInto(s, buf, opt...)
+	})
+
+	return c.Err()
+}
+
+// readStatus reads one part of the combined status into s, allocating it
+// if it is nil, or returns nil if the part is not supported.  If the part
+// cannot be read, then s is returned as passed, so that a reused part is
+// not discarded.
+func readStatus[T any](s *T, supported bool, read func(*T) error) (*T, error) {
+	if !supported {
+		return nil, nil
+	}
+	x := s
+	if x == nil {
+		x = new(T)
+	}
+	if err := read(x); err != nil {
+		return s, err
+	}
+	return x, nil
+}
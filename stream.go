@@ -0,0 +1,286 @@
+package ztex
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Stream transfers data to and from the FPGA through the bulk endpoints of
+// the default firmware interface.  It implements io.ReadWriteCloser.
+//
+// If the backend supports queued transfers, then several transfers are
+// kept in flight, as set by BulkTransfers.  Once read from, the stream
+// then keeps reading from the FPGA in the background until it is closed,
+// so data sent by the FPGA but not yet read is lost when the stream is
+// closed.
+//
+// Reads and writes may run concurrently, so that a design which answers
+// the data written to it can be driven by one goroutine writing and
+// another reading.  Concurrent reads, and concurrent writes, are
+// serialized.
+//
+// On multi-FPGA devices, the FPGA of the stream stays selected during
+// each read and write, and accesses to other FPGAs wait until the
+// transfer is done.  Reads are not queued in the background there, as
+// queued reads would receive data from whichever FPGA is selected later.
+type Stream struct {
+	d    *Device
+	fpga int
+
+	// fifo is the FIFO size of the board from the catalog, or zero.
+	fifo int
+
+	intf USBInterface
+	in   BulkIn
+	out  BulkOut
+
+	// rmu serializes reads and guards rs, wmu serializes writes.
+	rmu sync.Mutex
+	wmu sync.Mutex
+
+	// ctx is done once the stream is closed, which aborts pending
+	// transfers.
+	ctx    context.Context
+	cancel context.CancelFunc
+	once   sync.Once
+
+	// rs reads from in through queued transfers, once opened.
+	rs BulkInStream
+}
+
+// FIFOSize returns the size in bytes of the FIFO behind the endpoints of
+// the stream, as recorded by the board catalog, or zero if the board is
+// not in the catalog.
+func (s *Stream) FIFOSize() int { return s.fifo }
+
+// transfers returns the size and number of the bulk transfers of the
+// stream, which are those of the device, except that the default size is
+// rounded down to a multiple of the FIFO size, if known.
+func (s *Stream) transfers() (int, int) {
+	size, count := s.d.transfers()
+	if s.d.xferSize == 0 && s.fifo > 0 {
+		size = max(size/s.fifo, 1) * s.fifo
+	}
+	return size, count
+}
+
+// transferContext returns a context for transfers of the stream, which is
+// done when ctx is done or the stream is closed.
+func (s *Stream) transferContext(ctx context.Context) (context.Context, context.CancelFunc, error) {
+	if s.ctx.Err() != nil {
+		return nil, nil, io.ErrClosedPipe
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(s.ctx, cancel)
+	return ctx, func() { stop(); cancel() }, nil
+}
+
+// Read reads data sent by the FPGA.
+func (s *Stream) Read(p []byte) (int, error) {
+	return s.ReadContext(context.Background(), p)
+}
+
+// ReadContext reads data sent by the FPGA, aborting when the context is
+// done.
+func (s *Stream) ReadContext(ctx context.Context, p []byte) (int, error) {
+	s.rmu.Lock()
+	defer s.rmu.Unlock()
+
+	ctx, stop, err := s.transferContext(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("(*ztex.Stream).ReadContext: %w", err)
+	}
+	defer stop()
+
+	release, err := s.d.acquireFPGA(s.fpga, false)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	size, count := s.transfers()
+	if x, ok := s.in.(StreamingBulkIn); ok && s.rs == nil && count > 1 && !s.d.Capability().MultiFPGA() {
+		rs, err := x.NewStream(size, count)
+		if err != nil {
+			return 0, fmt.Errorf("(ztex.StreamingBulkIn).NewStream: %w", err)
+		}
+		s.rs = rs
+	}
+
+	ctx, cancel := s.d.dataContext(ctx)
+	defer cancel()
+
+	start := s.d.clock.Now()
+	if s.rs != nil {
+		n, err := s.rs.ReadContext(ctx, p)
+		s.d.recordBulk(true, n, s.d.clock.Now().Sub(start), err)
+		if err != nil {
+			// A failed stream is not usable any more, so the next read
+			// opens a new one.
+			s.rs.Close()
+			s.rs = nil
+			return n, fmt.Errorf("(ztex.BulkInStream).ReadContext: %w", err)
+		}
+		return n, nil
+	}
+
+	n, err := s.in.ReadContext(ctx, p)
+	s.d.recordBulk(true, n, s.d.clock.Now().Sub(start), err)
+	if err != nil {
+		return n, fmt.Errorf("(ztex.BulkIn).ReadContext: %w", err)
+	}
+	return n, nil
+}
+
+// Write writes data to the FPGA.
+func (s *Stream) Write(p []byte) (int, error) {
+	return s.WriteContext(context.Background(), p)
+}
+
+// WriteContext writes data to the FPGA, aborting when the context is done.
+func (s *Stream) WriteContext(ctx context.Context, p []byte) (int, error) {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+
+	ctx, stop, err := s.transferContext(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("(*ztex.Stream).WriteContext: %w", err)
+	}
+	defer stop()
+
+	release, err := s.d.acquireFPGA(s.fpga, false)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	size, count := s.transfers()
+	return s.d.writeBulk(ctx, s.out, p, size, count, nil)
+}
+
+// ReadInto reads n bytes sent by the FPGA and writes them to w, using buf
+// for the transfers, so that no memory is allocated per transfer.  It
+// returns the number of bytes written to w, aborting when the context is
+// done.
+func (s *Stream) ReadInto(ctx context.Context, w io.Writer, n int64, buf []byte) (int64, error) {
+	if len(buf) == 0 {
+		return 0, fmt.Errorf("(*ztex.Stream).ReadInto: got empty buffer, want non-empty buffer")
+	}
+
+	t := int64(0)
+	for t < n {
+		k, err := s.ReadContext(ctx, buf[:min(int64(len(buf)), n-t)])
+		if k > 0 {
+			if _, err := w.Write(buf[:k]); err != nil {
+				return t, fmt.Errorf("(io.Writer).Write: %w", err)
+			}
+			t += int64(k)
+		}
+		if err != nil {
+			return t, err
+		}
+	}
+	return t, nil
+}
+
+// WriteFrom reads data from r until the end of the input and writes it to
+// the FPGA, using buf for the transfers, so that no memory is allocated
+// per transfer.  It returns the number of bytes written to the FPGA,
+// aborting when the context is done.
+func (s *Stream) WriteFrom(ctx context.Context, r io.Reader, buf []byte) (int64, error) {
+	if len(buf) == 0 {
+		return 0, fmt.Errorf("(*ztex.Stream).WriteFrom: got empty buffer, want non-empty buffer")
+	}
+
+	t := int64(0)
+	for {
+		k, err := io.ReadFull(r, buf)
+		if k > 0 {
+			n, err := s.WriteContext(ctx, buf[:k])
+			t += int64(n)
+			if err != nil {
+				return t, err
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return t, nil
+		} else if err != nil {
+			return t, fmt.Errorf("(io.Reader).Read: %w", err)
+		}
+	}
+}
+
+// Close stops reading from the FPGA and releases the interface claimed by
+// the stream.  Pending reads and writes are aborted, so Close may be
+// called to unblock them; later reads and writes fail with
+// io.ErrClosedPipe.
+func (s *Stream) Close() error {
+	var err error
+	s.once.Do(func() {
+		s.cancel()
+
+		s.rmu.Lock()
+		defer s.rmu.Unlock()
+		s.wmu.Lock()
+		defer s.wmu.Unlock()
+
+		if s.rs != nil {
+			if err = s.rs.Close(); err != nil {
+				err = fmt.Errorf("(ztex.BulkInStream).Close: %w", err)
+			}
+			s.rs = nil
+		}
+		s.intf.Close()
+	})
+	return err
+}
+
+// FPGAHandle directs LSI accesses and streams to one FPGA of a multi-FPGA
+// device.  The FPGA is selected before every access, if necessary, and
+// stays selected until the access is done, so that handles for different
+// FPGAs can be used concurrently: accesses to the same FPGA proceed
+// together, and those to another FPGA wait for them.
+type FPGAHandle struct {
+	d *Device
+	i int
+}
+
+// Index returns the index of the FPGA.
+func (f *FPGAHandle) Index() int { return f.i }
+
+// LSIRead reads len(v) consecutive registers of the FPGA, starting at the
+// given address.
+func (f *FPGAHandle) LSIRead(addr uint8, v []uint32, opt ...CallOption) error {
+	release, err := f.d.acquireFPGA(f.i, false)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	f.d.mu.Lock()
+	defer f.d.mu.Unlock()
+
+	return f.d.call(opt, func(callOptions) error { return f.d.lsiRead(addr, v) })
+}
+
+// LSIWrite writes v to consecutive registers of the FPGA, starting at the
+// given address.
+func (f *FPGAHandle) LSIWrite(addr uint8, v []uint32, opt ...CallOption) error {
+	release, err := f.d.acquireFPGA(f.i, false)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	f.d.mu.Lock()
+	defer f.d.mu.Unlock()
+
+	return f.d.call(opt, func(callOptions) error { return f.d.lsiWrite(addr, v) })
+}
+
+// OpenStream opens a stream to the FPGA.
+func (f *FPGAHandle) OpenStream() (*Stream, error) {
+	return f.d.openStream(f.i)
+}
@@ -0,0 +1,242 @@
+package ztex_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/aljumi/ztex"
+	"github.com/aljumi/ztex/ztextest"
+)
+
+// openStream opens a stream to a loopback design of a fake device attached
+// to the simulated bus.
+func openStream(t *testing.T) *ztex.Stream {
+	t.Helper()
+	ds, err := ztex.OpenBackendDevices(ztextest.Backend{ztextest.NewFakeDevice("fake000001")})
+	if err != nil {
+		t.Fatalf("ztex.OpenBackendDevices: %v", err)
+	}
+	t.Cleanup(func() { ds[0].Close() })
+
+	s, err := ds[0].OpenStream()
+	if err != nil {
+		t.Fatalf("(*ztex.Device).OpenStream: %v", err)
+	}
+	return s
+}
+
+func TestStreamFullDuplex(t *testing.T) {
+	s := openStream(t)
+	defer s.Close()
+
+	// The read blocks until the write, issued after it, is echoed.
+	want := bytes.Repeat([]byte("ztex"), 25)
+	got := make(chan []byte, 1)
+	go func() {
+		b := make([]byte, len(want))
+		n, err := io.ReadFull(s, b)
+		if err != nil {
+			t.Errorf("io.ReadFull: %v", err)
+		}
+		got <- b[:n]
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	if _, err := s.Write(want); err != nil {
+		t.Fatalf("(*ztex.Stream).Write: %v", err)
+	}
+
+	select {
+	case b := <-got:
+		if !bytes.Equal(b, want) {
+			t.Errorf("(*ztex.Stream).Read: got %q, want %q", b, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("(*ztex.Stream).Read: got no data after 5s, want the data written concurrently")
+	}
+}
+
+func TestStreamCloseUnblocks(t *testing.T) {
+	s := openStream(t)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.ReadContext(context.Background(), make([]byte, 16))
+		done <- err
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	if err := s.Close(); err != nil {
+		t.Fatalf("(*ztex.Stream).Close: %v", err)
+	}
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Errorf("(*ztex.Stream).ReadContext: got no error after Close, want error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("(*ztex.Stream).ReadContext: still blocked 5s after Close, want it aborted")
+	}
+
+	if _, err := s.Write([]byte{1}); !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("(*ztex.Stream).Write after Close: got error %v, want %v", err, io.ErrClosedPipe)
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("(*ztex.Stream).Close again: %v", err)
+	}
+}
+
+func TestStreamUSBBackend(t *testing.T) {
+	f := ztextest.NewFakeDevice("fake000001")
+	us, err := ztextest.Backend{f}.OpenDevices(ztex.VendorID, ztex.ProductID)
+	if err != nil {
+		t.Fatalf("(ztextest.Backend).OpenDevices: %v", err)
+	}
+
+	// The controller is the fake device itself, which has no bulk
+	// endpoints; they are reached through the USB device of the option.
+	d, err := ztextest.Open(f, ztex.USBBackend(us[0]))
+	if err != nil {
+		t.Fatalf("ztextest.Open: %v", err)
+	}
+	defer d.Close()
+	if d.USB() != us[0] {
+		t.Errorf("(*ztex.Device).USB: got %v, want %v", d.USB(), us[0])
+	}
+	if d.Desc == nil || *d.Desc != us[0].Describe() {
+		t.Errorf("(*ztex.Device).Desc: got %v, want %v", d.Desc, us[0].Describe())
+	}
+
+	s, err := d.OpenStream()
+	if err != nil {
+		t.Fatalf("(*ztex.Device).OpenStream: %v", err)
+	}
+	defer s.Close()
+	want := []byte("ztex")
+	if _, err := s.Write(want); err != nil {
+		t.Fatalf("(*ztex.Stream).Write: %v", err)
+	}
+	got := make([]byte, len(want))
+	if _, err := io.ReadFull(s, got); err != nil || !bytes.Equal(got, want) {
+		t.Errorf("(*ztex.Stream).Read: got %q, %v, want %q", got, err, want)
+	}
+}
+
+func TestStreamBulkMetrics(t *testing.T) {
+	ds, err := ztex.OpenBackendDevices(ztextest.Backend{ztextest.NewFakeDevice("fake000001")})
+	if err != nil {
+		t.Fatalf("ztex.OpenBackendDevices: %v", err)
+	}
+	defer ds[0].Close()
+	s, err := ds[0].OpenStream()
+	if err != nil {
+		t.Fatalf("(*ztex.Device).OpenStream: %v", err)
+	}
+	defer s.Close()
+
+	b := make([]byte, 1000)
+	if _, err := s.Write(b); err != nil {
+		t.Fatalf("(*ztex.Stream).Write: %v", err)
+	}
+	if _, err := io.ReadFull(s, b[:600]); err != nil {
+		t.Fatalf("(*ztex.Stream).Read: %v", err)
+	}
+
+	out, in := ds[0].BulkMetrics()
+	if out.Bytes != 1000 || out.Transfers == 0 || out.Errors != 0 {
+		t.Errorf("(*ztex.Device).BulkMetrics: got out %v, want 1000 bytes without errors", out)
+	}
+	if in.Bytes != 600 || in.Transfers == 0 || in.Errors != 0 {
+		t.Errorf("(*ztex.Device).BulkMetrics: got in %v, want 600 bytes without errors", in)
+	}
+	if x := (ztex.BulkMetrics{Bytes: 1 << 20, Duration: time.Second / 2}).Throughput(); x != 2<<20 {
+		t.Errorf("(ztex.BulkMetrics).Throughput: got %v, want %v", x, 2<<20)
+	}
+	if x := (ztex.BulkMetrics{Bytes: 1 << 20}).Throughput(); x != 0 {
+		t.Errorf("(ztex.BulkMetrics).Throughput without duration: got %v, want 0", x)
+	}
+}
+
+func TestStreamCatalogEndpoints(t *testing.T) {
+	// Without endpoints in the descriptor, those of the board catalog
+	// are used.
+	f := ztextest.NewFakeDevice("fake000001")
+	f.Descriptor[19], f.Descriptor[20] = 0, 0
+	ds, err := ztex.OpenBackendDevices(ztextest.Backend{f})
+	if err != nil {
+		t.Fatalf("ztex.OpenBackendDevices: %v", err)
+	}
+	defer ds[0].Close()
+
+	s, err := ds[0].OpenStream()
+	if err != nil {
+		t.Fatalf("(*ztex.Device).OpenStream: %v", err)
+	}
+	defer s.Close()
+	b, _ := ztex.LookupBoard(ds[0].DescriptorConfig.DescriptorProduct)
+	if s.FIFOSize() != b.Stream.FIFOSize || s.FIFOSize() == 0 {
+		t.Errorf("(*ztex.Stream).FIFOSize: got %v, want %v", s.FIFOSize(), b.Stream.FIFOSize)
+	}
+
+	want := []byte("ztex")
+	if _, err := s.Write(want); err != nil {
+		t.Fatalf("(*ztex.Stream).Write: %v", err)
+	}
+	got := make([]byte, len(want))
+	if _, err := io.ReadFull(s, got); err != nil || !bytes.Equal(got, want) {
+		t.Errorf("(*ztex.Stream).Read: got %q, %v, want %q", got, err, want)
+	}
+}
+
+func TestStreamMultiFPGA(t *testing.T) {
+	f := ztextest.NewFakeDevice("fake000001")
+	f.Descriptor[12] |= 0x80
+	f.FPGAs, f.Loopback = 2, false
+	ds, err := ztex.OpenBackendDevices(ztextest.Backend{f}, ztex.BulkTransfers(64, 1))
+	if err != nil {
+		t.Fatalf("ztex.OpenBackendDevices: %v", err)
+	}
+	defer ds[0].Close()
+
+	// Each FPGA gets its own index as data, from its own goroutine, so
+	// that data sent while the other FPGA was selected shows up.
+	const n = 200
+	errs := make(chan error, 2)
+	for i := range 2 {
+		h, err := ds[0].FPGA(i)
+		if err != nil {
+			t.Fatalf("(*ztex.Device).FPGA: %v", err)
+		}
+		s, err := h.OpenStream()
+		if err != nil {
+			t.Fatalf("(*ztex.FPGAHandle).OpenStream: %v", err)
+		}
+		defer s.Close()
+		go func() {
+			for range n {
+				if _, err := s.Write(bytes.Repeat([]byte{byte(i)}, 64)); err != nil {
+					errs <- err
+					return
+				}
+			}
+			errs <- nil
+		}()
+	}
+	for range 2 {
+		if err := <-errs; err != nil {
+			t.Fatalf("(*ztex.Stream).Write: %v", err)
+		}
+	}
+
+	for i := range 2 {
+		b := f.FPGAData(i)
+		if want := bytes.Repeat([]byte{byte(i)}, 64*n); !bytes.Equal(b, want) {
+			t.Errorf("FPGA %v: got %v bytes including %v bytes for the other FPGA, want %v bytes for FPGA %v", i, len(b), len(b)-bytes.Count(b, []byte{byte(i)}), len(want), i)
+		}
+	}
+}
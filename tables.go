@@ -0,0 +1,123 @@
+package ztex
+
+import (
+	"fmt"
+	"sync"
+)
+
+// The names of ZTEX products and FPGA types are kept in tables rather than
+// in the String methods, so that boards and FPGAs which are newer than
+// this package can be named with RegisterProduct and RegisterFPGAType.
+var (
+	tablesMu sync.RWMutex
+
+	// products holds the names of the ZTEX products by prefix of their
+	// product IDs.  The longest matching prefix names a product, and of
+	// prefixes of the same length, the one registered last.
+	products = []productEntry{
+		{[]uint8{0, 0, 0, 0}, "Default"},
+		{[]uint8{1}, "Experimental"},
+		{[]uint8{10, 0, 1, 1}, "ZTEX BTCMiner"},
+		{[]uint8{10, 11}, "ZTEX USB-FPGA Module 1.2"},
+		{[]uint8{10, 12, 2, 1}, "NIT"},
+		{[]uint8{10, 12, 2, 2}, "NIT"},
+		{[]uint8{10, 12, 2, 3}, "NIT"},
+		{[]uint8{10, 12, 2, 4}, "NIT"},
+		{[]uint8{10, 12}, "ZTEX USB-FPGA Module 1.11"},
+		{[]uint8{10, 13}, "ZTEX USB-FPGA Module 1.15"},
+		{[]uint8{10, 14}, "ZTEX USB-FPGA Module 1.15x"},
+		{[]uint8{10, 15}, "ZTEX USB-FPGA Module 1.15y"},
+		{[]uint8{10, 16}, "ZTEX USB-FPGA Module 2.16"},
+		{[]uint8{10, 17}, "ZTEX USB-FPGA Module 2.13"},
+		{[]uint8{10, 18}, "ZTEX USB-FPGA Module 2.01"},
+		{[]uint8{10, 19}, "ZTEX USB-FPGA Module 2.04"},
+		{[]uint8{10, 20}, "ZTEX USB Module 1.0"},
+		{[]uint8{10, 30}, "ZTEX USB-XMEGA Module 1.0"},
+		{[]uint8{10, 40}, "ZTEX USB-FPGA Module 2.02"},
+		{[]uint8{10, 41}, "ZTEX USB-FPGA Module 2.14"},
+		{[]uint8{10, 42}, "ZTEX USB3-FPGA Module 2.18"},
+		{[]uint8{10}, "ZTEX"},
+	}
+
+	// fpgaTypes holds the names of the FPGA types.
+	fpgaTypes = map[FPGAType]string{
+		MakeFPGAType(FPGAXC6SLX9):     "Xilinx Spartan-6 XC6SLX9",
+		MakeFPGAType(FPGAXC6SLX16):    "Xilinx Spartan-6 XC6SLX16",
+		MakeFPGAType(FPGAXC6SLX25):    "Xilinx Spartan-6 XC6SLX25",
+		MakeFPGAType(FPGAXC6SLX45):    "Xilinx Spartan-6 XC6SLX45",
+		MakeFPGAType(FPGAXC6SLX75):    "Xilinx Spartan-6 XC6SLX75",
+		MakeFPGAType(FPGAXC6SLX100):   "Xilinx Spartan-6 XC6SLX100",
+		MakeFPGAType(FPGAXC6SLX150):   "Xilinx Spartan-6 XC6SLX150",
+		MakeFPGAType(FPGAXC7A35T):     "Xilinx Artix-7 XC7A35T",
+		MakeFPGAType(FPGAXC7A50T):     "Xilinx Artix-7 XC7A50T",
+		MakeFPGAType(FPGAXC7A75T):     "Xilinx Artix-7 XC7A75T",
+		MakeFPGAType(FPGAXC7A100T):    "Xilinx Artix-7 XC7A100T",
+		MakeFPGAType(FPGAXC7A200T):    "Xilinx Artix-7 XC7A200T",
+		MakeFPGAType(FPGAXC6SLX150x4): "Xilinx Spartan-6 XC6SLX150 [x4]",
+		MakeFPGAType(FPGAXC7A15T):     "Xilinx Artix-7 XC7A15T",
+	}
+)
+
+// productEntry names the products whose IDs start with prefix.
+type productEntry struct {
+	prefix []uint8
+	name   string
+}
+
+// RegisterProduct names the ZTEX products whose product IDs start with the
+// given prefix of one to four bytes, taking precedence over the names of
+// shorter prefixes, such as the generic name of the vendor.  It is meant
+// to be called from init functions, and panics if the prefix is empty or
+// too long.
+func RegisterProduct(name string, prefix ...uint8) {
+	if len(prefix) == 0 || len(prefix) > len(DescriptorProduct{}) {
+		panic(fmt.Sprintf("ztex.RegisterProduct: got prefix of %v bytes, want prefix of 1 to %v bytes", len(prefix), len(DescriptorProduct{})))
+	}
+
+	tablesMu.Lock()
+	defer tablesMu.Unlock()
+	products = append(products, productEntry{append([]uint8{}, prefix...), name})
+}
+
+// RegisterFPGAType names the FPGA type, replacing any previous name.  It
+// is meant to be called from init functions.
+func RegisterFPGAType(f FPGAType, name string) {
+	tablesMu.Lock()
+	defer tablesMu.Unlock()
+	fpgaTypes[f] = name
+}
+
+// productName returns the name of the product with the given ID.
+func productName(d DescriptorProduct) string {
+	tablesMu.RLock()
+	defer tablesMu.RUnlock()
+
+	p, n := Unknown, 0
+	for _, e := range products {
+		if len(e.prefix) >= n && hasPrefix(d, e.prefix) {
+			p, n = e.name, len(e.prefix)
+		}
+	}
+	return p
+}
+
+// hasPrefix reports whether or not the product ID starts with prefix.
+func hasPrefix(d DescriptorProduct, prefix []uint8) bool {
+	for i, c := range prefix {
+		if d[i] != c {
+			return false
+		}
+	}
+	return true
+}
+
+// fpgaTypeName returns the name of the FPGA type.
+func fpgaTypeName(f FPGAType) string {
+	tablesMu.RLock()
+	defer tablesMu.RUnlock()
+
+	if n, ok := fpgaTypes[f]; ok {
+		return n
+	}
+	return Unknown
+}
@@ -0,0 +1,320 @@
+package ztex
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The types in this file implement encoding.TextMarshaler and
+// encoding.TextUnmarshaler, so that they can be used directly in
+// configuration files and as flag values.  Values with a known name are
+// marshaled as that name and others as a decimal number.  Unmarshaling
+// accepts either form; names are compared without regard to case.
+
+// formatName returns the name of the value v, or v as a decimal number if
+// the value has no known name.
+func formatName(v int, name string) []byte {
+	if !isKnown(name) {
+		return []byte(strconv.Itoa(v))
+	}
+	return []byte(name)
+}
+
+// parseName returns the value below n whose text representation is b,
+// which is either a number or the name returned by name.
+func parseName(b []byte, n int, name func(int) string) (int, error) {
+	s := string(b)
+	if v, err := strconv.ParseUint(s, 0, 64); err == nil {
+		if v >= uint64(n) {
+			return 0, fmt.Errorf("got %v, want a value below %v", v, n)
+		}
+		return int(v), nil
+	}
+	for i := 0; i < n; i++ {
+		if x := name(i); isKnown(x) && strings.EqualFold(x, s) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("got %q, want a number or a known name", s)
+}
+
+// parseString returns the bytes of b, padded with zero bytes to n bytes.
+func parseString(b []byte, n int) ([]byte, error) {
+	if len(b) > n {
+		return nil, fmt.Errorf("got %v bytes, want at most %v bytes", len(b), n)
+	}
+	x := make([]byte, n)
+	copy(x, b)
+	return x, nil
+}
+
+// MarshalText returns the name or number of the board type.
+func (b BoardType) MarshalText() ([]byte, error) {
+	return formatName(int(b), b.String()), nil
+}
+
+// UnmarshalText sets the board type from its name or number.
+func (b *BoardType) UnmarshalText(x []byte) error {
+	v, err := parseName(x, 1<<8, func(i int) string { return BoardType(i).String() })
+	if err != nil {
+		return fmt.Errorf("board type: %w", err)
+	}
+	*b = BoardType(v)
+	return nil
+}
+
+// MarshalText returns the number of the board series.
+func (b BoardSeries) MarshalText() ([]byte, error) {
+	return formatName(int(b), b.String()), nil
+}
+
+// UnmarshalText sets the board series from its number.
+func (b *BoardSeries) UnmarshalText(x []byte) error {
+	v, err := parseName(x, 1<<8, func(i int) string { return BoardSeries(i).String() })
+	if err != nil {
+		return fmt.Errorf("board series: %w", err)
+	}
+	*b = BoardSeries(v)
+	return nil
+}
+
+// MarshalText returns the board number.
+func (b BoardNumber) MarshalText() ([]byte, error) {
+	return formatName(int(b), b.String()), nil
+}
+
+// UnmarshalText sets the board number.
+func (b *BoardNumber) UnmarshalText(x []byte) error {
+	v, err := parseName(x, 1<<8, func(i int) string { return BoardNumber(i).String() })
+	if err != nil {
+		return fmt.Errorf("board number: %w", err)
+	}
+	*b = BoardNumber(v)
+	return nil
+}
+
+// MarshalText returns the board variant, such as "b".
+func (b BoardVariant) MarshalText() ([]byte, error) {
+	return b.Bytes(), nil
+}
+
+// UnmarshalText sets the board variant, which has at most two bytes.
+func (b *BoardVariant) UnmarshalText(x []byte) error {
+	v, err := parseString(x, len(b))
+	if err != nil {
+		return fmt.Errorf("board variant: %w", err)
+	}
+	*b = BoardVariant(v)
+	return nil
+}
+
+// MarshalText returns the board version, such as "2.13b".
+func (b BoardVersion) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%d.%d%s", b.BoardSeries, b.BoardNumber, b.BoardVariant.Bytes())), nil
+}
+
+// UnmarshalText sets the board version from the series, the number, and
+// the variant, such as "2.13b".
+func (b *BoardVersion) UnmarshalText(x []byte) error {
+	s, n, ok := strings.Cut(string(x), ".")
+	if !ok {
+		return fmt.Errorf("board version: got %q, want series.number[variant]", x)
+	}
+	i := len(n) - len(strings.TrimLeft(n, "0123456789"))
+
+	v := BoardVersion{}
+	if err := v.BoardSeries.UnmarshalText([]byte(s)); err != nil {
+		return fmt.Errorf("board version: %w", err)
+	} else if err := v.BoardNumber.UnmarshalText([]byte(n[:i])); err != nil {
+		return fmt.Errorf("board version: %w", err)
+	} else if err := v.BoardVariant.UnmarshalText([]byte(n[i:])); err != nil {
+		return fmt.Errorf("board version: %w", err)
+	}
+	*b = v
+	return nil
+}
+
+// MarshalText returns the name or number of the FPGA type.
+func (f FPGAType) MarshalText() ([]byte, error) {
+	return formatName(int(f.Number()), f.String()), nil
+}
+
+// UnmarshalText sets the FPGA type from its name or number.
+func (f *FPGAType) UnmarshalText(x []byte) error {
+	v, err := parseName(x, 1<<16, func(i int) string { return MakeFPGAType(uint16(i)).String() })
+	if err != nil {
+		return fmt.Errorf("FPGA type: %w", err)
+	}
+	*f = MakeFPGAType(uint16(v))
+	return nil
+}
+
+// MarshalText returns the name or number of the FPGA package.
+func (f FPGAPackage) MarshalText() ([]byte, error) {
+	return formatName(int(f), f.String()), nil
+}
+
+// UnmarshalText sets the FPGA package from its name or number.
+func (f *FPGAPackage) UnmarshalText(x []byte) error {
+	v, err := parseName(x, 1<<8, func(i int) string { return FPGAPackage(i).String() })
+	if err != nil {
+		return fmt.Errorf("FPGA package: %w", err)
+	}
+	*f = FPGAPackage(v)
+	return nil
+}
+
+// MarshalText returns the FPGA grade, such as "2C".
+func (f FPGAGrade) MarshalText() ([]byte, error) {
+	return f.Bytes(), nil
+}
+
+// UnmarshalText sets the FPGA grade, which has at most three bytes.
+func (f *FPGAGrade) UnmarshalText(x []byte) error {
+	v, err := parseString(x, len(f))
+	if err != nil {
+		return fmt.Errorf("FPGA grade: %w", err)
+	}
+	*f = FPGAGrade(v)
+	return nil
+}
+
+// MarshalText returns the FPGA configuration indicator.
+func (f FPGAConfigured) MarshalText() ([]byte, error) {
+	return formatName(int(f), f.String()), nil
+}
+
+// UnmarshalText sets the FPGA configuration indicator from its name or
+// number.
+func (f *FPGAConfigured) UnmarshalText(x []byte) error {
+	v, err := parseName(x, 1<<8, func(i int) string { return FPGAConfigured(i).String() })
+	if err != nil {
+		return fmt.Errorf("FPGA configured: %w", err)
+	}
+	*f = FPGAConfigured(v)
+	return nil
+}
+
+// MarshalText returns the FPGA configuration result.
+func (f FPGAResult) MarshalText() ([]byte, error) {
+	return formatName(int(f), f.String()), nil
+}
+
+// UnmarshalText sets the FPGA configuration result from its name or
+// number.
+func (f *FPGAResult) UnmarshalText(x []byte) error {
+	v, err := parseName(x, 1<<8, func(i int) string { return FPGAResult(i).String() })
+	if err != nil {
+		return fmt.Errorf("FPGA result: %w", err)
+	}
+	*f = FPGAResult(v)
+	return nil
+}
+
+// MarshalText returns the bitstream bit order.
+func (f FPGASwapped) MarshalText() ([]byte, error) {
+	return formatName(int(f), f.String()), nil
+}
+
+// UnmarshalText sets the bitstream bit order from its name or number.
+func (f *FPGASwapped) UnmarshalText(x []byte) error {
+	v, err := parseName(x, 1<<8, func(i int) string { return FPGASwapped(i).String() })
+	if err != nil {
+		return fmt.Errorf("FPGA swapped: %w", err)
+	}
+	*f = FPGASwapped(v)
+	return nil
+}
+
+// MarshalText returns the raw number of the RAM size.  The human-readable
+// size is not used, since several raw numbers encode the same size.
+func (r RAMSize) MarshalText() ([]byte, error) {
+	return []byte(strconv.Itoa(int(r))), nil
+}
+
+// UnmarshalText sets the RAM size from its raw number.
+func (r *RAMSize) UnmarshalText(x []byte) error {
+	v, err := parseName(x, 1<<8, func(int) string { return Unknown })
+	if err != nil {
+		return fmt.Errorf("RAM size: %w", err)
+	}
+	*r = RAMSize(v)
+	return nil
+}
+
+// MarshalText returns the name or number of the RAM type.
+func (r RAMType) MarshalText() ([]byte, error) {
+	return formatName(int(r), r.String()), nil
+}
+
+// UnmarshalText sets the RAM type from its name or number.
+func (r *RAMType) UnmarshalText(x []byte) error {
+	v, err := parseName(x, 1<<8, func(i int) string { return RAMType(i).String() })
+	if err != nil {
+		return fmt.Errorf("RAM type: %w", err)
+	}
+	*r = RAMType(v)
+	return nil
+}
+
+// MarshalText returns whether or not the flash is enabled.
+func (f FlashEnabled) MarshalText() ([]byte, error) {
+	return formatName(int(f), f.String()), nil
+}
+
+// UnmarshalText sets whether or not the flash is enabled from its name or
+// number.
+func (f *FlashEnabled) UnmarshalText(x []byte) error {
+	v, err := parseName(x, 1<<8, func(i int) string { return FlashEnabled(i).String() })
+	if err != nil {
+		return fmt.Errorf("flash enabled: %w", err)
+	}
+	*f = FlashEnabled(v)
+	return nil
+}
+
+// MarshalText returns the name or number of the flash error code.
+func (f FlashError) MarshalText() ([]byte, error) {
+	return formatName(int(f), f.String()), nil
+}
+
+// UnmarshalText sets the flash error code from its name or number.
+func (f *FlashError) UnmarshalText(x []byte) error {
+	v, err := parseName(x, 1<<8, func(i int) string { return FlashError(i).String() })
+	if err != nil {
+		return fmt.Errorf("flash error: %w", err)
+	}
+	*f = FlashError(v)
+	return nil
+}
+
+// MarshalText returns the name of the monitor level.
+func (m MonitorLevel) MarshalText() ([]byte, error) {
+	return formatName(int(m), m.String()), nil
+}
+
+// UnmarshalText sets the monitor level from its name or number.
+func (m *MonitorLevel) UnmarshalText(x []byte) error {
+	v, err := parseName(x, 1<<8, func(i int) string { return MonitorLevel(i).String() })
+	if err != nil {
+		return fmt.Errorf("monitor level: %w", err)
+	}
+	*m = MonitorLevel(v)
+	return nil
+}
+
+// MarshalText returns the name of the report format.
+func (r ReportFormat) MarshalText() ([]byte, error) {
+	return formatName(int(r), r.String()), nil
+}
+
+// UnmarshalText sets the report format from its name or number.
+func (r *ReportFormat) UnmarshalText(x []byte) error {
+	v, err := parseName(x, 1<<8, func(i int) string { return ReportFormat(i).String() })
+	if err != nil {
+		return fmt.Errorf("report format: %w", err)
+	}
+	*r = ReportFormat(v)
+	return nil
+}
@@ -0,0 +1,61 @@
+package ztex_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aljumi/ztex"
+	"github.com/aljumi/ztex/ztextest"
+)
+
+// failingController fails every transfer with err.
+type failingController struct{ err error }
+
+func (c failingController) Control(rType, request uint8, val, idx uint16, data []byte) (int, error) {
+	return 0, c.err
+}
+
+func TestReplayerErrors(t *testing.T) {
+	cmd := &ztex.CommandError{RequestType: 0xc0, Request: 0x30, Want: 9, Err: ztex.ErrNoDevice}
+	for _, tc := range []struct {
+		name     string
+		err      error
+		sentinel error
+		command  bool
+	}{
+		{"plain error", errors.New("device on fire"), nil, false},
+		{"sentinel", fmt.Errorf("%w: unplugged", ztex.ErrNoDevice), ztex.ErrNoDevice, false},
+		{"stall", fmt.Errorf("%w: request 0x30", ztextest.ErrStall), ztextest.ErrStall, false},
+		{"command error", fmt.Errorf("get FPGA state: %w", cmd), ztex.ErrNoDevice, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			b := &bytes.Buffer{}
+			r := ztextest.NewRecorder(failingController{tc.err}, b)
+			if _, err := r.Control(0xc0, 0x30, 0, 0, make([]byte, 9)); err != tc.err {
+				t.Fatalf("(*ztextest.Recorder).Control: got error %v, want %v", err, tc.err)
+			} else if err := r.Err(); err != nil {
+				t.Fatalf("(*ztextest.Recorder).Err: %v", err)
+			}
+
+			p, err := ztextest.NewReplayer(b)
+			if err != nil {
+				t.Fatalf("ztextest.NewReplayer: %v", err)
+			}
+			_, err = p.Control(0xc0, 0x30, 0, 0, make([]byte, 9))
+			if err == nil || err.Error() != tc.err.Error() {
+				t.Fatalf("(*ztextest.Replayer).Control: got error %v, want %v", err, tc.err)
+			}
+			if tc.sentinel != nil && !errors.Is(err, tc.sentinel) {
+				t.Errorf("(*ztextest.Replayer).Control: got error %v, want an error matching %v", err, tc.sentinel)
+			}
+			c := (*ztex.CommandError)(nil)
+			if got := errors.As(err, &c); got != tc.command {
+				t.Errorf("(*ztextest.Replayer).Control: got *ztex.CommandError %v, want %v", got, tc.command)
+			} else if got && (c.Request != cmd.Request || c.Want != cmd.Want || c.RequestType != cmd.RequestType) {
+				t.Errorf("(*ztextest.Replayer).Control: got %#v, want %#v", c, cmd)
+			}
+		})
+	}
+}
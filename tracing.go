@@ -0,0 +1,78 @@
+package ztex
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Tracer starts a span for every long-running operation of a device, such
+// as ConfigureFPGA, WriteFlash, and UploadFirmware, so that provisioning
+// pipelines can be profiled end to end.  Package tracing adapts
+// OpenTelemetry tracers.
+type Tracer interface {
+	// Start starts a span for the operation with the given name, which is
+	// the name of the method, such as "ConfigureFPGA".  The returned
+	// context is passed to the operations performed as part of the
+	// operation, so that their spans are nested.
+	Start(ctx context.Context, name string, attr ...slog.Attr) (context.Context, Span)
+}
+
+// Span is an operation in progress.
+type Span interface {
+	// SetAttributes records attributes of the operation, such as the
+	// number of bytes transferred.
+	SetAttributes(attr ...slog.Attr)
+
+	// End ends the operation, which failed if err is not nil.
+	End(err error)
+}
+
+// WithTracer starts a span through t for every long-running operation of
+// the device.
+func WithTracer(t Tracer) DeviceOption {
+	return func(d *Device) error {
+		d.tracer = t
+		return nil
+	}
+}
+
+// noSpan is the span of operations of devices without a tracer.
+type noSpan struct{}
+
+func (noSpan) SetAttributes(...slog.Attr) {}
+
+func (noSpan) End(error) {}
+
+// operation is a span together with the number of bytes the operation
+// has processed so far, which is recorded as the "transferred" attribute
+// when the span ends.
+type operation struct {
+	Span
+	done int64
+}
+
+// startSpan starts a span for an operation of the device, with the serial
+// number of the device among its attributes.
+func (d *Device) startSpan(ctx context.Context, name string, attr ...slog.Attr) (context.Context, *operation) {
+	if d.tracer == nil {
+		return ctx, &operation{Span: noSpan{}}
+	}
+	attr = append([]slog.Attr{slog.String("serial", d.Serial().String())}, attr...)
+	ctx, s := d.tracer.Start(ctx, name, attr...)
+	return ctx, &operation{Span: s}
+}
+
+// progress returns a Progress which counts the bytes processed by the
+// operation and reports to p.
+func (o *operation) progress(p Progress) Progress {
+	return func(done, total int64) {
+		o.done = done
+		p.report(done, total)
+	}
+}
+
+// end ends the span of the operation.
+func (o *operation) end(err error) {
+	o.SetAttributes(slog.Int64("transferred", o.done))
+	o.End(err)
+}
@@ -0,0 +1,71 @@
+// Package tracing records the long-running operations of ZTEX modules,
+// such as FPGA configuration and flash writes, as OpenTelemetry spans.
+package tracing
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/aljumi/ztex"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracer adapts an OpenTelemetry tracer to ztex.Tracer.  Spans are named
+// after the operation with the prefix "ztex.", such as
+// "ztex.ConfigureFPGA", and their attributes carry the prefix "ztex." as
+// well.
+type Tracer struct {
+	trace.Tracer
+}
+
+// New returns a device option which records the operations of the device
+// as spans of t.
+func New(t trace.Tracer) ztex.DeviceOption {
+	return ztex.WithTracer(Tracer{t})
+}
+
+// Start implements ztex.Tracer.
+func (t Tracer) Start(ctx context.Context, name string, attr ...slog.Attr) (context.Context, ztex.Span) {
+	ctx, s := t.Tracer.Start(ctx, "ztex."+name, trace.WithAttributes(attributes(attr)...))
+	return ctx, span{s}
+}
+
+// span adapts an OpenTelemetry span to ztex.Span.
+type span struct {
+	s trace.Span
+}
+
+func (s span) SetAttributes(attr ...slog.Attr) {
+	s.s.SetAttributes(attributes(attr)...)
+}
+
+func (s span) End(err error) {
+	if err != nil {
+		s.s.RecordError(err)
+		s.s.SetStatus(codes.Error, err.Error())
+	}
+	s.s.End()
+}
+
+// attributes converts slog attributes into OpenTelemetry attributes.
+func attributes(attr []slog.Attr) []attribute.KeyValue {
+	x := []attribute.KeyValue{}
+	for _, a := range attr {
+		k, v := "ztex."+a.Key, a.Value.Resolve()
+		switch v.Kind() {
+		case slog.KindInt64:
+			x = append(x, attribute.Int64(k, v.Int64()))
+		case slog.KindUint64:
+			x = append(x, attribute.Int64(k, int64(v.Uint64())))
+		case slog.KindFloat64:
+			x = append(x, attribute.Float64(k, v.Float64()))
+		case slog.KindBool:
+			x = append(x, attribute.Bool(k, v.Bool()))
+		default:
+			x = append(x, attribute.String(k, v.String()))
+		}
+	}
+	return x
+}
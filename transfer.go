@@ -0,0 +1,140 @@
+package ztex
+
+import (
+	"context"
+	"fmt"
+)
+
+// The default size and number of the bulk transfers kept in flight by
+// high-speed FPGA configuration and streams.
+const (
+	defaultTransferSize  = 64 << 10
+	defaultTransferCount = 8
+)
+
+// BulkTransfers sets the size in bytes and the number of the bulk
+// transfers which high-speed FPGA configuration and streams keep in flight
+// at once, if the backend supports queued transfers.  A single outstanding
+// transfer leaves the bus idle between transfers, which limits USB 3.0
+// modules to a fraction of their bandwidth.  The default is 8 transfers
+// of 64 kiB, except that high-speed FPGA configuration probes several
+// transfer sizes and uses the fastest unless the size is set with this
+// option.
+func BulkTransfers(size, count int) DeviceOption {
+	return func(d *Device) error {
+		if size <= 0 || count <= 0 {
+			return fmt.Errorf("ztex.BulkTransfers: got %v transfers of %v bytes, want a positive number of transfers of a positive size", count, size)
+		}
+		d.xferSize, d.xferCount = size, count
+		return nil
+	}
+}
+
+// transfers returns the size and number of the bulk transfers kept in
+// flight.
+func (d *Device) transfers() (int, int) {
+	if d.xferSize == 0 {
+		return defaultTransferSize, defaultTransferCount
+	}
+	return d.xferSize, d.xferCount
+}
+
+// dataContext returns the context for one bulk transfer, which is done
+// after the timeout set by DataTimeout, if any.
+func (d *Device) dataContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if d.dataTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, d.dataTimeout)
+}
+
+// tuneSizes are the transfer sizes probed by writeBulkTuned, in
+// increasing order.
+var tuneSizes = []int{16 << 10, 64 << 10, 256 << 10}
+
+// tuneTransfers is the number of transfers with which writeBulkTuned
+// probes each of the sizes in tuneSizes.
+const tuneTransfers = 2
+
+// writeBulkTuned writes b to a bulk OUT endpoint like writeBulk, but
+// unless the transfer size was set with BulkTransfers, it first writes a
+// prefix of b with tuneTransfers transfers of each of the sizes in
+// tuneSizes and writes the remainder with the fastest of them.  The
+// fastest size depends on the EZ-USB controller and on the host
+// controller, so it is probed anew every time.  The prefix takes 672 kiB;
+// if b is shorter than twice that, then the default size is used.
+func (d *Device) writeBulkTuned(ctx context.Context, out BulkOut, b []byte, progress Progress) (int, error) {
+	size, count := d.transfers()
+	probe := 0
+	for _, z := range tuneSizes {
+		probe += tuneTransfers * z
+	}
+	if d.xferSize != 0 || len(b) < 2*probe {
+		return d.writeBulk(ctx, out, b, size, count, progress)
+	}
+
+	t, i := int64(len(b)), 0
+	best := 0.0
+	for _, z := range tuneSizes {
+		n := tuneTransfers * z
+		s := d.clock.Now()
+		if nbw, err := d.writeBulk(ctx, out, b[i:i+n], z, count, progress.offset(int64(i), t)); err != nil {
+			return i + nbw, err
+		}
+		if r := float64(n) / d.clock.Now().Sub(s).Seconds(); r > best {
+			best, size = r, z
+		}
+		i += n
+	}
+
+	n, err := d.writeBulk(ctx, out, b[i:], size, count, progress.offset(int64(i), t))
+	return i + n, err
+}
+
+// writeBulk writes b to a bulk OUT endpoint in transfers of the given
+// size, keeping count transfers in flight if the endpoint supports it, and
+// reports the progress after each transfer.  It returns once all data has
+// been sent.
+func (d *Device) writeBulk(ctx context.Context, out BulkOut, b []byte, size, count int, progress Progress) (int, error) {
+	var w BulkOut = out
+	var s BulkOutStream
+	if x, ok := out.(StreamingBulkOut); ok && count > 1 && len(b) > size {
+		var err error
+		if s, err = x.NewStream(size, count); err != nil {
+			return 0, fmt.Errorf("(ztex.StreamingBulkOut).NewStream: %w", err)
+		}
+		w = s
+	}
+
+	t := len(b)
+	for i := 0; i < t; {
+		n := min(t-i, size)
+		c, cancel := d.dataContext(ctx)
+		start := d.clock.Now()
+		nbw, err := w.WriteContext(c, b[i:i+n])
+		d.recordBulk(false, nbw, d.clock.Now().Sub(start), err)
+		cancel()
+		if err != nil {
+			if s != nil {
+				s.CloseContext(ctx)
+			}
+			return i + nbw, fmt.Errorf("(ztex.BulkOut).WriteContext: %w", err)
+		} else if nbw != n {
+			if s != nil {
+				s.CloseContext(ctx)
+			}
+			return i + nbw, fmt.Errorf("(ztex.BulkOut).WriteContext: got %v bytes, want %v bytes", nbw, n)
+		}
+		i += n
+		progress.report(int64(i), int64(t))
+	}
+
+	if s != nil {
+		c, cancel := d.dataContext(ctx)
+		defer cancel()
+		if err := s.CloseContext(c); err != nil {
+			return t, fmt.Errorf("(ztex.BulkOutStream).CloseContext: %w", err)
+		}
+	}
+	return t, nil
+}
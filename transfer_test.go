@@ -0,0 +1,67 @@
+package ztex_test
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/aljumi/ztex"
+	"github.com/aljumi/ztex/ztextest"
+)
+
+// testBitstream returns a raw bitstream of n bytes, which the fake device
+// accepts.
+func testBitstream(n int) []byte {
+	b := bytes.Repeat([]byte{0xff}, n)
+	copy(b[64:], []byte{0xaa, 0x99, 0x55, 0x66})
+	return b
+}
+
+func TestConfigureFPGAHighSpeedTuning(t *testing.T) {
+	const k = 1 << 10
+	probe := []int{16 * k, 16 * k, 64 * k, 64 * k, 256 * k, 256 * k}
+	for _, tc := range []struct {
+		name  string
+		size  int
+		opt   []ztex.DeviceOption
+		probe bool
+	}{
+		// An XC7A35T bitstream is 2,192,012 bytes long.
+		{"XC7A35T bitstream", 2192012, nil, true},
+		{"shortest tuned bitstream", 2 * 672 * k, nil, true},
+		{"short bitstream", 2*672*k - 1, nil, false},
+		{"transfer size set", 2192012, []ztex.DeviceOption{ztex.BulkTransfers(32*k, 2)}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f := ztextest.NewFakeDevice("fake000001")
+			f.Descriptor[12] |= 0x20
+			ds, err := ztex.OpenBackendDevices(ztextest.Backend{f}, tc.opt...)
+			if err != nil {
+				t.Fatalf("ztex.OpenBackendDevices: %v", err)
+			}
+			defer ds[0].Close()
+
+			if err := ds[0].ConfigureFPGAHighSpeed(context.Background(), bytes.NewReader(testBitstream(tc.size)), nil); err != nil {
+				t.Fatalf("(*ztex.Device).ConfigureFPGAHighSpeed: %v", err)
+			}
+
+			x := f.HighSpeedTransfers()
+			n := 0
+			for _, z := range x {
+				n += z
+			}
+			if n != tc.size {
+				t.Errorf("(*ztextest.FakeDevice).HighSpeedTransfers: got %v bytes, want %v bytes", n, tc.size)
+			}
+			if got := len(x) >= len(probe) && slices.Equal(x[:len(probe)], probe); got != tc.probe {
+				t.Errorf("(*ztextest.FakeDevice).HighSpeedTransfers: got transfers %v..., want probing %v", x[:min(len(x), len(probe))], tc.probe)
+			}
+			if tc.probe {
+				if z := x[len(probe)]; !slices.Contains(probe, z) {
+					t.Errorf("(*ztextest.FakeDevice).HighSpeedTransfers: got size %v after probing, want a probed size", z)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package ztex
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// UdevRulePath is where WriteUdevRule installs the udev rule by default.
+const UdevRulePath = "/etc/udev/rules.d/99-ztex.rules"
+
+// udevGroup matches the group names which udev rules may name, which are
+// those accepted by useradd and cannot break out of the quoted value.
+var udevGroup = regexp.MustCompile(`^[a-z_][a-z0-9_-]{0,31}$`)
+
+// UdevRule returns a udev rule which grants access to ZTEX modules, as
+// identified by their vendor ID, to the user logged in at the seat of the
+// host through the uaccess tag, and to the members of the given group, if
+// any, such as plugdev.  Other users are denied access.
+func UdevRule(group string) (string, error) {
+	return udevRule(fmt.Sprintf(`ATTR{idVendor}=="%04x"`, VendorID), group)
+}
+
+// udevRule returns a udev rule like UdevRule for the USB devices matched by
+// the given attributes.
+func udevRule(match, group string) (string, error) {
+	if group == "" {
+		return fmt.Sprintf(`SUBSYSTEM=="usb", %v, MODE="0660", TAG+="uaccess"`, match), nil
+	} else if !udevGroup.MatchString(group) {
+		return "", fmt.Errorf("ztex.UdevRule: got group %q, want a group name of lower case letters, digits, underscores, and hyphens", group)
+	}
+	return fmt.Sprintf(`SUBSYSTEM=="usb", %v, MODE="0660", GROUP="%v", TAG+="uaccess"`, match, group), nil
+}
+
+// WriteUdevRule writes the rule returned by UdevRule to the file at the
+// given path, or at UdevRulePath if the path is empty.  Writing to
+// /etc/udev/rules.d requires root privileges, and the rule applies to
+// modules which are connected after udev has reloaded its rules:
+//
+//	udevadm control --reload-rules && udevadm trigger
+func WriteUdevRule(path, group string) error {
+	if path == "" {
+		path = UdevRulePath
+	}
+	r, err := UdevRule(group)
+	if err != nil {
+		return err
+	}
+	b := fmt.Sprintf("# ZTEX USB-FPGA modules\n%v\n", r)
+	if err := os.WriteFile(path, []byte(b), 0o644); err != nil {
+		return fmt.Errorf("ztex.WriteUdevRule: %w", err)
+	}
+	return nil
+}
+
+// PermissionError indicates that the host denied access to ZTEX modules,
+// or other USB devices such as an FX3 in boot loader mode, which are
+// present, which on Linux usually means that no udev rule grants the user
+// access to them.  It is returned, possibly wrapped, when opening
+// devices fails for lack of permission.
+type PermissionError struct {
+	// Paths are the device nodes of the devices which could not be opened,
+	// such as "/dev/bus/usb/001/004", if they are known.
+	Paths []string
+
+	// Rule is a udev rule which grants access to the devices; see
+	// UdevRule.
+	Rule string
+
+	// Err is the error of the backend.
+	Err error
+}
+
+// Error returns a description of the error, with the udev rule which
+// would fix it.
+func (e *PermissionError) Error() string {
+	s := "permission denied"
+	if len(e.Paths) > 0 {
+		s = fmt.Sprintf("permission denied for %v", strings.Join(e.Paths, ", "))
+	}
+	if e.Err != nil {
+		s = fmt.Sprintf("%v: %v", s, e.Err)
+	}
+	return fmt.Sprintf("%v; grant access with the udev rule '%v' in %v", s, e.Rule, UdevRulePath)
+}
+
+// Unwrap returns the error of the backend.
+func (e *PermissionError) Unwrap() error { return e.Err }
+
+// usbPath returns the device node of the device at the given bus and
+// address on Linux.
+func usbPath(bus, address int) string {
+	return fmt.Sprintf("/dev/bus/usb/%03d/%03d", bus, address)
+}
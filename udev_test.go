@@ -0,0 +1,30 @@
+package ztex_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aljumi/ztex"
+)
+
+func TestUdevRule(t *testing.T) {
+	for _, tc := range []struct {
+		group string
+		want  string
+		ok    bool
+	}{
+		{"", `SUBSYSTEM=="usb", ATTR{idVendor}=="221a", MODE="0660", TAG+="uaccess"`, true},
+		{"plugdev", `SUBSYSTEM=="usb", ATTR{idVendor}=="221a", MODE="0660", GROUP="plugdev", TAG+="uaccess"`, true},
+		{"_ztex-users1", `SUBSYSTEM=="usb", ATTR{idVendor}=="221a", MODE="0660", GROUP="_ztex-users1", TAG+="uaccess"`, true},
+		{`x", MODE="0666`, "", false},
+		{"Plugdev", "", false},
+		{"1group", "", false},
+		{"plug dev", "", false},
+		{strings.Repeat("g", 33), "", false},
+	} {
+		got, err := ztex.UdevRule(tc.group)
+		if (err == nil) != tc.ok || got != tc.want {
+			t.Errorf("ztex.UdevRule(%q): got %q, %v, want %q, error %v", tc.group, got, err, tc.want, !tc.ok)
+		}
+	}
+}
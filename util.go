@@ -1,17 +1,13 @@
 package ztex
 
-import (
-	"fmt"
-
-	"github.com/google/gousb"
-)
+import "fmt"
 
 const (
 	// VendorID is the ZTEX USB vendor ID (VID).
-	VendorID = gousb.ID(0x221A)
+	VendorID = 0x221A
 
 	// ProductID is the standard ZTEX USB product ID (PID)
-	ProductID = gousb.ID(0x0100)
+	ProductID = 0x0100
 )
 
 func binaryPrefix(n uint64, unit string) string {
@@ -34,3 +30,23 @@ func bytesToUint16(b [2]uint8) uint16 {
 func bytesToUint32(b [4]uint8) uint32 {
 	return (uint32(b[0]) << 0) | (uint32(b[1]) << 8) | (uint32(b[2]) << 16) | (uint32(b[3]) << 24)
 }
+
+// Progress is called during long-running operations with the number of
+// bytes processed so far and the total number of bytes to process.
+type Progress func(done, total int64)
+
+// report calls p, if it is not nil.
+func (p Progress) report(done, total int64) {
+	if p != nil {
+		p(done, total)
+	}
+}
+
+// offset returns a Progress which reports the progress of a part of an
+// operation, starting at base, as progress of the whole operation.
+func (p Progress) offset(base, total int64) Progress {
+	if p == nil {
+		return nil
+	}
+	return func(done, _ int64) { p(base+done, total) }
+}
@@ -0,0 +1,152 @@
+package ztex
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ConfigSeverity indicates how serious a problem found in a configuration
+// is.
+type ConfigSeverity uint8
+
+// The severities of configuration problems.  Warnings describe unusual
+// but possible configurations; errors describe configurations which
+// cannot be right, typically because the MAC EEPROM is corrupted.
+const (
+	ConfigWarning ConfigSeverity = 1
+	ConfigError   ConfigSeverity = 2
+)
+
+// String returns a human-readable description of the severity.
+func (c ConfigSeverity) String() string {
+	switch c {
+	case ConfigWarning:
+		return "Warning"
+	case ConfigError:
+		return "Error"
+	default:
+		return Unknown
+	}
+}
+
+// ConfigIssue describes a problem found in a configuration.
+type ConfigIssue struct {
+	Severity ConfigSeverity
+	Field    string
+	Message  string
+}
+
+// String returns a human-readable description of the problem.
+func (c ConfigIssue) String() string {
+	return fmt.Sprintf("%v: %v: %v", c.Severity, c.Field, c.Message)
+}
+
+// ConfigIssues lists the problems found in a configuration.
+type ConfigIssues []ConfigIssue
+
+// String returns a human-readable description of the problems.
+func (c ConfigIssues) String() string {
+	x := []string{}
+	for _, i := range c {
+		x = append(x, i.String())
+	}
+	return strings.Join(x, "; ")
+}
+
+// Err returns an error wrapping ErrBadConfig which describes the problems
+// of severity ConfigError, or nil if there are none.
+func (c ConfigIssues) Err() error {
+	x := ConfigIssues{}
+	for _, i := range c {
+		if i.Severity == ConfigError {
+			x = append(x, i)
+		}
+	}
+	if len(x) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %v", ErrBadConfig, x)
+}
+
+// isSpartan6 reports whether or not the FPGA type is a Spartan-6.
+func isSpartan6(f FPGAType) bool {
+	n := f.Number()
+	return n >= FPGAXC6SLX9 && n <= FPGAXC6SLX150 || n == FPGAXC6SLX150x4
+}
+
+// Validate cross-checks the fields of the configuration and returns the
+// problems found, if any.  It catches corrupted MAC EEPROM contents, which
+// would otherwise only show up as confusing failures later on.
+func (d DeviceConfig) Validate() ConfigIssues {
+	x := ConfigIssues{}
+	add := func(s ConfigSeverity, field, format string, a ...any) {
+		x = append(x, ConfigIssue{s, field, fmt.Sprintf(format, a...)})
+	}
+
+	b := d.BoardConfig
+	if !b.BoardType.IsKnown() {
+		add(ConfigError, "board type", "got unknown board type %v", b.BoardType.Number())
+	}
+	if !b.BoardSeries.IsKnown() {
+		add(ConfigWarning, "board series", "got unknown board series %v", b.BoardSeries.Number())
+	}
+	if b.BoardNumber == 255 {
+		add(ConfigWarning, "board number", "got unknown board number")
+	}
+
+	f := d.FPGAConfig
+	switch {
+	case !f.FPGAType.IsKnown():
+		add(ConfigError, "FPGA type", "got unknown FPGA type %v", f.FPGAType.Number())
+	case b.BoardSeries == 1 && !isSpartan6(f.FPGAType):
+		add(ConfigWarning, "FPGA type", "got %v on a series 1 board, want a Spartan-6", f.FPGAType)
+	case f.FPGAType.Number() == FPGAXC6SLX150x4 && b.BoardSeries != 1:
+		add(ConfigWarning, "FPGA type", "got %v on a series %v board, want a series 1 board", f.FPGAType, b.BoardSeries)
+	}
+	switch {
+	case !f.FPGAPackage.IsKnown():
+		add(ConfigWarning, "FPGA package", "got unknown FPGA package %v", f.FPGAPackage.Number())
+	case f.FPGAPackage == PackageFBG484 && isSpartan6(f.FPGAType):
+		add(ConfigWarning, "FPGA package", "got package %v for %v, want a Spartan-6 package", f.FPGAPackage, f.FPGAType)
+	}
+	if len(f.FPGAGrade.Bytes()) == 0 {
+		add(ConfigWarning, "FPGA grade", "got no FPGA grade")
+	}
+
+	r := d.RAMConfig
+	switch {
+	case r.RAMSize == 0 && r.RAMType != 0:
+		add(ConfigWarning, "RAM size", "got no RAM size for RAM type %v", r.RAMType)
+	case r.RAMSize != 0 && !r.RAMType.IsKnown():
+		add(ConfigWarning, "RAM type", "got unknown RAM type %v for RAM size %v", uint8(r.RAMType), r.RAMSize)
+	case r.RAMSize != 0 && r.RAMSize&0xf0 == 0:
+		add(ConfigError, "RAM size", "got RAM size %#02x with zero mantissa", r.RAMSize.Number())
+	}
+
+	s := d.BitstreamConfig
+	if s.BitstreamSize.Number() > s.BitstreamCapacity.Number() {
+		add(ConfigError, "bitstream size", "got bitstream size %v, want at most the capacity %v", s.BitstreamSize, s.BitstreamCapacity)
+	}
+	if s.BitstreamCapacity.Number() == 0 && s.BitstreamStart.Number() != 0 {
+		add(ConfigWarning, "bitstream start", "got bitstream start %v without bitstream capacity", s.BitstreamStart)
+	}
+	if uint32(s.BitstreamStart.Number())+uint32(s.BitstreamCapacity.Number()) > 1<<16 {
+		add(ConfigError, "bitstream capacity", "got bitstream start %v and capacity %v beyond the addressable flash", s.BitstreamStart, s.BitstreamCapacity)
+	}
+
+	n := strings.TrimRight(d.DescriptorSerial.String(), "\x00")
+	for _, c := range n {
+		if c < 0x20 || c > 0x7e {
+			add(ConfigWarning, "serial number", "got serial number %q with unprintable characters", n)
+			break
+		}
+	}
+
+	return x
+}
+
+// Validate cross-checks the fields of the configuration data area and
+// returns the problems found, if any.
+func (b BoardConfigBlock) Validate() ConfigIssues {
+	return b.DeviceConfig.Validate()
+}
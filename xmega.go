@@ -0,0 +1,384 @@
+package ztex
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// XMEGAError represents the error code of the XMEGA support.
+type XMEGAError uint8
+
+// String returns a human-readable description of the XMEGA error code.
+func (x XMEGAError) String() string {
+	switch x {
+	case 0:
+		return "No Error"
+	case 1:
+		return "Command Error"
+	case 2:
+		return "Timeout Error"
+	case 3:
+		return "Busy Error"
+	case 4:
+		return "Programming Interface Error"
+	case 5:
+		return "Unsupported Device Error"
+	case 6:
+		return "Address Error"
+	default:
+		return fmt.Sprintf("%v Error [%v]", Unknown, uint8(x))
+	}
+}
+
+// IsKnown returns true if and only if the XMEGA error code has a known
+// name.
+func (x XMEGAError) IsKnown() bool { return isKnown(x.String()) }
+
+// XMEGABusy indicates whether or not the XMEGA is busy programming its
+// non-volatile memory.
+type XMEGABusy uint8
+
+// String returns a human-readable description of whether or not the XMEGA
+// is busy.
+func (x XMEGABusy) String() string {
+	switch x {
+	case 0:
+		return "Idle"
+	case 1:
+		return "Busy"
+	default:
+		return Unknown
+	}
+}
+
+// IsKnown returns true if and only if the XMEGA busy indicator has a known
+// name.
+func (x XMEGABusy) IsKnown() bool { return isKnown(x.String()) }
+
+// Bool returns true if and only if the XMEGA is busy.
+func (x XMEGABusy) Bool() bool { return x != 0 }
+
+// XMEGASignature represents the device signature bytes of the XMEGA.
+type XMEGASignature [3]uint8
+
+// String returns a human-readable description of the XMEGA signature.
+func (x XMEGASignature) String() string {
+	return fmt.Sprintf("%02x %02x %02x", x[0], x[1], x[2])
+}
+
+// Bytes returns a raw representation of the XMEGA signature.
+func (x XMEGASignature) Bytes() []byte { return []byte{x[0], x[1], x[2]} }
+
+// XMEGAFlashPage represents the size of a page of XMEGA flash memory.
+type XMEGAFlashPage [2]uint8
+
+// String returns a human-readable description of the flash page size.
+func (x XMEGAFlashPage) String() string { return binaryPrefix(uint64(x.Number()), "B") }
+
+// Number returns the size of a page of XMEGA flash memory (in bytes).
+func (x XMEGAFlashPage) Number() uint16 { return bytesToUint16(x) }
+
+// XMEGAEEPROMPage represents the size of a page of XMEGA EEPROM.
+type XMEGAEEPROMPage [2]uint8
+
+// String returns a human-readable description of the EEPROM page size.
+func (x XMEGAEEPROMPage) String() string { return binaryPrefix(uint64(x.Number()), "B") }
+
+// Number returns the size of a page of XMEGA EEPROM (in bytes).
+func (x XMEGAEEPROMPage) Number() uint16 { return bytesToUint16(x) }
+
+// XMEGAStatus indicates the current status of the XMEGA.
+type XMEGAStatus struct {
+	XMEGAError
+	XMEGABusy
+	XMEGASignature
+	XMEGAFlashPage
+	XMEGAEEPROMPage
+}
+
+// String returns a human-readable description of the XMEGA status.
+func (x XMEGAStatus) String() string {
+	b, _ := x.AppendText(nil)
+	return string(b)
+}
+
+// AppendText appends the description returned by String to b, so that
+// monitors which log the status repeatedly can reuse a buffer.
+func (x XMEGAStatus) AppendText(b []byte) ([]byte, error) {
+	return fmt.Appendf(b, "Error(%v), Busy(%v), Signature(%v), FlashPage(%v), EEPROMPage(%v)",
+		x.XMEGAError, x.XMEGABusy, x.XMEGASignature, x.XMEGAFlashPage, x.XMEGAEEPROMPage), nil
+}
+
+// XMEGAPart describes the memories of an XMEGA device.  The page sizes are
+// the ones reported by the firmware in the XMEGA status, and are zero in
+// the parts returned by (XMEGASignature).Part.
+type XMEGAPart struct {
+	Name        string
+	Signature   XMEGASignature
+	Application uint32
+	Boot        uint32
+	EEPROM      uint32
+	FlashPage   uint16
+	EEPROMPage  uint16
+}
+
+// String returns a human-readable description of the XMEGA part.
+func (x XMEGAPart) String() string {
+	y := []string{}
+	y = append(y, fmt.Sprintf("Name(%v)", x.Name))
+	y = append(y, fmt.Sprintf("Signature(%v)", x.Signature))
+	y = append(y, fmt.Sprintf("Application(%v)", binaryPrefix(uint64(x.Application), "B")))
+	y = append(y, fmt.Sprintf("Boot(%v)", binaryPrefix(uint64(x.Boot), "B")))
+	y = append(y, fmt.Sprintf("EEPROM(%v)", binaryPrefix(uint64(x.EEPROM), "B")))
+	y = append(y, fmt.Sprintf("FlashPage(%v)", binaryPrefix(uint64(x.FlashPage), "B")))
+	y = append(y, fmt.Sprintf("EEPROMPage(%v)", binaryPrefix(uint64(x.EEPROMPage), "B")))
+	return strings.Join(y, ", ")
+}
+
+// Flash returns the total size of the flash memory (in bytes), including
+// the boot section.
+func (x XMEGAPart) Flash() uint32 { return x.Application + x.Boot }
+
+// xmegaParts lists the supported XMEGA parts.  The page sizes are left
+// zero, since the firmware reports them in the XMEGA status.
+var xmegaParts = []XMEGAPart{
+	{"ATxmega16A4U", XMEGASignature{0x1e, 0x94, 0x41}, 16 << 10, 4 << 10, 1 << 10, 0, 0},
+	{"ATxmega32A4U", XMEGASignature{0x1e, 0x95, 0x41}, 32 << 10, 4 << 10, 1 << 10, 0, 0},
+	{"ATxmega64A4U", XMEGASignature{0x1e, 0x96, 0x46}, 64 << 10, 4 << 10, 2 << 10, 0, 0},
+	{"ATxmega128A4U", XMEGASignature{0x1e, 0x97, 0x46}, 128 << 10, 8 << 10, 2 << 10, 0, 0},
+}
+
+// Part returns the XMEGA part with this signature, and false if the
+// signature is not recognized.
+func (x XMEGASignature) Part() (XMEGAPart, bool) {
+	for _, p := range xmegaParts {
+		if p.Signature == x {
+			return p, true
+		}
+	}
+	return XMEGAPart{}, false
+}
+
+// XMEGAFuses represents the fuse bytes of the XMEGA: fuse bytes 0 to 5 at
+// the corresponding indices, followed by a reserved byte and the lock
+// bits.  Fuse bits are programmed when they are 0.
+type XMEGAFuses [8]uint8
+
+// String returns a human-readable description of the XMEGA fuses.
+func (x XMEGAFuses) String() string {
+	y := []string{}
+	y = append(y, fmt.Sprintf("JTAG User ID(%#02x)", x.JTAGUserID()))
+	y = append(y, fmt.Sprintf("Watchdog Window Period(%v)", x.WatchdogWindowPeriod()))
+	y = append(y, fmt.Sprintf("Watchdog Period(%v)", x.WatchdogPeriod()))
+	y = append(y, fmt.Sprintf("Boot Reset(%v)", x.BootReset()))
+	y = append(y, fmt.Sprintf("BOD Power Down(%v)", x.BODPowerDown()))
+	y = append(y, fmt.Sprintf("Reset Disabled(%v)", x.ResetDisabled()))
+	y = append(y, fmt.Sprintf("Startup Time(%v)", x.StartupTime()))
+	y = append(y, fmt.Sprintf("Watchdog Locked(%v)", x.WatchdogLocked()))
+	y = append(y, fmt.Sprintf("BOD Active(%v)", x.BODActive()))
+	y = append(y, fmt.Sprintf("EEPROM Save(%v)", x.EEPROMSave()))
+	y = append(y, fmt.Sprintf("BOD Level(%v)", x.BODLevel()))
+	y = append(y, fmt.Sprintf("Lock Bits(%v)", x.XMEGALockBits()))
+	return strings.Join(y, ", ")
+}
+
+// Bytes returns a raw representation of the XMEGA fuses.
+func (x XMEGAFuses) Bytes() []byte { return append([]byte{}, x[:]...) }
+
+// JTAGUserID returns the JTAG user ID (fuse byte 0).
+func (x XMEGAFuses) JTAGUserID() uint8 { return x[0] }
+
+// WatchdogWindowPeriod returns the watchdog window timeout period setting
+// (WDWP, fuse byte 1).
+func (x XMEGAFuses) WatchdogWindowPeriod() uint8 { return x[1] >> 4 }
+
+// WatchdogPeriod returns the watchdog timeout period setting (WDP, fuse
+// byte 1).
+func (x XMEGAFuses) WatchdogPeriod() uint8 { return x[1] & 0xf }
+
+// BootReset returns true if and only if the XMEGA starts from the boot
+// loader section after reset (BOOTRST, fuse byte 2).
+func (x XMEGAFuses) BootReset() bool { return x[2]&(1<<6) == 0 }
+
+// BODPowerDown returns the brown-out detection mode in power-down and
+// power-save sleep modes (BODPD, fuse byte 2).
+func (x XMEGAFuses) BODPowerDown() uint8 { return x[2] & 0x3 }
+
+// ResetDisabled returns true if and only if the external reset is
+// disabled (RSTDISBL, fuse byte 4).
+func (x XMEGAFuses) ResetDisabled() bool { return x[4]&(1<<4) == 0 }
+
+// StartupTime returns the start-up time setting (STARTUPTIME, fuse byte 4).
+func (x XMEGAFuses) StartupTime() uint8 { return (x[4] >> 2) & 0x3 }
+
+// WatchdogLocked returns true if and only if the watchdog configuration
+// is locked (WDLOCK, fuse byte 4).
+func (x XMEGAFuses) WatchdogLocked() bool { return x[4]&(1<<1) == 0 }
+
+// BODActive returns the brown-out detection mode in active and idle modes
+// (BODACT, fuse byte 5).
+func (x XMEGAFuses) BODActive() uint8 { return (x[5] >> 4) & 0x3 }
+
+// EEPROMSave returns true if and only if the EEPROM is preserved during a
+// chip erase (EESAVE, fuse byte 5).
+func (x XMEGAFuses) EEPROMSave() bool { return x[5]&(1<<3) == 0 }
+
+// BODLevel returns the brown-out detection level setting (BODLEVEL, fuse
+// byte 5).
+func (x XMEGAFuses) BODLevel() uint8 { return x[5] & 0x7 }
+
+// XMEGALockBits returns the lock bits.
+func (x XMEGAFuses) XMEGALockBits() XMEGALockBits { return XMEGALockBits(x[7]) }
+
+// XMEGALockBits represents the lock bits of the XMEGA.  Lock bits are
+// programmed when they are 0, and can only be cleared by a chip erase,
+// which also erases the flash memory and, unless preserved, the EEPROM.
+type XMEGALockBits uint8
+
+// String returns a human-readable description of the XMEGA lock bits.
+func (x XMEGALockBits) String() string {
+	y := []string{}
+	y = append(y, fmt.Sprintf("Boot(%v)", x.Boot()))
+	y = append(y, fmt.Sprintf("Application(%v)", x.Application()))
+	y = append(y, fmt.Sprintf("Application Table(%v)", x.ApplicationTable()))
+	y = append(y, fmt.Sprintf("Lock(%v)", x.Lock()))
+	return strings.Join(y, ", ")
+}
+
+// Boot returns the boot loader section lock mode (BLBB).
+func (x XMEGALockBits) Boot() uint8 { return uint8(x>>6) & 0x3 }
+
+// Application returns the application section lock mode (BLBA).
+func (x XMEGALockBits) Application() uint8 { return uint8(x>>4) & 0x3 }
+
+// ApplicationTable returns the application table section lock mode
+// (BLBAT).
+func (x XMEGALockBits) ApplicationTable() uint8 { return uint8(x>>2) & 0x3 }
+
+// Lock returns the external programming interface lock mode (LB).
+func (x XMEGALockBits) Lock() uint8 { return uint8(x) & 0x3 }
+
+// Bool returns true if and only if any lock bit is programmed.
+func (x XMEGALockBits) Bool() bool { return x != 0xff }
+
+// XMEGAMismatch describes a byte of XMEGA flash memory whose contents
+// differ from the expected contents.
+type XMEGAMismatch struct {
+	Address uint32
+	Got     uint8
+	Want    uint8
+}
+
+// String returns a human-readable description of the mismatch.
+func (x XMEGAMismatch) String() string {
+	return fmt.Sprintf("Address(%#x), Got(%#02x), Want(%#02x)", x.Address, x.Got, x.Want)
+}
+
+// XMEGAVerification reports the result of comparing the flash memory and
+// signature of the XMEGA with their expected values.
+type XMEGAVerification struct {
+	Signature     XMEGASignature
+	WantSignature XMEGASignature
+
+	// Compared holds the number of bytes compared, and Mismatched the
+	// number of bytes which differed.  Mismatches lists the first of the
+	// differing bytes, up to xmegaMismatches of them.
+	Compared   int
+	Mismatched int
+	Mismatches []XMEGAMismatch
+}
+
+// xmegaMismatches limits the number of mismatches listed in a report.
+const xmegaMismatches = 16
+
+// String returns a human-readable description of the verification.
+func (x XMEGAVerification) String() string {
+	y := []string{}
+	y = append(y, fmt.Sprintf("Signature(%v)", x.Signature))
+	y = append(y, fmt.Sprintf("WantSignature(%v)", x.WantSignature))
+	y = append(y, fmt.Sprintf("Compared(%v)", x.Compared))
+	y = append(y, fmt.Sprintf("Mismatched(%v)", x.Mismatched))
+	for _, m := range x.Mismatches {
+		y = append(y, fmt.Sprintf("Mismatch(%v)", m))
+	}
+	return strings.Join(y, ", ")
+}
+
+// Bool returns true if and only if the signature matched and no bytes
+// differed.
+func (x XMEGAVerification) Bool() bool {
+	return x.Signature == x.WantSignature && x.Mismatched == 0
+}
+
+// compare compares the data read at address a with the expected data.
+func (x *XMEGAVerification) compare(a uint32, got, want []byte) {
+	for i := range want {
+		x.Compared++
+		if got[i] == want[i] {
+			continue
+		}
+		x.Mismatched++
+		if len(x.Mismatches) < xmegaMismatches {
+			x.Mismatches = append(x.Mismatches, XMEGAMismatch{a + uint32(i), got[i], want[i]})
+		}
+	}
+}
+
+// XMEGAVerificationError is returned when programmed XMEGA flash memory
+// does not read back as expected.
+type XMEGAVerificationError struct {
+	*XMEGAVerification
+}
+
+// Error returns a description of the verification failure.
+func (x *XMEGAVerificationError) Error() string {
+	return fmt.Sprintf("XMEGA verification failed: %v", x.XMEGAVerification)
+}
+
+// XMEGAEEPROM provides access to the EEPROM of the XMEGA through the
+// io.ReaderAt and io.WriterAt interfaces, so that it can be used with
+// io.SectionReader, io.NewOffsetWriter, and similar helpers.
+type XMEGAEEPROM struct {
+	d    *Device
+	size int64
+}
+
+// Size returns the size of the EEPROM (in bytes).
+func (x *XMEGAEEPROM) Size() int64 { return x.size }
+
+// ReadAt implements io.ReaderAt.
+func (x *XMEGAEEPROM) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("(*ztex.XMEGAEEPROM).ReadAt: got offset %v, want non-negative offset", off)
+	} else if off >= x.size {
+		return 0, io.EOF
+	}
+
+	n := len(p)
+	if int64(n) > x.size-off {
+		n = int(x.size - off)
+	}
+	if err := x.d.ReadXMEGAEEPROM(context.Background(), uint32(off), p[:n], nil); err != nil {
+		return 0, err
+	} else if n < len(p) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+// WriteAt implements io.WriterAt.  Writes beyond the end of the EEPROM
+// are rejected without writing anything.
+func (x *XMEGAEEPROM) WriteAt(p []byte, off int64) (int, error) {
+	if off < 0 || off+int64(len(p)) > x.size {
+		return 0, fmt.Errorf("(*ztex.XMEGAEEPROM).WriteAt: got range [%v, %v), want range within [0, %v)", off, off+int64(len(p)), x.size)
+	}
+
+	if err := x.d.WriteXMEGAEEPROM(context.Background(), uint32(off), p, nil); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
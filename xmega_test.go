@@ -0,0 +1,353 @@
+package ztex_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/aljumi/ztex"
+	"github.com/aljumi/ztex/ihx"
+	"github.com/aljumi/ztex/ztextest"
+)
+
+// openXMEGA opens a fake device with the given fake XMEGA.
+func openXMEGA(t *testing.T, x *ztextest.FakeXMEGA) (*ztex.Device, *ztextest.FakeDevice) {
+	t.Helper()
+	f := ztextest.NewFakeDevice("fake000001")
+	f.Descriptor[12] |= 0x10
+	f.XMEGA = x
+	d, err := ztextest.Open(f)
+	if err != nil {
+		t.Fatalf("ztextest.Open: %v", err)
+	}
+	t.Cleanup(func() { d.Close() })
+	return d, f
+}
+
+func TestXMEGAPageSizes(t *testing.T) {
+	// The firmware reports page sizes which differ from the usual ones of
+	// the part; the fake rejects pages of any other size.
+	x := ztextest.NewFakeXMEGA()
+	x.FlashPage, x.EEPROMPage = 128, 16
+	d, f := openXMEGA(t, x)
+
+	p, err := d.XMEGAPart()
+	if err != nil {
+		t.Fatalf("(*ztex.Device).XMEGAPart: %v", err)
+	} else if p.Name != "ATxmega128A4U" || p.FlashPage != 128 || p.EEPROMPage != 16 {
+		t.Errorf("(*ztex.Device).XMEGAPart: got %v, want ATxmega128A4U with pages of 128 B and 16 B", p)
+	}
+
+	ctx := context.Background()
+	want := bytes.Repeat([]byte{0x12, 0x34, 0x56}, 100)
+	if err := d.WriteXMEGAFlash(ctx, 100, want, nil); err != nil {
+		t.Fatalf("(*ztex.Device).WriteXMEGAFlash: %v", err)
+	} else if n := f.Calls(0x4b); n != 4 {
+		t.Errorf("(*ztex.Device).WriteXMEGAFlash: got %v page writes, want 4", n)
+	}
+	got := make([]byte, len(want))
+	if err := d.ReadXMEGAFlash(ctx, 100, got, nil); err != nil {
+		t.Fatalf("(*ztex.Device).ReadXMEGAFlash: %v", err)
+	} else if !bytes.Equal(got, want) {
+		t.Errorf("(*ztex.Device).ReadXMEGAFlash: got %x, want %x", got, want)
+	} else if x.Flash[99] != 0xff || x.Flash[400] != 0xff {
+		t.Errorf("(*ztex.Device).WriteXMEGAFlash: got %#02x and %#02x around the data, want erased bytes", x.Flash[99], x.Flash[400])
+	}
+
+	want = want[:40]
+	if err := d.WriteXMEGAEEPROM(ctx, 10, want, nil); err != nil {
+		t.Fatalf("(*ztex.Device).WriteXMEGAEEPROM: %v", err)
+	} else if n := f.Calls(0x4d); n != 4 {
+		t.Errorf("(*ztex.Device).WriteXMEGAEEPROM: got %v page writes, want 4", n)
+	}
+	got = got[:len(want)]
+	if err := d.ReadXMEGAEEPROM(ctx, 10, got, nil); err != nil {
+		t.Fatalf("(*ztex.Device).ReadXMEGAEEPROM: %v", err)
+	} else if !bytes.Equal(got, want) {
+		t.Errorf("(*ztex.Device).ReadXMEGAEEPROM: got %x, want %x", got, want)
+	}
+}
+
+func TestXMEGAPartErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		edit func(*ztextest.FakeXMEGA)
+	}{
+		{"unknown signature", func(x *ztextest.FakeXMEGA) { x.Signature = [3]uint8{0x1e, 0x98, 0x44} }},
+		{"zero flash page", func(x *ztextest.FakeXMEGA) { x.FlashPage = 0 }},
+		{"zero EEPROM page", func(x *ztextest.FakeXMEGA) { x.EEPROMPage = 0 }},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			x := ztextest.NewFakeXMEGA()
+			tt.edit(x)
+			d, _ := openXMEGA(t, x)
+			if p, err := d.XMEGAPart(); err == nil {
+				t.Errorf("(*ztex.Device).XMEGAPart: got %v, want error", p)
+			}
+		})
+	}
+}
+
+func TestXMEGARead(t *testing.T) {
+	x := ztextest.NewFakeXMEGA()
+	copy(x.Flash[0x1fffe:], []byte{0xab, 0xcd, 0xef})
+	copy(x.EEPROM[0x7ff:], []byte{0x42})
+	x.Fuses = [8]uint8{0x12, 0xa5, 0xbf, 0xff, 0xe9, 0xd5, 0xff, 0xfc}
+	d, _ := openXMEGA(t, x)
+
+	// Reads may cross the 64 kiB boundaries and the page boundaries.
+	ctx := context.Background()
+	b := make([]byte, 3)
+	if err := d.ReadXMEGAFlash(ctx, 0x1fffe, b, nil); err != nil {
+		t.Fatalf("(*ztex.Device).ReadXMEGAFlash: %v", err)
+	} else if !bytes.Equal(b, []byte{0xab, 0xcd, 0xef}) {
+		t.Errorf("(*ztex.Device).ReadXMEGAFlash: got % x, want ab cd ef", b)
+	}
+	if err := d.ReadXMEGAEEPROM(ctx, 0x7ff, b[:1], nil); err != nil {
+		t.Fatalf("(*ztex.Device).ReadXMEGAEEPROM: %v", err)
+	} else if b[0] != 0x42 {
+		t.Errorf("(*ztex.Device).ReadXMEGAEEPROM: got %#02x, want 0x42", b[0])
+	}
+
+	if err := d.ReadXMEGAFlash(ctx, 136<<10-1, b, nil); err == nil {
+		t.Errorf("(*ztex.Device).ReadXMEGAFlash beyond the flash: got no error, want error")
+	}
+	if err := d.ReadXMEGAEEPROM(ctx, 2<<10-1, b, nil); err == nil {
+		t.Errorf("(*ztex.Device).ReadXMEGAEEPROM beyond the EEPROM: got no error, want error")
+	}
+
+	f, err := d.XMEGAFuses()
+	if err != nil {
+		t.Fatalf("(*ztex.Device).XMEGAFuses: %v", err)
+	}
+	for _, c := range []struct {
+		name      string
+		got, want any
+	}{
+		{"JTAGUserID", f.JTAGUserID(), uint8(0x12)},
+		{"WatchdogWindowPeriod", f.WatchdogWindowPeriod(), uint8(0xa)},
+		{"WatchdogPeriod", f.WatchdogPeriod(), uint8(0x5)},
+		{"BootReset", f.BootReset(), true},
+		{"BODPowerDown", f.BODPowerDown(), uint8(0x3)},
+		{"ResetDisabled", f.ResetDisabled(), true},
+		{"StartupTime", f.StartupTime(), uint8(0x2)},
+		{"WatchdogLocked", f.WatchdogLocked(), true},
+		{"BODActive", f.BODActive(), uint8(0x1)},
+		{"EEPROMSave", f.EEPROMSave(), true},
+		{"BODLevel", f.BODLevel(), uint8(0x5)},
+		{"XMEGALockBits", f.XMEGALockBits(), ztex.XMEGALockBits(0xfc)},
+	} {
+		if c.got != c.want {
+			t.Errorf("(ztex.XMEGAFuses).%v: got %v, want %v", c.name, c.got, c.want)
+		}
+	}
+	if l := f.XMEGALockBits(); !l.Bool() || l.Lock() != 0 || l.Boot() != 3 {
+		t.Errorf("(ztex.XMEGALockBits): got %v, want locked external programming interface only", l)
+	}
+}
+
+func TestXMEGAWrite(t *testing.T) {
+	x := ztextest.NewFakeXMEGA()
+	d, f := openXMEGA(t, x)
+
+	// The rest of a partially written page is programmed with its
+	// current contents.
+	ctx := context.Background()
+	x.Flash[0x200] = 0x11
+	if err := d.WriteXMEGAFlash(ctx, 0x201, []byte{0x22, 0x33}, nil); err != nil {
+		t.Fatalf("(*ztex.Device).WriteXMEGAFlash: %v", err)
+	} else if !bytes.Equal(x.Flash[0x200:0x204], []byte{0x11, 0x22, 0x33, 0xff}) {
+		t.Errorf("(*ztex.Device).WriteXMEGAFlash: got % x, want 11 22 33 ff", x.Flash[0x200:0x204])
+	} else if n := f.Calls(0x4b); n != 1 {
+		t.Errorf("(*ztex.Device).WriteXMEGAFlash: got %v page writes, want 1", n)
+	}
+
+	if err := d.WriteXMEGAFlash(ctx, 136<<10-1, []byte{1, 2}, nil); err == nil {
+		t.Errorf("(*ztex.Device).WriteXMEGAFlash beyond the flash: got no error, want error")
+	}
+	if err := d.WriteXMEGAEEPROM(ctx, 2<<10-1, []byte{1, 2}, nil); err == nil {
+		t.Errorf("(*ztex.Device).WriteXMEGAEEPROM beyond the EEPROM: got no error, want error")
+	}
+
+	// An error reported by the XMEGA after programming fails the write.
+	x.Error = 1
+	if err := d.WriteXMEGAEEPROM(ctx, 0, []byte{1}, nil); err == nil {
+		t.Errorf("(*ztex.Device).WriteXMEGAEEPROM with XMEGA error: got no error, want error")
+	}
+}
+
+// xmegaImage returns an Intel HEX image with a few bytes at 0x100 and a
+// segment which crosses a 64 kiB boundary and several pages.
+func xmegaImage(t *testing.T) ([]byte, *ihx.Image) {
+	t.Helper()
+	i := ihx.FromBytes(0x100, []byte("ztex xmega"))
+	if err := i.Add(0xfe00, bytes.Repeat([]byte{0x5a, 0xa5}, 700)); err != nil {
+		t.Fatalf("(*ihx.Image).Add: %v", err)
+	}
+	b := &bytes.Buffer{}
+	if err := ihx.Write(b, i); err != nil {
+		t.Fatalf("ihx.Write: %v", err)
+	}
+	return b.Bytes(), i
+}
+
+func TestProgramXMEGA(t *testing.T) {
+	x := ztextest.NewFakeXMEGA()
+	x.Flash[0x1000] = 0
+	d, _ := openXMEGA(t, x)
+	b, i := xmegaImage(t)
+
+	if err := d.ProgramXMEGA(context.Background(), bytes.NewReader(b), nil); err != nil {
+		t.Fatalf("(*ztex.Device).ProgramXMEGA: %v", err)
+	}
+	for _, s := range i.Segments {
+		if got := x.Flash[s.Address:s.End()]; !bytes.Equal(got, s.Data) {
+			t.Errorf("(*ztex.Device).ProgramXMEGA: got % x at %#x, want % x", got, s.Address, s.Data)
+		}
+	}
+	if x.Flash[0x1000] != 0xff {
+		t.Errorf("(*ztex.Device).ProgramXMEGA: got %#02x at 0x1000, want erased byte", x.Flash[0x1000])
+	}
+
+	// Images which reach into the boot section are rejected before the
+	// flash is erased.
+	x.Flash[0x1000] = 0
+	bb := &bytes.Buffer{}
+	if err := ihx.Write(bb, ihx.FromBytes(128<<10-1, []byte{1, 2})); err != nil {
+		t.Fatalf("ihx.Write: %v", err)
+	}
+	if err := d.ProgramXMEGA(context.Background(), bb, nil); err == nil {
+		t.Errorf("(*ztex.Device).ProgramXMEGA with image beyond the application section: got no error, want error")
+	} else if x.Flash[0x1000] != 0 {
+		t.Errorf("(*ztex.Device).ProgramXMEGA with image beyond the application section: got flash erased, want flash unchanged")
+	}
+}
+
+func TestVerifyXMEGA(t *testing.T) {
+	x := ztextest.NewFakeXMEGA()
+	d, _ := openXMEGA(t, x)
+	b, i := xmegaImage(t)
+	ctx := context.Background()
+	if err := d.ProgramXMEGA(ctx, bytes.NewReader(b), nil); err != nil {
+		t.Fatalf("(*ztex.Device).ProgramXMEGA: %v", err)
+	}
+
+	v, err := d.VerifyXMEGA(ctx, bytes.NewReader(b), x.Signature, nil)
+	if err != nil {
+		t.Fatalf("(*ztex.Device).VerifyXMEGA: %v", err)
+	} else if !v.Bool() || v.Compared != 1410 || v.Mismatched != 0 {
+		t.Errorf("(*ztex.Device).VerifyXMEGA: got %v, want 1410 matching bytes", v)
+	}
+
+	x.Flash[i.Segments[1].Address+3] ^= 0xff
+	if v, err = d.VerifyXMEGA(ctx, bytes.NewReader(b), x.Signature, nil); err != nil {
+		t.Fatalf("(*ztex.Device).VerifyXMEGA: %v", err)
+	} else if v.Bool() || v.Mismatched != 1 || len(v.Mismatches) != 1 || v.Mismatches[0].Address != i.Segments[1].Address+3 {
+		t.Errorf("(*ztex.Device).VerifyXMEGA with a corrupted byte: got %v, want a mismatch at %#x", v, i.Segments[1].Address+3)
+	}
+
+	// The flash is not compared with an image for another part.
+	if v, err = d.VerifyXMEGA(ctx, bytes.NewReader(b), ztex.XMEGASignature{0x1e, 0x96, 0x46}, nil); err != nil {
+		t.Fatalf("(*ztex.Device).VerifyXMEGA: %v", err)
+	} else if v.Bool() || v.Compared != 0 {
+		t.Errorf("(*ztex.Device).VerifyXMEGA with another signature: got %v, want a signature mismatch", v)
+	}
+}
+
+func TestWriteXMEGAFuses(t *testing.T) {
+	x := ztextest.NewFakeXMEGA()
+	d, f := openXMEGA(t, x)
+	ctx := context.Background()
+
+	// Only the fuses which differ are written.
+	want := ztex.XMEGAFuses(x.Fuses)
+	want[1], want[5] = 0xa5, 0xd5
+	if err := d.WriteXMEGAFuses(ctx, want); err != nil {
+		t.Fatalf("(*ztex.Device).WriteXMEGAFuses: %v", err)
+	} else if x.Fuses != want {
+		t.Errorf("(*ztex.Device).WriteXMEGAFuses: got fuses % x, want % x", x.Fuses, want)
+	} else if n := f.Calls(0x4f); n != 2 {
+		t.Errorf("(*ztex.Device).WriteXMEGAFuses: got %v fuse writes, want 2", n)
+	}
+
+	// The lock bits are only written by WriteXMEGALockBits, and only with
+	// confirmation.
+	l := want
+	l[7] = 0xfc
+	if err := d.WriteXMEGAFuses(ctx, l); err == nil {
+		t.Errorf("(*ztex.Device).WriteXMEGAFuses with lock bits: got no error, want error")
+	}
+	if err := d.WriteXMEGALockBits(ctx, 0xfc, false); err == nil {
+		t.Errorf("(*ztex.Device).WriteXMEGALockBits without confirmation: got no error, want error")
+	} else if x.Fuses[7] != 0xff {
+		t.Errorf("(*ztex.Device).WriteXMEGALockBits without confirmation: got lock bits %#02x, want 0xff", x.Fuses[7])
+	}
+	if err := d.WriteXMEGALockBits(ctx, 0xfc, true); err != nil {
+		t.Fatalf("(*ztex.Device).WriteXMEGALockBits: %v", err)
+	} else if x.Fuses[7] != 0xfc {
+		t.Errorf("(*ztex.Device).WriteXMEGALockBits: got lock bits %#02x, want 0xfc", x.Fuses[7])
+	}
+}
+
+func TestXMEGAEEPROM(t *testing.T) {
+	x := ztextest.NewFakeXMEGA()
+	d, _ := openXMEGA(t, x)
+
+	e, err := d.XMEGAEEPROM()
+	if err != nil {
+		t.Fatalf("(*ztex.Device).XMEGAEEPROM: %v", err)
+	} else if e.Size() != 2<<10 {
+		t.Errorf("(*ztex.XMEGAEEPROM).Size: got %v, want %v", e.Size(), 2<<10)
+	}
+
+	w := io.NewOffsetWriter(e, 0x7f0)
+	if _, err := w.Write([]byte("ztex eeprom")); err != nil {
+		t.Fatalf("(*io.OffsetWriter).Write: %v", err)
+	}
+	b, err := io.ReadAll(io.NewSectionReader(e, 0x7f0, 0x100))
+	if err != nil {
+		t.Fatalf("io.ReadAll: %v", err)
+	} else if want := append([]byte("ztex eeprom"), 0xff, 0xff, 0xff, 0xff, 0xff); !bytes.Equal(b, want) {
+		t.Errorf("(*ztex.XMEGAEEPROM).ReadAt: got %q, want %q", b, want)
+	}
+
+	if n, err := e.WriteAt([]byte{1, 2}, 2<<10-1); err == nil || n != 0 || x.EEPROM[2<<10-1] != 0xff {
+		t.Errorf("(*ztex.XMEGAEEPROM).WriteAt beyond the end: got %v and %v, want nothing written and error", n, err)
+	}
+	if n, err := e.ReadAt(b[:2], 2<<10-1); err != io.EOF || n != 1 {
+		t.Errorf("(*ztex.XMEGAEEPROM).ReadAt beyond the end: got %v and %v, want 1 and %v", n, err, io.EOF)
+	}
+}
+
+func TestProgramXMEGAProgress(t *testing.T) {
+	x := ztextest.NewFakeXMEGA()
+	d, f := openXMEGA(t, x)
+	b, _ := xmegaImage(t)
+
+	// Programming and verification are reported as one operation, after
+	// every page, and the progress only grows.
+	p := [][2]int64{}
+	if err := d.ProgramXMEGA(context.Background(), bytes.NewReader(b), func(done, total int64) { p = append(p, [2]int64{done, total}) }); err != nil {
+		t.Fatalf("(*ztex.Device).ProgramXMEGA: %v", err)
+	}
+	if len(p) == 0 || p[len(p)-1] != [2]int64{2820, 2820} {
+		t.Fatalf("(*ztex.Device).ProgramXMEGA: got progress %v, want progress ending at 2820 of 2820", p)
+	}
+	for i := 1; i < len(p); i++ {
+		if p[i][0] < p[i-1][0] || p[i][1] != 2820 {
+			t.Errorf("(*ztex.Device).ProgramXMEGA: got progress %v after %v, want growing progress of 2820", p[i], p[i-1])
+		}
+	}
+
+	// A cancelled operation stops before the next page.
+	ctx, cancel := context.WithCancel(context.Background())
+	n := f.Calls(0x4b)
+	err := d.WriteXMEGAFlash(ctx, 0, make([]byte, 4*x.FlashPage), func(done, total int64) { cancel() })
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("(*ztex.Device).WriteXMEGAFlash after cancellation: got %v, want %v", err, context.Canceled)
+	} else if m := f.Calls(0x4b) - n; m != 1 {
+		t.Errorf("(*ztex.Device).WriteXMEGAFlash after cancellation: got %v page writes, want 1", m)
+	}
+}
@@ -1,2 +1,23 @@
 // Package ztex manages ZTEX modules.
+//
+// The device core, through which modules are opened and operated, and the
+// types of the data exchanged with them are in this package.  The file
+// formats and layouts which do not need a device are in subpackages, so
+// that they can be used on their own: ihx reads Intel HEX files, firmware
+// encodes and decodes EZ-USB firmware images, bitstream prepares FPGA
+// bitstreams, and flash describes the sectors of the flash memory.  The
+// parts of them which programs operating modules commonly need are
+// re-exported by this package.
 package ztex
+
+import "strings"
+
+// Unknown is the name returned by the String methods of values which
+// cannot be decoded, such as a board type or FPGA type added after this
+// package was written.  Such types have an IsKnown method, so that callers
+// need not compare names.
+const Unknown = "Unknown"
+
+// isKnown reports whether or not the name is that of a decoded value,
+// including names which qualify Unknown, such as "Unknown Error [7]".
+func isKnown(name string) bool { return !strings.HasPrefix(name, Unknown) }
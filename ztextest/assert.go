@@ -0,0 +1,239 @@
+package ztextest
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/aljumi/ztex"
+)
+
+// TestingT is the subset of testing.TB used by the assertion helpers.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+// Diff returns a description of the fields in which two values of the same
+// struct type differ, one line per field, or the empty string if they are
+// equal.  Structs are compared field by field, and fields are shown with
+// their String methods along with their raw values.  A nil value or nil
+// pointer is compared as the zero value of the other, so that a missing
+// configuration reads as an empty one.
+func Diff(got, want any) string {
+	x := []string{}
+	diff(&x, "", reflect.ValueOf(got), reflect.ValueOf(want))
+	return strings.Join(x, "\n")
+}
+
+func diff(x *[]string, path string, got, want reflect.Value) {
+	if !got.IsValid() && !want.IsValid() {
+		return
+	}
+	got, want = orZero(got, want), orZero(want, got)
+	if got.Kind() == reflect.Pointer && want.Kind() == reflect.Pointer && !got.IsNil() && !want.IsNil() {
+		got, want = got.Elem(), want.Elem()
+	}
+
+	if got.Type() != want.Type() {
+		*x = append(*x, fmt.Sprintf("%v: got type %v, want type %v", orValue(path), got.Type(), want.Type()))
+		return
+	}
+	if got.Kind() == reflect.Struct && exported(got.Type()) {
+		for i := 0; i < got.NumField(); i++ {
+			f := got.Type().Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			p := f.Name
+			if path != "" {
+				p = path + "." + f.Name
+			}
+			if f.Anonymous && f.Type.Kind() == reflect.Struct {
+				p = path
+			}
+			diff(x, p, got.Field(i), want.Field(i))
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(got.Interface(), want.Interface()) {
+		*x = append(*x, fmt.Sprintf("%v: got %v, want %v", orValue(path), describe(got), describe(want)))
+	}
+}
+
+// orZero returns v, or if v is nil or a nil pointer, a zero value, or a
+// pointer to one, of the type of other.
+func orZero(v, other reflect.Value) reflect.Value {
+	switch {
+	case !v.IsValid() && other.Kind() == reflect.Pointer && !other.IsNil():
+		return reflect.New(other.Type().Elem())
+	case !v.IsValid():
+		return reflect.Zero(other.Type())
+	case v.Kind() == reflect.Pointer && v.IsNil() && other.Kind() == reflect.Pointer && !other.IsNil():
+		return reflect.New(v.Type().Elem())
+	}
+	return v
+}
+
+// exported reports whether the struct type has exported fields.
+func exported(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).IsExported() {
+			return true
+		}
+	}
+	return false
+}
+
+// describe formats a field value together with its raw representation if
+// the field has a String method.
+func describe(v reflect.Value) string {
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return fmt.Sprintf("%v [%#v]", s, v.Interface())
+	}
+	return fmt.Sprintf("%#v", v.Interface())
+}
+
+func orValue(path string) string {
+	if path == "" {
+		return "value"
+	}
+	return path
+}
+
+// AssertDescriptor reports an error listing the differing fields unless
+// got equals want.  It returns true if and only if they are equal.
+func AssertDescriptor(t TestingT, got, want ztex.DescriptorConfig) bool {
+	t.Helper()
+
+	if d := Diff(got, want); d != "" {
+		t.Errorf("ZTEX descriptor differs:\n%v", d)
+		return false
+	}
+	return true
+}
+
+// FPGAStatusMatcher checks an FPGA status, and returns an error describing
+// the mismatch if it does not match.
+type FPGAStatusMatcher func(ztex.FPGAStatus) error
+
+// FPGAStatusEquals matches the FPGA status equal to want.
+func FPGAStatusEquals(want ztex.FPGAStatus) FPGAStatusMatcher {
+	return func(s ztex.FPGAStatus) error {
+		if d := Diff(s, want); d != "" {
+			return fmt.Errorf("FPGA status differs:\n%v", d)
+		}
+		return nil
+	}
+}
+
+// FPGAConfigured matches FPGA statuses which report a configured FPGA and
+// no configuration error.
+func FPGAConfigured() FPGAStatusMatcher {
+	return func(s ztex.FPGAStatus) error {
+		if !s.FPGAConfigured.Bool() {
+			return fmt.Errorf("got FPGA %v, want FPGA %v", s.FPGAConfigured, ztex.FPGAConfigured(0))
+		} else if !s.FPGAResult.Bool() {
+			return fmt.Errorf("got result %v, want result %v", s.FPGAResult, ztex.FPGAResult(0))
+		}
+		return nil
+	}
+}
+
+// FPGAUnconfigured matches FPGA statuses which report an unconfigured FPGA.
+func FPGAUnconfigured() FPGAStatusMatcher {
+	return func(s ztex.FPGAStatus) error {
+		if s.FPGAConfigured.Bool() {
+			return fmt.Errorf("got FPGA %v, want FPGA %v", s.FPGAConfigured, ztex.FPGAConfigured(1))
+		}
+		return nil
+	}
+}
+
+// FPGATransferred matches FPGA statuses which report that n bytes of
+// configuration data were transferred.
+func FPGATransferred(n uint32) FPGAStatusMatcher {
+	return func(s ztex.FPGAStatus) error {
+		if s.FPGATransferred.Number() != n {
+			return fmt.Errorf("got %v bytes transferred, want %v bytes", s.FPGATransferred.Number(), n)
+		}
+		return nil
+	}
+}
+
+// AssertFPGAStatus reports an error for every matcher which does not match
+// the status.  It returns true if and only if all matchers match.
+func AssertFPGAStatus(t TestingT, got *ztex.FPGAStatus, m ...FPGAStatusMatcher) bool {
+	t.Helper()
+
+	if got == nil {
+		t.Errorf("got no FPGA status, want FPGA status")
+		return false
+	}
+	ok := true
+	for _, f := range m {
+		if err := f(*got); err != nil {
+			t.Errorf("%w", err)
+			ok = false
+		}
+	}
+	return ok
+}
+
+// FlashStatusMatcher checks a flash status, and returns an error
+// describing the mismatch if it does not match.
+type FlashStatusMatcher func(ztex.FlashStatus) error
+
+// FlashStatusEquals matches the flash status equal to want.
+func FlashStatusEquals(want ztex.FlashStatus) FlashStatusMatcher {
+	return func(s ztex.FlashStatus) error {
+		if d := Diff(s, want); d != "" {
+			return fmt.Errorf("flash status differs:\n%v", d)
+		}
+		return nil
+	}
+}
+
+// FlashReady matches flash statuses which report an enabled flash and no
+// error.
+func FlashReady() FlashStatusMatcher {
+	return func(s ztex.FlashStatus) error {
+		if s.FlashEnabled != 1 {
+			return fmt.Errorf("got flash %v, want flash %v", s.FlashEnabled, ztex.FlashEnabled(1))
+		} else if s.FlashError != 0 {
+			return fmt.Errorf("got error %v, want error %v", s.FlashError, ztex.FlashError(0))
+		}
+		return nil
+	}
+}
+
+// FlashGeometry matches flash statuses which report count sectors of the
+// given size in bytes.
+func FlashGeometry(size uint64, count uint32) FlashStatusMatcher {
+	return func(s ztex.FlashStatus) error {
+		if s.FlashSector.Number() != size || s.FlashCount.Number() != count {
+			return fmt.Errorf("got %v sectors of %v bytes, want %v sectors of %v bytes", s.FlashCount.Number(), s.FlashSector.Number(), count, size)
+		}
+		return nil
+	}
+}
+
+// AssertFlashStatus reports an error for every matcher which does not
+// match the status.  It returns true if and only if all matchers match.
+func AssertFlashStatus(t TestingT, got *ztex.FlashStatus, m ...FlashStatusMatcher) bool {
+	t.Helper()
+
+	if got == nil {
+		t.Errorf("got no flash status, want flash status")
+		return false
+	}
+	ok := true
+	for _, f := range m {
+		if err := f(*got); err != nil {
+			t.Errorf("%w", err)
+			ok = false
+		}
+	}
+	return ok
+}
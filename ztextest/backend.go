@@ -0,0 +1,46 @@
+package ztextest
+
+import (
+	"time"
+
+	"github.com/aljumi/ztex"
+)
+
+// Backend is a ztex.Backend which opens fake devices instead of USB
+// devices, so that code which selects modules by their location can be
+// exercised without libusb.  The devices are attached to bus 1, at
+// addresses and ports counting from 1 in order.  The bulk endpoints of
+// the default firmware interface lead to the simulated design of each
+// device; see FakeDevice.
+type Backend []*FakeDevice
+
+// OpenDevices implements ztex.Backend.
+func (b Backend) OpenDevices(vendor, product uint16) ([]ztex.USBDevice, error) {
+	x := []ztex.USBDevice{}
+	if vendor != ztex.VendorID || product != ztex.ProductID {
+		return x, nil
+	}
+	for i, f := range b {
+		x = append(x, &usbDevice{f, ztex.USBDesc{
+			Bus:     1,
+			Address: i + 1,
+			Port:    i + 1,
+			Vendor:  vendor,
+			Product: product,
+			Speed:   "High",
+		}})
+	}
+	return x, nil
+}
+
+// usbDevice attaches a fake device to the simulated bus.
+type usbDevice struct {
+	*FakeDevice
+	desc ztex.USBDesc
+}
+
+func (u *usbDevice) Describe() ztex.USBDesc { return u.desc }
+
+func (u *usbDevice) SetControlTimeout(timeout time.Duration) {}
+
+func (u *usbDevice) Close() error { return nil }
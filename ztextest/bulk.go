@@ -0,0 +1,199 @@
+package ztextest
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+
+	"github.com/aljumi/ztex"
+)
+
+// DefaultFIFOSize is the size of the FIFO of the simulated design of fake
+// devices returned by NewFakeDevice.
+const DefaultFIFOSize = 16 << 10
+
+// HighSpeedEndpoint is the bulk OUT endpoint of interface 0 through which
+// fake devices receive bitstreams for high-speed FPGA configuration.
+const HighSpeedEndpoint = 4
+
+// fakeInterface is the default firmware interface of a fake device
+// attached to the simulated bus, whose bulk endpoints lead to the
+// simulated design.
+type fakeInterface struct{ f *FakeDevice }
+
+func (u *usbDevice) Interface(num int) (ztex.USBInterface, error) {
+	if num != 0 {
+		return nil, fmt.Errorf("%w: got interface %v, want interface 0", ErrStall, num)
+	}
+	return fakeInterface{u.FakeDevice}, nil
+}
+
+func (i fakeInterface) InEndpoint(n int) (ztex.BulkIn, error) {
+	if n != int(i.f.endpoint(20)) {
+		return nil, fmt.Errorf("%w: got IN endpoint %v, want IN endpoint %v", ErrStall, n, i.f.endpoint(20))
+	}
+	return fakeEndpoint{i.f}, nil
+}
+
+func (i fakeInterface) OutEndpoint(n int) (ztex.BulkOut, error) {
+	if n == HighSpeedEndpoint {
+		return highSpeedEndpoint{i.f}, nil
+	} else if n != int(i.f.endpoint(19)) {
+		return nil, fmt.Errorf("%w: got OUT endpoint %v, want OUT endpoint %v", ErrStall, n, i.f.endpoint(19))
+	}
+	return fakeEndpoint{i.f}, nil
+}
+
+func (i fakeInterface) Close() {}
+
+// endpoint returns the endpoint number at the given offset of the
+// descriptor, or, if the descriptor does not announce one, that of the
+// board catalog.
+func (f *FakeDevice) endpoint(off int) uint8 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if n := f.Descriptor[off]; n != 0 {
+		return n
+	}
+	b, _ := ztex.LookupBoard(ztex.DescriptorProduct(f.Descriptor[6:10]))
+	if off == 19 {
+		return b.Stream.OutEndpoint
+	}
+	return b.Stream.InEndpoint
+}
+
+// fakeEndpoint is a bulk endpoint of the default firmware interface.
+type fakeEndpoint struct{ f *FakeDevice }
+
+func (e fakeEndpoint) ReadContext(ctx context.Context, p []byte) (int, error) {
+	return e.f.bulkRead(ctx, p)
+}
+
+func (e fakeEndpoint) WriteContext(ctx context.Context, p []byte) (int, error) {
+	return e.f.bulkWrite(ctx, p)
+}
+
+// highSpeedEndpoint is the bulk endpoint for high-speed FPGA
+// configuration.
+type highSpeedEndpoint struct{ f *FakeDevice }
+
+func (e highSpeedEndpoint) WriteContext(ctx context.Context, p []byte) (int, error) {
+	e.f.mu.Lock()
+	defer e.f.mu.Unlock()
+
+	if e.f.hs == nil {
+		return 0, fmt.Errorf("%w: got bulk transfer, want high-speed FPGA configuration started", ErrStall)
+	}
+	e.f.hs = append(e.f.hs, len(p))
+	e.f.bitstream = append(e.f.bitstream, p...)
+	return len(p), nil
+}
+
+// HighSpeedTransfers returns the sizes of the bulk transfers of the last
+// finished high-speed FPGA configuration, in order.
+func (f *FakeDevice) HighSpeedTransfers() []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]int{}, f.hsOK...)
+}
+
+// changedLocked wakes up the transfers waiting for the FIFO.  The caller
+// must hold f.mu.
+func (f *FakeDevice) changedLocked() {
+	if f.changed != nil {
+		close(f.changed)
+	}
+	f.changed = make(chan struct{})
+}
+
+// wait waits until the FIFO changes or the context is done.  The caller
+// must hold f.mu, which is released while waiting.
+func (f *FakeDevice) wait(ctx context.Context) error {
+	if f.changed == nil {
+		f.changed = make(chan struct{})
+	}
+	ch := f.changed
+	f.mu.Unlock()
+	defer f.mu.Lock()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-ch:
+		return nil
+	}
+}
+
+// bulkWrite passes p to the simulated design.  Data written to a loopback
+// design is queued in the FIFO, and the write blocks while the FIFO is
+// full.
+func (f *FakeDevice) bulkWrite(ctx context.Context, p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.Loopback {
+		f.bulkOut += int64(len(p))
+		if f.FPGAs > 1 {
+			// The transfer takes time, during which the data goes to
+			// whichever FPGA is selected.
+			f.mu.Unlock()
+			runtime.Gosched()
+			f.mu.Lock()
+			if f.fpgaData == nil {
+				f.fpgaData = map[int][]byte{}
+			}
+			f.fpgaData[f.selected] = append(f.fpgaData[f.selected], p...)
+		}
+		return len(p), nil
+	}
+
+	n := 0
+	for n < len(p) {
+		if k := min(len(p)-n, f.FIFOSize-len(f.fifo)); k > 0 {
+			f.fifo = append(f.fifo, p[n:n+k]...)
+			f.bulkOut += int64(k)
+			n += k
+			f.changedLocked()
+			continue
+		}
+		if err := f.wait(ctx); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// bulkRead reads from the simulated design.  A loopback design returns the
+// data queued in the FIFO, and the read blocks while the FIFO is empty;
+// otherwise, the design produces a counting byte pattern.
+func (f *FakeDevice) bulkRead(ctx context.Context, p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.Loopback {
+		for i := range p {
+			p[i] = uint8(f.bulkIn)
+			f.bulkIn++
+		}
+		return len(p), nil
+	}
+
+	for len(f.fifo) == 0 && len(p) > 0 {
+		if err := f.wait(ctx); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, f.fifo)
+	f.fifo = f.fifo[n:]
+	f.bulkIn += int64(n)
+	f.changedLocked()
+	return n, nil
+}
+
+// BulkBytes returns the numbers of bytes written to and read from the
+// simulated design through the bulk endpoints so far.
+func (f *FakeDevice) BulkBytes() (out, in int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.bulkOut, f.bulkIn
+}
@@ -0,0 +1,131 @@
+package ztextest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aljumi/ztex"
+)
+
+// FakeClock is a ztex.Clock whose time only moves when it is advanced, so
+// that polling and timeout logic runs without waiting.  Sleep advances the
+// clock by the given duration, and timers and tickers fire as the clock
+// passes them.  It is safe for concurrent use.
+type FakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+// fakeTimer is a pending timer or ticker of a fake clock.  Tickers have a
+// positive period.
+type fakeTimer struct {
+	at     time.Time
+	period time.Duration
+	c      chan time.Time
+}
+
+// NewFakeClock returns a fake clock set to the given time.
+func NewFakeClock(t time.Time) *FakeClock {
+	return &FakeClock{now: t}
+}
+
+// Now implements ztex.Clock.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// Sleep implements ztex.Clock by advancing the clock.
+func (c *FakeClock) Sleep(d time.Duration) { c.Advance(d) }
+
+// After implements ztex.Clock.
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &fakeTimer{at: c.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.c <- c.now
+		return t.c
+	}
+	c.timers = append(c.timers, t)
+	return t.c
+}
+
+// NewTicker implements ztex.Clock.  Like time.NewTicker, it panics if d is
+// not positive.
+func (c *FakeClock) NewTicker(d time.Duration) ztex.Ticker {
+	if d <= 0 {
+		panic("ztextest: non-positive interval for NewTicker")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &fakeTimer{at: c.now.Add(d), period: d, c: make(chan time.Time, 1)}
+	c.timers = append(c.timers, t)
+	return &fakeTicker{c, t}
+}
+
+// Advance moves the clock forward by d, firing the timers and tickers
+// which become due.  Like time.Ticker, a ticker drops ticks which its
+// receiver is not ready for.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+
+	x := []*fakeTimer{}
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			x = append(x, t)
+			continue
+		}
+		select {
+		case t.c <- t.at:
+		default:
+		}
+		if t.period > 0 {
+			for !t.at.After(c.now) {
+				t.at = t.at.Add(t.period)
+			}
+			x = append(x, t)
+		}
+	}
+	c.timers = x
+}
+
+// Timers returns the number of pending timers and tickers, so that a test
+// can wait until the code under test has started waiting before it
+// advances the clock.
+func (c *FakeClock) Timers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.timers)
+}
+
+// stop removes a ticker from the pending timers.
+func (c *FakeClock) stop(t *fakeTimer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, x := range c.timers {
+		if x == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return
+		}
+	}
+}
+
+type fakeTicker struct {
+	c *FakeClock
+	t *fakeTimer
+}
+
+func (f *fakeTicker) C() <-chan time.Time { return f.t.c }
+func (f *fakeTicker) Stop()               { f.c.stop(f.t) }
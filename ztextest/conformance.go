@@ -0,0 +1,282 @@
+package ztextest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aljumi/ztex"
+)
+
+// CheckStatus indicates the outcome of a conformance check.
+type CheckStatus string
+
+// These are the outcomes of a conformance check.  A check is skipped if
+// the device does not announce the capability it exercises, or if the
+// suite was not given what the check needs, such as a bitstream.
+const (
+	CheckPass CheckStatus = "pass"
+	CheckFail CheckStatus = "fail"
+	CheckSkip CheckStatus = "skip"
+)
+
+// CheckResult represents the outcome of one conformance check.
+type CheckResult struct {
+	Name     string        `json:"name"`
+	Status   CheckStatus   `json:"status"`
+	Detail   string        `json:"detail,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// String returns a human-readable description of the check result.
+func (c CheckResult) String() string {
+	x := []string{}
+	x = append(x, fmt.Sprintf("Name(%v)", c.Name))
+	x = append(x, fmt.Sprintf("Status(%v)", c.Status))
+	x = append(x, fmt.Sprintf("Detail(%v)", c.Detail))
+	x = append(x, fmt.Sprintf("Duration(%v)", c.Duration))
+	return strings.Join(x, ", ")
+}
+
+// ConformanceReport represents the outcome of the conformance suite for
+// one device.
+type ConformanceReport struct {
+	Serial   string        `json:"serial"`
+	Product  string        `json:"product"`
+	Board    string        `json:"board"`
+	Firmware string        `json:"firmware"`
+	Checks   []CheckResult `json:"checks"`
+}
+
+// Passed returns true if and only if no check failed.
+func (c ConformanceReport) Passed() bool {
+	for _, r := range c.Checks {
+		if r.Status == CheckFail {
+			return false
+		}
+	}
+	return true
+}
+
+// Conformance configures the conformance suite.
+type Conformance struct {
+	// Bitstream is used to check FPGA configuration.  If nil, then the
+	// configuration and stream checks are skipped.
+	Bitstream []byte
+
+	// Loopback indicates that the bitstream implements a loopback design,
+	// which sends back the data written to the stream, so that the stream
+	// check transfers and compares data.
+	Loopback bool
+
+	// StreamSize is the number of bytes transferred by the stream check.
+	// If zero, then 64 kiB are transferred.
+	StreamSize int
+}
+
+// Run exercises the device and reports the outcome of each check: reading
+// the descriptor and the configuration data area, the status requests,
+// FPGA configuration, GPIO, and streaming.  Apart from configuring the
+// FPGA, the checks leave the state of the device unchanged.
+func (c *Conformance) Run(ctx context.Context, d *ztex.Device) *ConformanceReport {
+	r := &ConformanceReport{
+		Serial:   d.Serial().String(),
+		Product:  d.DescriptorConfig.DescriptorProduct.String(),
+		Board:    fmt.Sprintf("%v %v", d.BoardConfig.BoardType, d.BoardConfig.BoardVersion),
+		Firmware: fmt.Sprint(d.DescriptorConfig.DescriptorFirmware),
+	}
+
+	x := []struct {
+		name  string
+		check func(context.Context, *ztex.Device) (CheckStatus, string)
+	}{
+		{"descriptor", c.checkDescriptor},
+		{"config", c.checkConfig},
+		{"fpga-status", c.checkFPGAStatus},
+		{"flash-status", c.checkFlashStatus},
+		{"sensor-status", c.checkSensorStatus},
+		{"configure", c.checkConfigure},
+		{"gpio", c.checkGPIO},
+		{"stream", c.checkStream},
+	}
+	for _, k := range x {
+		t := time.Now()
+		s, detail := k.check(ctx, d)
+		r.Checks = append(r.Checks, CheckResult{k.name, s, detail, time.Since(t)})
+	}
+
+	return r
+}
+
+// result turns an error into the outcome of a check.
+func result(err error) (CheckStatus, string) {
+	if err != nil {
+		return CheckFail, err.Error()
+	}
+	return CheckPass, ""
+}
+
+func (c *Conformance) checkDescriptor(ctx context.Context, d *ztex.Device) (CheckStatus, string) {
+	b := make([]byte, 40)
+	if n, err := d.Control(0xc0, 0x22, 0, 0, b); err != nil {
+		return result(err)
+	} else if n != 40 {
+		return CheckFail, fmt.Sprintf("got %v bytes, want %v bytes", n, 40)
+	}
+
+	x, err := ztex.ParseDescriptorConfig(b)
+	if err != nil {
+		return result(err)
+	} else if x.DescriptorMagic.String() != "ZTEX" {
+		return CheckFail, fmt.Sprintf("got magic %q, want magic %q", x.DescriptorMagic, "ZTEX")
+	} else if x != d.DescriptorConfig {
+		return CheckFail, fmt.Sprintf("got descriptor %v, want descriptor %v", x, d.DescriptorConfig)
+	}
+	return CheckPass, ""
+}
+
+func (c *Conformance) checkConfig(ctx context.Context, d *ztex.Device) (CheckStatus, string) {
+	if !d.Capability().MACEEPROM() {
+		return CheckSkip, "no MAC EEPROM"
+	}
+
+	b := make([]byte, 128)
+	if err := d.ReadMACEEPROM(0, b); err != nil {
+		return result(err)
+	}
+	x, err := ztex.ParseBoardConfigBlock(b)
+	if err != nil {
+		return result(err)
+	} else if !bytes.Equal(x.Bytes(), b) {
+		return CheckFail, "configuration data area does not encode to the bytes it was decoded from"
+	} else if x.DeviceConfig.BoardConfig != d.BoardConfig || x.DeviceConfig.FPGAConfig != d.FPGAConfig || x.DeviceConfig.RAMConfig != d.RAMConfig {
+		return CheckFail, fmt.Sprintf("got configuration %v, want the configuration read when the device was opened", x.DeviceConfig)
+	} else if err := x.Validate().Err(); err != nil {
+		return result(err)
+	}
+	return CheckPass, x.Validate().String()
+}
+
+func (c *Conformance) checkFPGAStatus(ctx context.Context, d *ztex.Device) (CheckStatus, string) {
+	if !d.Capability().FPGAConfiguration() {
+		return CheckSkip, "no FPGA configuration support"
+	}
+	_, err := d.FPGAStatus()
+	return result(err)
+}
+
+func (c *Conformance) checkFlashStatus(ctx context.Context, d *ztex.Device) (CheckStatus, string) {
+	if !d.Capability().FlashMemory() {
+		return CheckSkip, "no flash memory support"
+	}
+	s, err := d.FlashStatus()
+	if err != nil {
+		return result(err)
+	} else if s.FlashEnabled != 1 {
+		return CheckFail, "flash memory is not enabled"
+	}
+	return CheckPass, ""
+}
+
+func (c *Conformance) checkSensorStatus(ctx context.Context, d *ztex.Device) (CheckStatus, string) {
+	if !d.Capability().TemperatureSensor() {
+		return CheckSkip, "no temperature sensor"
+	}
+	_, err := d.SensorStatus()
+	return result(err)
+}
+
+func (c *Conformance) checkConfigure(ctx context.Context, d *ztex.Device) (CheckStatus, string) {
+	if !d.Capability().FPGAConfiguration() {
+		return CheckSkip, "no FPGA configuration support"
+	} else if c.Bitstream == nil {
+		return CheckSkip, "no bitstream"
+	}
+
+	if err := d.ConfigureFPGA(ctx, bytes.NewReader(c.Bitstream), nil); err != nil {
+		return result(err)
+	}
+	s, err := d.FPGAStatus()
+	if err != nil {
+		return result(err)
+	} else if !s.FPGAConfigured.Bool() {
+		return CheckFail, "FPGA is not configured after configuration"
+	}
+	return CheckPass, ""
+}
+
+func (c *Conformance) checkGPIO(ctx context.Context, d *ztex.Device) (CheckStatus, string) {
+	if !d.Capability().DefaultFirmware() {
+		return CheckSkip, "no default firmware"
+	}
+
+	// A zero mask reads the pins without changing them.
+	a, err := d.GPIO(0, 0)
+	if err != nil {
+		return result(err)
+	}
+	b, err := d.GPIO(0, 0)
+	if err != nil {
+		return result(err)
+	} else if a&0x0f != b&0x0f {
+		return CheckFail, fmt.Sprintf("got GPIO state %#02x, then %#02x, want the same state", a, b)
+	}
+	return CheckPass, ""
+}
+
+func (c *Conformance) checkStream(ctx context.Context, d *ztex.Device) (CheckStatus, string) {
+	if !d.Capability().DefaultFirmware() {
+		return CheckSkip, "no default firmware"
+	} else if c.Bitstream == nil {
+		return CheckSkip, "no bitstream"
+	} else if d.Desc == nil {
+		return CheckSkip, "no bulk endpoints"
+	}
+
+	s, err := d.OpenStream()
+	if err != nil {
+		return result(err)
+	}
+	defer s.Close()
+
+	if !c.Loopback {
+		return CheckPass, "opened stream without loopback design"
+	}
+
+	n := c.StreamSize
+	if n == 0 {
+		n = 64 << 10
+	}
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = uint8(i*7 + i>>8)
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := s.WriteContext(ctx, b)
+		errc <- err
+	}()
+	x := make([]byte, n)
+	if _, err := io.ReadFull(readerContext{ctx, s}, x); err != nil {
+		return result(err)
+	}
+	if err := <-errc; err != nil {
+		return result(err)
+	}
+	if !bytes.Equal(x, b) {
+		return CheckFail, "data read from the loopback design differs from the data written"
+	}
+	return CheckPass, ""
+}
+
+// readerContext reads from a stream, aborting when the context is done.
+type readerContext struct {
+	ctx context.Context
+	s   *ztex.Stream
+}
+
+func (r readerContext) Read(p []byte) (int, error) { return r.s.ReadContext(r.ctx, p) }
@@ -0,0 +1,92 @@
+package ztextest
+
+import "fmt"
+
+// FakeDebug simulates the debug stack of the firmware, which holds the
+// most recent messages written by the firmware.  It is operated by a fake
+// device whose Debug field points to it, which is announced by setting bit
+// 3 of Descriptor[12] for the debug helper, and bit 3 of Descriptor[13]
+// for the advanced debug helper.
+type FakeDebug struct {
+	// StackSize is the number of messages the stack holds, and
+	// MessageSize is the number of bytes in a message.
+	StackSize   int
+	MessageSize int
+
+	// Counter is the number of messages written since the firmware
+	// started, modulo 65536.
+	Counter uint16
+
+	// messages holds the most recent messages, oldest first.
+	messages [][]byte
+}
+
+// NewFakeDebug returns an empty fake debug stack of the given number of
+// messages of the given size.
+func NewFakeDebug(stackSize, messageSize int) *FakeDebug {
+	return &FakeDebug{StackSize: stackSize, MessageSize: messageSize}
+}
+
+// WriteDebug writes a message to the debug stack of the device, as the
+// firmware would.  The message is truncated or padded with zero bytes to
+// the message size.
+func (f *FakeDevice) WriteDebug(message []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	x := f.Debug
+	m := make([]byte, x.MessageSize)
+	copy(m, message)
+	x.messages = append(x.messages, m)
+	if len(x.messages) > x.StackSize {
+		x.messages = x.messages[len(x.messages)-x.StackSize:]
+	}
+	x.Counter++
+}
+
+// debugControl handles the requests of the debug helper, and reports
+// false for other requests.
+func (f *FakeDevice) debugControl(in bool, request uint8, val, idx uint16, data []byte) (int, bool, error) {
+	x := f.Debug
+	if x == nil {
+		return 0, false, nil
+	}
+
+	switch {
+	// VR 0x28: debug helper: read debug stack
+	case in && request == 0x28:
+		if x.StackSize > 0xff || x.MessageSize > 0xff {
+			return 0, true, fmt.Errorf("%w: got stack of %v messages of %v bytes, want at most 255 of each", ErrStall, x.StackSize, x.MessageSize)
+		}
+		b := []byte{uint8(x.Counter), uint8(x.Counter >> 8), uint8(x.StackSize), uint8(x.MessageSize)}
+		for i := len(x.messages) - 1; i >= 0; i-- {
+			b = append(b, x.messages[i]...)
+		}
+		return copy(data, b), true, nil
+	// VC 0x29: debug helper: reset debug stack
+	case !in && request == 0x29:
+		x.Counter, x.messages = 0, nil
+		return 0, true, nil
+
+	// VR 0x2a: advanced debug helper: read debug messages
+	case in && request == 0x2a:
+		// The messages are returned from sequence val on, or from the
+		// oldest message held if it was written after val.
+		o := x.Counter - uint16(len(x.messages))
+		q := val
+		if int16(q-o) < 0 {
+			q = o
+		}
+		b := []byte{uint8(x.Counter), uint8(x.Counter >> 8), uint8(x.StackSize), uint8(x.StackSize >> 8), uint8(x.MessageSize), uint8(x.MessageSize >> 8), uint8(q), uint8(q >> 8)}
+		for i := int(q - o); i < len(x.messages) && len(b)+x.MessageSize <= len(data); i++ {
+			b = append(b, x.messages[i]...)
+		}
+		return copy(data, b), true, nil
+	// VC 0x2b: advanced debug helper: reset debug messages
+	case !in && request == 0x2b:
+		x.Counter, x.messages = 0, nil
+		return 0, true, nil
+	}
+
+	return 0, false, nil
+}
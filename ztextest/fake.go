@@ -0,0 +1,347 @@
+// Package ztextest provides a simulated ZTEX module for testing code built
+// on package ztex without hardware.
+package ztextest
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/aljumi/ztex"
+)
+
+// ErrStall is returned by a fake device for requests which it does not
+// implement, much like real firmware stalls the control endpoint.
+var ErrStall = errors.New("ztextest: control endpoint stalled")
+
+// FakeDevice simulates the firmware of a ZTEX module at the level of
+// control transfers.  It implements ztex.Controller, and is safe for
+// concurrent use.
+//
+// The descriptor, the configuration data area, the EEPROM, and the flash
+// are held in memory and may be inspected and modified through the
+// exported fields before the device is opened.  FPGA configuration
+// succeeds if the transferred bitstream contains the bit-swapped Xilinx
+// synchronization word.  An XMEGA and the debug stack are simulated if the
+// XMEGA and Debug fields are set; see FakeXMEGA and FakeDebug.
+//
+// When the device is attached to the simulated bus of a Backend, the bulk
+// endpoints of the default firmware interface lead to a simulated FPGA
+// design: by default a loopback design, which returns the data written to
+// it through a FIFO of FIFOSize bytes, so that writes block while the FIFO
+// is full and reads block while it is empty, like a loopback design on a
+// real module.  High-speed FPGA configuration through bulk endpoint
+// HighSpeedEndpoint is simulated as well, but not announced in the
+// descriptor, since devices opened with Open have no bulk endpoints; set
+// bit 5 of Descriptor[12] to announce it.
+type FakeDevice struct {
+	mu sync.Mutex
+
+	// Descriptor holds the 40-byte ZTEX descriptor.
+	Descriptor []byte
+
+	// MACEEPROM holds the MAC EEPROM, which starts with the 128-byte
+	// configuration data area.
+	MACEEPROM []byte
+
+	// EEPROM holds the firmware EEPROM.
+	EEPROM []byte
+
+	// Flash holds the flash, which is divided into sectors of
+	// FlashSectorSize bytes.
+	Flash           []byte
+	FlashSectorSize int
+
+	// FlashError is reported as the error code of the flash.
+	FlashError uint8
+
+	// Sensors holds the sensor data reported by the temperature sensor
+	// support, starting with the protocol, or nil if the device has no
+	// sensors.  Sensors are announced by setting bit 0 of Descriptor[13].
+	Sensors []byte
+
+	// GPIO holds the state of the general purpose I/O pins.
+	GPIO uint8
+
+	// Loopback selects the simulated design.  If true, then the design
+	// returns the data written to it; otherwise it discards the data
+	// written to it and produces a counting byte pattern, so that the
+	// bulk endpoints can be used independently.
+	Loopback bool
+
+	// FIFOSize is the number of bytes the loopback design buffers.
+	FIFOSize int
+
+	// FPGAs is the number of FPGAs.  If it is more than one, then
+	// multi-FPGA support is simulated, which is announced by setting bit 7
+	// of Descriptor[12], and the data written to a counting design is
+	// recorded per FPGA; see FPGAData.
+	FPGAs int
+
+	// XMEGA is the simulated XMEGA, or nil if the device has none.
+	XMEGA *FakeXMEGA
+
+	// Debug is the simulated debug stack, or nil if the device has none.
+	Debug *FakeDebug
+
+	bitstream []byte
+	errors    map[uint8]error
+	selected  int
+	fpgaData  map[int][]byte
+	calls     map[uint8]int
+
+	fifo    []byte
+	changed chan struct{}
+	bulkOut int64
+	bulkIn  int64
+
+	// hs holds the sizes of the bulk transfers of the high-speed FPGA
+	// configuration in progress, or nil if none is in progress.
+	hs   []int
+	hsOK []int
+}
+
+// NewFakeDevice returns a fake device with the given serial number, of up
+// to 10 characters, which resembles a ZTEX USB-FPGA Module 2.13 with the
+// default firmware: an Artix-7 XC7A35T FPGA, 256 MiB of DDR3 SDRAM, a
+// 64 kiB EEPROM, a MAC EEPROM, and 16 MiB of flash in 64 kiB sectors, of
+// which the first 4 MiB are reserved for the bitstream.
+func NewFakeDevice(serial string) *FakeDevice {
+	d := &FakeDevice{
+		Descriptor:      make([]byte, 40),
+		MACEEPROM:       make([]byte, 256),
+		EEPROM:          make([]byte, 1<<16),
+		Flash:           make([]byte, 1<<24),
+		FlashSectorSize: 1 << 16,
+		Loopback:        true,
+		FIFOSize:        DefaultFIFOSize,
+		errors:          map[uint8]error{},
+		calls:           map[uint8]int{},
+	}
+	for i := range d.Flash {
+		d.Flash[i] = 0xff
+	}
+
+	copy(d.Descriptor, []byte{40, 1, 'Z', 'T', 'E', 'X', 10, 17, 0, 0, 0, 1})
+	// EEPROM, FPGA configuration, flash memory, and MAC EEPROM support,
+	// and the default firmware interface.
+	copy(d.Descriptor[12:], []byte{0x47, 0x10})
+	// Version 2 of the default firmware interface, with bulk endpoints 2
+	// and 6.
+	copy(d.Descriptor[18:], []byte{2, 2, 6})
+	copy(d.Descriptor[30:], serial)
+
+	copy(d.MACEEPROM, []byte{'C', 'D', '0', 2, 2, 13, 'a', 0, 8, 0, 2, '1', 'C', 0, 0x18, 10})
+	copy(d.MACEEPROM[16:], serial)
+	copy(d.MACEEPROM[26:], []byte{0, 0, 0, 4, 0, 0})
+
+	return d
+}
+
+// Open returns a ztex.Device operated through a fake device.
+func Open(f *FakeDevice, opt ...ztex.DeviceOption) (*ztex.Device, error) {
+	return ztex.NewDevice(f, opt...)
+}
+
+// Fail makes every later request with the given code fail with err, or
+// succeed again if err is nil.
+func (f *FakeDevice) Fail(request uint8, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err == nil {
+		delete(f.errors, request)
+	} else {
+		f.errors[request] = err
+	}
+}
+
+// Calls returns the number of requests with the given code received so
+// far.
+func (f *FakeDevice) Calls(request uint8) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.calls[request]
+}
+
+// FPGAData returns the data written to the counting design of FPGA i of
+// a multi-FPGA device, that is, while FPGA i was selected.
+func (f *FakeDevice) FPGAData(i int) []byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]byte{}, f.fpgaData[i]...)
+}
+
+// Bitstream returns the configuration data received since the last FPGA
+// reset.
+func (f *FakeDevice) Bitstream() []byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]byte{}, f.bitstream...)
+}
+
+// Control implements ztex.Controller.
+func (f *FakeDevice) Control(rType, request uint8, val, idx uint16, data []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls[request]++
+	if err := f.errors[request]; err != nil {
+		return 0, err
+	}
+
+	in := rType&0x80 != 0
+	if n, ok, err := f.xmegaControl(in, request, val, idx, data); ok {
+		return n, err
+	} else if n, ok, err := f.debugControl(in, request, val, idx, data); ok {
+		return n, err
+	}
+
+	switch {
+	// VR 0x22: ZTEX descriptor: read ZTEX descriptor
+	case in && request == 0x22:
+		return copy(data, f.Descriptor), nil
+
+	// VR 0x30: FPGA configuration: get FPGA state
+	case in && request == 0x30:
+		b := f.fpgaState()
+		return copy(data, b[:]), nil
+	// VC 0x31: FPGA configuration: reset FPGA
+	case !in && request == 0x31:
+		f.bitstream = nil
+		return 0, nil
+	// VC 0x32: FPGA configuration: send FPGA configuration data
+	case !in && request == 0x32:
+		f.bitstream = append(f.bitstream, data...)
+		return len(data), nil
+
+	// VR 0x33: high-speed FPGA configuration: get endpoint and interface
+	case in && request == 0x33:
+		return copy(data, []byte{HighSpeedEndpoint, 0}), nil
+	// VC 0x34: high-speed FPGA configuration: start
+	case !in && request == 0x34:
+		f.hs = []int{}
+		return 0, nil
+	// VC 0x35: high-speed FPGA configuration: finish
+	case !in && request == 0x35:
+		f.hs, f.hsOK = nil, f.hs
+		return 0, nil
+
+	// VR 0x38: EEPROM support: read from EEPROM
+	case in && request == 0x38:
+		return f.read(f.EEPROM, int(val), data)
+	// VC 0x39: EEPROM support: write to EEPROM
+	case !in && request == 0x39:
+		return f.write(f.EEPROM, int(val), data)
+	// VR 0x3a: EEPROM support: get EEPROM state
+	case in && request == 0x3a:
+		return copy(data, []byte{0, 0, 0, 0}), nil
+
+	// VR 0x3b: MAC EEPROM support: read from MAC EEPROM
+	case in && request == 0x3b:
+		return f.read(f.MACEEPROM, int(val), data)
+	// VC 0x3c: MAC EEPROM support: write to MAC EEPROM
+	case !in && request == 0x3c:
+		return f.write(f.MACEEPROM, int(val), data)
+	// VR 0x3d: MAC EEPROM support: get MAC EEPROM state
+	case in && request == 0x3d:
+		return copy(data, []byte{0, 0, 0, 0}), nil
+
+	// VR 0x40: flash memory support: get flash state
+	case in && request == 0x40:
+		return copy(data, f.flashState()), nil
+	// VR 0x41: flash memory support: read sector
+	case in && request == 0x41:
+		return f.read(f.Flash, f.sector(val, idx, data), data)
+	// VC 0x42: flash memory support: write sector
+	case !in && request == 0x42:
+		return f.write(f.Flash, f.sector(val, idx, data), data)
+
+	// VR 0x50: multi-FPGA support: get multi-FPGA information
+	case in && request == 0x50 && f.FPGAs > 1:
+		return copy(data, []byte{uint8(f.FPGAs - 1), uint8(f.selected), 0}), nil
+	// VC 0x51: multi-FPGA support: select FPGA
+	case !in && request == 0x51 && f.FPGAs > 1 && idx == 0:
+		if int(val) >= f.FPGAs {
+			return 0, fmt.Errorf("%w: got FPGA %v, want FPGA in [0, %v)", ErrStall, val, f.FPGAs)
+		}
+		f.selected = int(val)
+		return 0, nil
+
+	// VR 0x58: temperature sensor support: read sensors
+	case in && request == 0x58 && f.Sensors != nil:
+		return copy(data, f.Sensors), nil
+
+	// VC 0x60: default firmware interface: reset
+	case !in && request == 0x60:
+		return 0, nil
+	// VR 0x61: default firmware interface: set and get GPIO
+	case in && request == 0x61:
+		f.GPIO = f.GPIO&^uint8(val) | uint8(idx)&uint8(val)
+		return copy(data, []byte{f.GPIO}), nil
+
+	default:
+		return 0, fmt.Errorf("%w: request %#02x", ErrStall, request)
+	}
+}
+
+// fpgaState encodes the FPGA state reported by VR 0x30.
+func (f *FakeDevice) fpgaState() [9]byte {
+	b := [9]byte{}
+	b[0] = 1
+	for i := 0; i+4 <= len(f.bitstream); i++ {
+		if f.bitstream[i] == 0x55 && f.bitstream[i+1] == 0x99 && f.bitstream[i+2] == 0xaa && f.bitstream[i+3] == 0x66 {
+			b[0] = 0
+			break
+		}
+	}
+	for _, c := range f.bitstream {
+		b[1] += c
+	}
+	n := uint32(len(f.bitstream))
+	b[2], b[3], b[4], b[5] = uint8(n), uint8(n>>8), uint8(n>>16), uint8(n>>24)
+	b[6], b[8] = 1, 1
+	return b
+}
+
+// flashState encodes the flash state reported by VR 0x40.
+func (f *FakeDevice) flashState() []byte {
+	z := uint16(f.FlashSectorSize)
+	if f.FlashSectorSize > 0xffff {
+		for i := uint16(0); i < 32; i++ {
+			if 1<<i == f.FlashSectorSize {
+				z = 0x8000 | i
+			}
+		}
+	}
+	n := uint32(len(f.Flash) / f.FlashSectorSize)
+	return []byte{1, uint8(z), uint8(z >> 8), uint8(n), uint8(n >> 8), uint8(n >> 16), uint8(n >> 24), f.FlashError}
+}
+
+// sector returns the offset of the flash sector addressed by a request,
+// or -1 if the request does not transfer exactly one sector.
+func (f *FakeDevice) sector(val, idx uint16, data []byte) int {
+	if len(data) != f.FlashSectorSize {
+		return -1
+	}
+	return (int(val) | int(idx)<<16) * f.FlashSectorSize
+}
+
+// read copies memory at the given offset into data.
+func (f *FakeDevice) read(m []byte, off int, data []byte) (int, error) {
+	if off < 0 || off+len(data) > len(m) {
+		return 0, fmt.Errorf("%w: got %v bytes at offset %v, want bytes in [0, %v)", ErrStall, len(data), off, len(m))
+	}
+	return copy(data, m[off:]), nil
+}
+
+// write copies data into memory at the given offset.
+func (f *FakeDevice) write(m []byte, off int, data []byte) (int, error) {
+	if off < 0 || off+len(data) > len(m) {
+		return 0, fmt.Errorf("%w: got %v bytes at offset %v, want bytes in [0, %v)", ErrStall, len(data), off, len(m))
+	}
+	return copy(m[off:], data), nil
+}
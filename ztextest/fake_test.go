@@ -0,0 +1,141 @@
+package ztextest_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aljumi/ztex"
+	"github.com/aljumi/ztex/ztextest"
+)
+
+// bitstream returns a raw bitstream of n bytes with the synchronization
+// word, which the fake device accepts.
+func bitstream(n int) []byte {
+	b := bytes.Repeat([]byte{0xff}, n)
+	copy(b[16:], []byte{0xaa, 0x99, 0x55, 0x66})
+	return b
+}
+
+func TestFakeDevice(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		f    func(*ztextest.FakeDevice, *ztex.Device) error
+	}{
+		{"descriptor", func(f *ztextest.FakeDevice, d *ztex.Device) error {
+			if got := d.Serial().String(); got != "fake000001" {
+				return errors.New("got serial number " + got + ", want fake000001")
+			} else if !d.Capability().FlashMemory() || d.Capability().MultiFPGA() {
+				return errors.New("got capabilities " + d.Capability().String() + ", want flash memory and no multi-FPGA support")
+			}
+			return nil
+		}},
+		{"EEPROM", func(f *ztextest.FakeDevice, d *ztex.Device) error {
+			if err := d.WriteEEPROM(0x100, []byte("ztex")); err != nil {
+				return err
+			}
+			b := make([]byte, 4)
+			if err := d.ReadEEPROM(0x100, b); err != nil {
+				return err
+			} else if string(b) != "ztex" || string(f.EEPROM[0x100:0x104]) != "ztex" {
+				return errors.New("got EEPROM contents " + string(b) + ", want ztex")
+			}
+			return nil
+		}},
+		{"MAC EEPROM", func(f *ztextest.FakeDevice, d *ztex.Device) error {
+			b := make([]byte, 3)
+			if err := d.ReadMACEEPROM(0, b); err != nil {
+				return err
+			} else if string(b) != "CD0" {
+				return errors.New("got signature " + string(b) + ", want CD0")
+			}
+			return nil
+		}},
+		{"flash", func(f *ztextest.FakeDevice, d *ztex.Device) error {
+			b := bytes.Repeat([]byte{0x5a}, f.FlashSectorSize)
+			if err := d.WriteFlash(context.Background(), 3, b, nil); err != nil {
+				return err
+			}
+			c := make([]byte, len(b))
+			if err := d.ReadFlash(context.Background(), 3, c, nil); err != nil {
+				return err
+			} else if !bytes.Equal(c, b) || !bytes.Equal(f.Flash[3*len(b):4*len(b)], b) {
+				return errors.New("got different flash contents, want the sector written")
+			}
+			return nil
+		}},
+		{"FPGA configuration", func(f *ztextest.FakeDevice, d *ztex.Device) error {
+			if err := d.ConfigureFPGA(context.Background(), bytes.NewReader(bitstream(1<<12)), nil); err != nil {
+				return err
+			} else if s, err := d.FPGAStatus(); err != nil {
+				return err
+			} else if !s.FPGAConfigured.Bool() || len(f.Bitstream()) == 0 {
+				return errors.New("got unconfigured FPGA, want configured FPGA")
+			}
+			return nil
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f := ztextest.NewFakeDevice("fake000001")
+			d, err := ztextest.Open(f)
+			if err != nil {
+				t.Fatalf("ztextest.Open: %v", err)
+			}
+			defer d.Close()
+
+			if err := tc.f(f, d); err != nil {
+				t.Errorf("%v: %v", tc.name, err)
+			}
+		})
+	}
+}
+
+func TestFakeDeviceErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		fail uint8
+		f    func(*ztex.Device) error
+	}{
+		// VR 0x38: EEPROM support: read from EEPROM
+		{"EEPROM out of range", 0, func(d *ztex.Device) error { return d.ReadEEPROM(0xfffe, make([]byte, 4)) }},
+		{"EEPROM stalled", 0x38, func(d *ztex.Device) error { return d.ReadEEPROM(0, make([]byte, 4)) }},
+		// VR 0x30: FPGA configuration: get FPGA state
+		{"FPGA status stalled", 0x30, func(d *ztex.Device) error { _, err := d.FPGAStatus(); return err }},
+		// VR 0x41: flash memory support: read sector
+		{"flash stalled", 0x41, func(d *ztex.Device) error {
+			return d.ReadFlash(context.Background(), 0, make([]byte, 1<<16), nil)
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f := ztextest.NewFakeDevice("fake000001")
+			d, err := ztextest.Open(f)
+			if err != nil {
+				t.Fatalf("ztextest.Open: %v", err)
+			}
+			defer d.Close()
+
+			if tc.fail != 0 {
+				f.Fail(tc.fail, ztextest.ErrStall)
+			}
+			err = tc.f(d)
+			c := (*ztex.CommandError)(nil)
+			if !errors.Is(err, ztextest.ErrStall) || !errors.As(err, &c) {
+				t.Errorf("%v: got error %v, want a *ztex.CommandError wrapping ztextest.ErrStall", tc.name, err)
+			}
+			if tc.fail != 0 && f.Calls(tc.fail) == 0 {
+				t.Errorf("(*ztextest.FakeDevice).Calls(%#02x): got 0 calls, want at least 1", tc.fail)
+			}
+		})
+	}
+
+	// An invalid bitstream leaves the FPGA unconfigured.
+	d, err := ztextest.Open(ztextest.NewFakeDevice("fake000001"))
+	if err != nil {
+		t.Fatalf("ztextest.Open: %v", err)
+	}
+	defer d.Close()
+	if err := d.ConfigureFPGA(context.Background(), bytes.NewReader(make([]byte, 1<<12)), nil); err == nil {
+		t.Errorf("(*ztex.Device).ConfigureFPGA with invalid bitstream: got no error, want error")
+	}
+}
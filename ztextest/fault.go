@@ -0,0 +1,159 @@
+package ztextest
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/aljumi/ztex"
+)
+
+// Fault describes a fault injected into the control transfers of a
+// controller.  A fault applies to the matching transfers selected by
+// Call, and its effects are applied in order: the delay, the error, which
+// replaces the transfer, and otherwise the short transfer and the garbage.
+type Fault struct {
+	// Requests are the requests which the fault matches.  If empty, then
+	// the fault matches all requests.
+	Requests []uint8
+
+	// Call selects the matching transfer to which the fault applies,
+	// starting at 1.  If zero, then the fault applies to all matching
+	// transfers.
+	Call int
+
+	// Delay is the time to wait before the transfer.
+	Delay time.Duration
+
+	// Err is returned instead of performing the transfer.
+	Err error
+
+	// Short is the number of bytes by which the transfer falls short of
+	// its length.  The data is still transferred in full.
+	Short int
+
+	// Garbage replaces the data received from the device by pseudo-random
+	// bytes, which are the same for every run.
+	Garbage bool
+}
+
+// matches returns true if and only if the fault matches the request.
+func (f Fault) matches(request uint8) bool {
+	if len(f.Requests) == 0 {
+		return true
+	}
+	for _, r := range f.Requests {
+		if r == request {
+			return true
+		}
+	}
+	return false
+}
+
+// FaultInjector wraps a controller and injects faults into its control
+// transfers, so that error handling can be exercised deterministically.
+// It is safe for concurrent use.
+type FaultInjector struct {
+	mu     sync.Mutex
+	c      ztex.Controller
+	faults []Fault
+	calls  []int
+	seed   uint32
+	clock  ztex.Clock
+}
+
+// InjectFaults returns a controller which forwards transfers to c and
+// injects the given faults.
+func InjectFaults(c ztex.Controller, f ...Fault) *FaultInjector {
+	return &FaultInjector{c: c, faults: f, calls: make([]int, len(f)), seed: 0x2545f491, clock: ztex.SystemClock}
+}
+
+// SetClock sets the clock on which delays are waited for.
+func (f *FaultInjector) SetClock(c ztex.Clock) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.clock = c
+}
+
+// Faults returns a device option which injects the given faults into the
+// transfers of a device, including those which initialize it.
+func Faults(f ...Fault) ztex.DeviceOption {
+	return ztex.WrapController(func(c ztex.Controller) ztex.Controller {
+		return InjectFaults(c, f...)
+	})
+}
+
+// Control implements ztex.Controller.
+func (f *FaultInjector) Control(rType, request uint8, val, idx uint16, data []byte) (int, error) {
+	x, clock := f.active(request)
+
+	for _, a := range x {
+		if a.Delay > 0 {
+			clock.Sleep(a.Delay)
+		}
+	}
+	for _, a := range x {
+		if a.Err != nil {
+			return 0, a.Err
+		}
+	}
+
+	n, err := f.c.Control(rType, request, val, idx, data)
+	if err != nil {
+		return n, err
+	}
+
+	for _, a := range x {
+		if a.Short > 0 {
+			n -= a.Short
+			if n < 0 {
+				n = 0
+			}
+		}
+		if a.Garbage && rType&0x80 != 0 {
+			f.garbage(data[:n])
+		}
+	}
+	return n, nil
+}
+
+// active counts the transfer and returns the faults which apply to it,
+// along with the clock.
+func (f *FaultInjector) active(request uint8) ([]Fault, ztex.Clock) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	x := []Fault{}
+	for i, a := range f.faults {
+		if !a.matches(request) {
+			continue
+		}
+		f.calls[i]++
+		if a.Call == 0 || a.Call == f.calls[i] {
+			x = append(x, a)
+		}
+	}
+	return x, f.clock
+}
+
+// garbage fills b with pseudo-random bytes.
+func (f *FaultInjector) garbage(b []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i := range b {
+		f.seed ^= f.seed << 13
+		f.seed ^= f.seed >> 17
+		f.seed ^= f.seed << 5
+		b[i] = uint8(f.seed)
+	}
+}
+
+// Close closes the wrapped controller, if it can be closed.
+func (f *FaultInjector) Close() error {
+	if x, ok := f.c.(io.Closer); ok {
+		return x.Close()
+	}
+	return nil
+}
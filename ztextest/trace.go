@@ -0,0 +1,313 @@
+package ztextest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/aljumi/ztex"
+)
+
+// ErrReplayMismatch is returned by a replayer when a request differs from
+// the next exchange in the trace.
+var ErrReplayMismatch = errors.New("ztextest: request does not match trace")
+
+// Exchange represents one transfer in a trace.  Kind is "control",
+// "bulk-in", or "bulk-out".  For control transfers, Length is the size of
+// the data stage requested by the host.  Data holds the bytes sent to the
+// device, or the bytes received from it, and N is the number of bytes
+// transferred.  Error holds the message of the error of the transfer, if
+// any, Sentinel the name of a well-known error which it wraps, such as
+// "ErrNoDevice", and Command the failed request of a *ztex.CommandError
+// which it wraps, so that the replayed error matches the recorded one
+// with errors.Is and errors.As.
+type Exchange struct {
+	Kind    string `json:"kind"`
+	Type    uint8  `json:"type,omitempty"`
+	Request uint8  `json:"request,omitempty"`
+	Value   uint16 `json:"value,omitempty"`
+	Index   uint16 `json:"index,omitempty"`
+	Length  int    `json:"length"`
+	Data    []byte `json:"data,omitempty"`
+	N       int    `json:"n"`
+	Error   string `json:"error,omitempty"`
+
+	Sentinel string         `json:"sentinel,omitempty"`
+	Command  *CommandRecord `json:"command,omitempty"`
+}
+
+// CommandRecord holds the fields of a *ztex.CommandError in a trace,
+// except for the underlying error.
+type CommandRecord struct {
+	RequestType uint8  `json:"type"`
+	Request     uint8  `json:"request"`
+	Value       uint16 `json:"value"`
+	Index       uint16 `json:"index"`
+	Want        int    `json:"want"`
+	Got         int    `json:"got"`
+}
+
+// sentinels are the errors which are recorded in traces by name, in the
+// order in which they are looked for.
+var sentinels = []struct {
+	name string
+	err  error
+}{
+	{"ErrNotSupported", ztex.ErrNotSupported},
+	{"ErrBadDescriptor", ztex.ErrBadDescriptor},
+	{"ErrBadConfig", ztex.ErrBadConfig},
+	{"ErrNoDevice", ztex.ErrNoDevice},
+	{"ErrTimeout", ztex.ErrTimeout},
+	{"ErrStall", ErrStall},
+	{"ErrReplayMismatch", ErrReplayMismatch},
+	{"Canceled", context.Canceled},
+	{"DeadlineExceeded", context.DeadlineExceeded},
+	{"EOF", io.EOF},
+	{"ErrUnexpectedEOF", io.ErrUnexpectedEOF},
+}
+
+// setError records err in the exchange.
+func (e *Exchange) setError(err error) {
+	e.Error = err.Error()
+	for _, x := range sentinels {
+		if errors.Is(err, x.err) {
+			e.Sentinel = x.name
+			break
+		}
+	}
+	if c := (*ztex.CommandError)(nil); errors.As(err, &c) {
+		e.Command = &CommandRecord{c.RequestType, c.Request, c.Value, c.Index, c.Want, c.Got}
+	}
+}
+
+// replayedError is an error read from a trace.  It has the recorded
+// message and wraps the recorded sentinel and command error, if any.
+type replayedError struct {
+	msg string
+	err error
+}
+
+func (e *replayedError) Error() string { return e.msg }
+
+func (e *replayedError) Unwrap() error { return e.err }
+
+// String returns a human-readable description of the exchange.
+func (e Exchange) String() string {
+	if e.Kind == "control" {
+		return fmt.Sprintf("%v %#02x %#02x %#04x %#04x [%v]", e.Kind, e.Type, e.Request, e.Value, e.Index, e.Length)
+	}
+	return fmt.Sprintf("%v [%v]", e.Kind, e.Length)
+}
+
+// Recorder wraps a controller and writes every exchange with it to a
+// trace, one JSON object per line.  Streams can be recorded as well by
+// wrapping them with RecordStream.  It is safe for concurrent use.
+type Recorder struct {
+	mu  sync.Mutex
+	c   ztex.Controller
+	enc *json.Encoder
+	err error
+}
+
+// NewRecorder returns a recorder which forwards transfers to c and writes
+// the trace to w.
+func NewRecorder(c ztex.Controller, w io.Writer) *Recorder {
+	return &Recorder{c: c, enc: json.NewEncoder(w)}
+}
+
+// Err returns the first error which occurred while writing the trace.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.err
+}
+
+// record writes an exchange to the trace.
+func (r *Recorder) record(e Exchange, err error) {
+	if err != nil {
+		e.setError(err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err == nil {
+		r.err = r.enc.Encode(e)
+	}
+}
+
+// Control implements ztex.Controller.
+func (r *Recorder) Control(rType, request uint8, val, idx uint16, data []byte) (int, error) {
+	e := Exchange{Kind: "control", Type: rType, Request: request, Value: val, Index: idx, Length: len(data)}
+	if rType&0x80 == 0 {
+		e.Data = append([]byte{}, data...)
+	}
+
+	n, err := r.c.Control(rType, request, val, idx, data)
+	e.N = n
+	if rType&0x80 != 0 && n > 0 {
+		e.Data = append([]byte{}, data[:n]...)
+	}
+	r.record(e, err)
+
+	return n, err
+}
+
+// Close closes the wrapped controller, if it can be closed.
+func (r *Recorder) Close() error {
+	if x, ok := r.c.(io.Closer); ok {
+		return x.Close()
+	}
+	return nil
+}
+
+// RecordStream returns a stream which forwards to s and records its
+// transfers as bulk exchanges.
+func (r *Recorder) RecordStream(s io.ReadWriter) io.ReadWriter {
+	return &recordedStream{r, s}
+}
+
+type recordedStream struct {
+	r *Recorder
+	s io.ReadWriter
+}
+
+func (s *recordedStream) Read(p []byte) (int, error) {
+	n, err := s.s.Read(p)
+	s.r.record(Exchange{Kind: "bulk-in", Length: len(p), Data: append([]byte{}, p[:n]...), N: n}, err)
+	return n, err
+}
+
+func (s *recordedStream) Write(p []byte) (int, error) {
+	n, err := s.s.Write(p)
+	s.r.record(Exchange{Kind: "bulk-out", Length: len(p), Data: append([]byte{}, p...), N: n}, err)
+	return n, err
+}
+
+// Replayer serves the exchanges of a trace written by a recorder, in
+// order, in place of a device.  Requests which differ from the next
+// exchange in the trace fail with ErrReplayMismatch.  It is safe for
+// concurrent use.
+type Replayer struct {
+	mu sync.Mutex
+	x  []Exchange
+	i  int
+}
+
+// NewReplayer reads a trace from r.
+func NewReplayer(r io.Reader) (*Replayer, error) {
+	x := []Exchange{}
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1<<24)
+	for s.Scan() {
+		if len(bytes.TrimSpace(s.Bytes())) == 0 {
+			continue
+		}
+		e := Exchange{}
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("json.Unmarshal: exchange %v: %w", len(x), err)
+		}
+		x = append(x, e)
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("(*bufio.Scanner).Scan: %w", err)
+	}
+
+	return &Replayer{x: x}, nil
+}
+
+// Remaining returns the number of exchanges which have not been replayed.
+func (r *Replayer) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.x) - r.i
+}
+
+// next returns the next exchange if it matches want, which is compared
+// except for the transferred data and results.
+func (r *Replayer) next(want Exchange) (Exchange, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.i == len(r.x) {
+		return Exchange{}, fmt.Errorf("%w: got %v, want end of trace", ErrReplayMismatch, want)
+	}
+	e := r.x[r.i]
+	if e.Kind != want.Kind || e.Type != want.Type || e.Request != want.Request || e.Value != want.Value || e.Index != want.Index || e.Length != want.Length {
+		return Exchange{}, fmt.Errorf("%w: exchange %v: got %v, want %v", ErrReplayMismatch, r.i, want, e)
+	}
+	if want.Data != nil && !bytes.Equal(e.Data, want.Data) {
+		return Exchange{}, fmt.Errorf("%w: exchange %v: got data % x, want data % x", ErrReplayMismatch, r.i, want.Data, e.Data)
+	}
+	r.i++
+
+	return e, nil
+}
+
+// result returns the recorded outcome of an exchange.
+func (e Exchange) result() (int, error) {
+	if e.Error == "" {
+		return e.N, nil
+	}
+	var err error
+	for _, x := range sentinels {
+		if x.name == e.Sentinel {
+			err = x.err
+		}
+	}
+	if c := e.Command; c != nil {
+		err = &ztex.CommandError{RequestType: c.RequestType, Request: c.Request, Value: c.Value, Index: c.Index, Want: c.Want, Got: c.Got, Err: err}
+	}
+	return e.N, &replayedError{e.Error, err}
+}
+
+// Control implements ztex.Controller.
+func (r *Replayer) Control(rType, request uint8, val, idx uint16, data []byte) (int, error) {
+	w := Exchange{Kind: "control", Type: rType, Request: request, Value: val, Index: idx, Length: len(data)}
+	if rType&0x80 == 0 {
+		w.Data = append([]byte{}, data...)
+		if len(w.Data) == 0 {
+			w.Data = nil
+		}
+	}
+
+	e, err := r.next(w)
+	if err != nil {
+		return 0, err
+	}
+	if rType&0x80 != 0 {
+		copy(data, e.Data)
+	}
+	return e.result()
+}
+
+// Stream returns a stream which replays the bulk exchanges of the trace.
+func (r *Replayer) Stream() io.ReadWriter {
+	return replayedStream{r}
+}
+
+type replayedStream struct{ r *Replayer }
+
+func (s replayedStream) Read(p []byte) (int, error) {
+	e, err := s.r.next(Exchange{Kind: "bulk-in", Length: len(p)})
+	if err != nil {
+		return 0, err
+	}
+	copy(p, e.Data)
+	return e.result()
+}
+
+func (s replayedStream) Write(p []byte) (int, error) {
+	e, err := s.r.next(Exchange{Kind: "bulk-out", Length: len(p), Data: p})
+	if err != nil {
+		return 0, err
+	}
+	return e.result()
+}
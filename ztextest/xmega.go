@@ -0,0 +1,114 @@
+package ztextest
+
+import "fmt"
+
+// FakeXMEGA simulates the XMEGA of a module with XMEGA support, as seen
+// through the XMEGA support of the firmware.  It is operated by a fake
+// device whose XMEGA field points to it, which is announced by setting
+// bit 4 of Descriptor[12].
+type FakeXMEGA struct {
+	// Signature holds the device signature bytes.
+	Signature [3]uint8
+
+	// FlashPage and EEPROMPage are the page sizes reported by the
+	// firmware.  Pages written to the flash must be exactly FlashPage
+	// bytes long and aligned; pages written to the EEPROM must not cross
+	// a page boundary.
+	FlashPage  int
+	EEPROMPage int
+
+	// Flash holds the flash, of which the first Application bytes are the
+	// application section.
+	Flash       []byte
+	Application int
+
+	// EEPROM holds the EEPROM.
+	EEPROM []byte
+
+	// Fuses holds fuse bytes 0 to 5, a reserved byte, and the lock bits.
+	Fuses [8]uint8
+
+	// Error is reported as the error code of the XMEGA.
+	Error uint8
+}
+
+// NewFakeXMEGA returns an erased fake XMEGA which resembles an
+// ATxmega128A4U.
+func NewFakeXMEGA() *FakeXMEGA {
+	x := &FakeXMEGA{
+		Signature:   [3]uint8{0x1e, 0x97, 0x46},
+		FlashPage:   512,
+		EEPROMPage:  32,
+		Flash:       make([]byte, 136<<10),
+		Application: 128 << 10,
+		EEPROM:      make([]byte, 2<<10),
+		Fuses:       [8]uint8{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+	}
+	for i := range x.Flash {
+		x.Flash[i] = 0xff
+	}
+	for i := range x.EEPROM {
+		x.EEPROM[i] = 0xff
+	}
+	return x
+}
+
+// xmegaControl handles the requests of the XMEGA support, and reports
+// false for other requests.
+func (f *FakeDevice) xmegaControl(in bool, request uint8, val, idx uint16, data []byte) (int, bool, error) {
+	x := f.XMEGA
+	if x == nil {
+		return 0, false, nil
+	}
+
+	switch {
+	// VC 0x47: XMEGA support: erase XMEGA application section
+	case !in && request == 0x47:
+		for i := range x.Flash[:x.Application] {
+			x.Flash[i] = 0xff
+		}
+		return 0, true, nil
+	// VR 0x48: XMEGA support: get XMEGA state
+	case in && request == 0x48:
+		b := []byte{x.Error, 0, x.Signature[0], x.Signature[1], x.Signature[2], uint8(x.FlashPage), uint8(x.FlashPage >> 8), uint8(x.EEPROMPage), uint8(x.EEPROMPage >> 8)}
+		return copy(data, b), true, nil
+	// VC 0x49: XMEGA support: reset XMEGA
+	case !in && request == 0x49:
+		return 0, true, nil
+	// VR 0x4a: XMEGA support: read from XMEGA flash
+	case in && request == 0x4a:
+		n, err := f.read(x.Flash, int(val)|int(idx)<<16, data)
+		return n, true, err
+	// VC 0x4b: XMEGA support: write XMEGA flash page
+	case !in && request == 0x4b:
+		off := int(val) | int(idx)<<16
+		if len(data) != x.FlashPage || off%x.FlashPage != 0 {
+			return 0, true, fmt.Errorf("%w: got %v bytes at offset %v, want page of %v bytes", ErrStall, len(data), off, x.FlashPage)
+		}
+		n, err := f.write(x.Flash, off, data)
+		return n, true, err
+	// VR 0x4c: XMEGA support: read from XMEGA EEPROM
+	case in && request == 0x4c:
+		n, err := f.read(x.EEPROM, int(val), data)
+		return n, true, err
+	// VC 0x4d: XMEGA support: write XMEGA EEPROM page
+	case !in && request == 0x4d:
+		if int(val)/x.EEPROMPage != (int(val)+len(data)-1)/x.EEPROMPage {
+			return 0, true, fmt.Errorf("%w: got %v bytes at offset %v, want bytes within a page of %v bytes", ErrStall, len(data), val, x.EEPROMPage)
+		}
+		n, err := f.write(x.EEPROM, int(val), data)
+		return n, true, err
+	// VR 0x4e: XMEGA support: read XMEGA fuses
+	case in && request == 0x4e:
+		return copy(data, x.Fuses[:]), true, nil
+	// VC 0x4f: XMEGA support: write XMEGA fuse
+	case !in && request == 0x4f:
+		if int(idx) >= len(x.Fuses) {
+			return 0, true, fmt.Errorf("%w: got fuse %v, want fuse in [0, %v)", ErrStall, idx, len(x.Fuses))
+		}
+		x.Fuses[idx] = uint8(val)
+		return 0, true, nil
+	}
+
+	return 0, false, nil
+}